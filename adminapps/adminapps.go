@@ -0,0 +1,215 @@
+// Package adminapps provides handlers to process events about requests to install apps in workspaces
+// where admins must approve apps before they are installed.
+//
+// slack-go doesn't know these events, so the Router decodes them into the types in this package.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/app_requested
+//   - https://api.slack.com/admins/approvals
+package adminapps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// Types of events that this package processes.
+const (
+	// AppRequested is the type of events that are sent when a user requests to install an app.
+	AppRequested = "app_requested"
+
+	// AppApproved is the type of events that are sent when an admin approves a request.
+	AppApproved = "app_approved"
+
+	// AppRestricted is the type of events that are sent when an admin restricts a requested app.
+	AppRestricted = "app_restricted"
+)
+
+// Event is an inner event of any of AppRequested, AppApproved and AppRestricted.
+type Event struct {
+	Type string `json:"type"`
+
+	// AppRequest is the request to install the app. For AppApproved and AppRestricted, this is the request that is resolved.
+	AppRequest AppRequest `json:"app_request"`
+}
+
+// AppRequest is a request to install an app.
+type AppRequest struct {
+	ID  string `json:"id"`
+	App App    `json:"app"`
+
+	// PreviousResolution is how the previous request of the same app was resolved, if any.
+	PreviousResolution *Resolution `json:"previous_resolution,omitempty"`
+
+	// User is the user who requested the app.
+	User User `json:"user"`
+
+	// Team is the workspace in which the app is requested.
+	Team Team `json:"team"`
+
+	// Enterprise is the Enterprise Grid organization of the workspace, if any.
+	Enterprise *Enterprise `json:"enterprise,omitempty"`
+
+	Scopes []Scope `json:"scopes"`
+
+	// Message is the message that the user wrote to admins.
+	Message string `json:"message"`
+}
+
+// App is an app that is requested.
+type App struct {
+	ID                     string `json:"id"`
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	HelpURL                string `json:"help_url"`
+	PrivacyPolicyURL       string `json:"privacy_policy_url"`
+	AppHomepageURL         string `json:"app_homepage_url"`
+	AppDirectoryURL        string `json:"app_directory_url"`
+	IsAppDirectoryApproved bool   `json:"is_app_directory_approved"`
+	IsInternal             bool   `json:"is_internal"`
+	AdditionalInfo         string `json:"additional_info"`
+}
+
+// Resolution is how a request is resolved.
+type Resolution struct {
+	// Status is either `approved` or `restricted`.
+	Status string  `json:"status"`
+	Scopes []Scope `json:"scopes"`
+}
+
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type Team struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
+}
+
+type Enterprise struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Scope is an OAuth scope that the app requires.
+type Scope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsSensitive bool   `json:"is_sensitive"`
+	TokenType   string `json:"token_type"`
+}
+
+// Handler processes events about requests to install apps.
+type Handler interface {
+	HandleAppRequestEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleAppRequestEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type userPredicate struct {
+	id string
+}
+
+// RequestedBy is a predicate that is considered to be "true" if and only if the app is requested by the given user.
+func RequestedBy(id string) Predicate {
+	return &userPredicate{id: id}
+}
+
+func (p *userPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.AppRequest.User.ID != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleAppRequestEvent(ctx, e)
+	})
+}
+
+func (p *userPredicate) String() string {
+	return fmt.Sprintf("RequestedBy(%s)", p.id)
+}
+
+type teamPredicate struct {
+	id string
+}
+
+// InTeam is a predicate that is considered to be "true" if and only if the app is requested in the given workspace.
+func InTeam(id string) Predicate {
+	return &teamPredicate{id: id}
+}
+
+func (p *teamPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.AppRequest.Team.ID != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleAppRequestEvent(ctx, e)
+	})
+}
+
+func (p *teamPredicate) String() string {
+	return fmt.Sprintf("InTeam(%s)", p.id)
+}
+
+type enterprisePredicate struct {
+	id string
+}
+
+// InEnterprise is a predicate that is considered to be "true" if and only if the app is requested in a workspace of the given Enterprise Grid organization.
+func InEnterprise(id string) Predicate {
+	return &enterprisePredicate{id: id}
+}
+
+func (p *enterprisePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.AppRequest.Enterprise == nil || e.AppRequest.Enterprise.ID != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleAppRequestEvent(ctx, e)
+	})
+}
+
+func (p *enterprisePredicate) String() string {
+	return fmt.Sprintf("InEnterprise(%s)", p.id)
+}
+
+type appPredicate struct {
+	id string
+}
+
+// AppID is a predicate that is considered to be "true" if and only if the requested app is the given one.
+func AppID(id string) Predicate {
+	return &appPredicate{id: id}
+}
+
+func (p *appPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.AppRequest.App.ID != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleAppRequestEvent(ctx, e)
+	})
+}
+
+func (p *appPredicate) String() string {
+	return fmt.Sprintf("AppID(%s)", p.id)
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	return routerutils.Build(h, preds, Predicate.Wrap, isOutermost)
+}
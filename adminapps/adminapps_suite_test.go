@@ -0,0 +1,13 @@
+package adminapps_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAdminapps(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Adminapps Suite")
+}
@@ -0,0 +1,109 @@
+package adminapps_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/adminapps"
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+var _ = Describe("Adminapps", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = adminapps.HandlerFunc(func(_ context.Context, _ *adminapps.Event) error {
+			numHandlerCalled++
+			return nil
+		})
+		event = func() *adminapps.Event {
+			return &adminapps.Event{
+				Type: adminapps.AppRequested,
+				AppRequest: adminapps.AppRequest{
+					App:        adminapps.App{ID: "A001"},
+					User:       adminapps.User{ID: "U001"},
+					Team:       adminapps.Team{ID: "T001"},
+					Enterprise: &adminapps.Enterprise{ID: "E001"},
+				},
+			}
+		}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("RequestedBy", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := adminapps.Build(innerHandler, adminapps.RequestedBy("U001"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := adminapps.Build(innerHandler, adminapps.RequestedBy("U002"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("InTeam", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := adminapps.Build(innerHandler, adminapps.InTeam("T001"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := adminapps.Build(innerHandler, adminapps.InTeam("T002"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("InEnterprise", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := adminapps.Build(innerHandler, adminapps.InEnterprise("E001"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := adminapps.Build(innerHandler, adminapps.InEnterprise("E002"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("does not match requests outside Enterprise Grid", func() {
+			e := event()
+			e.AppRequest.Enterprise = nil
+			h := adminapps.Build(innerHandler, adminapps.InEnterprise("E001"))
+			Expect(h.HandleAppRequestEvent(ctx, e)).To(MatchError(errors.NotInterested))
+		})
+	})
+
+	Describe("AppID", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := adminapps.Build(innerHandler, adminapps.AppID("A001"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := adminapps.Build(innerHandler, adminapps.AppID("A002"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("applies generic predicates", func() {
+			h := adminapps.Build(innerHandler, adminapps.FromGenericPredicate(router.FailOnMismatch[*adminapps.Event]()), adminapps.InTeam("T002"))
+			Expect(h.HandleAppRequestEvent(ctx, event())).To(MatchError(errors.Mismatch))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+})
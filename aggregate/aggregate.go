@@ -0,0 +1,235 @@
+// Package aggregate maintains tallies of reactions per message by consuming `reaction_added` and `reaction_removed` events.
+//
+// An Aggregator is an Enricher, so it updates tallies before handlers are called and makes them available to handlers via Reactions:
+//
+//	agg := aggregate.New(aggregate.NewMemoryStore(), aggregate.WithReactions("white_check_mark"))
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(agg))
+//	r.OnReactionAdded(reaction.HandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+//		tally, err := aggregate.Reactions(ctx, e.Item.Channel, e.Item.Timestamp)
+//		if err != nil {
+//			return err
+//		}
+//		if tally.Count("white_check_mark") >= 2 {
+//			return deploy(ctx)
+//		}
+//		return nil
+//	}))
+//
+// Tallies record who reacted rather than how many times reactions are added, so retried events don't inflate them.
+// Note that reactions added before the Aggregator started running are unknown to it.
+package aggregate
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// ErrUnavailable indicates that the context is not enriched by an Aggregator.
+var ErrUnavailable = stderrors.New("reaction tallies are not available")
+
+// Tally is a set of users who reacted to a message, grouped by names of reactions.
+type Tally map[string][]string
+
+// Count returns the number of users who reacted with `name`.
+func (t Tally) Count(name string) int {
+	return len(t[name])
+}
+
+// Users returns IDs of users who reacted with `name`, in the order they reacted.
+func (t Tally) Users(name string) []string {
+	return t[name]
+}
+
+// Has returns true if and only if `user` reacted with `name`.
+func (t Tally) Has(name, user string) bool {
+	for _, u := range t[name] {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns names of reactions that the message has, in lexicographical order.
+func (t Tally) Names() []string {
+	names := make([]string, 0, len(t))
+	for name, users := range t {
+		if len(users) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Store persists tallies of reactions.
+//
+// Add and Remove must be idempotent, since the same event may be delivered more than once.
+type Store interface {
+	// Add records that `user` reacted to the message `ts` in `channel` with `reaction`.
+	Add(ctx context.Context, channel, ts, reaction, user string) error
+
+	// Remove records that `user` removed `reaction` from the message `ts` in `channel`.
+	Remove(ctx context.Context, channel, ts, reaction, user string) error
+
+	// Get returns the tally of the message `ts` in `channel`. It returns an empty Tally if the message has no known reactions.
+	Get(ctx context.Context, channel, ts string) (Tally, error)
+}
+
+// MemoryStore is a Store that keeps tallies in memory.
+//
+// Since it doesn't survive restarts and never forgets messages, it is mainly intended for tests and small bots.
+type MemoryStore struct {
+	mu      sync.Mutex
+	tallies map[string]Tally
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tallies: make(map[string]Tally)}
+}
+
+func messageKey(channel, ts string) string {
+	return channel + "/" + ts
+}
+
+func (s *MemoryStore) Add(_ context.Context, channel, ts, reaction, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := messageKey(channel, ts)
+	t, ok := s.tallies[key]
+	if !ok {
+		t = make(Tally)
+		s.tallies[key] = t
+	}
+	if t.Has(reaction, user) {
+		return nil
+	}
+	t[reaction] = append(t[reaction], user)
+	return nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, channel, ts, reaction, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := messageKey(channel, ts)
+	t, ok := s.tallies[key]
+	if !ok {
+		return nil
+	}
+	users := make([]string, 0, len(t[reaction]))
+	for _, u := range t[reaction] {
+		if u != user {
+			users = append(users, u)
+		}
+	}
+	if len(users) > 0 {
+		t[reaction] = users
+	} else {
+		delete(t, reaction)
+	}
+	if len(t) == 0 {
+		delete(s.tallies, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, channel, ts string) (Tally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := make(Tally)
+	for name, users := range s.tallies[messageKey(channel, ts)] {
+		t[name] = append([]string(nil), users...)
+	}
+	return t, nil
+}
+
+// Option configures the Aggregator.
+type Option interface {
+	apply(*Aggregator)
+}
+
+type optionFunc func(*Aggregator)
+
+func (f optionFunc) apply(a *Aggregator) {
+	f(a)
+}
+
+// WithReactions makes the Aggregator track only the given reactions. By default, all reactions are tracked.
+func WithReactions(names ...string) Option {
+	return optionFunc(func(a *Aggregator) {
+		if a.reactions == nil {
+			a.reactions = make(map[string]bool)
+		}
+		for _, name := range names {
+			a.reactions[name] = true
+		}
+	})
+}
+
+// Aggregator updates tallies of reactions on messages and stores them in contexts.
+type Aggregator struct {
+	store     Store
+	reactions map[string]bool
+}
+
+// New creates a new Aggregator that saves tallies in `store`.
+func New(store Store, opts ...Option) *Aggregator {
+	a := &Aggregator{store: store}
+	for _, o := range opts {
+		o.apply(a)
+	}
+	return a
+}
+
+type storeKey struct{}
+
+// Enrich updates the tally if `e` is a reaction to a message, and returns a new context in which Reactions is available.
+func (a *Aggregator) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	switch ev := e.InnerEvent.Data.(type) {
+	case *slackevents.ReactionAddedEvent:
+		if a.tracks(ev.Item.Type, ev.Reaction) {
+			if err := a.store.Add(ctx, ev.Item.Channel, ev.Item.Timestamp, ev.Reaction, ev.User); err != nil {
+				return nil, fmt.Errorf("failed to add reaction: %w", err)
+			}
+		}
+	case *slackevents.ReactionRemovedEvent:
+		if a.tracks(ev.Item.Type, ev.Reaction) {
+			if err := a.store.Remove(ctx, ev.Item.Channel, ev.Item.Timestamp, ev.Reaction, ev.User); err != nil {
+				return nil, fmt.Errorf("failed to remove reaction: %w", err)
+			}
+		}
+	}
+	return WithStore(ctx, a.store), nil
+}
+
+func (a *Aggregator) tracks(itemType, reaction string) bool {
+	if itemType != "message" {
+		return false
+	}
+	return a.reactions == nil || a.reactions[reaction]
+}
+
+// WithStore returns a new context in which Reactions reads tallies from `store`. This is mainly intended to test handlers that use Reactions.
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// Reactions returns the tally of reactions on the message `ts` in `channel`.
+//
+// When called while processing a `reaction_added` or `reaction_removed` event, the tally already reflects the event.
+// It returns ErrUnavailable if `ctx` is not enriched by an Aggregator.
+func Reactions(ctx context.Context, channel, ts string) (Tally, error) {
+	store, ok := ctx.Value(storeKey{}).(Store)
+	if !ok {
+		return nil, ErrUnavailable
+	}
+	return store.Get(ctx, channel, ts)
+}
@@ -0,0 +1,156 @@
+package aggregate_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/aggregate"
+)
+
+func reactionAdded(user, reaction, itemType string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.ReactionAdded,
+			Data: &slackevents.ReactionAddedEvent{
+				User:     user,
+				Reaction: reaction,
+				Item:     slackevents.Item{Type: itemType, Channel: "C001", Timestamp: "1234.5678"},
+			},
+		},
+	}
+}
+
+func reactionRemoved(user, reaction string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.ReactionRemoved,
+			Data: &slackevents.ReactionRemovedEvent{
+				User:     user,
+				Reaction: reaction,
+				Item:     slackevents.Item{Type: "message", Channel: "C001", Timestamp: "1234.5678"},
+			},
+		},
+	}
+}
+
+type failingStore struct {
+	*aggregate.MemoryStore
+}
+
+func (s *failingStore) Add(context.Context, string, string, string, string) error {
+	return errors.New("store is down")
+}
+
+var _ = Describe("Aggregate", func() {
+	var (
+		ctx   = context.Background()
+		store *aggregate.MemoryStore
+	)
+
+	BeforeEach(func() {
+		store = aggregate.NewMemoryStore()
+	})
+
+	enrich := func(agg *aggregate.Aggregator, e *slackevents.EventsAPIEvent) context.Context {
+		enriched, err := agg.Enrich(ctx, e)
+		Expect(err).NotTo(HaveOccurred())
+		return enriched
+	}
+
+	Describe("Enrich", func() {
+		It("counts users who reacted to the message", func() {
+			agg := aggregate.New(store)
+			enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			enriched := enrich(agg, reactionAdded("U002", "thumbsup", "message"))
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Count("thumbsup")).To(Equal(2))
+			Expect(tally.Users("thumbsup")).To(Equal([]string{"U001", "U002"}))
+			Expect(tally.Has("thumbsup", "U002")).To(BeTrue())
+			Expect(tally.Has("thumbsup", "U003")).To(BeFalse())
+		})
+
+		It("does not count retried events twice", func() {
+			agg := aggregate.New(store)
+			enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			enriched := enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Count("thumbsup")).To(Equal(1))
+		})
+
+		It("forgets removed reactions", func() {
+			agg := aggregate.New(store)
+			enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			enrich(agg, reactionAdded("U002", "eyes", "message"))
+			enriched := enrich(agg, reactionRemoved("U001", "thumbsup"))
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Count("thumbsup")).To(Equal(0))
+			Expect(tally.Names()).To(Equal([]string{"eyes"}))
+		})
+
+		It("tracks only the given reactions when WithReactions is given", func() {
+			agg := aggregate.New(store, aggregate.WithReactions("white_check_mark"))
+			enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			enriched := enrich(agg, reactionAdded("U001", "white_check_mark", "message"))
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Names()).To(Equal([]string{"white_check_mark"}))
+		})
+
+		It("ignores reactions to items other than messages", func() {
+			agg := aggregate.New(store)
+			enriched := enrich(agg, reactionAdded("U001", "thumbsup", "file"))
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally).To(BeEmpty())
+		})
+
+		It("makes tallies available while processing other events", func() {
+			agg := aggregate.New(store)
+			enrich(agg, reactionAdded("U001", "thumbsup", "message"))
+			enriched := enrich(agg, &slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: slackevents.Message,
+					Data: &slackevents.MessageEvent{Channel: "C001"},
+				},
+			})
+			tally, err := aggregate.Reactions(enriched, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Count("thumbsup")).To(Equal(1))
+		})
+
+		It("returns an error when the store fails", func() {
+			agg := aggregate.New(&failingStore{MemoryStore: store})
+			_, err := agg.Enrich(ctx, reactionAdded("U001", "thumbsup", "message"))
+			Expect(err).To(MatchError(ContainSubstring("store is down")))
+		})
+	})
+
+	Describe("Reactions", func() {
+		It("returns ErrUnavailable when the context is not enriched", func() {
+			_, err := aggregate.Reactions(ctx, "C001", "1234.5678")
+			Expect(err).To(MatchError(aggregate.ErrUnavailable))
+		})
+
+		It("reads tallies from the store given by WithStore", func() {
+			Expect(store.Add(ctx, "C001", "1234.5678", "eyes", "U001")).To(Succeed())
+			tally, err := aggregate.Reactions(aggregate.WithStore(ctx, store), "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tally.Users("eyes")).To(Equal([]string{"U001"}))
+		})
+
+		It("returns a copy that is not affected by later reactions", func() {
+			Expect(store.Add(ctx, "C001", "1234.5678", "eyes", "U001")).To(Succeed())
+			tally, err := store.Get(ctx, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Add(ctx, "C001", "1234.5678", "eyes", "U002")).To(Succeed())
+			Expect(tally.Count("eyes")).To(Equal(1))
+		})
+	})
+})
@@ -5,11 +5,17 @@ package appmention
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/channeltype"
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/slacktext"
 )
 
 // Handler processes `app_mention` events.
@@ -23,6 +29,18 @@ func (f HandlerFunc) HandleAppMentionEvent(ctx context.Context, e *slackevents.A
 	return f(ctx, e)
 }
 
+// HandlerProvider constructs Handlers lazily. See `router.HandlerProvider`.
+type HandlerProvider = router.HandlerProvider[Handler]
+
+type HandlerProviderFunc = router.HandlerProviderFunc[Handler]
+
+// FromProvider returns a Handler that obtains a Handler from `p` every time it processes an event and delegates the event to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func FromProvider(p HandlerProvider) Handler {
+	return FromGeneric(router.FromProvider(p, Handler.HandleAppMentionEvent))
+}
+
 // Predicate disthinguishes whether or not a certain handler should process coming events.
 type Predicate interface {
 	Wrap(Handler) Handler
@@ -46,6 +64,10 @@ func (p *inChannelPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *inChannelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.channel)
+}
+
 type textRegexpPredicate struct {
 	re *regexp.Regexp
 }
@@ -65,10 +87,131 @@ func (p *textRegexpPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *textRegexpPredicate) String() string {
+	return fmt.Sprintf("TextRegexp(%s)", p.re)
+}
+
+type normalizedTextRegexpPredicate struct {
+	re *regexp.Regexp
+}
+
+// NormalizedTextRegexp is a predicate that is considered to be "true" if and only if a text of a message matches to the given regexp
+// after it is normalized by `slacktext.Normalize`.
+func NormalizedTextRegexp(re *regexp.Regexp) Predicate {
+	return &normalizedTextRegexpPredicate{re: re}
+}
+
+func (p *normalizedTextRegexpPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		if !p.re.MatchString(slacktext.Normalize(e.Text)) {
+			return errors.NotInterested
+		}
+		return h.HandleAppMentionEvent(ctx, e)
+	})
+}
+
+func (p *normalizedTextRegexpPredicate) String() string {
+	return fmt.Sprintf("NormalizedTextRegexp(%s)", p.re)
+}
+
+type channelTypePredicate struct {
+	resolver channeltype.Resolver
+	types    []string
+}
+
+// ChannelType is a predicate that is considered to be "true" if and only if the type of the channel where the app is mentioned is one of the given ones.
+//
+// Since `app_mention` events don't have `channel_type`, the type is resolved by `resolver` (e.g. `channeltype.ByPrefix`).
+// Errors returned from `resolver` are returned as is.
+func ChannelType(resolver channeltype.Resolver, types ...string) Predicate {
+	return &channelTypePredicate{resolver: resolver, types: types}
+}
+
+func (p *channelTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		channelType, err := p.resolver.Resolve(ctx, e.Channel)
+		if err != nil {
+			return err
+		}
+		for _, t := range p.types {
+			if channelType == t {
+				return h.HandleAppMentionEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *channelTypePredicate) String() string {
+	return fmt.Sprintf("ChannelType(%s)", strings.Join(p.types, ", "))
+}
+
+// Condition is a condition that does not depend on events. See `router.Condition`.
+type Condition = router.Condition
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When(cond Condition) Predicate {
+	return FromGenericPredicate(router.When[*slackevents.AppMentionEvent](cond))
+}
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+// ChannelIn is a predicate that is considered to be "true" if and only if the channel where a bot is mentioned is in `set`.
+func ChannelIn(set Set) Predicate {
+	return FromGenericPredicate(router.In("ChannelIn", set, func(e *slackevents.AppMentionEvent) string { return e.Channel }))
+}
+
+// UserIn is a predicate that is considered to be "true" if and only if the user who mentioned a bot is in `set`.
+func UserIn(set Set) Predicate {
+	return FromGenericPredicate(router.In("UserIn", set, func(e *slackevents.AppMentionEvent) string { return e.User }))
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description(text string) Predicate {
+	return FromGenericPredicate(router.Description[*slackevents.AppMentionEvent](text))
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue(key, value interface{}) Predicate {
+	return FromGenericPredicate(router.WithValue[*slackevents.AppMentionEvent](key, value))
+}
+
+type failOnMismatchPredicate struct{}
+
+// FailOnMismatch is a predicate that turns mismatches of the other predicates into `errors.Mismatch`.
+//
+// By default, the Router silently falls back to other handlers when predicates don't match.
+// With FailOnMismatch, the Router stops there and reports the mismatch (e.g. to the hook set by `eventrouter.OnError`) as a misrouted event,
+// while still acknowledging the event. Unlike other predicates, it takes effect regardless of its position in the predicates.
+func FailOnMismatch() Predicate {
+	return &failOnMismatchPredicate{}
+}
+
+func (p *failOnMismatchPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		return routerutils.ToMismatch(h.HandleAppMentionEvent(ctx, e))
+	})
+}
+
+func (p *failOnMismatchPredicate) Outermost() {}
+
+func (p *failOnMismatchPredicate) String() string {
+	return "FailOnMismatch()"
+}
+
 // Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func Build(h Handler, preds ...Predicate) Handler {
-	for _, p := range preds {
-		h = p.Wrap(h)
-	}
-	return h
+	return routerutils.BuildMarked(h, preds, Predicate.Wrap, isOutermost, markHandler)
+}
+
+// markHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markHandler(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		return routerutils.MarkHandlerError(h.HandleAppMentionEvent(ctx, e))
+	})
 }
@@ -9,9 +9,17 @@ import (
 	"github.com/slack-go/slack/slackevents"
 
 	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/channeltype"
 	"github.com/genkami/go-slack-event-router/errors"
 )
 
+// staticCondition is a Condition that always returns the same result.
+type staticCondition bool
+
+func (c staticCondition) Match(context.Context) bool {
+	return bool(c)
+}
+
 var _ = Describe("AppMention", func() {
 	var (
 		numHandlerCalled int
@@ -152,4 +160,53 @@ var _ = Describe("AppMention", func() {
 			})
 		})
 	})
+
+	Describe("ChannelType", func() {
+		Context("when the type of the channel is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := appmention.Build(innerHandler, appmention.ChannelType(channeltype.ByPrefix, channeltype.IM, channeltype.MPIM))
+				err := h.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Channel: "D0123"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the type of the channel is not any of the given ones", func() {
+			It("does not call the inner handler", func() {
+				h := appmention.Build(innerHandler, appmention.ChannelType(channeltype.ByPrefix, channeltype.IM))
+				err := h.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Channel: "C0123"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("NormalizedTextRegexp", func() {
+		It("matches the regexp against the normalized text", func() {
+			h := appmention.Build(innerHandler, appmention.NormalizedTextRegexp(regexp.MustCompile(`^@bot deploy #prod & more$`)))
+			err := h.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Text: "<@U123|bot> deploy <#C123|prod> &amp; more"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Text: "<@U123> deploy <#C123> &amp; more"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		It("returns Mismatch when the other predicates don't match", func() {
+			h := appmention.Build(innerHandler, appmention.FailOnMismatch(), appmention.TextRegexp(regexp.MustCompile("deploy")))
+			err := h.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Text: "hello world"})
+			Expect(err).To(MatchError(errors.Mismatch))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("When", func() {
+		It("calls the inner handler only when the condition matches", func() {
+			e := &slackevents.AppMentionEvent{}
+			Expect(appmention.Build(innerHandler, appmention.When(staticCondition(true))).HandleAppMentionEvent(ctx, e)).To(Succeed())
+			Expect(appmention.Build(innerHandler, appmention.When(staticCondition(false))).HandleAppMentionEvent(ctx, e)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
 })
@@ -0,0 +1,330 @@
+// Package approval implements a common workflow of bots in which a message needs approvals of a certain number of people.
+//
+// A Workflow registers handlers that count approvals by reactions, buttons, or both, and calls a completion callback once enough approvers approve:
+//
+//	w := approval.New(approval.Spec{
+//		Name:      "deploy",
+//		Approvers: sreTeam,
+//		Threshold: 2,
+//		Emoji:     "white_check_mark",
+//		BlockID:   "deploy-approval",
+//		ActionID:  "approve",
+//	}, func(ctx context.Context, res *approval.Result) error {
+//		return deploy(ctx, res.Channel, res.Timestamp)
+//	})
+//	w.Register(r, ir)
+//
+//	// Post a message (optionally with w.Block("Approve")) and then start counting approvals of it.
+//	err := w.Request(ctx, channel, ts)
+//
+// Only messages given to Request are considered, and reactions and actions on other messages fall through to other handlers.
+package approval
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/reaction"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// ErrNotPending indicates that the message is not waiting for approvals, either because it is never requested or because it is already approved.
+var ErrNotPending = stderrors.New("the message is not pending approval")
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+// Spec describes who can approve messages and how.
+//
+// At least one of Emoji and ActionID must be set.
+type Spec struct {
+	// Name identifies the workflow in errors and in route introspection.
+	Name string
+
+	// Approvers is a set of IDs of users who can approve. If nil, anyone can approve.
+	Approvers Set
+
+	// Threshold is the number of approvers needed to complete the workflow. Zero is treated as one.
+	Threshold int
+
+	// Emoji is the name of the reaction that approves messages (e.g. `white_check_mark`).
+	// Removing the reaction withdraws the approval.
+	Emoji string
+
+	// BlockID and ActionID identify the button that approves messages. See Block.
+	BlockID  string
+	ActionID string
+}
+
+func (s *Spec) threshold() int {
+	if s.Threshold <= 0 {
+		return 1
+	}
+	return s.Threshold
+}
+
+// Key identifies a message that needs approvals.
+type Key struct {
+	Channel   string
+	Timestamp string
+}
+
+// Result is given to the completion callback when a message is approved.
+type Result struct {
+	Key
+
+	// Approvers are IDs of users who approved the message, in the order they approved.
+	Approvers []string
+}
+
+// CompletionFunc is called once a message is approved by enough approvers.
+//
+// If it returns an error, the message is reopened with the same approvals, and the error is returned to the router so that Slack retries the event.
+type CompletionFunc func(ctx context.Context, res *Result) error
+
+// Store persists messages waiting for approvals and approvals given to them.
+type Store interface {
+	// Open starts counting approvals of the message `key`.
+	Open(ctx context.Context, key Key) error
+
+	// Approve records an approval by `user` and returns all approvers of the message so far.
+	// Approving more than once has no effect. It returns ErrNotPending if the message is not open.
+	Approve(ctx context.Context, key Key, user string) ([]string, error)
+
+	// Withdraw removes an approval by `user`. It returns ErrNotPending if the message is not open.
+	Withdraw(ctx context.Context, key Key, user string) error
+
+	// Close stops counting approvals of the message `key`. It returns ErrNotPending if the message is not open,
+	// so that only one of concurrent callers can close the message.
+	Close(ctx context.Context, key Key) error
+}
+
+// MemoryStore is a Store that keeps approvals in memory.
+//
+// Since it doesn't survive restarts, it is mainly intended for tests and small bots.
+type MemoryStore struct {
+	mu      sync.Mutex
+	pending map[Key][]string
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{pending: make(map[Key][]string)}
+}
+
+func (s *MemoryStore) Open(_ context.Context, key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[key]; !ok {
+		s.pending[key] = []string{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Approve(_ context.Context, key Key, user string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, ok := s.pending[key]
+	if !ok {
+		return nil, ErrNotPending
+	}
+	for _, u := range users {
+		if u == user {
+			return append([]string(nil), users...), nil
+		}
+	}
+	users = append(users, user)
+	s.pending[key] = users
+	return append([]string(nil), users...), nil
+}
+
+func (s *MemoryStore) Withdraw(_ context.Context, key Key, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, ok := s.pending[key]
+	if !ok {
+		return ErrNotPending
+	}
+	rest := make([]string, 0, len(users))
+	for _, u := range users {
+		if u != user {
+			rest = append(rest, u)
+		}
+	}
+	s.pending[key] = rest
+	return nil
+}
+
+func (s *MemoryStore) Close(_ context.Context, key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[key]; !ok {
+		return ErrNotPending
+	}
+	delete(s.pending, key)
+	return nil
+}
+
+// Option configures the Workflow.
+type Option interface {
+	apply(*Workflow)
+}
+
+type optionFunc func(*Workflow)
+
+func (f optionFunc) apply(w *Workflow) {
+	f(w)
+}
+
+// WithStore sets the Store of the Workflow. If not set, a new MemoryStore is used.
+func WithStore(store Store) Option {
+	return optionFunc(func(w *Workflow) {
+		w.store = store
+	})
+}
+
+// Workflow counts approvals of messages according to a Spec.
+type Workflow struct {
+	spec       Spec
+	onComplete CompletionFunc
+	store      Store
+}
+
+// New creates a new Workflow that calls `onComplete` when a message is approved.
+//
+// It panics if `spec` has neither Emoji nor ActionID.
+func New(spec Spec, onComplete CompletionFunc, opts ...Option) *Workflow {
+	if spec.Emoji == "" && spec.ActionID == "" {
+		panic("approval: Spec must have Emoji or ActionID")
+	}
+	w := &Workflow{spec: spec, onComplete: onComplete}
+	for _, o := range opts {
+		o.apply(w)
+	}
+	if w.store == nil {
+		w.store = NewMemoryStore()
+	}
+	return w
+}
+
+// Request starts counting approvals of the message `ts` in `channel`.
+func (w *Workflow) Request(ctx context.Context, channel, ts string) error {
+	if err := w.store.Open(ctx, Key{Channel: channel, Timestamp: ts}); err != nil {
+		return fmt.Errorf("approval %s: failed to open %s/%s: %w", w.spec.Name, channel, ts, err)
+	}
+	return nil
+}
+
+// Block returns an action block that contains a button to approve, which can be posted along with the message.
+//
+// It panics if the Spec has no ActionID.
+func (w *Workflow) Block(text string) *slack.ActionBlock {
+	if w.spec.ActionID == "" {
+		panic("approval: Block requires ActionID")
+	}
+	button := slack.NewButtonBlockElement(w.spec.ActionID, w.spec.Name, slack.NewTextBlockObject(slack.PlainTextType, text, false, false))
+	button.Style = slack.StylePrimary
+	return slack.NewActionBlock(w.spec.BlockID, button)
+}
+
+// Register registers handlers that count approvals.
+//
+// Reaction handlers are registered to `r` if the Spec has Emoji, and a block action handler is registered to `ir` if the Spec has ActionID.
+// The router that is not needed may be nil.
+func (w *Workflow) Register(r *eventrouter.Router, ir *interactionrouter.Router) {
+	if w.spec.Emoji != "" {
+		desc := reaction.Description(fmt.Sprintf("approval %s", w.spec.Name))
+		r.OnReactionAdded(reaction.AddedHandlerFunc(w.handleReactionAdded), reaction.Name(w.spec.Emoji), desc)
+		r.OnReactionRemoved(reaction.RemovedHandlerFunc(w.handleReactionRemoved), reaction.Name(w.spec.Emoji), desc)
+	}
+	if w.spec.ActionID != "" {
+		ir.On(slack.InteractionTypeBlockActions, interactionrouter.HandlerFunc(w.handleBlockActions),
+			interactionrouter.BlockAction(w.spec.BlockID, w.spec.ActionID),
+			interactionrouter.Description(fmt.Sprintf("approval %s", w.spec.Name)))
+	}
+}
+
+func (w *Workflow) handleReactionAdded(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+	if e.Item.Type != "message" {
+		return errors.NotInterested
+	}
+	return w.approve(ctx, Key{Channel: e.Item.Channel, Timestamp: e.Item.Timestamp}, e.User)
+}
+
+func (w *Workflow) handleReactionRemoved(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+	if e.Item.Type != "message" {
+		return errors.NotInterested
+	}
+	err := w.store.Withdraw(ctx, Key{Channel: e.Item.Channel, Timestamp: e.Item.Timestamp}, e.User)
+	if stderrors.Is(err, ErrNotPending) {
+		return errors.NotInterested
+	} else if err != nil {
+		return fmt.Errorf("approval %s: failed to withdraw: %w", w.spec.Name, err)
+	}
+	return nil
+}
+
+func (w *Workflow) handleBlockActions(ctx context.Context, callback *slack.InteractionCallback) error {
+	key := Key{Channel: callback.Container.ChannelID, Timestamp: callback.Container.MessageTs}
+	if key.Channel == "" {
+		key.Channel = callback.Channel.ID
+	}
+	if key.Timestamp == "" {
+		key.Timestamp = callback.Message.Timestamp
+	}
+	return w.approve(ctx, key, callback.User.ID)
+}
+
+func (w *Workflow) approve(ctx context.Context, key Key, user string) error {
+	if w.spec.Approvers != nil && !w.spec.Approvers.Contains(ctx, user) {
+		return errors.NotInterested
+	}
+	approvers, err := w.store.Approve(ctx, key, user)
+	if stderrors.Is(err, ErrNotPending) {
+		return errors.NotInterested
+	} else if err != nil {
+		return fmt.Errorf("approval %s: failed to approve: %w", w.spec.Name, err)
+	}
+	if len(approvers) < w.spec.threshold() {
+		return nil
+	}
+	// Closing first makes sure that the callback is called at most once even if approvals arrive concurrently.
+	err = w.store.Close(ctx, key)
+	if stderrors.Is(err, ErrNotPending) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("approval %s: failed to close: %w", w.spec.Name, err)
+	}
+	if err := w.onComplete(ctx, &Result{Key: key, Approvers: approvers}); err != nil {
+		// Otherwise the approvals are lost, since retries of the event would find the message already closed.
+		if reopenErr := w.reopen(ctx, key, approvers); reopenErr != nil {
+			return fmt.Errorf("approval %s: failed to reopen %s/%s: %s: %w", w.spec.Name, key.Channel, key.Timestamp, reopenErr.Error(), err)
+		}
+		return err
+	}
+	return nil
+}
+
+// reopen restores approvals of the message `key` after the completion callback fails,
+// so that the callback is called again when the event is retried or someone approves again.
+func (w *Workflow) reopen(ctx context.Context, key Key, approvers []string) error {
+	if err := w.store.Open(ctx, key); err != nil {
+		return err
+	}
+	for _, user := range approvers {
+		if _, err := w.store.Approve(ctx, key, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
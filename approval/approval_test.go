@@ -0,0 +1,250 @@
+package approval_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/approval"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/reaction"
+)
+
+type staticSet map[string]bool
+
+func (s staticSet) Contains(_ context.Context, value string) bool {
+	return s[value]
+}
+
+var numEvents int
+
+func reactionEvent(typeName, user, emoji, ts string) string {
+	numEvents++
+	return fmt.Sprintf(`{
+		"type": "event_callback",
+		"event_id": "Ev%04d",
+		"event_time": 1234567890,
+		"event": {
+			"type": %q,
+			"user": %q,
+			"reaction": %q,
+			"item": {"type": "message", "channel": "C001", "ts": %q},
+			"event_ts": "1360782804.083113"
+		}
+	}`, numEvents, typeName, user, emoji, ts)
+}
+
+func blockActions(user, blockID, actionID, ts string) string {
+	return fmt.Sprintf(`{
+		"type": "block_actions",
+		"user": {"id": %q},
+		"container": {"type": "message", "channel_id": "C001", "message_ts": %q},
+		"actions": [{"type": "button", "block_id": %q, "action_id": %q}]
+	}`, user, ts, blockID, actionID)
+}
+
+var _ = Describe("Approval", func() {
+	var (
+		ctx     = context.Background()
+		r       *eventrouter.Router
+		ir      *interactionrouter.Router
+		results []*approval.Result
+		spec    approval.Spec
+	)
+
+	onComplete := func(_ context.Context, res *approval.Result) error {
+		results = append(results, res)
+		return nil
+	}
+
+	serveEvent := func(body string) int {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/events", bytes.NewReader([]byte(body)))
+		Expect(err).NotTo(HaveOccurred())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	serveInteraction := func(payload string) int {
+		body := url.Values{"payload": {payload}}.Encode()
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/interactions", bytes.NewReader([]byte(body)))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		ir.ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	BeforeEach(func() {
+		var err error
+		r, err = eventrouter.New(eventrouter.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		ir, err = interactionrouter.New(interactionrouter.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		results = nil
+		spec = approval.Spec{
+			Name:      "deploy",
+			Approvers: staticSet{"U001": true, "U002": true, "U003": true},
+			Threshold: 2,
+			Emoji:     "white_check_mark",
+			BlockID:   "deploy-approval",
+			ActionID:  "approve",
+		}
+	})
+
+	Describe("reactions", func() {
+		var w *approval.Workflow
+
+		BeforeEach(func() {
+			w = approval.New(spec, onComplete)
+			w.Register(r, ir)
+			Expect(w.Request(ctx, "C001", "1111.0000")).To(Succeed())
+		})
+
+		It("completes once enough approvers react", func() {
+			Expect(serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))).To(Equal(http.StatusOK))
+			Expect(results).To(BeEmpty())
+			Expect(serveEvent(reactionEvent("reaction_added", "U002", "white_check_mark", "1111.0000"))).To(Equal(http.StatusOK))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Channel).To(Equal("C001"))
+			Expect(results[0].Timestamp).To(Equal("1111.0000"))
+			Expect(results[0].Approvers).To(Equal([]string{"U001", "U002"}))
+		})
+
+		It("completes only once", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U002", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U003", "white_check_mark", "1111.0000"))
+			Expect(results).To(HaveLen(1))
+		})
+
+		It("does not count the same approver twice", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			Expect(results).To(BeEmpty())
+		})
+
+		It("ignores users who are not approvers", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U999", "white_check_mark", "1111.0000"))
+			Expect(results).To(BeEmpty())
+		})
+
+		It("ignores other reactions", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U002", "eyes", "1111.0000"))
+			Expect(results).To(BeEmpty())
+		})
+
+		It("withdraws approvals when reactions are removed", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_removed", "U001", "white_check_mark", "1111.0000"))
+			serveEvent(reactionEvent("reaction_added", "U002", "white_check_mark", "1111.0000"))
+			Expect(results).To(BeEmpty())
+		})
+
+		It("lets other handlers process reactions on messages that are not requested", func() {
+			var called bool
+			r.OnReactionAdded(reaction.AddedHandlerFunc(func(context.Context, *slackevents.ReactionAddedEvent) error {
+				called = true
+				return nil
+			}))
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "2222.0000"))
+			Expect(called).To(BeTrue())
+			Expect(results).To(BeEmpty())
+		})
+	})
+
+	Describe("when the callback fails", func() {
+		It("calls the callback again when Slack retries the event", func() {
+			numCalls := 0
+			w := approval.New(spec, func(ctx context.Context, res *approval.Result) error {
+				numCalls++
+				if numCalls == 1 {
+					return errors.New("deploy failed")
+				}
+				return onComplete(ctx, res)
+			})
+			w.Register(r, ir)
+			Expect(w.Request(ctx, "C001", "1111.0000")).To(Succeed())
+			Expect(serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))).To(Equal(http.StatusOK))
+			second := reactionEvent("reaction_added", "U002", "white_check_mark", "1111.0000")
+			Expect(serveEvent(second)).To(Equal(http.StatusInternalServerError))
+			Expect(results).To(BeEmpty())
+			Expect(serveEvent(second)).To(Equal(http.StatusOK))
+			Expect(numCalls).To(Equal(2))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Approvers).To(Equal([]string{"U001", "U002"}))
+		})
+	})
+
+	Describe("buttons", func() {
+		var w *approval.Workflow
+
+		BeforeEach(func() {
+			w = approval.New(spec, onComplete)
+			w.Register(r, ir)
+			Expect(w.Request(ctx, "C001", "1111.0000")).To(Succeed())
+		})
+
+		It("completes once enough approvers press the button", func() {
+			Expect(serveInteraction(blockActions("U001", "deploy-approval", "approve", "1111.0000"))).To(Equal(http.StatusOK))
+			Expect(results).To(BeEmpty())
+			Expect(serveInteraction(blockActions("U002", "deploy-approval", "approve", "1111.0000"))).To(Equal(http.StatusOK))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Approvers).To(Equal([]string{"U001", "U002"}))
+		})
+
+		It("counts reactions and buttons together", func() {
+			serveEvent(reactionEvent("reaction_added", "U001", "white_check_mark", "1111.0000"))
+			serveInteraction(blockActions("U002", "deploy-approval", "approve", "1111.0000"))
+			Expect(results).To(HaveLen(1))
+		})
+
+		It("builds a block that contains the button", func() {
+			block := w.Block("Approve")
+			Expect(block.BlockID).To(Equal("deploy-approval"))
+			Expect(block.Elements.ElementSet).To(HaveLen(1))
+			button, ok := block.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+			Expect(ok).To(BeTrue())
+			Expect(button.ActionID).To(Equal("approve"))
+			Expect(button.Text.Text).To(Equal("Approve"))
+		})
+	})
+
+	Describe("New", func() {
+		It("panics when the Spec has neither Emoji nor ActionID", func() {
+			Expect(func() { approval.New(approval.Spec{Name: "deploy"}, onComplete) }).To(Panic())
+		})
+
+		It("registers only reaction handlers when the Spec has no ActionID", func() {
+			w := approval.New(approval.Spec{Name: "deploy", Emoji: "white_check_mark"}, onComplete)
+			w.Register(r, nil)
+			Expect(w.Request(ctx, "C001", "1111.0000")).To(Succeed())
+			serveEvent(reactionEvent("reaction_added", "U999", "white_check_mark", "1111.0000"))
+			Expect(results).To(HaveLen(1))
+		})
+	})
+
+	Describe("MemoryStore", func() {
+		It("returns ErrNotPending for messages that are not open", func() {
+			store := approval.NewMemoryStore()
+			key := approval.Key{Channel: "C001", Timestamp: "1111.0000"}
+			_, err := store.Approve(ctx, key, "U001")
+			Expect(err).To(MatchError(approval.ErrNotPending))
+			Expect(store.Open(ctx, key)).To(Succeed())
+			Expect(store.Close(ctx, key)).To(Succeed())
+			Expect(store.Close(ctx, key)).To(MatchError(approval.ErrNotPending))
+		})
+	})
+})
@@ -0,0 +1,426 @@
+// Package asyncqueue processes requests from Slack in the background in the order of their priorities,
+// so that user-facing interactions are not starved behind bulk events (e.g. messages for analytics) during bursts.
+//
+// A Queue sits between a `bridge.Publisher` and a `bridge.Subscriber` in place of a message broker:
+//
+//	sub, err := bridge.NewSubscriber(mux, bridge.InsecureSkipVerification())
+//	q := asyncqueue.New(sub.DispatchEnvelope, asyncqueue.WithWorkers(8))
+//	pub, err := bridge.NewPublisher(q.Publish, bridge.WithSigningSecret(secret))
+//	http.Handle("/slack", pub)
+//	...
+//	q.Shutdown(ctx) // processes pending requests
+//
+// The Publisher responds to Slack as soon as requests are queued. Requests are classified by a Classifier (DefaultClassifier by default),
+// and workers always take the oldest request of the highest priority.
+//
+// When the Queue is full, it rejects requests so that Slack retries them later, unless a Shedder (see WithShedder) decides to drop some requests instead.
+// Requests that are accepted but not processed successfully are passed to the hook set by OnDeadLetter.
+package asyncqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/attempt"
+	"github.com/genkami/go-slack-event-router/bridge"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// DefaultCapacity is the default maximum number of requests in a Queue.
+const DefaultCapacity = 1000
+
+var (
+	// ErrFull is returned when requests are given to a Queue that is full.
+	//
+	// It wraps `errors.HttpError(503)`, so the Publisher responds with Service Unavailable and Slack retries the request later.
+	ErrFull = fmt.Errorf("queue is full: %w", routererrors.HttpError(http.StatusServiceUnavailable))
+
+	// ErrClosed is returned when requests are given to a Queue that has been shut down.
+	ErrClosed = errors.New("queue is closed")
+
+	// ErrShed is passed to the hook set by OnDeadLetter when a request is dropped by a Shedder.
+	ErrShed = errors.New("request is shed")
+)
+
+// Priority is a priority class of requests. Requests with higher priorities are processed first.
+type Priority int
+
+const (
+	// PriorityLow is for bulk events that nobody is waiting for, such as `message` events.
+	PriorityLow Priority = iota
+
+	// PriorityNormal is for events that are not classified into other classes.
+	PriorityNormal
+
+	// PriorityHigh is for events that users may be waiting for responses to, such as `app_mention` events.
+	PriorityHigh
+
+	// PriorityUrgent is for interactions and slash commands, which users are waiting for.
+	PriorityUrgent
+
+	numPriorities = int(PriorityUrgent) + 1
+)
+
+// Classifier decides the Priority of a request.
+//
+// It is called synchronously before the request is queued, so it should return quickly.
+type Classifier func(env *bridge.Envelope) Priority
+
+// DefaultClassifier classifies interactions and slash commands (i.e. form-encoded requests) as PriorityUrgent,
+// `app_mention` events as PriorityHigh, `message` events as PriorityLow, and others as PriorityNormal.
+func DefaultClassifier(env *bridge.Envelope) Priority {
+	if isForm(env) {
+		return PriorityUrgent
+	}
+	switch EventType(env) {
+	case slackevents.AppMention:
+		return PriorityHigh
+	case slackevents.Message:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// EventType returns the type of the inner event (e.g. `message`) if `env` holds an `event_callback` event,
+// and the type of the outer event otherwise. It returns an empty string for interactions and slash commands.
+func EventType(env *bridge.Envelope) string {
+	if isForm(env) {
+		return ""
+	}
+	var outer struct {
+		Type  string `json:"type"`
+		Event struct {
+			Type string `json:"type"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(env.Body, &outer); err != nil {
+		return ""
+	}
+	if outer.Type == slackevents.CallbackEvent {
+		return outer.Event.Type
+	}
+	return outer.Type
+}
+
+func isForm(env *bridge.Envelope) bool {
+	mediaType, _, err := mime.ParseMediaType(env.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// Item is a request in a Queue.
+type Item struct {
+	Envelope *bridge.Envelope
+
+	// Priority is the Priority given by the Classifier.
+	Priority Priority
+
+	// EnqueuedAt is the time when the request was added to the Queue.
+	EnqueuedAt time.Time
+
+	// Attempt identifies the attempt to deliver the event, so that dead letters can be correlated with retries by Slack.
+	// This is the zero value for requests other than `event_callback` events.
+	Attempt attempt.Key
+}
+
+// DispatchFunc processes a request taken from a Queue (e.g. `(*bridge.Subscriber).DispatchEnvelope`).
+type DispatchFunc func(ctx context.Context, env *bridge.Envelope) error
+
+// Option configures the Queue.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+type config struct {
+	capacity       int
+	workers        int
+	classifier     Classifier
+	shedder        Shedder
+	errorHook      func(context.Context, error)
+	deadLetterHook func(context.Context, *Item, error)
+}
+
+// WithCapacity sets the maximum number of requests waiting in the Queue.
+// Requests given to a full Queue are rejected with ErrFull, unless WithShedder is set.
+func WithCapacity(n int) Option {
+	return optionFunc(func(c *config) {
+		c.capacity = n
+	})
+}
+
+// WithWorkers sets the number of goroutines that process requests concurrently. The default is `runtime.GOMAXPROCS(0)`.
+func WithWorkers(n int) Option {
+	return optionFunc(func(c *config) {
+		c.workers = n
+	})
+}
+
+// WithClassifier sets a Classifier that decides priorities of requests instead of DefaultClassifier.
+func WithClassifier(classify Classifier) Option {
+	return optionFunc(func(c *config) {
+		c.classifier = classify
+	})
+}
+
+// WithShedder sets a Shedder that decides which request to drop when the Queue is full.
+func WithShedder(shedder Shedder) Option {
+	return optionFunc(func(c *config) {
+		c.shedder = shedder
+	})
+}
+
+// OnError sets a hook that is called when the DispatchFunc returns an error. If not set, errors are ignored.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.errorHook = hook
+	})
+}
+
+// OnDeadLetter sets a hook that is called with requests that the Queue accepted but failed to process,
+// so that they can be saved somewhere else (e.g. to a `checkpoint.Store`) and processed later.
+//
+// The hook is given an error that wraps ErrShed if the request is dropped by a Shedder, or the error returned from the DispatchFunc.
+func OnDeadLetter(hook func(ctx context.Context, item *Item, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.deadLetterHook = hook
+	})
+}
+
+// Queue holds requests and processes them in the background in the order of their priorities.
+//
+// Requests with the same priority are processed in the order in which they are queued.
+// Since priorities are strict, requests with lower priorities wait as long as there are ones with higher priorities.
+type Queue struct {
+	dispatch DispatchFunc
+	config
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	classes  [numPriorities][]*Item
+	size     int
+	closed   bool
+	running  sync.WaitGroup
+	shed     [numPriorities]int64
+	rejected int64
+}
+
+// New creates a new Queue that passes requests to `dispatch`, and starts its workers.
+func New(dispatch DispatchFunc, opts ...Option) *Queue {
+	q := &Queue{
+		dispatch: dispatch,
+		config: config{
+			capacity:   DefaultCapacity,
+			workers:    runtime.GOMAXPROCS(0),
+			classifier: DefaultClassifier,
+		},
+	}
+	for _, o := range opts {
+		o.apply(&q.config)
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.running.Add(q.workers)
+	for i := 0; i < q.workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds `env` to the Queue and returns immediately.
+//
+// It returns ErrFull if the Queue is full and the Shedder doesn't drop any request, and ErrClosed after Shutdown is called.
+// If the Shedder drops `env` itself, it returns nil since `env` is accepted (and passed to the hook set by OnDeadLetter).
+func (q *Queue) Enqueue(ctx context.Context, env *bridge.Envelope) error {
+	item := &Item{Envelope: env, Priority: q.classify(env), EnqueuedAt: time.Now()}
+	item.Attempt, _ = attempt.Parse(env.Body, env.Header)
+	shed, err := q.push(item)
+	if shed != nil {
+		q.deadLetter(ctx, shed, ErrShed)
+	}
+	return err
+}
+
+// push adds `item` to the Queue and returns the item that is dropped to make room for it, if any.
+func (q *Queue) push(item *Item) (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	var shed *Item
+	if q.size >= q.capacity {
+		if q.shedder != nil {
+			shed = q.shedder.Shed(item, q.pendingLocked())
+		}
+		if shed == nil {
+			q.rejected++
+			return nil, ErrFull
+		}
+		if shed != item && !q.removeLocked(shed) {
+			return nil, errors.New("Shedder returned an item that is not in the queue")
+		}
+		q.shed[shed.Priority]++
+		if shed == item {
+			return shed, nil
+		}
+	}
+	q.classes[item.Priority] = append(q.classes[item.Priority], item)
+	q.size++
+	q.cond.Signal()
+	return shed, nil
+}
+
+// pendingLocked returns pending items in the order in which they are to be processed.
+func (q *Queue) pendingLocked() []*Item {
+	pending := make([]*Item, 0, q.size)
+	for p := numPriorities - 1; p >= 0; p-- {
+		pending = append(pending, q.classes[p]...)
+	}
+	return pending
+}
+
+func (q *Queue) removeLocked(item *Item) bool {
+	class := q.classes[item.Priority]
+	for i, it := range class {
+		if it == item {
+			q.classes[item.Priority] = append(class[:i:i], class[i+1:]...)
+			q.size--
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queue) deadLetter(ctx context.Context, item *Item, err error) {
+	if q.deadLetterHook != nil {
+		q.deadLetterHook(ctx, item, err)
+	}
+}
+
+// Publish decodes an Envelope encoded by `(*bridge.Envelope).Marshal` and adds it to the Queue. It can be used as a `bridge.PublishFunc`.
+func (q *Queue) Publish(ctx context.Context, data []byte) error {
+	env, err := bridge.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	return q.Enqueue(ctx, env)
+}
+
+// Len returns the number of requests waiting in the Queue.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// Stats is a snapshot of the state of a Queue.
+type Stats struct {
+	// Pending is the number of requests waiting in the Queue.
+	Pending int
+
+	// Shed is the number of requests dropped by the Shedder for each Priority.
+	Shed map[Priority]int64
+
+	// Rejected is the number of requests rejected with ErrFull.
+	Rejected int64
+}
+
+// Stats returns the current Stats, which can be exported by metrics libraries.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	shed := make(map[Priority]int64)
+	for p, n := range q.shed {
+		if n > 0 {
+			shed[Priority(p)] = n
+		}
+	}
+	return Stats{Pending: q.size, Shed: shed, Rejected: q.rejected}
+}
+
+func (q *Queue) classify(env *bridge.Envelope) Priority {
+	p := q.classifier(env)
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	if p > PriorityUrgent {
+		return PriorityUrgent
+	}
+	return p
+}
+
+func (q *Queue) work() {
+	defer q.running.Done()
+	for {
+		item, ok := q.next()
+		if !ok {
+			return
+		}
+		ctx := context.Background()
+		if item.Attempt.EventID != "" {
+			ctx = attempt.NewContext(ctx, item.Attempt)
+		}
+		if err := q.dispatch(ctx, item.Envelope); err != nil {
+			if q.errorHook != nil {
+				q.errorHook(ctx, err)
+			}
+			q.deadLetter(ctx, item, err)
+		}
+	}
+}
+
+// next waits for a request and takes the oldest one of the highest priority. It returns false if the Queue is closed and empty.
+func (q *Queue) next() (*Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.size == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.classes[p]) == 0 {
+			continue
+		}
+		item := q.classes[p][0]
+		q.classes[p][0] = nil
+		q.classes[p] = q.classes[p][1:]
+		q.size--
+		return item, true
+	}
+	return nil, false
+}
+
+// Shutdown stops accepting requests, and waits for the workers to process pending ones.
+//
+// If `ctx` is done before that, it returns the error of `ctx`. Workers keep processing pending requests in such case.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,13 @@
+package asyncqueue_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAsyncqueue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Asyncqueue Suite")
+}
@@ -0,0 +1,304 @@
+package asyncqueue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/asyncqueue"
+	"github.com/genkami/go-slack-event-router/attempt"
+	"github.com/genkami/go-slack-event-router/bridge"
+)
+
+func callback(eventType string) *bridge.Envelope {
+	body := `{"type": "event_callback", "event": {"type": "` + eventType + `"}}`
+	return &bridge.Envelope{Body: []byte(body), Header: http.Header{"Content-Type": []string{"application/json"}}}
+}
+
+func interaction() *bridge.Envelope {
+	return &bridge.Envelope{
+		Body:   []byte(`payload=%7B%22type%22%3A%22block_actions%22%7D`),
+		Header: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+	}
+}
+
+// blockingDispatcher records dispatched requests. It blocks until Release is called.
+type blockingDispatcher struct {
+	mu       sync.Mutex
+	received []string
+	started  chan struct{}
+	released chan struct{}
+	release  sync.Once
+}
+
+func newBlockingDispatcher() *blockingDispatcher {
+	return &blockingDispatcher{started: make(chan struct{}, 100), released: make(chan struct{})}
+}
+
+func (d *blockingDispatcher) Dispatch(_ context.Context, env *bridge.Envelope) error {
+	d.started <- struct{}{}
+	<-d.released
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.received = append(d.received, string(env.Body))
+	return nil
+}
+
+func (d *blockingDispatcher) Release() {
+	d.release.Do(func() { close(d.released) })
+}
+
+func (d *blockingDispatcher) Received() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.received
+}
+
+var _ = Describe("Asyncqueue", func() {
+	Describe("DefaultClassifier", func() {
+		It("classifies requests by their types", func() {
+			Expect(asyncqueue.DefaultClassifier(interaction())).To(Equal(asyncqueue.PriorityUrgent))
+			Expect(asyncqueue.DefaultClassifier(callback("app_mention"))).To(Equal(asyncqueue.PriorityHigh))
+			Expect(asyncqueue.DefaultClassifier(callback("reaction_added"))).To(Equal(asyncqueue.PriorityNormal))
+			Expect(asyncqueue.DefaultClassifier(callback("message"))).To(Equal(asyncqueue.PriorityLow))
+			Expect(asyncqueue.DefaultClassifier(&bridge.Envelope{Body: []byte("invalid")})).To(Equal(asyncqueue.PriorityNormal))
+		})
+	})
+
+	Describe("EventType", func() {
+		It("returns the type of the inner event", func() {
+			Expect(asyncqueue.EventType(callback("message"))).To(Equal("message"))
+			Expect(asyncqueue.EventType(&bridge.Envelope{Body: []byte(`{"type": "app_rate_limited"}`)})).To(Equal("app_rate_limited"))
+			Expect(asyncqueue.EventType(interaction())).To(Equal(""))
+		})
+	})
+
+	Describe("Queue", func() {
+		var (
+			d *blockingDispatcher
+			q *asyncqueue.Queue
+		)
+
+		BeforeEach(func() {
+			d = newBlockingDispatcher()
+		})
+
+		AfterEach(func() {
+			d.Release()
+			Expect(q.Shutdown(context.Background())).To(Succeed())
+		})
+
+		It("processes requests with higher priorities first", func() {
+			q = asyncqueue.New(d.Dispatch, asyncqueue.WithWorkers(1))
+			first := callback("message")
+			Expect(q.Enqueue(context.Background(), first)).To(Succeed())
+			Eventually(d.started).Should(Receive())
+
+			message := callback("message")
+			mention := callback("app_mention")
+			reaction := callback("reaction_added")
+			action := interaction()
+			for _, env := range []*bridge.Envelope{message, mention, reaction, action} {
+				Expect(q.Enqueue(context.Background(), env)).To(Succeed())
+			}
+			Expect(q.Len()).To(Equal(4))
+			d.Release()
+
+			Eventually(d.Received).Should(HaveLen(5))
+			Expect(d.Received()).To(Equal([]string{
+				string(first.Body), string(action.Body), string(mention.Body), string(reaction.Body), string(message.Body),
+			}))
+		})
+
+		It("uses the classifier set by WithClassifier", func() {
+			q = asyncqueue.New(d.Dispatch, asyncqueue.WithWorkers(1), asyncqueue.WithClassifier(func(env *bridge.Envelope) asyncqueue.Priority {
+				if bytes.Contains(env.Body, []byte("message")) {
+					return asyncqueue.PriorityUrgent
+				}
+				return asyncqueue.PriorityLow
+			}))
+			Expect(q.Enqueue(context.Background(), callback("reaction_added"))).To(Succeed())
+			Eventually(d.started).Should(Receive())
+			Expect(q.Enqueue(context.Background(), callback("app_mention"))).To(Succeed())
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+			d.Release()
+
+			Eventually(d.Received).Should(HaveLen(3))
+			Expect(d.Received()[1]).To(ContainSubstring(`"message"`))
+		})
+
+		It("rejects requests when it is full", func() {
+			q = asyncqueue.New(d.Dispatch, asyncqueue.WithWorkers(1), asyncqueue.WithCapacity(1))
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+			Eventually(d.started).Should(Receive())
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+			err := q.Enqueue(context.Background(), callback("message"))
+			Expect(errors.Is(err, asyncqueue.ErrFull)).To(BeTrue())
+		})
+
+		It("processes pending requests on Shutdown, and rejects requests after that", func() {
+			q = asyncqueue.New(d.Dispatch, asyncqueue.WithWorkers(1))
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			Expect(q.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(MatchError(asyncqueue.ErrClosed))
+
+			d.Release()
+			Expect(q.Shutdown(context.Background())).To(Succeed())
+			Expect(d.Received()).To(HaveLen(2))
+		})
+
+		It("can be used with bridge.Publisher", func() {
+			d.Release()
+			q = asyncqueue.New(d.Dispatch)
+			pub, err := bridge.NewPublisher(q.Publish, bridge.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+
+			env := callback("app_mention")
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(env.Body))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			pub.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Eventually(d.Received).Should(Equal([]string{string(env.Body)}))
+		})
+
+		It("passes requests that fail to be processed to the dead-letter hook", func() {
+			d.Release()
+			dead := make(chan error, 1)
+			q = asyncqueue.New(func(context.Context, *bridge.Envelope) error {
+				return errors.New("oops")
+			}, asyncqueue.OnDeadLetter(func(_ context.Context, _ *asyncqueue.Item, err error) {
+				dead <- err
+			}))
+			Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+			Eventually(dead).Should(Receive(MatchError("oops")))
+		})
+
+		It("records the attempt key of the request in dead letters", func() {
+			d.Release()
+			dead := make(chan *asyncqueue.Item, 1)
+			var hookKey attempt.Key
+			q = asyncqueue.New(func(context.Context, *bridge.Envelope) error {
+				return errors.New("oops")
+			}, asyncqueue.OnDeadLetter(func(ctx context.Context, item *asyncqueue.Item, _ error) {
+				hookKey, _ = attempt.FromContext(ctx)
+				dead <- item
+			}))
+			env := &bridge.Envelope{
+				Body:   []byte(`{"type": "event_callback", "event_id": "Ev123", "event": {"type": "message"}}`),
+				Header: http.Header{"X-Slack-Retry-Num": []string{"1"}},
+			}
+			Expect(q.Enqueue(context.Background(), env)).To(Succeed())
+			var item *asyncqueue.Item
+			Eventually(dead).Should(Receive(&item))
+			Expect(item.Attempt).To(Equal(attempt.Key{EventID: "Ev123", RetryNum: 1}))
+			Expect(hookKey).To(Equal(item.Attempt))
+		})
+	})
+
+	Describe("Shedder", func() {
+		var (
+			d    *blockingDispatcher
+			q    *asyncqueue.Queue
+			dead []*asyncqueue.Item
+		)
+
+		// newFullQueue creates a Queue whose worker is busy and that holds `pending`.
+		newFullQueue := func(shedder asyncqueue.Shedder, pending ...*bridge.Envelope) {
+			q = asyncqueue.New(d.Dispatch, asyncqueue.WithWorkers(1), asyncqueue.WithCapacity(len(pending)), asyncqueue.WithShedder(shedder),
+				asyncqueue.OnDeadLetter(func(_ context.Context, item *asyncqueue.Item, err error) {
+					Expect(errors.Is(err, asyncqueue.ErrShed)).To(BeTrue())
+					dead = append(dead, item)
+				}))
+			Expect(q.Enqueue(context.Background(), callback("busy"))).To(Succeed())
+			Eventually(d.started).Should(Receive())
+			for _, env := range pending {
+				Expect(q.Enqueue(context.Background(), env)).To(Succeed())
+			}
+		}
+
+		BeforeEach(func() {
+			d = newBlockingDispatcher()
+			dead = nil
+		})
+
+		AfterEach(func() {
+			d.Release()
+			Expect(q.Shutdown(context.Background())).To(Succeed())
+		})
+
+		Describe("DropOldest", func() {
+			It("drops the oldest request with the lowest priority", func() {
+				message := callback("message")
+				newFullQueue(asyncqueue.DropOldest(), message, callback("message"), callback("app_mention"))
+				Expect(q.Enqueue(context.Background(), interaction())).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Envelope).To(BeIdenticalTo(message))
+				Expect(q.Stats()).To(Equal(asyncqueue.Stats{Pending: 3, Shed: map[asyncqueue.Priority]int64{asyncqueue.PriorityLow: 1}}))
+			})
+
+			It("drops the incoming request if it has the lowest priority", func() {
+				newFullQueue(asyncqueue.DropOldest(), callback("app_mention"))
+				message := callback("message")
+				Expect(q.Enqueue(context.Background(), message)).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Envelope).To(BeIdenticalTo(message))
+				Expect(q.Len()).To(Equal(1))
+			})
+		})
+
+		Describe("DropByType", func() {
+			It("drops the oldest pending request of the given types", func() {
+				message := callback("message")
+				newFullQueue(asyncqueue.DropByType("message"), callback("reaction_added"), message, callback("message"))
+				Expect(q.Enqueue(context.Background(), callback("reaction_added"))).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Envelope).To(BeIdenticalTo(message))
+			})
+
+			It("drops the incoming request if no pending request has the given types", func() {
+				newFullQueue(asyncqueue.DropByType("message"), callback("reaction_added"))
+				Expect(q.Enqueue(context.Background(), callback("message"))).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(q.Stats().Shed).To(Equal(map[asyncqueue.Priority]int64{asyncqueue.PriorityLow: 1}))
+			})
+
+			It("rejects the incoming request if no request has the given types", func() {
+				newFullQueue(asyncqueue.DropByType("message"), callback("reaction_added"))
+				err := q.Enqueue(context.Background(), callback("app_mention"))
+				Expect(errors.Is(err, asyncqueue.ErrFull)).To(BeTrue())
+				Expect(dead).To(BeEmpty())
+				Expect(q.Stats().Rejected).To(Equal(int64(1)))
+			})
+		})
+
+		Describe("Sample", func() {
+			It("drops every incoming request if the rate is 0", func() {
+				newFullQueue(asyncqueue.Sample(0), callback("message"))
+				incoming := interaction()
+				Expect(q.Enqueue(context.Background(), incoming)).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Envelope).To(BeIdenticalTo(incoming))
+			})
+
+			It("accepts every incoming request if the rate is 1", func() {
+				message := callback("message")
+				newFullQueue(asyncqueue.Sample(1), message)
+				Expect(q.Enqueue(context.Background(), interaction())).To(Succeed())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Envelope).To(BeIdenticalTo(message))
+			})
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package asyncqueue
+
+import (
+	"math/rand"
+)
+
+// Shedder decides which request to drop when a request is given to a full Queue.
+//
+// Dropped requests are counted in Stats and passed to the hook set by OnDeadLetter, but never processed.
+// Shedders are called while the Queue is locked, so they should return quickly.
+type Shedder interface {
+	// Shed returns the request to drop: either `incoming` or one of `pending`, which holds requests in the Queue in the order in which they are to be processed.
+	// If it returns nil, `incoming` is rejected with ErrFull as if no Shedder is set.
+	Shed(incoming *Item, pending []*Item) *Item
+}
+
+type ShedderFunc func(incoming *Item, pending []*Item) *Item
+
+func (f ShedderFunc) Shed(incoming *Item, pending []*Item) *Item {
+	return f(incoming, pending)
+}
+
+// DropOldest returns a Shedder that drops the oldest request with the lowest priority, so that new requests are always accepted.
+//
+// If `incoming` has a lower priority than every pending request, `incoming` itself is dropped.
+func DropOldest() Shedder {
+	return ShedderFunc(func(incoming *Item, pending []*Item) *Item {
+		var oldest *Item
+		// pending is sorted by priority in descending order, so the first one of the last class is the oldest one with the lowest priority.
+		for i := len(pending) - 1; i >= 0; i-- {
+			if oldest != nil && pending[i].Priority != oldest.Priority {
+				break
+			}
+			oldest = pending[i]
+		}
+		if oldest == nil || incoming.Priority < oldest.Priority {
+			return incoming
+		}
+		return oldest
+	})
+}
+
+// DropByType returns a Shedder that drops the oldest pending request whose event type (see EventType) is one of `types`.
+//
+// If there is no such request, `incoming` is dropped if its event type is one of `types`, and rejected otherwise.
+func DropByType(types ...string) Shedder {
+	set := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		set[t] = struct{}{}
+	}
+	matches := func(item *Item) bool {
+		_, ok := set[EventType(item.Envelope)]
+		return ok
+	}
+	return ShedderFunc(func(incoming *Item, pending []*Item) *Item {
+		var oldest *Item
+		for _, item := range pending {
+			if matches(item) && (oldest == nil || item.EnqueuedAt.Before(oldest.EnqueuedAt)) {
+				oldest = item
+			}
+		}
+		if oldest != nil {
+			return oldest
+		}
+		if matches(incoming) {
+			return incoming
+		}
+		return nil
+	})
+}
+
+// Sample returns a Shedder that accepts only a fraction `rate` (between 0 and 1) of requests given to a full Queue
+// by dropping pending requests as DropOldest does, and drops the others.
+//
+// This keeps a representative sample of requests during bursts, which is useful for analytics handlers.
+func Sample(rate float64) Shedder {
+	dropOldest := DropOldest()
+	return ShedderFunc(func(incoming *Item, pending []*Item) *Item {
+		if rand.Float64() < rate {
+			return dropOldest.Shed(incoming, pending)
+		}
+		return incoming
+	})
+}
@@ -0,0 +1,86 @@
+// Package attempt correlates deliveries of the same event when Slack retries it.
+//
+// Slack retries an event when the app responds with an error or doesn't respond in time, sending the same `event_id` with
+// the `X-Slack-Retry-Num` header incremented. A Key combines both, so that it identifies each attempt to deliver an event
+// and stays the same across processes and restarts.
+//
+// The eventrouter puts the Key of every `event_callback` event into the context before calling enrichers and handlers,
+// so it is available to handlers, to the hook set by `routeroptions.OnError`, and to ResultSinks:
+//
+//	routeroptions.OnError(func(ctx context.Context, err error) {
+//		if key, ok := attempt.FromContext(ctx); ok {
+//			log.Printf("attempt %s failed: %v", key, err)
+//		}
+//	})
+package attempt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+// Key identifies an attempt to deliver an event.
+type Key struct {
+	// EventID is the ID of the event (i.e. `event_id` in the outer event). This is the same in all attempts.
+	EventID string `json:"event_id"`
+
+	// RetryNum is the number of times Slack has retried the event. This is 0 for the first attempt.
+	RetryNum int `json:"retry_num"`
+}
+
+// New returns the Key of the attempt to deliver the event `eventID` with the request headers `h`.
+//
+// If the `X-Slack-Retry-Num` header is absent or malformed, the attempt is considered to be the first one.
+func New(eventID string, h http.Header) Key {
+	// ParseRetryNum returns 0 for malformed headers as well.
+	retryNum, _ := slackheaders.ParseRetryNum(h)
+	return Key{EventID: eventID, RetryNum: retryNum}
+}
+
+// Parse returns the Key of the attempt to deliver the event in the raw request `body` with the request headers `h`.
+//
+// It returns false if `body` is not an `event_callback` event with an ID (e.g. interactions and slash commands).
+func Parse(body []byte, h http.Header) (Key, bool) {
+	var outer struct {
+		Type    string `json:"type"`
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(body, &outer); err != nil {
+		return Key{}, false
+	}
+	if outer.Type != slackevents.CallbackEvent || outer.EventID == "" {
+		return Key{}, false
+	}
+	return New(outer.EventID, h), true
+}
+
+// IsRetry returns true if and only if the attempt is a retry of a previous one.
+func (k Key) IsRetry() bool {
+	return k.RetryNum > 0
+}
+
+// String returns the Key in the form of `<event_id>#<retry_num>` (e.g. `Ev08MFMKH6#1`), which is suitable for logs and metrics.
+func (k Key) String() string {
+	return k.EventID + "#" + strconv.Itoa(k.RetryNum)
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context that holds `key`.
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the Key stored by NewContext.
+//
+// It returns false if the context doesn't hold any Key (e.g. contexts of interactions and slash commands).
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
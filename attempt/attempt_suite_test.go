@@ -0,0 +1,13 @@
+package attempt_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAttempt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Attempt Suite")
+}
@@ -0,0 +1,75 @@
+package attempt_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/attempt"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+var _ = Describe("Attempt", func() {
+	retry := func(n string) http.Header {
+		h := http.Header{}
+		h.Set(slackheaders.RetryNum, n)
+		return h
+	}
+
+	Describe("New", func() {
+		It("returns the key of the first attempt if the request is not a retry", func() {
+			key := attempt.New("Ev001", http.Header{})
+			Expect(key).To(Equal(attempt.Key{EventID: "Ev001"}))
+			Expect(key.IsRetry()).To(BeFalse())
+		})
+
+		It("returns the key of the retry", func() {
+			key := attempt.New("Ev001", retry("2"))
+			Expect(key).To(Equal(attempt.Key{EventID: "Ev001", RetryNum: 2}))
+			Expect(key.IsRetry()).To(BeTrue())
+		})
+
+		It("treats malformed headers as the first attempt", func() {
+			Expect(attempt.New("Ev001", retry("invalid"))).To(Equal(attempt.Key{EventID: "Ev001"}))
+		})
+	})
+
+	Describe("Parse", func() {
+		It("returns the key of the event in the body", func() {
+			key, ok := attempt.Parse([]byte(`{"type": "event_callback", "event_id": "Ev001", "event": {"type": "message"}}`), retry("1"))
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(attempt.Key{EventID: "Ev001", RetryNum: 1}))
+		})
+
+		It("returns false if the body is not an event with an ID", func() {
+			_, ok := attempt.Parse([]byte(`{"type": "url_verification", "challenge": "abc"}`), http.Header{})
+			Expect(ok).To(BeFalse())
+			_, ok = attempt.Parse([]byte(`{"type": "event_callback", "event": {"type": "message"}}`), http.Header{})
+			Expect(ok).To(BeFalse())
+			_, ok = attempt.Parse([]byte(`payload=%7B%7D`), http.Header{})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("String", func() {
+		It("combines the event ID and the retry number", func() {
+			Expect(attempt.Key{EventID: "Ev001", RetryNum: 1}.String()).To(Equal("Ev001#1"))
+		})
+	})
+
+	Describe("FromContext", func() {
+		It("returns the key stored by NewContext", func() {
+			ctx := attempt.NewContext(context.Background(), attempt.Key{EventID: "Ev001", RetryNum: 1})
+			key, ok := attempt.FromContext(ctx)
+			Expect(ok).To(BeTrue())
+			Expect(key).To(Equal(attempt.Key{EventID: "Ev001", RetryNum: 1}))
+		})
+
+		It("returns false if the context doesn't hold any key", func() {
+			_, ok := attempt.FromContext(context.Background())
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,261 @@
+// Package audit provides a handler that archives every event and interaction to a pluggable Sink, for compliance purposes.
+//
+// Since Logger is both an `eventrouter.Handler` and an `interactionrouter.Handler`, it can be registered like any other handler,
+// or used as a target of mirroring and fan-out:
+//
+//	logger := audit.New(audit.JSONLines(os.Stdout), audit.RedactText(), audit.FallThrough())
+//	r.On(slackevents.Message, logger)
+//	r.OnMessage(handleMessage)
+//
+// Sinks for other destinations (e.g. object storages) can be implemented by users.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/attempt"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/redact"
+)
+
+// Kinds of Records.
+const (
+	// KindEvent is the kind of Records of events from Events API.
+	KindEvent = "event"
+
+	// KindInteraction is the kind of Records of interactions.
+	KindInteraction = "interaction"
+)
+
+// Redacted is the text that RedactText replaces texts of messages with.
+const Redacted = "[REDACTED]"
+
+// Record is an entry of the audit log.
+type Record struct {
+	// Time is the time when the Logger received the event or the interaction.
+	Time time.Time `json:"time"`
+
+	// Kind is either KindEvent or KindInteraction.
+	Kind string `json:"kind"`
+
+	// Type is the type of the inner event or the interaction.
+	Type string `json:"type"`
+
+	// TeamID is the ID of the workspace, if any.
+	TeamID string `json:"team_id,omitempty"`
+
+	// EventID is the ID of the event. This is empty for interactions.
+	EventID string `json:"event_id,omitempty"`
+
+	// Attempt identifies the attempt to deliver the event (see `attempt.Key.String`), so that retries of the same event can be told apart.
+	// This is empty for interactions.
+	Attempt string `json:"attempt,omitempty"`
+
+	// Payload is the event or the interaction in JSON.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Sink stores Records.
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+}
+
+type SinkFunc func(ctx context.Context, record *Record) error
+
+func (f SinkFunc) Write(ctx context.Context, record *Record) error {
+	return f(ctx, record)
+}
+
+type jsonLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// JSONLines returns a Sink that writes each Record to `w` as a line of JSON (e.g. to `os.Stdout` or a file).
+//
+// Writes are serialized, so `w` doesn't need to be safe for concurrent use.
+func JSONLines(w io.Writer) Sink {
+	return &jsonLinesSink{w: w}
+}
+
+func (s *jsonLinesSink) Write(_ context.Context, record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Option configures the Logger.
+type Option interface {
+	apply(*Logger)
+}
+
+type optionFunc func(*Logger)
+
+func (f optionFunc) apply(l *Logger) {
+	f(l)
+}
+
+// RedactText makes the Logger replace texts of messages in payloads with Redacted.
+func RedactText() Option {
+	return WithTextRedactor(func(string) string {
+		return Redacted
+	})
+}
+
+// WithTextRedactor makes the Logger replace texts of messages in payloads with what `redactor` returns.
+//
+// Texts are values of `text` fields at any depth of payloads, so texts in blocks, attachments and edited messages are also redacted.
+func WithTextRedactor(redactor func(text string) string) Option {
+	return optionFunc(func(l *Logger) {
+		l.redactText = redactor
+	})
+}
+
+// WithPolicy makes the Logger apply `policy` to all string values in payloads (e.g. to strip tokens and mask email addresses).
+//
+// This is applied after WithTextRedactor (or RedactText), if any.
+func WithPolicy(policy *redact.Policy) Option {
+	return optionFunc(func(l *Logger) {
+		l.policy = policy
+	})
+}
+
+// FallThrough makes the Logger return `routererrors.NotInterested` after writing Records, so that the Router passes events to other handlers.
+func FallThrough() Option {
+	return optionFunc(func(l *Logger) {
+		l.fallThrough = true
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(l *Logger) {
+		l.now = now
+	})
+}
+
+// Logger writes Records of events and interactions that it handles to a Sink.
+type Logger struct {
+	sink        Sink
+	redactText  func(string) string
+	policy      *redact.Policy
+	fallThrough bool
+	now         func() time.Time
+}
+
+// New creates a new Logger that writes Records to `sink`.
+func New(sink Sink, opts ...Option) *Logger {
+	l := &Logger{sink: sink, now: time.Now}
+	for _, o := range opts {
+		o.apply(l)
+	}
+	return l
+}
+
+// HandleEventsAPIEvent writes a Record of `e`. Errors returned from the Sink are returned as is.
+//
+// The payload is the raw request body if it is available, and otherwise the outer event encoded by slack-go.
+func (l *Logger) HandleEventsAPIEvent(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	record := &Record{
+		Time:   l.now(),
+		Kind:   KindEvent,
+		Type:   e.InnerEvent.Type,
+		TeamID: e.TeamID,
+	}
+	if cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent); ok {
+		record.EventID = cb.EventID
+	}
+	if key, ok := attempt.FromContext(ctx); ok {
+		record.Attempt = key.String()
+	}
+	payload := routerutils.RawBody(ctx)
+	if payload == nil {
+		var err error
+		if payload, err = json.Marshal(e.Data); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+	return l.write(ctx, record, payload)
+}
+
+// HandleInteraction writes a Record of `callback`. Errors returned from the Sink are returned as is.
+func (l *Logger) HandleInteraction(ctx context.Context, callback *slack.InteractionCallback) error {
+	record := &Record{
+		Time:   l.now(),
+		Kind:   KindInteraction,
+		Type:   string(callback.Type),
+		TeamID: callback.Team.ID,
+	}
+	payload, err := json.Marshal(callback)
+	if err != nil {
+		return fmt.Errorf("failed to encode interaction: %w", err)
+	}
+	return l.write(ctx, record, payload)
+}
+
+func (l *Logger) write(ctx context.Context, record *Record, payload []byte) error {
+	if l.redactText != nil || l.policy != nil {
+		var err error
+		if payload, err = l.redact(payload); err != nil {
+			return err
+		}
+	}
+	record.Payload = payload
+	if err := l.sink.Write(ctx, record); err != nil {
+		return err
+	}
+	if l.fallThrough {
+		return routererrors.NotInterested
+	}
+	return nil
+}
+
+func (l *Logger) redact(payload []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	v = l.redactValue(v)
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return redacted, nil
+}
+
+func (l *Logger) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && key == "text" && l.redactText != nil {
+				v[key] = l.policy.String(l.redactText(s))
+				continue
+			}
+			v[key] = l.redactValue(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = l.redactValue(child)
+		}
+	case string:
+		return l.policy.String(v)
+	}
+	return v
+}
@@ -0,0 +1,200 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/audit"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+const messageBody = `{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "my password is hunter2",
+		"ts": "1355517523.000005",
+		"blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "my password is hunter2"}}]
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+
+var _ = Describe("Audit", func() {
+	var (
+		ctx     = context.Background()
+		now     = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		records []*audit.Record
+		sink    = audit.SinkFunc(func(_ context.Context, record *audit.Record) error {
+			records = append(records, record)
+			return nil
+		})
+		serve = func(r *eventrouter.Router) int {
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(messageBody)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result().StatusCode
+		}
+	)
+	BeforeEach(func() {
+		records = nil
+	})
+
+	Describe("HandleEventsAPIEvent", func() {
+		It("writes the raw body of the event", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, audit.New(sink, audit.WithNowFunc(func() time.Time { return now })))
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Time).To(Equal(now))
+			Expect(records[0].Kind).To(Equal(audit.KindEvent))
+			Expect(records[0].Type).To(Equal("message"))
+			Expect(records[0].TeamID).To(Equal("TXXXXXXXX"))
+			Expect(records[0].EventID).To(Equal("Ev08MFMKH6"))
+			Expect(records[0].Attempt).To(Equal("Ev08MFMKH6#0"))
+			Expect(records[0].Payload).To(MatchJSON(messageBody))
+		})
+
+		It("tells retries of the same event apart", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, audit.New(sink))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(messageBody)))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set(slackheaders.RetryNum, "3")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Attempt).To(Equal("Ev08MFMKH6#3"))
+		})
+
+		It("redacts texts at any depth", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, audit.New(sink, audit.RedactText()))
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(records).To(HaveLen(1))
+			Expect(string(records[0].Payload)).NotTo(ContainSubstring("hunter2"))
+			var payload struct {
+				Event struct {
+					Text   string `json:"text"`
+					User   string `json:"user"`
+					Blocks []struct {
+						Text struct {
+							Text string `json:"text"`
+						} `json:"text"`
+					} `json:"blocks"`
+				} `json:"event"`
+			}
+			Expect(json.Unmarshal(records[0].Payload, &payload)).To(Succeed())
+			Expect(payload.Event.Text).To(Equal(audit.Redacted))
+			Expect(payload.Event.Blocks[0].Text.Text).To(Equal(audit.Redacted))
+			Expect(payload.Event.User).To(Equal("U2147483697"))
+		})
+
+		It("applies the redaction policy to all strings", func() {
+			e := &slackevents.EventsAPIEvent{
+				Data: &slackevents.EventsAPICallbackEvent{EventID: "Ev001"},
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: slackevents.Message,
+					Data: &slackevents.MessageEvent{Text: "hello"},
+				},
+			}
+			ctx := routerutils.WithRequest(ctx, []byte(`{"event": {"text": "mail alice@example.com", "user_email": "bob@example.com"}}`), http.Header{})
+			logger := audit.New(sink, audit.WithTextRedactor(strings.ToUpper), audit.WithPolicy(redact.New(redact.MaskEmails())))
+			Expect(logger.HandleEventsAPIEvent(ctx, e)).To(Succeed())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Payload).To(MatchJSON(`{"event": {"text": "MAIL ***@EXAMPLE.COM", "user_email": "***@example.com"}}`))
+		})
+
+		It("lets other handlers process the event with FallThrough", func() {
+			numHandled := 0
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, audit.New(sink, audit.FallThrough()))
+			r.OnMessage(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+				numHandled++
+				return nil
+			}))
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(records).To(HaveLen(1))
+			Expect(numHandled).To(Equal(1))
+		})
+
+		It("encodes the event when the raw body is not available", func() {
+			e := &slackevents.EventsAPIEvent{
+				TeamID: "T001",
+				Data:   &slackevents.EventsAPICallbackEvent{EventID: "Ev001"},
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: slackevents.Message,
+					Data: &slackevents.MessageEvent{Text: "hello"},
+				},
+			}
+			Expect(audit.New(sink).HandleEventsAPIEvent(ctx, e)).To(Succeed())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].EventID).To(Equal("Ev001"))
+			Expect(string(records[0].Payload)).To(ContainSubstring("Ev001"))
+		})
+
+		It("returns errors from the sink", func() {
+			failing := audit.SinkFunc(func(context.Context, *audit.Record) error {
+				return errors.New("disk full")
+			})
+			e := &slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Type: slackevents.Message}}
+			err := audit.New(failing, audit.FallThrough()).HandleEventsAPIEvent(ctx, e)
+			Expect(err).To(MatchError("disk full"))
+			Expect(errors.Is(err, routererrors.NotInterested)).To(BeFalse())
+		})
+	})
+
+	Describe("HandleInteraction", func() {
+		It("writes the interaction", func() {
+			callback := &slack.InteractionCallback{Type: slack.InteractionTypeBlockActions}
+			callback.Team.ID = "T001"
+			Expect(audit.New(sink).HandleInteraction(ctx, callback)).To(Succeed())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Kind).To(Equal(audit.KindInteraction))
+			Expect(records[0].Type).To(Equal("block_actions"))
+			Expect(records[0].TeamID).To(Equal("T001"))
+		})
+	})
+
+	Describe("JSONLines", func() {
+		It("writes each record as a line of JSON", func() {
+			var buf bytes.Buffer
+			s := audit.JSONLines(&buf)
+			for _, id := range []string{"Ev001", "Ev002"} {
+				Expect(s.Write(ctx, &audit.Record{Kind: audit.KindEvent, EventID: id, Payload: json.RawMessage(`{}`)})).To(Succeed())
+			}
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			Expect(lines).To(HaveLen(2))
+			var record audit.Record
+			Expect(json.Unmarshal(lines[1], &record)).To(Succeed())
+			Expect(record.EventID).To(Equal("Ev002"))
+		})
+	})
+})
@@ -0,0 +1,276 @@
+// Package authctx resolves the installation of the app for each request and puts an authorized client into the context.
+//
+// Handlers can obtain the client by `Client(ctx)` regardless of which router (eventrouter, interactionrouter or commandrouter) they are registered to,
+// so the same handlers work in both single-workspace and multi-workspace deployments.
+package authctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/internal/lrucache"
+)
+
+const (
+	// DefaultCacheSize is the default maximum number of cached installations.
+	DefaultCacheSize = 1000
+
+	// DefaultTTL is the default time to live of cached installations.
+	DefaultTTL = 10 * time.Minute
+)
+
+// ErrNotInstalled indicates that there is no installation for the team (or the organization) that sent a request.
+//
+// InstallationStores should return this (or errors that wrap it) when installations are not found.
+var ErrNotInstalled = errors.New("the app is not installed")
+
+// Installation is an installation of the app to a workspace (or an organization).
+type Installation struct {
+	// TeamID is the ID of the workspace. This is empty for org-wide installations.
+	TeamID string
+
+	// EnterpriseID is the ID of the Enterprise Grid organization, if any.
+	EnterpriseID string
+
+	// BotToken is a bot token (`xoxb-...`) issued for the installation.
+	BotToken string
+
+	// BotUserID is the ID of the bot user.
+	BotUserID string
+
+	// Scopes are the scopes granted to BotToken.
+	Scopes []string
+}
+
+// HasScope returns true if and only if `scope` is granted to the installation.
+func (i *Installation) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallationStore finds installations of the app.
+//
+// Typically it is backed by the storage where the OAuth flow saves installations.
+// For single-workspace deployments, Static can be used instead.
+type InstallationStore interface {
+	// FindInstallation returns an installation for the given team and organization.
+	// Either of them may be empty.
+	FindInstallation(ctx context.Context, teamID, enterpriseID string) (*Installation, error)
+}
+
+type InstallationStoreFunc func(ctx context.Context, teamID, enterpriseID string) (*Installation, error)
+
+func (f InstallationStoreFunc) FindInstallation(ctx context.Context, teamID, enterpriseID string) (*Installation, error) {
+	return f(ctx, teamID, enterpriseID)
+}
+
+// Static returns an InstallationStore that always returns `inst`. This is useful for single-workspace deployments.
+func Static(inst *Installation) InstallationStore {
+	return InstallationStoreFunc(func(context.Context, string, string) (*Installation, error) {
+		return inst, nil
+	})
+}
+
+// Auth is an authorization resolved for a request.
+type Auth struct {
+	Installation *Installation
+	Client       *slack.Client
+}
+
+type authKey struct{}
+
+// WithAuth returns a new context that holds `auth`. This is mainly intended to be used in tests.
+func WithAuth(ctx context.Context, auth *Auth) context.Context {
+	return context.WithValue(ctx, authKey{}, auth)
+}
+
+// FromContext returns the Auth resolved for the current request, or nil if it is not resolved.
+func FromContext(ctx context.Context) *Auth {
+	auth, _ := ctx.Value(authKey{}).(*Auth)
+	return auth
+}
+
+// Client returns a client authorized for the current request, or nil if it is not resolved.
+func Client(ctx context.Context) *slack.Client {
+	if auth := FromContext(ctx); auth != nil {
+		return auth.Client
+	}
+	return nil
+}
+
+// Option configures the Resolver.
+type Option interface {
+	apply(*Resolver)
+}
+
+type optionFunc func(*Resolver)
+
+func (f optionFunc) apply(r *Resolver) {
+	f(r)
+}
+
+// WithClientFactory sets a function that creates a client from an installation.
+//
+// If not set, clients are created by `slack.New(inst.BotToken)`.
+func WithClientFactory(newClient func(inst *Installation) *slack.Client) Option {
+	return optionFunc(func(r *Resolver) {
+		r.newClient = newClient
+	})
+}
+
+// WithCacheSize sets the maximum number of cached installations and their clients.
+//
+// Setting 0 or less disables the limit.
+func WithCacheSize(size int) Option {
+	return optionFunc(func(r *Resolver) {
+		r.cacheSize = size
+	})
+}
+
+// WithTTL sets the time to live of cached installations and their clients.
+func WithTTL(ttl time.Duration) Option {
+	return optionFunc(func(r *Resolver) {
+		r.ttl = ttl
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Resolver) {
+		r.now = now
+	})
+}
+
+// Resolver resolves installations from requests by using an InstallationStore.
+//
+// Resolved installations and their clients are cached. See LifecycleHandler to evict them when tokens are revoked.
+type Resolver struct {
+	store       InstallationStore
+	newClient   func(*Installation) *slack.Client
+	cacheSize   int
+	ttl         time.Duration
+	now         func() time.Time
+	revokedHook func(ctx context.Context, teamID, enterpriseID string, e *slackevents.EventsAPIEvent) error
+	cache       *lrucache.Cache
+
+	// generation is incremented by Invalidate. Resolve doesn't cache installations found by lookups
+	// that started before an invalidation, since they may have been revoked in the meantime.
+	mu         sync.Mutex
+	generation uint64
+}
+
+// New creates a new Resolver.
+func New(store InstallationStore, opts ...Option) *Resolver {
+	r := &Resolver{
+		store: store,
+		newClient: func(inst *Installation) *slack.Client {
+			return slack.New(inst.BotToken)
+		},
+		cacheSize: DefaultCacheSize,
+		ttl:       DefaultTTL,
+		now:       time.Now,
+	}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	r.cache = lrucache.New(r.cacheSize, r.ttl, r.now)
+	return r
+}
+
+// Resolve finds an installation for the given team and organization, and returns a new context that holds the Auth.
+func (r *Resolver) Resolve(ctx context.Context, teamID, enterpriseID string) (context.Context, error) {
+	key := cacheKey(teamID, enterpriseID)
+	if auth, ok := r.cache.Get(key); ok {
+		return WithAuth(ctx, auth.(*Auth)), nil
+	}
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+	inst, err := r.store.FindInstallation(ctx, teamID, enterpriseID)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to find installation for team %q (enterprise %q): %w", teamID, enterpriseID, err)
+	}
+	if inst == nil {
+		return ctx, fmt.Errorf("team %q (enterprise %q): %w", teamID, enterpriseID, ErrNotInstalled)
+	}
+	auth := &Auth{Installation: inst, Client: r.newClient(inst)}
+	r.mu.Lock()
+	if r.generation == generation {
+		r.cache.Add(key, auth)
+	}
+	r.mu.Unlock()
+	return WithAuth(ctx, auth), nil
+}
+
+// Invalidate evicts the cached installation and its client for the given team and organization.
+//
+// Installations that are being resolved concurrently are not cached either, so that they don't bring revoked installations back.
+func (r *Resolver) Invalidate(teamID, enterpriseID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	r.cache.Remove(cacheKey(teamID, enterpriseID))
+}
+
+func cacheKey(teamID, enterpriseID string) string {
+	return teamID + "/" + enterpriseID
+}
+
+// Enrich resolves the installation for an event. This makes the Resolver usable as an Enricher of eventrouter:
+//
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(resolver))
+//
+// Lifecycle events (`tokens_revoked` and `app_uninstalled`) are passed to handlers even if the app is no longer installed,
+// so that LifecycleHandler can process them.
+func (r *Resolver) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	teamID, enterpriseID := e.TeamID, e.EnterpriseID
+	newCtx, err := r.Resolve(ctx, teamID, enterpriseID)
+	if errors.Is(err, ErrNotInstalled) && isLifecycleEvent(e) {
+		return ctx, nil
+	}
+	return newCtx, err
+}
+
+// PreflightCheck checks that the InstallationStore is reachable, if it implements `eventrouter.PreflightChecker`.
+// Otherwise it does nothing.
+func (r *Resolver) PreflightCheck(ctx context.Context) error {
+	if c, ok := r.store.(eventrouter.PreflightChecker); ok {
+		return c.PreflightCheck(ctx)
+	}
+	return nil
+}
+
+// Interaction wraps `h` so that the installation is resolved before `h` is called.
+func (r *Resolver) Interaction(h interactionrouter.Handler) interactionrouter.Handler {
+	return interactionrouter.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		ctx, err := r.Resolve(ctx, callback.Team.ID, callback.Enterprise.ID)
+		if err != nil {
+			return err
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+// Command wraps `h` so that the installation is resolved before `h` is called.
+func (r *Resolver) Command(h commandrouter.Handler) commandrouter.Handler {
+	return commandrouter.HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) error {
+		ctx, err := r.Resolve(ctx, cmd.TeamID, cmd.EnterpriseID)
+		if err != nil {
+			return err
+		}
+		return h.HandleSlashCommand(ctx, cmd)
+	})
+}
@@ -0,0 +1,13 @@
+package authctx_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuthctx(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Authctx Suite")
+}
@@ -0,0 +1,380 @@
+package authctx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/authctx"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+var _ = Describe("Authctx", func() {
+	var (
+		installations = map[string]*authctx.Installation{
+			"T111/": {TeamID: "T111", BotToken: "xoxb-111", Scopes: []string{"chat:write"}},
+			"/E999": {EnterpriseID: "E999", BotToken: "xoxb-999"},
+		}
+		store = authctx.InstallationStoreFunc(func(_ context.Context, teamID, enterpriseID string) (*authctx.Installation, error) {
+			if inst, ok := installations["/"+enterpriseID]; ok && enterpriseID != "" {
+				return inst, nil
+			}
+			return installations[teamID+"/"], nil
+		})
+		resolver *authctx.Resolver
+	)
+	BeforeEach(func() {
+		resolver = authctx.New(store)
+	})
+
+	Describe("Resolve", func() {
+		It("puts the Auth into the context", func() {
+			ctx, err := resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			auth := authctx.FromContext(ctx)
+			Expect(auth).NotTo(BeNil())
+			Expect(auth.Installation.BotToken).To(Equal("xoxb-111"))
+			Expect(auth.Installation.HasScope("chat:write")).To(BeTrue())
+			Expect(authctx.Client(ctx)).NotTo(BeNil())
+		})
+
+		It("returns ErrNotInstalled if there is no installation", func() {
+			_, err := resolver.Resolve(context.Background(), "T000", "")
+			Expect(err).To(MatchError(authctx.ErrNotInstalled))
+		})
+
+		It("uses the client factory if given", func() {
+			var created *authctx.Installation
+			resolver = authctx.New(store, authctx.WithClientFactory(func(inst *authctx.Installation) *slack.Client {
+				created = inst
+				return slack.New(inst.BotToken)
+			}))
+			_, err := resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created.TeamID).To(Equal("T111"))
+		})
+	})
+
+	Describe("Static", func() {
+		It("always returns the installation", func() {
+			inst := &authctx.Installation{BotToken: "xoxb-static"}
+			ctx, err := authctx.New(authctx.Static(inst)).Resolve(context.Background(), "TANY", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(authctx.FromContext(ctx).Installation).To(Equal(inst))
+		})
+	})
+
+	Describe("FromContext", func() {
+		It("returns nil if the Auth is not resolved", func() {
+			Expect(authctx.FromContext(context.Background())).To(BeNil())
+			Expect(authctx.Client(context.Background())).To(BeNil())
+		})
+	})
+
+	Describe("Enrich", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "T222",
+				"enterprise_id": "E999",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("resolves the installation of the organization", func() {
+			var token string
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				token = authctx.FromContext(ctx).Installation.BotToken
+				return nil
+			}))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/events", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(token).To(Equal("xoxb-999"))
+		})
+	})
+
+	Describe("Interaction", func() {
+		It("resolves the installation before calling the handler", func() {
+			var token string
+			h := resolver.Interaction(interactionrouter.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				token = authctx.FromContext(ctx).Installation.BotToken
+				return nil
+			}))
+			callback := &slack.InteractionCallback{}
+			callback.Team.ID = "T111"
+			Expect(h.HandleInteraction(context.Background(), callback)).To(Succeed())
+			Expect(token).To(Equal("xoxb-111"))
+		})
+
+		It("does not call the handler if the app is not installed", func() {
+			called := false
+			h := resolver.Interaction(interactionrouter.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				called = true
+				return nil
+			}))
+			callback := &slack.InteractionCallback{}
+			callback.Team.ID = "T000"
+			Expect(h.HandleInteraction(context.Background(), callback)).To(MatchError(authctx.ErrNotInstalled))
+			Expect(called).To(BeFalse())
+		})
+	})
+
+	Describe("Command", func() {
+		It("resolves the installation before calling the handler", func() {
+			var token string
+			h := resolver.Command(commandrouter.HandlerFunc(func(ctx context.Context, _ *slack.SlashCommand) error {
+				token = authctx.FromContext(ctx).Installation.BotToken
+				return nil
+			}))
+			Expect(h.HandleSlashCommand(context.Background(), &slack.SlashCommand{TeamID: "T111"})).To(Succeed())
+			Expect(token).To(Equal("xoxb-111"))
+		})
+	})
+
+	Describe("RequireScopes", func() {
+		var ctx context.Context
+		BeforeEach(func() {
+			var err error
+			ctx, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("succeeds if all scopes are granted", func() {
+			req := authctx.RequireScopes("chat:write")
+			Expect(req.Check(ctx)).To(Succeed())
+			Expect(req.Match(ctx)).To(BeTrue())
+		})
+
+		It("tells which scopes are missing", func() {
+			req := authctx.RequireScopes("chat:write", "reactions:read", "users:read")
+			err := req.Check(ctx)
+			Expect(err).To(MatchError(authctx.ErrMissingScopes))
+			var missing *authctx.MissingScopesError
+			Expect(errors.As(err, &missing)).To(BeTrue())
+			Expect(missing.TeamID).To(Equal("T111"))
+			Expect(missing.Missing).To(Equal([]string{"reactions:read", "users:read"}))
+			Expect(req.Match(ctx)).To(BeFalse())
+		})
+
+		It("returns ErrNotResolved if no installation is resolved", func() {
+			Expect(authctx.RequireScopes("chat:write").Check(context.Background())).To(MatchError(authctx.ErrNotResolved))
+		})
+
+		It("does not call wrapped handlers if scopes are missing", func() {
+			called := false
+			h := authctx.RequireScopes("reactions:read").Command(commandrouter.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) error {
+				called = true
+				return nil
+			}))
+			Expect(h.HandleSlashCommand(ctx, &slack.SlashCommand{})).To(MatchError(authctx.ErrMissingScopes))
+			Expect(called).To(BeFalse())
+		})
+
+		It("calls wrapped handlers if scopes are granted", func() {
+			called := false
+			h := authctx.RequireScopes("chat:write").Events(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				called = true
+				return nil
+			}))
+			Expect(h.HandleEventsAPIEvent(ctx, &slackevents.EventsAPIEvent{})).To(Succeed())
+			Expect(called).To(BeTrue())
+		})
+	})
+
+	Describe("Caching", func() {
+		var (
+			numFound  int
+			installed bool
+			counting  = authctx.InstallationStoreFunc(func(_ context.Context, teamID, _ string) (*authctx.Installation, error) {
+				numFound++
+				if !installed {
+					return nil, nil
+				}
+				return &authctx.Installation{TeamID: teamID, BotToken: "xoxb-" + teamID}, nil
+			})
+			revoked = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "T111",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "tokens_revoked",
+					"tokens": {"oauth": [], "bot": ["UBOT"]}
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			uninstalled = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "T111",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "app_uninstalled"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH7",
+				"event_time": 1234567890
+			}`
+			serve = func(r *eventrouter.Router, body string) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/events", bytes.NewReader([]byte(body)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+		BeforeEach(func() {
+			numFound = 0
+			installed = true
+		})
+
+		It("caches installations", func() {
+			resolver = authctx.New(counting)
+			for i := 0; i < 3; i++ {
+				_, err := resolver.Resolve(context.Background(), "T111", "")
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(numFound).To(Equal(1))
+		})
+
+		It("expires cached installations after the TTL", func() {
+			now := time.Unix(1234567890, 0)
+			resolver = authctx.New(counting, authctx.WithTTL(time.Minute), authctx.WithNowFunc(func() time.Time { return now }))
+			_, err := resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			now = now.Add(2 * time.Minute)
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numFound).To(Equal(2))
+		})
+
+		It("evicts installations when tokens are revoked", func() {
+			var revokedTeam string
+			resolver = authctx.New(counting, authctx.OnRevoked(func(_ context.Context, teamID, _ string, e *slackevents.EventsAPIEvent) error {
+				revokedTeam = teamID
+				return nil
+			}))
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			resolver.Register(r)
+
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(serve(r, revoked)).To(Equal(http.StatusOK))
+			Expect(revokedTeam).To(Equal("T111"))
+
+			numFound = 0
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numFound).To(Equal(1))
+		})
+
+		It("does not keep installations resolved concurrently while the hook cleans them up", func() {
+			resolver = authctx.New(counting, authctx.OnRevoked(func(_ context.Context, _, _ string, _ *slackevents.EventsAPIEvent) error {
+				// A request of the same team arrives before the installation is deleted.
+				done := make(chan error)
+				go func() {
+					_, err := resolver.Resolve(context.Background(), "T111", "")
+					done <- err
+				}()
+				Expect(<-done).NotTo(HaveOccurred())
+				installed = false
+				return nil
+			}))
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			resolver.Register(r)
+
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(serve(r, revoked)).To(Equal(http.StatusOK))
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).To(MatchError(authctx.ErrNotInstalled))
+		})
+
+		It("does not cache installations found before they are revoked", func() {
+			found := make(chan struct{})
+			release := make(chan struct{})
+			slow := authctx.InstallationStoreFunc(func(ctx context.Context, teamID, enterpriseID string) (*authctx.Installation, error) {
+				inst, err := counting(ctx, teamID, enterpriseID)
+				if numFound == 1 {
+					close(found)
+					<-release
+				}
+				return inst, err
+			})
+			resolver = authctx.New(slow)
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			resolver.Register(r)
+
+			done := make(chan error)
+			go func() {
+				_, err := resolver.Resolve(context.Background(), "T111", "")
+				done <- err
+			}()
+			// The installation is found, and then revoked before the lookup finishes.
+			<-found
+			installed = false
+			Expect(serve(r, revoked)).To(Equal(http.StatusOK))
+			close(release)
+			Expect(<-done).NotTo(HaveOccurred())
+
+			_, err = resolver.Resolve(context.Background(), "T111", "")
+			Expect(err).To(MatchError(authctx.ErrNotInstalled))
+		})
+
+		It("processes app_uninstalled even if the installation is already deleted", func() {
+			installed = false
+			numRevoked := 0
+			resolver = authctx.New(counting, authctx.OnRevoked(func(_ context.Context, _, _ string, _ *slackevents.EventsAPIEvent) error {
+				numRevoked++
+				return nil
+			}))
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			resolver.Register(r)
+			Expect(serve(r, uninstalled)).To(Equal(http.StatusOK))
+			Expect(numRevoked).To(Equal(1))
+		})
+
+		It("responds with an error if the hook fails", func() {
+			resolver = authctx.New(counting, authctx.OnRevoked(func(_ context.Context, _, _ string, _ *slackevents.EventsAPIEvent) error {
+				return errors.New("storage is down")
+			}))
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(resolver))
+			Expect(err).NotTo(HaveOccurred())
+			resolver.Register(r)
+			Expect(serve(r, revoked)).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})
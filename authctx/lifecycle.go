@@ -0,0 +1,61 @@
+package authctx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// OnRevoked sets a hook that is called when tokens of an installation are revoked or the app is uninstalled.
+//
+// This is called by LifecycleHandler so that the app can clean up its own storage (e.g. the InstallationStore).
+// The cached installation is evicted both before and after the hook is called, and installations resolved concurrently
+// with either eviction are not cached (see Resolver.Invalidate), so the revoked installation doesn't stay in the cache.
+// `e` is either `tokens_revoked` or `app_uninstalled` event.
+func OnRevoked(hook func(ctx context.Context, teamID, enterpriseID string, e *slackevents.EventsAPIEvent) error) Option {
+	return optionFunc(func(r *Resolver) {
+		r.revokedHook = hook
+	})
+}
+
+// LifecycleHandler returns a Handler that evicts cached installations when `tokens_revoked` or `app_uninstalled` arrives.
+// Other events are ignored (i.e. it returns `routererrors.NotInterested`).
+//
+// Typically it is registered by Register.
+func (r *Resolver) LifecycleHandler() eventrouter.Handler {
+	return eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		if !isLifecycleEvent(e) {
+			return routererrors.NotInterested
+		}
+		teamID, enterpriseID := e.TeamID, e.EnterpriseID
+		r.Invalidate(teamID, enterpriseID)
+		if r.revokedHook == nil {
+			return nil
+		}
+		err := r.revokedHook(ctx, teamID, enterpriseID, e)
+		r.Invalidate(teamID, enterpriseID)
+		if err != nil {
+			return fmt.Errorf("failed to clean up installation for team %q (enterprise %q): %w", teamID, enterpriseID, err)
+		}
+		return nil
+	})
+}
+
+// Register registers LifecycleHandler to `router` for `tokens_revoked` and `app_uninstalled` events.
+func (r *Resolver) Register(router *eventrouter.Router) {
+	h := r.LifecycleHandler()
+	router.On(slackevents.TokensRevoked, h)
+	router.On(slackevents.AppUninstalled, h)
+}
+
+func isLifecycleEvent(e *slackevents.EventsAPIEvent) bool {
+	switch e.InnerEvent.Type {
+	case slackevents.TokensRevoked, slackevents.AppUninstalled:
+		return true
+	}
+	return false
+}
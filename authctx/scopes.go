@@ -0,0 +1,116 @@
+package authctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+)
+
+var (
+	// ErrNotResolved indicates that no installation is resolved for the current request.
+	// This usually means that the Resolver is not set up for the router.
+	ErrNotResolved = errors.New("no installation is resolved; use Resolver")
+
+	// ErrMissingScopes indicates that the installation lacks scopes that handlers need.
+	// Errors returned by ScopeRequirement are `*MissingScopesError`, which are equivalent to this in the sense of `errors.Is`.
+	ErrMissingScopes = errors.New("missing scopes")
+)
+
+// MissingScopesError is an error that tells which scopes are missing.
+type MissingScopesError struct {
+	TeamID       string
+	EnterpriseID string
+	Missing      []string
+}
+
+func (e *MissingScopesError) Error() string {
+	return fmt.Sprintf("missing scopes for team %q (enterprise %q): %s", e.TeamID, e.EnterpriseID, strings.Join(e.Missing, ", "))
+}
+
+func (e *MissingScopesError) Is(target error) bool {
+	return target == ErrMissingScopes
+}
+
+// ScopeRequirement checks whether the resolved installation has the scopes that handlers need.
+type ScopeRequirement struct {
+	scopes []string
+}
+
+// RequireScopes returns a ScopeRequirement that requires all of `scopes`.
+//
+// This is useful to fail fast with a clear error instead of getting confusing errors from Web API.
+func RequireScopes(scopes ...string) *ScopeRequirement {
+	return &ScopeRequirement{scopes: scopes}
+}
+
+// Check returns `*MissingScopesError` if the installation resolved for the current request lacks any of the required scopes.
+func (s *ScopeRequirement) Check(ctx context.Context) error {
+	auth := FromContext(ctx)
+	if auth == nil || auth.Installation == nil {
+		return ErrNotResolved
+	}
+	var missing []string
+	for _, scope := range s.scopes {
+		if !auth.Installation.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingScopesError{
+			TeamID:       auth.Installation.TeamID,
+			EnterpriseID: auth.Installation.EnterpriseID,
+			Missing:      missing,
+		}
+	}
+	return nil
+}
+
+// Match returns true if and only if Check succeeds.
+//
+// This makes the ScopeRequirement usable as a Condition (e.g. `message.When(authctx.RequireScopes("chat:write"))`),
+// in which case handlers are just skipped when scopes are missing.
+func (s *ScopeRequirement) Match(ctx context.Context) bool {
+	return s.Check(ctx) == nil
+}
+
+func (s *ScopeRequirement) String() string {
+	return fmt.Sprintf("RequireScopes(%s)", strings.Join(s.scopes, ", "))
+}
+
+// Events wraps `h` so that it is called only if the required scopes are granted. Otherwise the error from Check is returned.
+func (s *ScopeRequirement) Events(h eventrouter.Handler) eventrouter.Handler {
+	return eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		if err := s.Check(ctx); err != nil {
+			return err
+		}
+		return h.HandleEventsAPIEvent(ctx, e)
+	})
+}
+
+// Interaction wraps `h` so that it is called only if the required scopes are granted. Otherwise the error from Check is returned.
+func (s *ScopeRequirement) Interaction(h interactionrouter.Handler) interactionrouter.Handler {
+	return interactionrouter.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if err := s.Check(ctx); err != nil {
+			return err
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+// Command wraps `h` so that it is called only if the required scopes are granted. Otherwise the error from Check is returned.
+func (s *ScopeRequirement) Command(h commandrouter.Handler) commandrouter.Handler {
+	return commandrouter.HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) error {
+		if err := s.Check(ctx); err != nil {
+			return err
+		}
+		return h.HandleSlashCommand(ctx, cmd)
+	})
+}
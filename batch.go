@@ -0,0 +1,107 @@
+package eventrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/genkami/go-slack-event-router/bridge"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// BatchResult is a result of an envelope given to the batch endpoint.
+type BatchResult struct {
+	// Index is the index of the envelope in the request.
+	Index int `json:"index"`
+
+	// Status is the HTTP status code that the Router would respond with if the envelope was sent alone.
+	Status int `json:"status"`
+
+	// Body is the response body, if any.
+	Body string `json:"body,omitempty"`
+}
+
+// WithBatchEndpoint makes the Router accept a JSON array of `bridge.Envelope`s at `path` and dispatch each of them.
+//
+// This is intended for tools that replay recorded events. The response is a JSON array of BatchResults in the same order as the envelopes.
+// Envelopes are processed in order in the same way as ordinary requests, except that their signatures are not verified.
+// Instead, every request to `path` must be authorized by `authorize`. If it returns an error, the Router responds in the same way as when handlers return the error,
+// so it should return `routererrors.HttpError(http.StatusUnauthorized)` or the like.
+func WithBatchEndpoint(path string, authorize func(*http.Request) error) Option {
+	return optionFunc(func(r *Router) {
+		r.batchPath = path
+		r.batchAuthorizer = authorize
+	})
+}
+
+func (r *Router) routeBatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != r.batchPath {
+			next.ServeHTTP(w, req)
+			return
+		}
+		r.serveBatch(w, req)
+	})
+}
+
+func (r *Router) serveBatch(w http.ResponseWriter, req *http.Request) {
+	if err := r.batchAuthorizer(req); err != nil {
+		r.respondWithError(req.Context(), w, err)
+		return
+	}
+	body, err := r.readBody(req)
+	if err != nil {
+		r.respondWithError(req.Context(), w, err)
+		return
+	}
+	var envelopes []*bridge.Envelope
+	if err := json.Unmarshal(body, &envelopes); err != nil {
+		r.respondWithError(
+			req.Context(),
+			w,
+			fmt.Errorf("%s: %w", "invalid batch: "+err.Error(), routererrors.HttpError(http.StatusBadRequest)))
+		return
+	}
+
+	results := make([]BatchResult, 0, len(envelopes))
+	for i, env := range envelopes {
+		results = append(results, r.dispatchEnvelope(req, i, env))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (r *Router) dispatchEnvelope(batchReq *http.Request, index int, env *bridge.Envelope) BatchResult {
+	if env == nil {
+		return BatchResult{Index: index, Status: http.StatusBadRequest, Body: "missing envelope"}
+	}
+	resp := r.redeliver(batchReq.Context(), batchReq.URL.String(), env)
+	return BatchResult{Index: index, Status: resp.Status, Body: string(resp.Body)}
+}
+
+// Redeliver processes `env` in the same way as envelopes given to the batch endpoint, and returns the response.
+//
+// Its signature is NOT verified, so it must only be used for envelopes that were recorded after verification
+// (e.g. by `checkpoint.Recorder`). Never pass envelopes from untrusted sources.
+func (r *Router) Redeliver(ctx context.Context, env *bridge.Envelope) Response {
+	return r.redeliver(ctx, "/", env)
+}
+
+func (r *Router) redeliver(ctx context.Context, url string, env *bridge.Envelope) Response {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(env.Body))
+	if err != nil {
+		return Response{Status: routerutils.StatusCode(err), Body: []byte(err.Error())}
+	}
+	for k, vs := range env.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	w := &dispatchWriter{header: make(http.Header)}
+	r.serveHTTP(w, req)
+	return w.response()
+}
@@ -0,0 +1,180 @@
+// Package batching processes events in batches rather than one by one, which is useful for analytics handlers
+// that write events to data warehouses.
+//
+// A Batcher is a `router.Handler`, so it can be used with typed registrations through their generic adapters:
+//
+//	b := batching.New[*slackevents.MessageEvent](batching.BatchHandlerFunc[*slackevents.MessageEvent](insertMessages), batching.WithMaxSize(500))
+//	r.OnMessage(message.FromGeneric(b), message.Channel("C123"))
+//	...
+//	b.Shutdown(ctx) // flushes pending events
+//
+// The Batcher responds to Slack immediately after it accepts events, and processes batches in the background.
+// Errors returned from BatchHandlers can't be responded to Slack, so they are passed to the hook set by OnError.
+package batching
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+const (
+	// DefaultMaxSize is the default maximum number of events in a batch.
+	DefaultMaxSize = 100
+
+	// DefaultMaxDelay is the default maximum time that events wait in a batch.
+	DefaultMaxDelay = time.Second
+)
+
+// ErrClosed is returned when events are given to a Batcher that has been shut down.
+//
+// Since it makes the Router respond with Internal Server Error, Slack retries such events later (possibly to another instance).
+var ErrClosed = errors.New("batcher is closed")
+
+// BatchHandler processes a batch of events.
+type BatchHandler[T any] interface {
+	HandleBatch(ctx context.Context, events []T) error
+}
+
+type BatchHandlerFunc[T any] func(ctx context.Context, events []T) error
+
+func (f BatchHandlerFunc[T]) HandleBatch(ctx context.Context, events []T) error {
+	return f(ctx, events)
+}
+
+// Option configures the Batcher.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+type config struct {
+	maxSize   int
+	maxDelay  time.Duration
+	errorHook func(context.Context, error)
+}
+
+// WithMaxSize sets the maximum number of events in a batch. A batch is processed as soon as it becomes full.
+func WithMaxSize(n int) Option {
+	return optionFunc(func(c *config) {
+		c.maxSize = n
+	})
+}
+
+// WithMaxDelay sets the maximum time that events wait in a batch. A batch is processed when its first event has waited for `d`, even if it is not full.
+func WithMaxDelay(d time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.maxDelay = d
+	})
+}
+
+// OnError sets a hook that is called when a BatchHandler returns an error. If not set, errors are ignored.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.errorHook = hook
+	})
+}
+
+// Batcher accumulates events into batches and passes them to a BatchHandler.
+type Batcher[T any] struct {
+	handler BatchHandler[T]
+	config
+
+	mu      sync.Mutex
+	pending []T
+	timer   *time.Timer
+	closed  bool
+	running sync.WaitGroup
+}
+
+var _ router.Handler[any] = &Batcher[any]{}
+
+// New creates a new Batcher that passes batches to `h`.
+func New[T any](h BatchHandler[T], opts ...Option) *Batcher[T] {
+	b := &Batcher[T]{
+		handler: h,
+		config: config{
+			maxSize:  DefaultMaxSize,
+			maxDelay: DefaultMaxDelay,
+		},
+	}
+	for _, o := range opts {
+		o.apply(&b.config)
+	}
+	return b
+}
+
+// Handle adds `event` to the current batch and returns immediately. It returns ErrClosed after Shutdown is called.
+func (b *Batcher[T]) Handle(_ context.Context, event T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.pending = append(b.pending, event)
+	if len(b.pending) >= b.maxSize {
+		b.flushLocked()
+		return nil
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.maxDelay, b.Flush)
+	}
+	return nil
+}
+
+// Flush processes pending events immediately, without waiting for the batch to become full.
+func (b *Batcher[T]) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *Batcher[T]) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	b.running.Add(1)
+	go func() {
+		defer b.running.Done()
+		ctx := context.Background()
+		if err := b.handler.HandleBatch(ctx, batch); err != nil && b.errorHook != nil {
+			b.errorHook(ctx, err)
+		}
+	}()
+}
+
+// Shutdown stops accepting events, processes pending ones and waits for all the batches to be processed.
+//
+// If `ctx` is done before that, it returns the error of `ctx`. Batches being processed are not canceled in such case.
+func (b *Batcher[T]) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
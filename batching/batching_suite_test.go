@@ -0,0 +1,13 @@
+package batching_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBatching(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Batching Suite")
+}
@@ -0,0 +1,150 @@
+package batching_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/batching"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+const messageBody = `{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *recorder) HandleBatch(_ context.Context, events []*slackevents.MessageEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var texts []string
+	for _, e := range events {
+		texts = append(texts, e.Text)
+	}
+	r.batches = append(r.batches, texts)
+	return nil
+}
+
+func (r *recorder) Batches() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches
+}
+
+var _ = Describe("Batcher", func() {
+	var (
+		ctx = context.Background()
+		rec *recorder
+		msg = func(text string) *slackevents.MessageEvent {
+			return &slackevents.MessageEvent{Text: text}
+		}
+	)
+	BeforeEach(func() {
+		rec = &recorder{}
+	})
+
+	It("processes a batch when it becomes full", func() {
+		b := batching.New[*slackevents.MessageEvent](rec, batching.WithMaxSize(2), batching.WithMaxDelay(time.Hour))
+		for _, text := range []string{"a", "b", "c"} {
+			Expect(b.Handle(ctx, msg(text))).To(Succeed())
+		}
+		Eventually(rec.Batches).Should(Equal([][]string{{"a", "b"}}))
+		Consistently(rec.Batches, 50*time.Millisecond).Should(HaveLen(1))
+	})
+
+	It("processes a batch when its first event has waited for the max delay", func() {
+		b := batching.New[*slackevents.MessageEvent](rec, batching.WithMaxDelay(10*time.Millisecond))
+		Expect(b.Handle(ctx, msg("a"))).To(Succeed())
+		Expect(b.Handle(ctx, msg("b"))).To(Succeed())
+		Eventually(rec.Batches).Should(Equal([][]string{{"a", "b"}}))
+	})
+
+	It("processes pending events on Flush", func() {
+		b := batching.New[*slackevents.MessageEvent](rec, batching.WithMaxDelay(time.Hour))
+		Expect(b.Handle(ctx, msg("a"))).To(Succeed())
+		b.Flush()
+		Eventually(rec.Batches).Should(Equal([][]string{{"a"}}))
+	})
+
+	Describe("Shutdown", func() {
+		It("processes pending events and waits for them", func() {
+			b := batching.New[*slackevents.MessageEvent](rec, batching.WithMaxDelay(time.Hour))
+			Expect(b.Handle(ctx, msg("a"))).To(Succeed())
+			Expect(b.Shutdown(ctx)).To(Succeed())
+			Expect(rec.Batches()).To(Equal([][]string{{"a"}}))
+		})
+
+		It("rejects events after shutdown", func() {
+			b := batching.New[*slackevents.MessageEvent](rec)
+			Expect(b.Shutdown(ctx)).To(Succeed())
+			Expect(b.Handle(ctx, msg("a"))).To(MatchError(batching.ErrClosed))
+		})
+
+		It("returns the error of the context if batches are not processed in time", func() {
+			release := make(chan struct{})
+			defer close(release)
+			slow := batching.BatchHandlerFunc[*slackevents.MessageEvent](func(context.Context, []*slackevents.MessageEvent) error {
+				<-release
+				return nil
+			})
+			b := batching.New[*slackevents.MessageEvent](slow)
+			Expect(b.Handle(ctx, msg("a"))).To(Succeed())
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			Expect(b.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
+	It("passes errors to the hook", func() {
+		errs := make(chan error, 1)
+		failing := batching.BatchHandlerFunc[*slackevents.MessageEvent](func(context.Context, []*slackevents.MessageEvent) error {
+			return errors.New("warehouse unavailable")
+		})
+		b := batching.New[*slackevents.MessageEvent](failing, batching.OnError(func(_ context.Context, err error) {
+			errs <- err
+		}))
+		Expect(b.Handle(ctx, msg("a"))).To(Succeed())
+		Expect(b.Shutdown(ctx)).To(Succeed())
+		Expect(<-errs).To(MatchError("warehouse unavailable"))
+	})
+
+	It("acks Slack immediately when registered to a Router", func() {
+		b := batching.New[*slackevents.MessageEvent](rec, batching.WithMaxDelay(time.Hour))
+		r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		r.OnMessage(message.FromGeneric(b), message.Channel("C2147483705"))
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(messageBody)))
+		Expect(err).NotTo(HaveOccurred())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(rec.Batches()).To(BeEmpty())
+		Expect(b.Shutdown(ctx)).To(Succeed())
+		Expect(rec.Batches()).To(Equal([][]string{{"Hello world"}}))
+	})
+})
@@ -0,0 +1,66 @@
+package eventrouter_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+const benchmarkSecret = "THE_SIGNING_SECRET"
+
+var benchmarkBody = []byte(`{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`)
+
+// BenchmarkServeHTTP measures the common case: the signature is valid, the event type is known and one handler matches.
+//
+// Most of the allocations are made by slack-go to parse events, and by crypto/hmac.
+func BenchmarkServeHTTP(b *testing.B) {
+	r, err := eventrouter.New(eventrouter.WithSigningSecret(benchmarkSecret))
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.OnMessage(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+		return nil
+	}))
+	header := make(http.Header)
+	if err := signature.AddSignature(header, []byte(benchmarkSecret), benchmarkBody, time.Now()); err != nil {
+		b.Fatal(err)
+	}
+	body := bytes.NewReader(benchmarkBody)
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header = header
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body.Reset(benchmarkBody)
+		req.Body = io.NopCloser(body)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status: %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package bookmark provides handlers to process events that are sent when bookmarks of channels are added, changed or removed.
+//
+// slack-go doesn't know these events, so the Router decodes them into the types in this package.
+//
+// For more details about bookmarks, see https://api.slack.com/methods/bookmarks.add.
+package bookmark
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// Types of events that this package processes.
+const (
+	// BookmarkAdded is the type of events that are sent when a bookmark is added to a channel.
+	BookmarkAdded = "bookmark_added"
+
+	// BookmarkChanged is the type of events that are sent when a bookmark is edited.
+	BookmarkChanged = "bookmark_changed"
+
+	// BookmarkRemoved is the type of events that are sent when a bookmark is removed from a channel.
+	BookmarkRemoved = "bookmark_removed"
+)
+
+// Event is an inner event of any of BookmarkAdded, BookmarkChanged and BookmarkRemoved.
+type Event struct {
+	Type string `json:"type"`
+
+	// ChannelID is the ID of the channel that the bookmark belongs to. This may be empty, in which case `Bookmark.ChannelID` is used.
+	ChannelID string `json:"channel_id,omitempty"`
+
+	// Bookmark is the bookmark after the change. For BookmarkRemoved, this is the bookmark that is removed.
+	Bookmark Bookmark `json:"bookmark"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// Channel returns the ID of the channel that the bookmark belongs to.
+func (e *Event) Channel() string {
+	if e.ChannelID != "" {
+		return e.ChannelID
+	}
+	return e.Bookmark.ChannelID
+}
+
+// Bookmark is a bookmark of a channel.
+type Bookmark struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Emoji     string `json:"emoji"`
+	IconURL   string `json:"icon_url"`
+
+	// Type is the type of the bookmark (e.g. `link`).
+	Type string `json:"type"`
+
+	EntityID            string `json:"entity_id"`
+	DateCreated         int64  `json:"date_created"`
+	DateUpdated         int64  `json:"date_updated"`
+	Rank                string `json:"rank"`
+	LastUpdatedByUserID string `json:"last_updated_by_user_id"`
+	LastUpdatedByTeamID string `json:"last_updated_by_team_id"`
+	ShortcutID          string `json:"shortcut_id"`
+	AppID               string `json:"app_id"`
+}
+
+// Handler processes events about bookmarks.
+type Handler interface {
+	HandleBookmarkEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleBookmarkEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type channelPredicate struct {
+	id string
+}
+
+// Channel is a predicate that is considered to be "true" if and only if the bookmark belongs to the given channel.
+func Channel(id string) Predicate {
+	return &channelPredicate{id: id}
+}
+
+func (p *channelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.Channel() != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleBookmarkEvent(ctx, e)
+	})
+}
+
+func (p *channelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.id)
+}
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+type channelInPredicate struct {
+	set Set
+}
+
+// ChannelIn is a predicate that is considered to be "true" if and only if the bookmark belongs to a channel in `set`.
+func ChannelIn(set Set) Predicate {
+	return &channelInPredicate{set: set}
+}
+
+func (p *channelInPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if !p.set.Contains(ctx, e.Channel()) {
+			return errors.NotInterested
+		}
+		return h.HandleBookmarkEvent(ctx, e)
+	})
+}
+
+func (p *channelInPredicate) String() string {
+	return fmt.Sprintf("ChannelIn(%v)", p.set)
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	return routerutils.Build(h, preds, Predicate.Wrap, isOutermost)
+}
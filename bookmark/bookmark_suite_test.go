@@ -0,0 +1,13 @@
+package bookmark_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBookmark(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bookmark Suite")
+}
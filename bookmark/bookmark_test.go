@@ -0,0 +1,73 @@
+package bookmark_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/bookmark"
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+type staticSet map[string]bool
+
+func (s staticSet) Contains(_ context.Context, value string) bool {
+	return s[value]
+}
+
+var _ = Describe("Bookmark", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = bookmark.HandlerFunc(func(_ context.Context, _ *bookmark.Event) error {
+			numHandlerCalled++
+			return nil
+		})
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Event", func() {
+		It("returns the channel of the event, or that of the bookmark", func() {
+			Expect((&bookmark.Event{ChannelID: "C001", Bookmark: bookmark.Bookmark{ChannelID: "C002"}}).Channel()).To(Equal("C001"))
+			Expect((&bookmark.Event{Bookmark: bookmark.Bookmark{ChannelID: "C002"}}).Channel()).To(Equal("C002"))
+		})
+	})
+
+	Describe("Channel", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := bookmark.Build(innerHandler, bookmark.Channel("C001"))
+			Expect(h.HandleBookmarkEvent(ctx, &bookmark.Event{ChannelID: "C001"})).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := bookmark.Build(innerHandler, bookmark.Channel("C001"))
+			Expect(h.HandleBookmarkEvent(ctx, &bookmark.Event{ChannelID: "C002"})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("ChannelIn", func() {
+		It("calls the inner handler only if the channel is in the set", func() {
+			h := bookmark.Build(innerHandler, bookmark.ChannelIn(staticSet{"C001": true}))
+			Expect(h.HandleBookmarkEvent(ctx, &bookmark.Event{Bookmark: bookmark.Bookmark{ChannelID: "C001"}})).To(Succeed())
+			Expect(h.HandleBookmarkEvent(ctx, &bookmark.Event{Bookmark: bookmark.Bookmark{ChannelID: "C002"}})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("applies generic predicates", func() {
+			h := bookmark.Build(innerHandler, bookmark.FromGenericPredicate(router.Filter(func(context.Context, *bookmark.Event) bool {
+				return false
+			})))
+			Expect(h.HandleBookmarkEvent(ctx, &bookmark.Event{})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+})
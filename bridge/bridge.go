@@ -0,0 +1,309 @@
+// Package bridge forwards requests from Slack to routers through message brokers (e.g. NATS).
+//
+// A Publisher receives requests from Slack, wraps them into Envelopes and publishes them to a broker.
+// A Subscriber receives Envelopes from the broker and dispatches them to a router as if they came from Slack directly.
+//
+// Adapters for specific brokers (e.g. the `natsadapter` module) connect them to brokers.
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/urlverification"
+)
+
+// Envelope is a request from Slack that is transferred through message brokers.
+type Envelope struct {
+	// Body is the original request body.
+	Body []byte `json:"body"`
+
+	// Header holds the original request headers, including signatures.
+	Header http.Header `json:"header"`
+
+	// ReceivedAt is the time when the Publisher received the request.
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Marshal encodes the Envelope.
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal decodes an Envelope encoded by Marshal.
+func Unmarshal(data []byte) (*Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+	return &e, nil
+}
+
+// Option configures Publishers and Subscribers.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+const (
+	// DefaultMaxQueueAge is the default maximum time that Envelopes can stay in the broker. See WithMaxQueueAge.
+	DefaultMaxQueueAge = 1 * time.Hour
+
+	// maxClockSkew is the maximum difference between clocks of Publishers and Subscribers.
+	maxClockSkew = 5 * time.Minute
+)
+
+type config struct {
+	signingSecret    string
+	skipVerification bool
+	maxQueueAge      time.Duration
+	now              func() time.Time
+	errorHook        func(context.Context, error)
+}
+
+func newConfig(opts []Option) (*config, error) {
+	c := &config{maxQueueAge: DefaultMaxQueueAge, now: time.Now}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	if c.signingSecret == "" && !c.skipVerification {
+		return nil, fmt.Errorf("WithSigningSecret or InsecureSkipVerification must be given: %w", routererrors.ErrMissingSigningSecret)
+	}
+	if c.signingSecret != "" && c.skipVerification {
+		return nil, fmt.Errorf("WithSigningSecret and InsecureSkipVerification are mutually exclusive: %w", routererrors.ErrConflictingOptions)
+	}
+	return c, nil
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful in tests, or when the broker only carries requests that have already been verified.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(c *config) {
+		c.skipVerification = true
+	})
+}
+
+// WithSigningSecret sets a signing secret to verify requests.
+func WithSigningSecret(secret string) Option {
+	return optionFunc(func(c *config) {
+		c.signingSecret = secret
+	})
+}
+
+// WithMaxQueueAge sets the maximum time that Envelopes can stay in the broker (DefaultMaxQueueAge by default).
+// The Subscriber rejects older Envelopes, since their ReceivedAt is not covered by the signature and could otherwise be replayed forever.
+//
+// This has no effect on Publishers, nor when InsecureSkipVerification is given.
+func WithMaxQueueAge(age time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.maxQueueAge = age
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(c *config) {
+		c.now = now
+	})
+}
+
+// OnError sets a hook that is called when the Publisher fails to publish a request, or the Subscriber fails to dispatch an Envelope.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.errorHook = hook
+	})
+}
+
+func (c *config) onError(ctx context.Context, err error) {
+	if c.errorHook != nil {
+		c.errorHook(ctx, err)
+	}
+}
+
+// PublishFunc publishes an encoded Envelope to a broker.
+type PublishFunc func(ctx context.Context, data []byte) error
+
+// Publisher is an http.Handler that receives requests from Slack and publishes them as Envelopes.
+//
+// It responds to `url_verification` requests by itself because they can't be answered asynchronously.
+// If it fails to publish a request, it responds with 500 so that Slack retries the request later.
+type Publisher struct {
+	publish PublishFunc
+	config  *config
+	handler http.Handler
+}
+
+// NewPublisher creates a new Publisher.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func NewPublisher(publish PublishFunc, opts ...Option) (*Publisher, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &Publisher{publish: publish, config: c}
+	p.handler = http.HandlerFunc(p.serveHTTP)
+	if !c.skipVerification {
+		p.handler = &signature.Middleware{
+			SigningSecret: c.signingSecret,
+			Handler:       p.handler,
+			Now:           c.now,
+		}
+	}
+	return p, nil
+}
+
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.handler.ServeHTTP(w, req)
+}
+
+func (p *Publisher) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		routerutils.RespondWithError(w, err, false)
+		return
+	}
+	if ev, ok := parseURLVerification(body); ok {
+		resp, err := urlverification.DefaultHandler.HandleURLVerification(ctx, ev)
+		if err == nil {
+			err = urlverification.JSONResponder.RespondURLVerification(w, resp)
+		}
+		if err != nil {
+			routerutils.RespondWithError(w, err, false)
+		}
+		return
+	}
+
+	env := &Envelope{Body: body, Header: req.Header.Clone(), ReceivedAt: p.config.now()}
+	data, err := env.Marshal()
+	if err == nil {
+		err = p.publish(ctx, data)
+	}
+	if err != nil {
+		p.config.onError(ctx, err)
+		routerutils.RespondWithError(w, err, false)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseURLVerification(body []byte) (*slackevents.EventsAPIURLVerificationEvent, bool) {
+	var ev slackevents.EventsAPIURLVerificationEvent
+	if err := json.Unmarshal(body, &ev); err != nil || ev.Type != slackevents.URLVerification {
+		return nil, false
+	}
+	return &ev, true
+}
+
+// Subscriber dispatches Envelopes to an http.Handler (typically `*eventrouter.Router`).
+//
+// If WithSigningSecret is given, the Subscriber verifies signatures as of the time when the Publisher received requests,
+// so Envelopes that stayed in the broker for a while are still accepted.
+// Envelopes that stayed longer than the limit set by WithMaxQueueAge are rejected.
+// In this case the handler should skip verification (e.g. by `eventrouter.InsecureSkipVerification()`).
+type Subscriber struct {
+	handler http.Handler
+	config  *config
+}
+
+// NewSubscriber creates a new Subscriber.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func NewSubscriber(h http.Handler, opts ...Option) (*Subscriber, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{handler: h, config: c}, nil
+}
+
+// Dispatch decodes an Envelope and passes it to the handler.
+//
+// It returns an error that wraps `errors.HttpError` if the handler responds with a status code other than 2xx.
+func (s *Subscriber) Dispatch(ctx context.Context, data []byte) error {
+	err := s.dispatch(ctx, data)
+	if err != nil {
+		s.config.onError(ctx, err)
+	}
+	return err
+}
+
+func (s *Subscriber) dispatch(ctx context.Context, data []byte) error {
+	env, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	return s.dispatchEnvelope(ctx, env)
+}
+
+// DispatchEnvelope is the same as Dispatch, except that it takes a decoded Envelope.
+func (s *Subscriber) DispatchEnvelope(ctx context.Context, env *Envelope) error {
+	err := s.dispatchEnvelope(ctx, env)
+	if err != nil {
+		s.config.onError(ctx, err)
+	}
+	return err
+}
+
+func (s *Subscriber) dispatchEnvelope(ctx context.Context, env *Envelope) error {
+	if !s.config.skipVerification {
+		age := s.config.now().Sub(env.ReceivedAt)
+		if age > s.config.maxQueueAge || age < -maxClockSkew {
+			return fmt.Errorf("envelope received at %s is too old or in the future: %w", env.ReceivedAt, routererrors.HttpError(http.StatusUnauthorized))
+		}
+		if err := signature.Verify(env.Header, env.Body, s.config.signingSecret, env.ReceivedAt); err != nil {
+			return fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(http.StatusUnauthorized))
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(env.Body))
+	if err != nil {
+		return err
+	}
+	req.Header = env.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	w := &statusRecorder{header: make(http.Header)}
+	s.handler.ServeHTTP(w, req)
+	if w.status != 0 && (w.status < 200 || 300 <= w.status) {
+		return fmt.Errorf("handler responded with %d: %w", w.status, routererrors.HttpError(w.status))
+	}
+	return nil
+}
+
+// statusRecorder is an http.ResponseWriter that only records status codes.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (w *statusRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
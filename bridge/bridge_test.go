@@ -0,0 +1,196 @@
+package bridge_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/bridge"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+const content = `
+{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+
+func newSignedRequest(signingSecret, body string, ts time.Time) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/slack/events", bytes.NewReader([]byte(body)))
+	Expect(err).NotTo(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+	err = signature.AddSignature(req.Header, []byte(signingSecret), []byte(body), ts)
+	Expect(err).NotTo(HaveOccurred())
+	return req
+}
+
+var _ = Describe("Bridge", func() {
+	var (
+		token     = "THE_TOKEN"
+		published [][]byte
+		publish   = func(_ context.Context, data []byte) error {
+			published = append(published, data)
+			return nil
+		}
+	)
+	BeforeEach(func() {
+		published = nil
+	})
+
+	Describe("NewPublisher", func() {
+		It("requires either WithSigningSecret or InsecureSkipVerification", func() {
+			_, err := bridge.NewPublisher(publish)
+			Expect(err).To(HaveOccurred())
+			_, err = bridge.NewPublisher(publish, bridge.WithSigningSecret(token), bridge.InsecureSkipVerification())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Publisher", func() {
+		It("publishes verified requests as envelopes", func() {
+			receivedAt := time.Now()
+			p, err := bridge.NewPublisher(publish, bridge.WithSigningSecret(token), bridge.WithNowFunc(func() time.Time { return receivedAt }))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, newSignedRequest(token, content, time.Now()))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(published).To(HaveLen(1))
+			env, err := bridge.Unmarshal(published[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(env.Body)).To(Equal(content))
+			Expect(env.Header.Get("X-Slack-Signature")).NotTo(BeEmpty())
+			Expect(env.ReceivedAt).To(BeTemporally("==", receivedAt))
+		})
+
+		It("rejects requests with invalid signatures", func() {
+			p, err := bridge.NewPublisher(publish, bridge.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, newSignedRequest("WRONG_TOKEN", content, time.Now()))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(published).To(BeEmpty())
+		})
+
+		It("responds to url_verification by itself", func() {
+			p, err := bridge.NewPublisher(publish, bridge.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, newSignedRequest(token, `{"type": "url_verification", "challenge": "the_challenge"}`, time.Now()))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("the_challenge"))
+			Expect(published).To(BeEmpty())
+		})
+
+		It("responds with 500 when it fails to publish", func() {
+			var hookErr error
+			p, err := bridge.NewPublisher(func(_ context.Context, _ []byte) error {
+				return errors.New("broker is down")
+			}, bridge.InsecureSkipVerification(), bridge.OnError(func(_ context.Context, err error) { hookErr = err }))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, newSignedRequest(token, content, time.Now()))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(hookErr).To(MatchError("broker is down"))
+		})
+	})
+
+	Describe("Subscriber", func() {
+		var (
+			numHandlerCalled int
+			handlerErr       error
+			router           *eventrouter.Router
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			handlerErr = nil
+			var err error
+			router, err = eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			router.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numHandlerCalled++
+				return handlerErr
+			}))
+		})
+
+		envelope := func(secret string, receivedAt time.Time) []byte {
+			req := newSignedRequest(secret, content, receivedAt)
+			env := &bridge.Envelope{Body: []byte(content), Header: req.Header, ReceivedAt: receivedAt}
+			data, err := env.Marshal()
+			Expect(err).NotTo(HaveOccurred())
+			return data
+		}
+
+		It("dispatches envelopes verified as of the time they were received", func() {
+			s, err := bridge.NewSubscriber(router, bridge.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), envelope(token, time.Now().Add(-30*time.Minute)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("rejects envelopes that stayed in the broker for too long", func() {
+			now := time.Now()
+			s, err := bridge.NewSubscriber(router, bridge.WithSigningSecret(token), bridge.WithMaxQueueAge(10*time.Minute),
+				bridge.WithNowFunc(func() time.Time { return now }))
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), envelope(token, now.Add(-11*time.Minute)))
+			Expect(errors.Is(err, routererrors.HttpError(http.StatusUnauthorized))).To(BeTrue())
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("rejects envelopes received in the future", func() {
+			now := time.Now()
+			s, err := bridge.NewSubscriber(router, bridge.WithSigningSecret(token), bridge.WithNowFunc(func() time.Time { return now }))
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), envelope(token, now.Add(24*time.Hour)))
+			Expect(errors.Is(err, routererrors.HttpError(http.StatusUnauthorized))).To(BeTrue())
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("rejects envelopes with invalid signatures", func() {
+			s, err := bridge.NewSubscriber(router, bridge.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), envelope("WRONG_TOKEN", time.Now()))
+			Expect(errors.Is(err, routererrors.HttpError(http.StatusUnauthorized))).To(BeTrue())
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("returns an error when the handler fails", func() {
+			handlerErr = routererrors.HttpError(http.StatusServiceUnavailable)
+			var hookErr error
+			s, err := bridge.NewSubscriber(router, bridge.InsecureSkipVerification(), bridge.OnError(func(_ context.Context, err error) { hookErr = err }))
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), envelope(token, time.Now()))
+			Expect(errors.Is(err, routererrors.HttpError(http.StatusServiceUnavailable))).To(BeTrue())
+			Expect(hookErr).To(Equal(err))
+		})
+
+		It("returns an error when the envelope is broken", func() {
+			s, err := bridge.NewSubscriber(router, bridge.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			err = s.Dispatch(context.Background(), []byte("not json"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
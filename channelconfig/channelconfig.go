@@ -0,0 +1,140 @@
+// Package channelconfig makes routing depend on per-channel configurations stored outside Slack (e.g. in a database).
+//
+// Configurations are loaded by Enricher and stored in contexts, so predicates and handlers can refer to them:
+//
+//	store := channelconfig.NewCachedStore(myStore, 1000, 5*time.Minute)
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(channelconfig.NewEnricher(store)))
+//	r.OnMessage(handleAutoReply, message.When(channelconfig.FeatureEnabled("autoreply")))
+package channelconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/enrich"
+	"github.com/genkami/go-slack-event-router/internal/lrucache"
+)
+
+// Config is a configuration of a channel.
+type Config interface {
+	// FeatureEnabled returns true if the feature is enabled in the channel.
+	FeatureEnabled(feature string) bool
+}
+
+// Features is a simple Config that holds a set of enabled features.
+type Features map[string]bool
+
+func (f Features) FeatureEnabled(feature string) bool {
+	return f[feature]
+}
+
+// Store loads configurations of channels.
+type Store interface {
+	// Lookup returns the configuration of the channel.
+	// It may return nil if the channel has no configuration, in which case all features are considered to be disabled.
+	Lookup(ctx context.Context, channelID string) (Config, error)
+}
+
+type StoreFunc func(ctx context.Context, channelID string) (Config, error)
+
+func (f StoreFunc) Lookup(ctx context.Context, channelID string) (Config, error) {
+	return f(ctx, channelID)
+}
+
+// CachedStore is a Store that caches results of another Store.
+type CachedStore struct {
+	store Store
+	cache *lrucache.Cache
+}
+
+// NewCachedStore creates a new CachedStore that caches at most `size` configurations loaded from `store` for `ttl` each.
+func NewCachedStore(store Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		store: store,
+		cache: lrucache.New(size, ttl, nil),
+	}
+}
+
+func (s *CachedStore) Lookup(ctx context.Context, channelID string) (Config, error) {
+	if v, ok := s.cache.Get(channelID); ok {
+		cfg, _ := v.(Config)
+		return cfg, nil
+	}
+	cfg, err := s.store.Lookup(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Add(channelID, cfg)
+	return cfg, nil
+}
+
+// Invalidate removes the cached configuration of the channel. This is useful when the configuration has been changed.
+func (s *CachedStore) Invalidate(channelID string) {
+	s.cache.Remove(channelID)
+}
+
+type configKey struct{}
+
+// FromContext returns the configuration of the channel where the event being processed happened.
+//
+// This is only available in contexts enriched by Enricher. Otherwise it returns nil.
+func FromContext(ctx context.Context) Config {
+	cfg, _ := ctx.Value(configKey{}).(Config)
+	return cfg
+}
+
+// WithConfig returns a new context that holds `cfg`. This is mainly intended to test handlers that use FromContext.
+func WithConfig(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// Enricher loads configurations of channels where events happened and stores them in contexts.
+//
+// It can be passed to `eventrouter.WithEnricher`.
+type Enricher struct {
+	store Store
+}
+
+// NewEnricher creates a new Enricher that loads configurations from `store`.
+func NewEnricher(store Store) *Enricher {
+	return &Enricher{store: store}
+}
+
+func (e *Enricher) Enrich(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	channelID := enrich.ChannelID(ev)
+	if channelID == "" {
+		return ctx, nil
+	}
+	cfg, err := e.store.Lookup(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration of %s: %w", channelID, err)
+	}
+	if cfg == nil {
+		return ctx, nil
+	}
+	return WithConfig(ctx, cfg), nil
+}
+
+// FeatureCondition is a condition returned by FeatureEnabled.
+type FeatureCondition struct {
+	feature string
+}
+
+// FeatureEnabled returns a condition that matches if and only if the feature is enabled in the configuration stored in the context.
+//
+// It can be used with predicates named `When` (e.g. `message.When`).
+func FeatureEnabled(feature string) *FeatureCondition {
+	return &FeatureCondition{feature: feature}
+}
+
+func (c *FeatureCondition) Match(ctx context.Context) bool {
+	cfg := FromContext(ctx)
+	return cfg != nil && cfg.FeatureEnabled(c.feature)
+}
+
+func (c *FeatureCondition) String() string {
+	return fmt.Sprintf("FeatureEnabled(%s)", c.feature)
+}
@@ -0,0 +1,13 @@
+package channelconfig_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChannelconfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Channelconfig Suite")
+}
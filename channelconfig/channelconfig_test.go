@@ -0,0 +1,109 @@
+package channelconfig_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/channelconfig"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+func messageEvent(channel string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.Message,
+			Data: &slackevents.MessageEvent{Channel: channel},
+		},
+	}
+}
+
+var _ = Describe("Channelconfig", func() {
+	var (
+		ctx      = context.Background()
+		numCalls int
+		store    = channelconfig.StoreFunc(func(_ context.Context, channelID string) (channelconfig.Config, error) {
+			numCalls++
+			switch channelID {
+			case "CENABLED":
+				return channelconfig.Features{"autoreply": true}, nil
+			case "CERROR":
+				return nil, errors.New("database is down")
+			}
+			return nil, nil
+		})
+	)
+	BeforeEach(func() {
+		numCalls = 0
+	})
+
+	Describe("Enricher", func() {
+		It("stores the configuration of the channel in the context", func() {
+			enriched, err := channelconfig.NewEnricher(store).Enrich(ctx, messageEvent("CENABLED"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channelconfig.FromContext(enriched)).To(Equal(channelconfig.Features{"autoreply": true}))
+		})
+
+		It("leaves the context as is when the channel has no configuration", func() {
+			enriched, err := channelconfig.NewEnricher(store).Enrich(ctx, messageEvent("COTHER"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channelconfig.FromContext(enriched)).To(BeNil())
+		})
+
+		It("returns an error when the store fails", func() {
+			_, err := channelconfig.NewEnricher(store).Enrich(ctx, messageEvent("CERROR"))
+			Expect(err).To(MatchError(ContainSubstring("database is down")))
+		})
+	})
+
+	Describe("CachedStore", func() {
+		It("caches configurations including missing ones", func() {
+			cached := channelconfig.NewCachedStore(store, 10, time.Minute)
+			for i := 0; i < 3; i++ {
+				_, err := cached.Lookup(ctx, "CENABLED")
+				Expect(err).NotTo(HaveOccurred())
+				cfg, err := cached.Lookup(ctx, "COTHER")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg).To(BeNil())
+			}
+			Expect(numCalls).To(Equal(2))
+		})
+
+		It("does not cache errors", func() {
+			cached := channelconfig.NewCachedStore(store, 10, time.Minute)
+			_, err := cached.Lookup(ctx, "CERROR")
+			Expect(err).To(HaveOccurred())
+			_, err = cached.Lookup(ctx, "CERROR")
+			Expect(err).To(HaveOccurred())
+			Expect(numCalls).To(Equal(2))
+		})
+
+		It("reloads invalidated configurations", func() {
+			cached := channelconfig.NewCachedStore(store, 10, time.Minute)
+			_, err := cached.Lookup(ctx, "CENABLED")
+			Expect(err).NotTo(HaveOccurred())
+			cached.Invalidate("CENABLED")
+			_, err = cached.Lookup(ctx, "CENABLED")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numCalls).To(Equal(2))
+		})
+	})
+
+	Describe("FeatureEnabled", func() {
+		It("can be used with When predicates", func() {
+			numHandlerCalled := 0
+			h := message.Build(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numHandlerCalled++
+				return nil
+			}), message.When(channelconfig.FeatureEnabled("autoreply")))
+			Expect(h.HandleMessageEvent(channelconfig.WithConfig(ctx, channelconfig.Features{"autoreply": true}), &slackevents.MessageEvent{})).To(Succeed())
+			Expect(h.HandleMessageEvent(channelconfig.WithConfig(ctx, channelconfig.Features{"other": true}), &slackevents.MessageEvent{})).NotTo(Succeed())
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})).NotTo(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,126 @@
+// Package channeltype resolves types of conversations (public channels, private channels, DMs and so on).
+//
+// Some events such as `app_mention` and `reaction_added` don't have `channel_type` unlike `message`,
+// so predicates that match on channel types need a Resolver.
+package channeltype
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Channel types. These are the same values as `channel_type` in `message` events.
+const (
+	// Channel is a public channel.
+	Channel = "channel"
+
+	// Group is a private channel.
+	Group = "group"
+
+	// IM is a direct message.
+	IM = "im"
+
+	// MPIM is a multi-party direct message.
+	MPIM = "mpim"
+)
+
+// Resolver resolves the type of a conversation from its ID.
+type Resolver interface {
+	Resolve(ctx context.Context, channelID string) (string, error)
+}
+
+type ResolverFunc func(ctx context.Context, channelID string) (string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, channelID string) (string, error) {
+	return f(ctx, channelID)
+}
+
+// ByPrefix is a Resolver that guesses types of conversations from prefixes of their IDs without calling Slack API.
+//
+// This is accurate for direct messages (`D...`), but not for channels: private channels created recently have IDs starting with `C`
+// just like public ones, and `G...` may be either a private channel or a multi-party direct message.
+// ByPrefix treats `C...` as Channel and `G...` as Group. Use NewCachedResolver if you need exact types.
+var ByPrefix Resolver = ResolverFunc(func(_ context.Context, channelID string) (string, error) {
+	if channelID == "" {
+		return "", nil
+	}
+	switch channelID[0] {
+	case 'D':
+		return IM, nil
+	case 'G':
+		return Group, nil
+	default:
+		return Channel, nil
+	}
+})
+
+// ConversationInfoGetter is a subset of `slack.Client` that NewCachedResolver uses.
+type ConversationInfoGetter interface {
+	GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error)
+}
+
+var _ ConversationInfoGetter = &slack.Client{}
+
+// CachedResolver is a Resolver that calls `conversations.info` and caches the results.
+type CachedResolver struct {
+	client ConversationInfoGetter
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	channelType string
+	expiresAt   time.Time
+}
+
+// NewCachedResolver creates a new CachedResolver that caches results for `ttl`.
+//
+// Typically `client` is a `*slack.Client`.
+func NewCachedResolver(client ConversationInfoGetter, ttl time.Duration) *CachedResolver {
+	return &CachedResolver{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+func (r *CachedResolver) Resolve(ctx context.Context, channelID string) (string, error) {
+	now := r.now()
+	r.mu.Lock()
+	entry, ok := r.cache[channelID]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.channelType, nil
+	}
+
+	ch, err := r.client.GetConversationInfoContext(ctx, channelID, false)
+	if err != nil {
+		return "", err
+	}
+	channelType := FromConversation(ch)
+	r.mu.Lock()
+	r.cache[channelID] = cacheEntry{channelType: channelType, expiresAt: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return channelType, nil
+}
+
+// FromConversation returns the type of the conversation.
+func FromConversation(ch *slack.Channel) string {
+	switch {
+	case ch.IsIM:
+		return IM
+	case ch.IsMpIM:
+		return MPIM
+	case ch.IsPrivate || ch.IsGroup:
+		return Group
+	default:
+		return Channel
+	}
+}
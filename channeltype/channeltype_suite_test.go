@@ -0,0 +1,13 @@
+package channeltype_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChanneltype(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Channeltype Suite")
+}
@@ -0,0 +1,93 @@
+package channeltype_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	"github.com/genkami/go-slack-event-router/channeltype"
+)
+
+type fakeClient struct {
+	channels map[string]*slack.Channel
+	numCalls int
+}
+
+func (c *fakeClient) GetConversationInfoContext(_ context.Context, channelID string, _ bool) (*slack.Channel, error) {
+	c.numCalls++
+	ch, ok := c.channels[channelID]
+	if !ok {
+		return nil, errors.New("channel_not_found")
+	}
+	return ch, nil
+}
+
+func newChannel(id string, f func(*slack.Channel)) *slack.Channel {
+	ch := &slack.Channel{}
+	ch.ID = id
+	f(ch)
+	return ch
+}
+
+var _ = Describe("Channeltype", func() {
+	ctx := context.Background()
+
+	Describe("ByPrefix", func() {
+		It("guesses types from prefixes", func() {
+			for id, expected := range map[string]string{
+				"D0123": channeltype.IM,
+				"G0123": channeltype.Group,
+				"C0123": channeltype.Channel,
+			} {
+				actual, err := channeltype.ByPrefix.Resolve(ctx, id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actual).To(Equal(expected))
+			}
+		})
+	})
+
+	Describe("CachedResolver", func() {
+		var client *fakeClient
+		BeforeEach(func() {
+			client = &fakeClient{channels: map[string]*slack.Channel{
+				"CPUBLIC":  newChannel("CPUBLIC", func(ch *slack.Channel) {}),
+				"CPRIVATE": newChannel("CPRIVATE", func(ch *slack.Channel) { ch.IsPrivate = true }),
+				"GMPIM":    newChannel("GMPIM", func(ch *slack.Channel) { ch.IsMpIM = true; ch.IsPrivate = true }),
+				"DIM":      newChannel("DIM", func(ch *slack.Channel) { ch.IsIM = true }),
+			}}
+		})
+
+		It("resolves types by conversations.info", func() {
+			r := channeltype.NewCachedResolver(client, time.Minute)
+			for id, expected := range map[string]string{
+				"CPUBLIC":  channeltype.Channel,
+				"CPRIVATE": channeltype.Group,
+				"GMPIM":    channeltype.MPIM,
+				"DIM":      channeltype.IM,
+			} {
+				actual, err := r.Resolve(ctx, id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actual).To(Equal(expected))
+			}
+		})
+
+		It("caches results", func() {
+			r := channeltype.NewCachedResolver(client, time.Minute)
+			_, err := r.Resolve(ctx, "CPRIVATE")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = r.Resolve(ctx, "CPRIVATE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(1))
+		})
+
+		It("returns errors from the client", func() {
+			r := channeltype.NewCachedResolver(client, time.Minute)
+			_, err := r.Resolve(ctx, "CUNKNOWN")
+			Expect(err).To(MatchError("channel_not_found"))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+// Package checkpoint records events before they are processed, so that events interrupted by a crash can be replayed on startup.
+//
+// A Recorder saves every verified event to a Store before handlers are called, and marks it completed after they finish.
+// Events that are still pending on startup are the ones that were being processed when the process stopped, and Replay passes them to the Router again:
+//
+//	store, err := checkpoint.NewFileStore("/var/lib/myapp/checkpoints")
+//	rec := checkpoint.NewRecorder(store)
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(rec), eventrouter.WithResultSink(rec))
+//	// register handlers...
+//	if _, err := rec.Replay(ctx, r); err != nil {
+//		// ...
+//	}
+//
+// This gives at-least-once semantics without external queues: an event may be processed twice if the process stops right after handlers finish,
+// so handlers should be idempotent (see the `outbox` package).
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/bridge"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// Entry is an event saved in a Store.
+type Entry struct {
+	// EventID is the ID of the event (i.e. `event_id` in the outer event).
+	EventID string `json:"event_id"`
+
+	// Envelope holds the verified request of the event.
+	Envelope *bridge.Envelope `json:"envelope"`
+}
+
+// Store persists events that are being processed.
+type Store interface {
+	// Save records that the event is about to be processed. Saving an event that is already saved overwrites it.
+	Save(ctx context.Context, entry *Entry) error
+
+	// Complete records that the event has been processed. Completing an unknown event is not an error.
+	Complete(ctx context.Context, eventID string) error
+
+	// Pending returns events that are saved but not completed, in the order in which they were received.
+	Pending(ctx context.Context) ([]*Entry, error)
+}
+
+// Option configures the Recorder.
+type Option interface {
+	apply(*Recorder)
+}
+
+type optionFunc func(*Recorder)
+
+func (f optionFunc) apply(r *Recorder) {
+	f(r)
+}
+
+// OnError sets a hook that is called when the Store fails to complete an event.
+//
+// Such events are replayed on the next startup. If not set, errors are ignored.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(r *Recorder) {
+		r.errorHook = hook
+	})
+}
+
+// OnReplayFailure sets a hook that is called when the Router responds to a replayed event with an error status.
+func OnReplayFailure(hook func(ctx context.Context, entry *Entry, resp eventrouter.Response)) Option {
+	return optionFunc(func(r *Recorder) {
+		r.replayFailureHook = hook
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Recorder) {
+		r.now = now
+	})
+}
+
+// Recorder saves events to a Store while they are processed.
+//
+// It must be passed to both `eventrouter.WithEnricher` and `eventrouter.WithResultSink`.
+// Since enrichers are called in order, it should be the first enricher so that failures of other enrichers can also be replayed.
+type Recorder struct {
+	store             Store
+	errorHook         func(context.Context, error)
+	replayFailureHook func(context.Context, *Entry, eventrouter.Response)
+	now               func() time.Time
+}
+
+var (
+	_ eventrouter.Enricher         = &Recorder{}
+	_ eventrouter.ResultSink       = &Recorder{}
+	_ eventrouter.PreflightChecker = &Recorder{}
+)
+
+// NewRecorder creates a new Recorder that saves events to `store`.
+func NewRecorder(store Store, opts ...Option) *Recorder {
+	r := &Recorder{store: store, now: time.Now}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	return r
+}
+
+// Enrich saves `ev` to the Store. It doesn't change the context.
+//
+// If the Store fails, the error is returned so that the Router responds with an error and Slack retries the event.
+// Events without IDs are not saved.
+func (r *Recorder) Enrich(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	cb, ok := ev.Data.(*slackevents.EventsAPICallbackEvent)
+	body := routerutils.RawBody(ctx)
+	if !ok || cb.EventID == "" || body == nil {
+		return ctx, nil
+	}
+	entry := &Entry{
+		EventID: cb.EventID,
+		Envelope: &bridge.Envelope{
+			Body:       body,
+			Header:     routerutils.Header(ctx).Clone(),
+			ReceivedAt: r.now(),
+		},
+	}
+	if err := r.store.Save(ctx, entry); err != nil {
+		return ctx, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return ctx, nil
+}
+
+// Record marks the event as completed.
+//
+// Events that handlers failed to process are left pending, so that they are replayed on the next startup
+// even if Slack doesn't retry them (e.g. when they are processed in the background by `eventrouter.AckBefore`).
+func (r *Recorder) Record(ctx context.Context, result *eventrouter.Result) {
+	if result.EventID == "" {
+		return
+	}
+	if result.Err != nil && !errors.Is(result.Err, routererrors.NotInterested) && !errors.Is(result.Err, routererrors.Mismatch) {
+		return
+	}
+	if err := r.store.Complete(ctx, result.EventID); err != nil && r.errorHook != nil {
+		r.errorHook(ctx, fmt.Errorf("failed to complete checkpoint: %w", err))
+	}
+}
+
+// PreflightCheck checks that the Store is ready, if it implements `eventrouter.PreflightChecker`. Otherwise it does nothing.
+func (r *Recorder) PreflightCheck(ctx context.Context) error {
+	if c, ok := r.store.(eventrouter.PreflightChecker); ok {
+		return c.PreflightCheck(ctx)
+	}
+	return nil
+}
+
+// Replay passes each pending event to `router` once, and returns the number of replayed events.
+//
+// Replayed events are completed whatever the outcome, so that events that always fail are not replayed forever.
+// Failures are reported to the hook set by OnReplayFailure. It returns an error only if the Store fails.
+// It should be called before the Router starts to accept requests.
+func (r *Recorder) Replay(ctx context.Context, router *eventrouter.Router) (int, error) {
+	entries, err := r.store.Pending(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load checkpoints: %w", err)
+	}
+	for i, entry := range entries {
+		resp := router.Redeliver(ctx, entry.Envelope)
+		if resp.Status >= 400 && r.replayFailureHook != nil {
+			r.replayFailureHook(ctx, entry, resp)
+		}
+		if err := r.store.Complete(ctx, entry.EventID); err != nil {
+			return i, fmt.Errorf("failed to complete checkpoint: %w", err)
+		}
+	}
+	return len(entries), nil
+}
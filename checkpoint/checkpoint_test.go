@@ -0,0 +1,176 @@
+package checkpoint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/bridge"
+	"github.com/genkami/go-slack-event-router/checkpoint"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+const (
+	signingSecret = "THE_SIGNING_SECRET"
+	messageBody   = `{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+)
+
+type failingStore struct {
+	checkpoint.Store
+}
+
+func (failingStore) Save(context.Context, *checkpoint.Entry) error {
+	return errors.New("disk full")
+}
+
+var _ = Describe("Checkpoint", func() {
+	var (
+		ctx   = context.Background()
+		store *checkpoint.MemoryStore
+		serve = func(r *eventrouter.Router) int {
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(messageBody)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature.AddSignature(req.Header, []byte(signingSecret), []byte(messageBody), time.Now())).To(Succeed())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result().StatusCode
+		}
+		newRouter = func(rec *checkpoint.Recorder, h message.HandlerFunc) *eventrouter.Router {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(signingSecret), eventrouter.WithEnricher(rec), eventrouter.WithResultSink(rec))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(h)
+			return r
+		}
+	)
+	BeforeEach(func() {
+		store = checkpoint.NewMemoryStore()
+	})
+
+	Describe("Recorder", func() {
+		It("completes events that are processed", func() {
+			var saved []*checkpoint.Entry
+			rec := checkpoint.NewRecorder(store)
+			r := newRouter(rec, func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				var err error
+				saved, err = store.Pending(ctx)
+				return err
+			})
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(saved).To(HaveLen(1))
+			Expect(saved[0].EventID).To(Equal("Ev08MFMKH6"))
+			Expect(saved[0].Envelope.Body).To(MatchJSON(messageBody))
+			Expect(saved[0].Envelope.Header.Get("X-Slack-Signature")).NotTo(BeEmpty())
+			Expect(store.Pending(ctx)).To(BeEmpty())
+		})
+
+		It("leaves events that handlers failed to process", func() {
+			rec := checkpoint.NewRecorder(store)
+			r := newRouter(rec, func(context.Context, *slackevents.MessageEvent) error {
+				return errors.New("crashed")
+			})
+			Expect(serve(r)).To(Equal(http.StatusInternalServerError))
+			Expect(store.Pending(ctx)).To(HaveLen(1))
+		})
+
+		It("makes the Router respond with an error if the Store fails", func() {
+			numHandled := 0
+			rec := checkpoint.NewRecorder(failingStore{store})
+			r := newRouter(rec, func(context.Context, *slackevents.MessageEvent) error {
+				numHandled++
+				return nil
+			})
+			Expect(serve(r)).To(Equal(http.StatusInternalServerError))
+			Expect(numHandled).To(Equal(0))
+		})
+	})
+
+	Describe("Replay", func() {
+		It("passes pending events to the Router even if their signatures have expired", func() {
+			rec := checkpoint.NewRecorder(store, checkpoint.WithNowFunc(func() time.Time { return time.Now().Add(-time.Hour) }))
+			Expect(serve(newRouter(rec, func(context.Context, *slackevents.MessageEvent) error {
+				return errors.New("crashed")
+			}))).To(Equal(http.StatusInternalServerError))
+
+			var texts []string
+			r := newRouter(rec, func(_ context.Context, e *slackevents.MessageEvent) error {
+				texts = append(texts, e.Text)
+				return nil
+			})
+			Expect(rec.Replay(ctx, r)).To(Equal(1))
+			Expect(texts).To(Equal([]string{"Hello world"}))
+			Expect(store.Pending(ctx)).To(BeEmpty())
+		})
+
+		It("reports failures and completes the events anyway", func() {
+			var failed []string
+			rec := checkpoint.NewRecorder(store, checkpoint.OnReplayFailure(func(_ context.Context, entry *checkpoint.Entry, resp eventrouter.Response) {
+				Expect(resp.Status).To(Equal(http.StatusInternalServerError))
+				failed = append(failed, entry.EventID)
+			}))
+			r := newRouter(rec, func(context.Context, *slackevents.MessageEvent) error {
+				return errors.New("still broken")
+			})
+			Expect(serve(r)).To(Equal(http.StatusInternalServerError))
+			Expect(rec.Replay(ctx, r)).To(Equal(1))
+			Expect(failed).To(Equal([]string{"Ev08MFMKH6"}))
+			Expect(store.Pending(ctx)).To(BeEmpty())
+		})
+	})
+
+	Describe("FileStore", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "checkpoint")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("returns pending events in the order in which they were received", func() {
+			s, err := checkpoint.NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, id := range []string{"Ev002", "Ev/001", "Ev003"} {
+				env := &bridge.Envelope{Body: []byte(`{}`), ReceivedAt: now.Add(time.Duration(-i) * time.Second)}
+				Expect(s.Save(ctx, &checkpoint.Entry{EventID: id, Envelope: env})).To(Succeed())
+			}
+			Expect(s.Complete(ctx, "Ev002")).To(Succeed())
+			Expect(s.Complete(ctx, "EvUNKNOWN")).To(Succeed())
+
+			reopened, err := checkpoint.NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			entries, err := reopened.Pending(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].EventID).To(Equal("Ev003"))
+			Expect(entries[1].EventID).To(Equal("Ev/001"))
+			Expect(entries[1].Envelope.Body).To(MatchJSON(`{}`))
+		})
+	})
+})
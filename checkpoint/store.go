@@ -0,0 +1,149 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps events in memory.
+//
+// Since it doesn't survive crashes, it is mainly intended for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.EventID] = entry
+	return nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, eventID)
+	return nil
+}
+
+func (s *MemoryStore) Pending(_ context.Context) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+const fileSuffix = ".json"
+
+// FileStore is a Store that saves each event as a JSON file in a directory.
+//
+// Files are written atomically by renaming, so a crash never leaves a partially written event.
+type FileStore struct {
+	dir string
+}
+
+var _ Store = &FileStore{}
+
+// NewFileStore creates a new FileStore that saves events in `dir`, creating it if it doesn't exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// PreflightCheck checks that the directory is writable.
+func (s *FileStore) PreflightCheck(_ context.Context) error {
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("checkpoint directory is not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (s *FileStore) path(eventID string) string {
+	return filepath.Join(s.dir, url.PathEscape(eventID)+fileSuffix)
+}
+
+func (s *FileStore) Save(_ context.Context, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(entry.EventID))
+}
+
+func (s *FileStore) Complete(_ context.Context, eventID string) error {
+	err := os.Remove(s.path(eventID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) Pending(_ context.Context) ([]*Entry, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") || !strings.HasSuffix(f.Name(), fileSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint %s: %w", f.Name(), err)
+		}
+		entries = append(entries, &entry)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func sortEntries(entries []*Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Envelope.ReceivedAt.Before(entries[j].Envelope.ReceivedAt)
+	})
+}
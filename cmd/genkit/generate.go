@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Spec describes a package to generate.
+type Spec struct {
+	// Package is the name of the generated package (e.g. "channelarchive").
+	Package string `json:"package"`
+
+	// Event is the type of events (e.g. "channel_archive").
+	Event string `json:"event"`
+
+	// Payload is the name of the type in slackevents that represents inner events (e.g. "ChannelArchiveEvent").
+	Payload string `json:"payload"`
+
+	// Predicates are predicates on fields of the payload.
+	Predicates []PredicateSpec `json:"predicates"`
+}
+
+// PredicateSpec describes a predicate that is "true" if and only if a string field of the payload equals to the given value.
+type PredicateSpec struct {
+	// Name is the name of the function that creates the predicate (e.g. "Channel").
+	Name string `json:"name"`
+
+	// Field is the name of the field of the payload (e.g. "Channel").
+	Field string `json:"field"`
+
+	// Doc completes the sentence "... is a predicate that is considered to be "true" if and only if ...".
+	Doc string `json:"doc"`
+}
+
+var (
+	packageNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	eventTypePattern   = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+func (s *Spec) validate() error {
+	if !packageNamePattern.MatchString(s.Package) {
+		return fmt.Errorf("invalid package name %q", s.Package)
+	}
+	if !eventTypePattern.MatchString(s.Event) {
+		return fmt.Errorf("invalid event type %q", s.Event)
+	}
+	if !token.IsIdentifier(s.Payload) || !token.IsExported(s.Payload) {
+		return fmt.Errorf("invalid payload type %q", s.Payload)
+	}
+	seen := make(map[string]bool)
+	for _, p := range s.Predicates {
+		if !token.IsIdentifier(p.Name) || !token.IsExported(p.Name) {
+			return fmt.Errorf("invalid predicate name %q", p.Name)
+		}
+		if !token.IsIdentifier(p.Field) || !token.IsExported(p.Field) {
+			return fmt.Errorf("invalid field name %q of predicate %s", p.Field, p.Name)
+		}
+		if reserved[p.Name] {
+			return fmt.Errorf("predicate name %q is reserved", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate predicate name %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// reserved are names declared by the generated package itself.
+var reserved = map[string]bool{
+	"EventType": true, "Handler": true, "HandlerFunc": true, "Predicate": true, "Build": true, "Register": true,
+	"ToGeneric": true, "FromGeneric": true, "FromGenericPredicate": true,
+}
+
+type templateData struct {
+	*Spec
+	Method string
+}
+
+func (d *templateData) PredicateType(p PredicateSpec) string {
+	return strings.ToLower(p.Name[:1]) + p.Name[1:] + "Predicate"
+}
+
+// Generate generates files of the package described by `spec`. Keys of the result are file names.
+func Generate(spec *Spec) (map[string][]byte, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	data := &templateData{Spec: spec, Method: "Handle" + spec.Payload}
+	files := map[string]*template.Template{
+		spec.Package + ".go":            packageTemplate,
+		spec.Package + "_suite_test.go": suiteTemplate,
+		spec.Package + "_test.go":       testTemplate,
+	}
+	result := make(map[string][]byte, len(files))
+	for name, tmpl := range files {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", name, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", name, err)
+		}
+		result[name] = src
+	}
+	return result, nil
+}
+
+var funcs = template.FuncMap{
+	"title": func(s string) string {
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+var packageTemplate = template.Must(template.New("package").Funcs(funcs).Parse(`// Code generated by genkit. DO NOT EDIT.
+
+// Package {{.Package}} provides handlers to process ` + "`{{.Event}}`" + ` events.
+//
+// For more details, see https://api.slack.com/events/{{.Event}}.
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// EventType is the type of events that this package processes.
+const EventType = "{{.Event}}"
+
+// Handler processes ` + "`{{.Event}}`" + ` events.
+type Handler interface {
+	{{.Method}}(context.Context, *slackevents.{{.Payload}}) error
+}
+
+type HandlerFunc func(context.Context, *slackevents.{{.Payload}}) error
+
+func (f HandlerFunc) {{.Method}}(ctx context.Context, e *slackevents.{{.Payload}}) error {
+	return f(ctx, e)
+}
+
+// Predicate distinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+{{range .Predicates}}
+type {{$.PredicateType .}} struct {
+	value string
+}
+
+// {{.Name}} is a predicate that is considered to be "true" if and only if {{if .Doc}}{{.Doc}}{{else}}` + "`{{.Field}}`" + ` of an event equals to the given value{{end}}.
+func {{.Name}}(value string) Predicate {
+	return &{{$.PredicateType .}}{value: value}
+}
+
+func (p *{{$.PredicateType .}}) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.{{$.Payload}}) error {
+		if e.{{.Field}} != p.value {
+			return errors.NotInterested
+		}
+		return h.{{$.Method}}(ctx, e)
+	})
+}
+
+func (p *{{$.PredicateType .}}) String() string {
+	return fmt.Sprintf("{{.Name}}(%s)", p.value)
+}
+{{end}}
+// Build decorates ` + "`h`" + ` with the given Predicates and returns a new Handler that calls the original handler ` + "`h`" + ` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	generic := make([]router.Predicate[*slackevents.{{.Payload}}], 0, len(preds))
+	for _, p := range preds {
+		p := p
+		if g, ok := p.(*genericPredicate); ok {
+			generic = append(generic, g.pred)
+			continue
+		}
+		generic = append(generic, router.PredicateFunc[*slackevents.{{.Payload}}](func(h router.Handler[*slackevents.{{.Payload}}]) router.Handler[*slackevents.{{.Payload}}] {
+			return ToGeneric(p.Wrap(FromGeneric(h)))
+		}))
+	}
+	return FromGeneric(router.Build(ToGeneric(h), generic...))
+}
+
+// ToGeneric converts ` + "`h`" + ` to a generic ` + "`router.Handler`" + `.
+func ToGeneric(h Handler) router.Handler[*slackevents.{{.Payload}}] {
+	return router.HandlerFunc[*slackevents.{{.Payload}}](h.{{.Method}})
+}
+
+// FromGeneric converts a generic ` + "`router.Handler`" + ` to a Handler.
+func FromGeneric(h router.Handler[*slackevents.{{.Payload}}]) Handler {
+	return HandlerFunc(h.Handle)
+}
+
+type genericPredicate struct {
+	pred router.Predicate[*slackevents.{{.Payload}}]
+}
+
+// FromGenericPredicate converts a generic ` + "`router.Predicate`" + ` to a Predicate, so that generic middleware can be used with this package.
+func FromGenericPredicate(p router.Predicate[*slackevents.{{.Payload}}]) Predicate {
+	return &genericPredicate{pred: p}
+}
+
+func (p *genericPredicate) Wrap(h Handler) Handler {
+	return FromGeneric(p.pred.Wrap(ToGeneric(h)))
+}
+
+func (p *genericPredicate) String() string {
+	if s, ok := p.pred.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p.pred)
+}
+
+// Unwrap returns the generic predicate, so that route introspection can see through the adapter.
+func (p *genericPredicate) Unwrap() interface{} {
+	return p.pred
+}
+
+// Register registers a handler that processes ` + "`{{.Event}}`" + ` events to ` + "`r`" + `.
+//
+// The handler ` + "`h`" + ` will be called only when all of given Predicates are true.
+func Register(r *eventrouter.Router, h Handler, preds ...Predicate) {
+	h = Build(h, preds...)
+	r.On(EventType, eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		inner, ok := e.InnerEvent.Data.(*slackevents.{{.Payload}})
+		if !ok {
+			return fmt.Errorf("%w: expected *slackevents.{{.Payload}} but got %T", eventrouter.ErrInnerEventTypeMismatch, e.InnerEvent.Data)
+		}
+		return h.{{.Method}}(ctx, inner)
+	}))
+}
+`))
+
+var suiteTemplate = template.Must(template.New("suite").Funcs(funcs).Parse(`// Code generated by genkit. DO NOT EDIT.
+
+package {{.Package}}_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test{{title .Package}}(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "{{title .Package}} Suite")
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Funcs(funcs).Parse(`// Code generated by genkit. DO NOT EDIT.
+
+package {{.Package}}_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/{{.Package}}"
+)
+
+var _ = Describe("{{title .Package}}", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = {{.Package}}.HandlerFunc(func(_ context.Context, _ *slackevents.{{.Payload}}) error {
+			numHandlerCalled++
+			return nil
+		})
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Build", func() {
+		It("returns the original handler when no predicate is given", func() {
+			h := {{.Package}}.Build(innerHandler)
+			Expect(h.{{.Method}}(ctx, &slackevents.{{.Payload}}{})).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("applies generic predicates", func() {
+			h := {{.Package}}.Build(innerHandler, {{.Package}}.FromGenericPredicate(router.Filter(func(context.Context, *slackevents.{{.Payload}}) bool {
+				return false
+			})))
+			Expect(h.{{.Method}}(ctx, &slackevents.{{.Payload}}{})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+{{range .Predicates}}
+	Describe("{{.Name}}", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := {{$.Package}}.Build(innerHandler, {{$.Package}}.{{.Name}}("expected"))
+			Expect(h.{{$.Method}}(ctx, &slackevents.{{$.Payload}}{ {{.Field}}: "expected" })).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := {{$.Package}}.Build(innerHandler, {{$.Package}}.{{.Name}}("expected"))
+			Expect(h.{{$.Method}}(ctx, &slackevents.{{$.Payload}}{ {{.Field}}: "other" })).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+{{end}}})
+`))
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate", func() {
+	var spec *Spec
+	BeforeEach(func() {
+		spec = &Spec{
+			Package: "channelarchive",
+			Event:   "channel_archive",
+			Payload: "ChannelArchiveEvent",
+			Predicates: []PredicateSpec{
+				{Name: "Channel", Field: "Channel", Doc: "an event happened in the given channel"},
+				{Name: "User", Field: "User"},
+			},
+		}
+	})
+
+	declarations := func(src []byte) []string {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					names = append(names, d.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, s := range d.Specs {
+					switch s := s.(type) {
+					case *ast.TypeSpec:
+						names = append(names, s.Name.Name)
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							names = append(names, n.Name)
+						}
+					}
+				}
+			}
+		}
+		return names
+	}
+
+	It("generates a package, its test suite and tests", func() {
+		files, err := Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(3))
+		Expect(files).To(HaveKey("channelarchive_suite_test.go"))
+		Expect(files).To(HaveKey("channelarchive_test.go"))
+		Expect(declarations(files["channelarchive.go"])).To(ContainElements(
+			"EventType", "Handler", "HandlerFunc", "Predicate", "Channel", "User",
+			"Build", "ToGeneric", "FromGeneric", "FromGenericPredicate", "Register",
+		))
+		Expect(string(files["channelarchive.go"])).To(ContainSubstring("HandleChannelArchiveEvent(context.Context, *slackevents.ChannelArchiveEvent) error"))
+		Expect(string(files["channelarchive.go"])).To(ContainSubstring("if and only if `User` of an event equals to the given value."))
+		Expect(string(files["channelarchive_test.go"])).To(ContainSubstring(`Describe("Channel"`))
+	})
+
+	It("generates a package that builds, passes go vet and passes its own tests", func() {
+		if _, err := exec.LookPath("go"); err != nil {
+			Skip("the go command is not available")
+		}
+		root, err := filepath.Abs("../..")
+		Expect(err).NotTo(HaveOccurred())
+		dir, err := os.MkdirTemp("", "genkit")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		files, err := Generate(spec)
+		Expect(err).NotTo(HaveOccurred())
+		for name, src := range files {
+			Expect(os.WriteFile(filepath.Join(dir, name), src, 0o644)).To(Succeed())
+		}
+		// The generated package imports itself as a package of this module, so put it in a module of that path
+		// that uses this module from the working tree.
+		goMod := fmt.Sprintf("module github.com/genkami/go-slack-event-router/%s\n\ngo 1.18\n\n"+
+			"require github.com/genkami/go-slack-event-router v0.0.0\n\n"+
+			"replace github.com/genkami/go-slack-event-router => %s\n", spec.Package, root)
+		Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644)).To(Succeed())
+		goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0o644)).To(Succeed())
+
+		for _, args := range [][]string{{"mod", "tidy"}, {"vet", "./..."}, {"test", "./..."}} {
+			cmd := exec.Command("go", args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), "go %v:\n%s", args, out)
+		}
+	})
+
+	It("rejects invalid package names", func() {
+		spec.Package = "channel-archive"
+		_, err := Generate(spec)
+		Expect(err).To(MatchError(ContainSubstring("invalid package name")))
+	})
+
+	It("rejects unexported payload types", func() {
+		spec.Payload = "channelArchiveEvent"
+		_, err := Generate(spec)
+		Expect(err).To(MatchError(ContainSubstring("invalid payload type")))
+	})
+
+	It("rejects reserved predicate names", func() {
+		spec.Predicates = append(spec.Predicates, PredicateSpec{Name: "Build", Field: "Channel"})
+		_, err := Generate(spec)
+		Expect(err).To(MatchError(ContainSubstring("reserved")))
+	})
+
+	It("rejects duplicate predicate names", func() {
+		spec.Predicates = append(spec.Predicates, PredicateSpec{Name: "User", Field: "Channel"})
+		_, err := Generate(spec)
+		Expect(err).To(MatchError(ContainSubstring("duplicate")))
+	})
+
+	Describe("run", func() {
+		It("writes generated files to the output directory", func() {
+			dir, err := os.MkdirTemp("", "genkit")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			out := filepath.Join(dir, "channelarchive")
+			Expect(run([]string{"-spec", "testdata/channelarchive.json", "-out", out}, GinkgoWriter)).To(Succeed())
+			for _, name := range []string{"channelarchive.go", "channelarchive_suite_test.go", "channelarchive_test.go"} {
+				Expect(filepath.Join(out, name)).To(BeAnExistingFile())
+			}
+		})
+
+		It("requires a spec", func() {
+			Expect(run(nil, GinkgoWriter)).To(MatchError(ContainSubstring("-spec")))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+// Command genkit generates a typed package that processes a certain type of events, from a small JSON spec.
+//
+// Usage:
+//
+//	go run ./cmd/genkit -spec channelarchive.json -out ./channelarchive
+//
+// A spec looks like this:
+//
+//	{
+//		"package": "channelarchive",
+//		"event": "channel_archive",
+//		"payload": "ChannelArchiveEvent",
+//		"predicates": [
+//			{"name": "Channel", "field": "Channel", "doc": "an event happened in the given channel"},
+//			{"name": "User", "field": "User", "doc": "an event was triggered by the given user"}
+//		]
+//	}
+//
+// `payload` is the name of the type in `github.com/slack-go/slack/slackevents` that represents the inner event,
+// and `field` of each predicate is the name of a string field of the payload.
+//
+// The generated package contains Handler, HandlerFunc, Predicate, the predicates, Build, adapters to the `router` package
+// and Register, together with tests for them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "genkit: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("genkit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	specPath := fs.String("spec", "", "path to the spec file")
+	outDir := fs.String("out", "", "output directory (default: the package name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *specPath == "" {
+		return fmt.Errorf("-spec is required")
+	}
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		return err
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *specPath, err)
+	}
+	files, err := Generate(&spec)
+	if err != nil {
+		return err
+	}
+	dir := *outDir
+	if dir == "" {
+		dir = spec.Package
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
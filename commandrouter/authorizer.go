@@ -0,0 +1,88 @@
+package commandrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// DefaultDeniedMessage is the default message that the Router responds with when users are not allowed to use commands.
+const DefaultDeniedMessage = "You are not allowed to use this command."
+
+// ErrNoAuthorizer indicates that authorization predicates are used without WithAuthorizer.
+var ErrNoAuthorizer = errors.New("no Authorizer is set; use WithAuthorizer")
+
+// Authorizer decides whether users are allowed to use commands.
+//
+// Typically it consults an RBAC service.
+type Authorizer interface {
+	// IsUserIn returns true if and only if the user who invoked `cmd` belongs to `group`.
+	IsUserIn(ctx context.Context, cmd *slack.SlashCommand, group string) (bool, error)
+}
+
+type AuthorizerFunc func(ctx context.Context, cmd *slack.SlashCommand, group string) (bool, error)
+
+func (f AuthorizerFunc) IsUserIn(ctx context.Context, cmd *slack.SlashCommand, group string) (bool, error) {
+	return f(ctx, cmd, group)
+}
+
+// WithAuthorizer sets an Authorizer that is used by authorization predicates such as RequireUserIn.
+func WithAuthorizer(a Authorizer) Option {
+	return optionFunc(func(r *Router) {
+		r.authorizer = a
+	})
+}
+
+// WithDeniedMessage sets a message that the Router responds with (as an ephemeral message) when users are not allowed to use commands.
+//
+// If not set, DefaultDeniedMessage is used.
+func WithDeniedMessage(text string) Option {
+	return optionFunc(func(r *Router) {
+		r.deniedMessage = text
+	})
+}
+
+type authorization struct {
+	authorizer    Authorizer
+	deniedMessage string
+}
+
+type authorizerKey struct{}
+
+type requireUserInPredicate struct {
+	groups []string
+}
+
+// RequireUserIn is a predicate that allows only users who belong to at least one of `groups`, according to the Authorizer set by WithAuthorizer.
+//
+// Unlike other predicates, this does not fall back to other handlers when users are not allowed.
+// Instead, the Router responds with an ephemeral message set by WithDeniedMessage.
+func RequireUserIn(groups ...string) Predicate {
+	return &requireUserInPredicate{groups: groups}
+}
+
+func (p *requireUserInPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) error {
+		authz, ok := ctx.Value(authorizerKey{}).(*authorization)
+		if !ok {
+			return ErrNoAuthorizer
+		}
+		for _, group := range p.groups {
+			allowed, err := authz.authorizer.IsUserIn(ctx, cmd, group)
+			if err != nil {
+				return fmt.Errorf("failed to authorize user %s: %w", cmd.UserID, err)
+			}
+			if allowed {
+				return h.HandleSlashCommand(ctx, cmd)
+			}
+		}
+		return Ephemeral(authz.deniedMessage)
+	})
+}
+
+func (p *requireUserInPredicate) String() string {
+	return fmt.Sprintf("RequireUserIn(%s)", strings.Join(p.groups, ", "))
+}
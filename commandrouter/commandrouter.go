@@ -0,0 +1,410 @@
+// Package commandrouter provides a way to dispatch slash commands sent from Slack.
+//
+// For more details, see https://api.slack.com/interactivity/slash-commands.
+package commandrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/routeinfo"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/routeroptions"
+)
+
+// Handler processes slash commands sent from Slack.
+type Handler interface {
+	HandleSlashCommand(context.Context, *slack.SlashCommand) error
+}
+
+type HandlerFunc func(context.Context, *slack.SlashCommand) error
+
+func (f HandlerFunc) HandleSlashCommand(ctx context.Context, cmd *slack.SlashCommand) error {
+	return f(ctx, cmd)
+}
+
+// HandlerProvider constructs Handlers lazily. See `router.HandlerProvider`.
+type HandlerProvider = router.HandlerProvider[Handler]
+
+type HandlerProviderFunc = router.HandlerProviderFunc[Handler]
+
+// FromProvider returns a Handler that obtains a Handler from `p` every time it processes a command and delegates the command to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func FromProvider(p HandlerProvider) Handler {
+	return FromGeneric(router.FromProvider(p, Handler.HandleSlashCommand))
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming commands.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+// Condition is a condition that does not depend on events. See `router.Condition`.
+type Condition = router.Condition
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When(cond Condition) Predicate {
+	return FromGenericPredicate(router.When[*slack.SlashCommand](cond))
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description(text string) Predicate {
+	return FromGenericPredicate(router.Description[*slack.SlashCommand](text))
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue(key, value interface{}) Predicate {
+	return FromGenericPredicate(router.WithValue[*slack.SlashCommand](key, value))
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	return routerutils.BuildMarked(h, preds, Predicate.Wrap, isOutermost, markHandler)
+}
+
+// markHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markHandler(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) error {
+		return routerutils.MarkHandlerError(h.HandleSlashCommand(ctx, cmd))
+	})
+}
+
+// Response is a directive that handlers can return (as an error) to make the Router respond with a message.
+// Use Ephemeral or InChannel to create one.
+//
+// For more details, see https://api.slack.com/interactivity/slash-commands#responding_immediate_response.
+type Response struct {
+	Message *slack.Msg
+}
+
+func (r *Response) Error() string {
+	return "immediate response to the slash command"
+}
+
+var _ error = &Response{}
+
+// Ephemeral returns a Response that is only visible to the user who invoked the command.
+func Ephemeral(text string) *Response {
+	return &Response{Message: &slack.Msg{ResponseType: slack.ResponseTypeEphemeral, Text: text}}
+}
+
+// InChannel returns a Response that is visible to everyone in the channel.
+func InChannel(text string) *Response {
+	return &Response{Message: &slack.Msg{ResponseType: slack.ResponseTypeInChannel, Text: text}}
+}
+
+// Option configures the Router.
+type Option interface {
+	apply(*Router)
+}
+
+type optionFunc func(*Router)
+
+func (f optionFunc) apply(r *Router) {
+	f(r)
+}
+
+// WithShared applies options shared by all routers. See the `routeroptions` package for details.
+func WithShared(opts ...routeroptions.Option) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(opts...)
+	})
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful to test your handlers, but do not use this in production environments.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.InsecureSkipVerification())
+	})
+}
+
+// WithSigningSecret sets a signing token to verify requests from Slack.
+//
+// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func WithSigningSecret(token string) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithSigningSecret(token))
+	})
+}
+
+// WithPreviousSigningSecrets sets signing secrets that are accepted in addition to the one set by WithSigningSecret.
+//
+// This is useful to rotate signing secrets without downtime. Handlers can find which secret matched by `signature.VerificationFromContext`.
+func WithPreviousSigningSecrets(secrets ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithPreviousSigningSecrets(secrets...))
+	})
+}
+
+// If VerboseResponse is set, the Router shows error details when it fails to process requests.
+func VerboseResponse() Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.VerboseResponse())
+	})
+}
+
+// WithRedaction makes the Router redact error details by `policy` before showing them (see VerboseResponse) or passing them to the hook set by OnError.
+func WithRedaction(policy *redact.Policy) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithRedaction(policy))
+	})
+}
+
+// AcceptHealthProbes makes the Router respond to GET and HEAD requests with 200 (OK), so that load balancers can probe the path of the Router.
+//
+// Regardless of this option, the Router responds to requests with other methods with 405 (Method Not Allowed).
+func AcceptHealthProbes() Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.AcceptHealthProbes())
+	})
+}
+
+// WithHTTPMiddleware adds middlewares that wrap the Router. The first middleware is the outermost one.
+//
+// Middlewares are applied outside of signature verification, and may read request bodies without breaking it.
+// See `routeroptions.WithHTTPMiddleware` for details.
+func WithHTTPMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithHTTPMiddleware(mw...))
+	})
+}
+
+// OnError sets a hook that is called every time the Router responds with an error, including verification failures.
+//
+// Errors given to the hook wrap sentinel errors such as `signature.ErrBadSignature` when applicable,
+// so the hook can distinguish reasons by `errors.Is`.
+// The hook is also called with `routererrors.Mismatch` when predicates marked by `router.FailOnMismatch` don't match, even though the Router responds with 200 (OK) in such case.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.OnError(hook))
+	})
+}
+
+// WithNowFunc sets a function that returns the current time.
+//
+// The Router uses it wherever it depends on the current time (e.g. checking whether request timestamps are too old).
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithNowFunc(now))
+	})
+}
+
+// StrictRegistration makes the Router panic when it detects registrations that are likely to be mistakes.
+//
+// This includes registering more than one handlers for the same command with equal predicates
+// (regardless of the order of predicates), and calling SetFallback more than once.
+func StrictRegistration() Option {
+	return optionFunc(func(r *Router) {
+		r.registry = routerutils.NewRegistry()
+	})
+}
+
+// Router is an http.Handler that processes slash commands from Slack.
+//
+// For more details, see https://api.slack.com/interactivity/slash-commands.
+type Router struct {
+	shared           routeroptions.Config
+	handlers         map[string][]Handler
+	fallbackHandlers []Handler
+	registry         *routerutils.Registry
+	routes           []routeinfo.Route
+	authorizer       Authorizer
+	deniedMessage    string
+	httpHandler      http.Handler
+}
+
+// New creates a new Router.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func New(opts ...Option) (*Router, error) {
+	r := &Router{
+		handlers:      make(map[string][]Handler),
+		deniedMessage: DefaultDeniedMessage,
+		shared:        routeroptions.NewConfig(),
+	}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	if err := r.shared.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.httpHandler = r.shared.WrapHTTP(r.shared.CheckMethod(r.shared.Verify(http.HandlerFunc(r.serveHTTP))))
+	return r, nil
+}
+
+// On registers a handler for a specific command (e.g. `/deploy`).
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Handlers may return `routererrors.NotInterested` (or its equivalents in the sense of `errors.Is`). In such case the Router falls back to other handlers.
+//
+// Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
+//
+// Handlers may also return `*Response` (or its equivalents in the sense of `errors.As`), which can be created by Ephemeral or InChannel.
+// In such case the Router responds with the message.
+//
+// If any other errors are returned, the Router responds with Internal Server Error.
+func (r *Router) On(command string, h Handler, preds ...Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(command, ps)
+	r.routes = append(r.routes, routerutils.NewRoute(command, h, ps))
+	h = Build(h, preds...)
+	r.handlers[command] = append(r.handlers[command], h)
+}
+
+// SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming command.
+//
+// If more than one handlers are registered, the last one will be used.
+// Note that this discards all fallback handlers registered so far, including ones registered by AddFallback.
+// If you want to register more than one fallback handlers, use AddFallback instead.
+func (r *Router) SetFallback(h Handler) {
+	r.checkDuplicate("SetFallback", nil)
+	r.fallbackHandlers = []Handler{h}
+}
+
+// AddFallback adds a fallback handler that is called when none of the registered handlers matches to a coming command.
+//
+// Fallback handlers form a chain in the order of registration. If a fallback handler returns `routererrors.NotInterested`
+// (or its equivalents in the sense of `errors.Is`), the Router falls back to the next one.
+func (r *Router) AddFallback(h Handler) {
+	r.fallbackHandlers = append(r.fallbackHandlers, h)
+}
+
+// Routes returns all routes registered to the Router in the order of registration.
+func (r *Router) Routes() []routeinfo.Route {
+	routes := make([]routeinfo.Route, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+func (r *Router) checkDuplicate(key string, preds []interface{}) {
+	if r.registry == nil {
+		return
+	}
+	if err := r.registry.Register(key, preds); err != nil {
+		panic(err)
+	}
+}
+
+func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	router.httpHandler.ServeHTTP(w, req)
+}
+
+func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		router.shared.RespondWithError(req.Context(), w,
+			fmt.Errorf("unexpected Content-Type: %w", routererrors.HttpError(http.StatusBadRequest)))
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		router.shared.RespondWithError(req.Context(), w, err)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	cmd, err := slack.SlashCommandParse(req)
+	if err != nil {
+		router.shared.RespondWithError(req.Context(), w,
+			fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(http.StatusBadRequest)))
+		return
+	}
+	if cmd.Command == "" {
+		router.shared.RespondWithError(req.Context(), w,
+			fmt.Errorf("missing command: %w", routererrors.HttpError(http.StatusBadRequest)))
+		return
+	}
+
+	ctx := routerutils.WithRequest(req.Context(), body, req.Header)
+	if router.authorizer != nil {
+		ctx = context.WithValue(ctx, authorizerKey{}, &authorization{authorizer: router.authorizer, deniedMessage: router.deniedMessage})
+	}
+	router.respond(ctx, w, router.dispatch(ctx, &cmd))
+}
+
+func (r *Router) dispatch(ctx context.Context, cmd *slack.SlashCommand) error {
+	var err error = routererrors.NotInterested
+	for _, h := range r.handlers[cmd.Command] {
+		err = h.HandleSlashCommand(ctx, cmd)
+		if !errors.Is(err, routererrors.NotInterested) {
+			break
+		}
+	}
+
+	if errors.Is(err, routererrors.NotInterested) {
+		err = r.handleFallback(ctx, cmd)
+	}
+	return err
+}
+
+func (r *Router) respond(ctx context.Context, w http.ResponseWriter, err error) {
+	var resp *Response
+	if errors.As(err, &resp) {
+		r.respondWithJSON(w, resp.Message)
+		return
+	}
+
+	if errors.Is(err, routererrors.Mismatch) {
+		// Misrouted commands are reported, but retrying them doesn't help.
+		r.shared.ReportError(ctx, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err != nil && !errors.Is(err, routererrors.NotInterested) {
+		r.shared.RespondWithError(ctx, w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Router) respondWithJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (r *Router) handleFallback(ctx context.Context, cmd *slack.SlashCommand) error {
+	var err error = routererrors.NotInterested
+	for _, h := range r.fallbackHandlers {
+		err = h.HandleSlashCommand(ctx, cmd)
+		if !errors.Is(err, routererrors.NotInterested) {
+			break
+		}
+	}
+	return err
+}
+
+// RawBody returns the raw request body of the slash command that is being processed.
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RawBody(ctx context.Context) []byte {
+	return routerutils.RawBody(ctx)
+}
+
+// RequestHeader returns the HTTP headers of the slash command that is being processed (e.g. `X-Slack-Retry-Num`).
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RequestHeader(ctx context.Context) http.Header {
+	return routerutils.Header(ctx)
+}
@@ -0,0 +1,13 @@
+package commandrouter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCommandrouter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Commandrouter Suite")
+}
@@ -0,0 +1,283 @@
+package commandrouter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// staticCondition is a Condition that always returns the same result.
+type staticCondition bool
+
+func (c staticCondition) Match(context.Context) bool {
+	return bool(c)
+}
+
+var _ = Describe("CommandRouter", func() {
+	var (
+		r                *commandrouter.Router
+		numHandlerCalled int
+		handler          = commandrouter.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) error {
+			numHandlerCalled++
+			return commandrouter.InChannel("deploying")
+		})
+		groups = map[string][]string{
+			"admins": {"UADMIN"},
+			"devs":   {"UDEV"},
+		}
+		authorizer = commandrouter.AuthorizerFunc(func(_ context.Context, cmd *slack.SlashCommand, group string) (bool, error) {
+			if group == "broken" {
+				return false, errors.New("RBAC service is down")
+			}
+			for _, u := range groups[group] {
+				if u == cmd.UserID {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		newRequest = func(command, userID string) *http.Request {
+			form := url.Values{}
+			form.Set("command", command)
+			form.Set("user_id", userID)
+			form.Set("text", "production")
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(form.Encode())))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}
+		decode = func(w *httptest.ResponseRecorder) *slack.Msg {
+			msg := &slack.Msg{}
+			Expect(json.Unmarshal(w.Body.Bytes(), msg)).To(Succeed())
+			return msg
+		}
+	)
+	BeforeEach(func() {
+		numHandlerCalled = 0
+	})
+
+	Describe("New", func() {
+		Context("when neither WithSigningSecret nor InsecureSkipVerification is given", func() {
+			It("returns an error", func() {
+				_, err := commandrouter.New()
+				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+			})
+		})
+	})
+
+	Describe("On", func() {
+		BeforeEach(func() {
+			var err error
+			r, err = commandrouter.New(commandrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On("/deploy", handler)
+		})
+
+		Context("when the command matches", func() {
+			It("responds with the message returned by the handler", func() {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "UDEV"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				msg := decode(w)
+				Expect(msg.ResponseType).To(Equal(slack.ResponseTypeInChannel))
+				Expect(msg.Text).To(Equal("deploying"))
+			})
+		})
+
+		Context("when the command does not match", func() {
+			It("does not call the handler", func() {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/rollback", "UDEV"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the Content-Type is not a form", func() {
+			It("responds with BadRequest", func() {
+				req := newRequest("/deploy", "UDEV")
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		It("gives the raw request to the handler", func() {
+			var body []byte
+			var header http.Header
+			r.On("/echo", commandrouter.HandlerFunc(func(ctx context.Context, _ *slack.SlashCommand) error {
+				body = commandrouter.RawBody(ctx)
+				header = commandrouter.RequestHeader(ctx)
+				return nil
+			}))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newRequest("/echo", "UDEV"))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(string(body)).To(ContainSubstring("command=%2Fecho"))
+			Expect(header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+		})
+	})
+
+	Describe("AddFallback", func() {
+		It("calls fallback handlers in order until one of them is interested", func() {
+			r, err := commandrouter.New(commandrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			var called []string
+			r.AddFallback(commandrouter.HandlerFunc(func(context.Context, *slack.SlashCommand) error {
+				called = append(called, "first")
+				return routererrors.NotInterested
+			}))
+			r.AddFallback(commandrouter.HandlerFunc(func(context.Context, *slack.SlashCommand) error {
+				called = append(called, "second")
+				return commandrouter.Ephemeral("unknown command")
+			}))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newRequest("/unknown", "UDEV"))
+			Expect(called).To(Equal([]string{"first", "second"}))
+			Expect(decode(w).Text).To(Equal("unknown command"))
+		})
+	})
+
+	Describe("Routes", func() {
+		It("returns registered routes with their descriptions", func() {
+			r, err := commandrouter.New(commandrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On("/deploy", handler, commandrouter.Description("Deploys the given service"), commandrouter.RequireUserIn("admins"))
+			routes := r.Routes()
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Type).To(Equal("/deploy"))
+			Expect(routes[0].Description).To(Equal("Deploys the given service"))
+			Expect(routes[0].Predicates).To(Equal([]string{"RequireUserIn(admins)"}))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		var (
+			hookErrs []error
+			failOn   = commandrouter.FromGenericPredicate(router.FailOnMismatch[*slack.SlashCommand]())
+		)
+		BeforeEach(func() {
+			hookErrs = nil
+			var err error
+			r, err = commandrouter.New(commandrouter.InsecureSkipVerification(), commandrouter.OnError(func(_ context.Context, err error) {
+				hookErrs = append(hookErrs, err)
+			}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the other predicates don't match", func() {
+			It("responds with 200 and calls the hook with Mismatch", func() {
+				r.On("/deploy", handler, failOn, commandrouter.When(staticCondition(false)))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "UDEV"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(routererrors.Mismatch))
+			})
+		})
+
+		Context("when the handler itself is not interested", func() {
+			It("falls back to other handlers", func() {
+				r.On("/deploy", commandrouter.HandlerFunc(func(context.Context, *slack.SlashCommand) error {
+					return routererrors.NotInterested
+				}), failOn)
+				r.SetFallback(handler)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "UDEV"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(hookErrs).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("StrictRegistration", func() {
+		It("panics when the same command is registered twice with equal predicates", func() {
+			r, err := commandrouter.New(commandrouter.InsecureSkipVerification(), commandrouter.StrictRegistration())
+			Expect(err).NotTo(HaveOccurred())
+			r.On("/deploy", handler, commandrouter.RequireUserIn("admins"))
+			r.On("/rollback", handler, commandrouter.RequireUserIn("admins"))
+			Expect(func() { r.On("/deploy", handler, commandrouter.RequireUserIn("admins")) }).To(Panic())
+		})
+	})
+
+	Describe("RequireUserIn", func() {
+		BeforeEach(func() {
+			var err error
+			r, err = commandrouter.New(commandrouter.InsecureSkipVerification(),
+				commandrouter.WithAuthorizer(authorizer), commandrouter.WithDeniedMessage("admins only"))
+			Expect(err).NotTo(HaveOccurred())
+			r.On("/deploy", handler, commandrouter.RequireUserIn("admins", "devs"))
+			r.On("/broken", handler, commandrouter.RequireUserIn("broken"))
+		})
+
+		Context("when the user belongs to one of the groups", func() {
+			It("calls the handler", func() {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "UDEV"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the user does not belong to any of the groups", func() {
+			It("responds with an ephemeral message", func() {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "USOMEONE"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+				msg := decode(w)
+				Expect(msg.ResponseType).To(Equal(slack.ResponseTypeEphemeral))
+				Expect(msg.Text).To(Equal("admins only"))
+			})
+		})
+
+		Context("when the Authorizer fails", func() {
+			It("responds with Internal Server Error", func() {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/broken", "UADMIN"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when WithAuthorizer is not given", func() {
+			It("responds with Internal Server Error", func() {
+				r, err := commandrouter.New(commandrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On("/deploy", handler, commandrouter.RequireUserIn("admins"))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRequest("/deploy", "UADMIN"))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("When", func() {
+		It("calls the inner handler only when the condition matches", func() {
+			ctx := context.Background()
+			cmd := &slack.SlashCommand{}
+			var resp *commandrouter.Response
+			Expect(errors.As(commandrouter.Build(handler, commandrouter.When(staticCondition(true))).HandleSlashCommand(ctx, cmd), &resp)).To(BeTrue())
+			Expect(commandrouter.Build(handler, commandrouter.When(staticCondition(false))).HandleSlashCommand(ctx, cmd)).To(MatchError(routererrors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package eventrouter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// Response is a response to a request given to Dispatch.
+type Response struct {
+	// Status is an HTTP status code.
+	Status int
+
+	// Header contains response headers. Only the first value is kept if a header has more than one values.
+	Header map[string]string
+
+	// Body is a response body.
+	Body []byte
+}
+
+// Dispatch processes a raw request body and its headers in the same way as ServeHTTP, and returns the response.
+//
+// This is intended to be used in environments where there is no `http.Server`, such as WASM workers or TinyGo.
+// Header names are case-insensitive.
+func (r *Router) Dispatch(body []byte, headers map[string]string) Response {
+	return r.DispatchContext(context.Background(), body, headers)
+}
+
+// DispatchContext is the same as Dispatch except that it passes `ctx` to handlers.
+func (r *Router) DispatchContext(ctx context.Context, body []byte, headers map[string]string) Response {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(body))
+	if err != nil {
+		return Response{Status: http.StatusInternalServerError}
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := &dispatchWriter{header: make(http.Header)}
+	r.ServeHTTP(w, req)
+	return w.response()
+}
+
+type dispatchWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *dispatchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *dispatchWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *dispatchWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+func (w *dispatchWriter) response() Response {
+	resp := Response{
+		Status: w.status,
+		Header: make(map[string]string, len(w.header)),
+		Body:   w.body.Bytes(),
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	for k, vs := range w.header {
+		if len(vs) > 0 {
+			resp.Header[k] = vs[0]
+		}
+	}
+	return resp
+}
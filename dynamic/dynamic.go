@@ -0,0 +1,185 @@
+// Package dynamic provides sets of values (e.g. channel IDs, emoji names and user IDs) that are periodically reloaded from external sources.
+//
+// This makes it possible to change routing targets without redeploying:
+//
+//	channels := dynamic.NewSet(dynamic.SourceFunc(loadChannelsFromDB), time.Minute)
+//	r.OnMessage(handler, message.ChannelIn(channels))
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// DefaultRefreshTimeout is the default timeout of loading values. See WithRefreshTimeout.
+const DefaultRefreshTimeout = 30 * time.Second
+
+// Source loads values of a Set.
+type Source interface {
+	Load(ctx context.Context) ([]string, error)
+}
+
+type SourceFunc func(ctx context.Context) ([]string, error)
+
+func (f SourceFunc) Load(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// Option configures the Set.
+type Option interface {
+	apply(*Set)
+}
+
+type optionFunc func(*Set)
+
+func (f optionFunc) apply(s *Set) {
+	f(s)
+}
+
+// OnError sets a hook that is called when the Set fails to load values. If not set, errors are ignored.
+//
+// The Set keeps using the previous values until it succeeds in loading.
+func OnError(hook func(err error)) Option {
+	return optionFunc(func(s *Set) {
+		s.errorHook = hook
+	})
+}
+
+// WithRefreshTimeout sets the timeout of loading values from the Source (DefaultRefreshTimeout by default).
+//
+// Since values are reloaded in the background, this prevents a stuck Source from blocking reloads forever.
+func WithRefreshTimeout(timeout time.Duration) Option {
+	return optionFunc(func(s *Set) {
+		s.refreshTimeout = timeout
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(s *Set) {
+		s.now = now
+	})
+}
+
+// Set is a set of values that is reloaded from a Source.
+//
+// Values are loaded when the Set is used for the first time, and concurrent lookups wait for the same load.
+// If the first load fails, the next lookup tries again. After that, values are reloaded in the background
+// when the Set is used more than `refreshInterval` after the last load, so lookups never wait for reloading.
+type Set struct {
+	source          Source
+	refreshInterval time.Duration
+	refreshTimeout  time.Duration
+	errorHook       func(error)
+	now             func() time.Time
+
+	mu         sync.RWMutex
+	values     map[string]struct{}
+	loaded     bool
+	loadedAt   time.Time
+	refreshing bool
+
+	// loading is closed when the ongoing first load finishes. It is nil unless the first load is ongoing.
+	loading chan struct{}
+}
+
+// NewSet creates a new Set that reloads values from `source` every `refreshInterval`.
+func NewSet(source Source, refreshInterval time.Duration, opts ...Option) *Set {
+	s := &Set{
+		source:          source,
+		refreshInterval: refreshInterval,
+		refreshTimeout:  DefaultRefreshTimeout,
+		now:             time.Now,
+	}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	return s
+}
+
+// Contains returns true if `value` is in the Set.
+func (s *Set) Contains(ctx context.Context, value string) bool {
+	s.mu.Lock()
+	loaded := s.loaded
+	stale := loaded && !s.refreshing && s.now().Sub(s.loadedAt) >= s.refreshInterval
+	if stale {
+		s.refreshing = true
+	}
+	s.mu.Unlock()
+
+	if !loaded {
+		s.loadFirst(ctx)
+	} else if stale {
+		go func() {
+			ctx, cancel := context.WithTimeout(routerutils.Detach(ctx), s.refreshTimeout)
+			defer cancel()
+			_ = s.Refresh(ctx)
+		}()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.values[value]
+	return ok
+}
+
+// loadFirst loads values unless they are already loaded. Concurrent callers wait for the same load.
+func (s *Set) loadFirst(ctx context.Context) {
+	s.mu.Lock()
+	if s.loaded {
+		s.mu.Unlock()
+		return
+	}
+	if wait := s.loading; wait != nil {
+		s.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+		}
+		return
+	}
+	done := make(chan struct{})
+	s.loading = done
+	s.mu.Unlock()
+
+	loadCtx, cancel := context.WithTimeout(ctx, s.refreshTimeout)
+	_ = s.Refresh(loadCtx)
+	cancel()
+	s.mu.Lock()
+	s.loading = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+// Refresh loads values from the Source immediately.
+//
+// If it fails, the Set keeps the previous values. Values that have never been loaded are loaded again by the next lookup.
+func (s *Set) Refresh(ctx context.Context) error {
+	values, err := s.source.Load(ctx)
+	s.mu.Lock()
+	s.refreshing = false
+	if err == nil {
+		s.values = make(map[string]struct{}, len(values))
+		for _, v := range values {
+			s.values[v] = struct{}{}
+		}
+		s.loaded = true
+		s.loadedAt = s.now()
+	} else if s.loaded {
+		// Retry after the interval rather than on every lookup.
+		s.loadedAt = s.now()
+	}
+	s.mu.Unlock()
+	if err != nil && s.errorHook != nil {
+		s.errorHook(err)
+	}
+	return err
+}
+
+func (s *Set) String() string {
+	return fmt.Sprintf("dynamic.Set(%T)", s.source)
+}
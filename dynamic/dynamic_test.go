@@ -0,0 +1,154 @@
+package dynamic_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/dynamic"
+)
+
+type fakeSource struct {
+	mu       sync.Mutex
+	values   []string
+	err      error
+	numLoads int
+	deadline bool
+	block    chan struct{}
+}
+
+func (s *fakeSource) Load(ctx context.Context) ([]string, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numLoads++
+	_, s.deadline = ctx.Deadline()
+	return s.values, s.err
+}
+
+func (s *fakeSource) set(values []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = values
+	s.err = err
+}
+
+func (s *fakeSource) loads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.numLoads
+}
+
+var _ = Describe("Dynamic", func() {
+	var (
+		ctx    = context.Background()
+		source *fakeSource
+		nowMu  sync.Mutex
+		now    time.Time
+		nowFn  = func() time.Time {
+			nowMu.Lock()
+			defer nowMu.Unlock()
+			return now
+		}
+		advance = func(d time.Duration) {
+			nowMu.Lock()
+			defer nowMu.Unlock()
+			now = now.Add(d)
+		}
+	)
+	BeforeEach(func() {
+		source = &fakeSource{values: []string{"C001", "C002"}}
+		now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	Describe("Set", func() {
+		It("loads values when it is used for the first time", func() {
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn))
+			Expect(source.loads()).To(Equal(0))
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			Expect(s.Contains(ctx, "C003")).To(BeFalse())
+			Expect(source.loads()).To(Equal(1))
+		})
+
+		It("reloads values in the background after the refresh interval", func() {
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn))
+			Expect(s.Contains(ctx, "C003")).To(BeFalse())
+			source.set([]string{"C003"}, nil)
+			advance(30 * time.Second)
+			Expect(s.Contains(ctx, "C003")).To(BeFalse())
+			Expect(source.loads()).To(Equal(1))
+
+			advance(30 * time.Second)
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			Eventually(func() bool { return s.Contains(ctx, "C003") }).Should(BeTrue())
+			Expect(s.Contains(ctx, "C001")).To(BeFalse())
+			Expect(source.loads()).To(Equal(2))
+		})
+
+		It("keeps previous values when it fails to reload", func() {
+			var hookErrs []error
+			var hookMu sync.Mutex
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn), dynamic.OnError(func(err error) {
+				hookMu.Lock()
+				defer hookMu.Unlock()
+				hookErrs = append(hookErrs, err)
+			}))
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			source.set(nil, errors.New("unavailable"))
+			Expect(s.Refresh(ctx)).To(MatchError("unavailable"))
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			hookMu.Lock()
+			defer hookMu.Unlock()
+			Expect(hookErrs).To(HaveLen(1))
+		})
+
+		It("loads values only once when it is used concurrently for the first time", func() {
+			source.block = make(chan struct{})
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn))
+			var wg sync.WaitGroup
+			results := make([]bool, 10)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i] = s.Contains(ctx, "C001")
+				}(i)
+			}
+			close(source.block)
+			wg.Wait()
+			Expect(results).NotTo(ContainElement(BeFalse()))
+			Expect(source.loads()).To(Equal(1))
+		})
+
+		It("retries the first load when it fails", func() {
+			source.set(nil, errors.New("unavailable"))
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn))
+			Expect(s.Contains(ctx, "C001")).To(BeFalse())
+			source.set([]string{"C001"}, nil)
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			Expect(source.loads()).To(Equal(2))
+		})
+
+		It("loads values with a timeout", func() {
+			s := dynamic.NewSet(source, time.Minute, dynamic.WithNowFunc(nowFn), dynamic.WithRefreshTimeout(time.Second))
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			source.mu.Lock()
+			Expect(source.deadline).To(BeTrue())
+			source.deadline = false
+			source.mu.Unlock()
+
+			advance(time.Minute)
+			Expect(s.Contains(ctx, "C001")).To(BeTrue())
+			Eventually(source.loads).Should(Equal(2))
+			source.mu.Lock()
+			defer source.mu.Unlock()
+			Expect(source.deadline).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,294 @@
+// Package enrich resolves information related to events (e.g. conversations, users) via Slack Web API and stores it in contexts.
+//
+// This makes it possible to write predicates and handlers that match on names rather than hardcoded IDs:
+//
+//	enricher := enrich.New(slack.New(botToken))
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(enricher))
+//	r.OnMessage(handleIncident, message.ChannelNamed("incidents"))
+//
+// Users, workspaces (including their locales and time zones) and reacted messages can also be resolved by WithUsers, WithTeams and WithReactedMessages.
+// Results of API calls are cached in an LRU cache with TTL.
+//
+// Information that cannot be resolved (e.g. during an outage of Slack Web API) is just missing in contexts,
+// so that predicates on it are considered to be "false". Use OnLookupError to observe such failures,
+// and WithRequired to make the Router respond with an error instead.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/internal/lrucache"
+)
+
+const (
+	// DefaultCacheSize is the default maximum number of cached entries.
+	DefaultCacheSize = 1000
+
+	// DefaultTTL is the default time to live of cached entries.
+	DefaultTTL = 10 * time.Minute
+)
+
+// Client is a subset of `slack.Client` that Enricher uses.
+type Client interface {
+	GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error)
+	GetUserInfoContext(ctx context.Context, userID string) (*slack.User, error)
+}
+
+var _ Client = &slack.Client{}
+
+// Option configures the Enricher.
+type Option interface {
+	apply(*Enricher)
+}
+
+type optionFunc func(*Enricher)
+
+func (f optionFunc) apply(e *Enricher) {
+	f(e)
+}
+
+// WithCacheSize sets the maximum number of cached entries per kind of information.
+func WithCacheSize(size int) Option {
+	return optionFunc(func(e *Enricher) {
+		e.cacheSize = size
+	})
+}
+
+// WithTTL sets the time to live of cached entries.
+func WithTTL(ttl time.Duration) Option {
+	return optionFunc(func(e *Enricher) {
+		e.ttl = ttl
+	})
+}
+
+// WithUsers makes the Enricher also resolve users who triggered events via `users.info`.
+//
+// This requires `users:read` scope (and `users:read.email` to use email addresses).
+func WithUsers() Option {
+	return optionFunc(func(e *Enricher) {
+		e.resolveUsers = true
+	})
+}
+
+// WithRequired makes Enrich fail when it cannot resolve information related to events.
+//
+// By default, such information is just missing in contexts, so that a failure of one API does not block every event.
+func WithRequired() Option {
+	return optionFunc(func(e *Enricher) {
+		e.required = true
+	})
+}
+
+// OnLookupError sets a hook that is called when the Enricher cannot resolve information related to events.
+//
+// The hook is not called when WithRequired is given, since the error is returned from Enrich instead.
+func OnLookupError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(e *Enricher) {
+		e.lookupErrorHook = hook
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(e *Enricher) {
+		e.now = now
+	})
+}
+
+// Enricher resolves information related to events and stores it in contexts.
+type Enricher struct {
+	client          Client
+	cacheSize       int
+	ttl             time.Duration
+	now             func() time.Time
+	required        bool
+	lookupErrorHook func(context.Context, error)
+	resolveUsers    bool
+	teamSettings    TeamSettingsFunc
+	teamClients     TeamClientFunc
+	resolveMessages bool
+	messageClient   MessageClient
+	channels        *lrucache.Cache
+	users           *lrucache.Cache
+	teams           *lrucache.Cache
+	messages        *lrucache.Cache
+}
+
+// New creates a new Enricher. Typically `client` is a `*slack.Client`.
+//
+// It panics if WithTeams (or WithReactedMessages) is given but `client` doesn't implement TeamClient (or MessageClient),
+// unless WithTeamClients is given.
+func New(client Client, opts ...Option) *Enricher {
+	e := &Enricher{
+		client:    client,
+		cacheSize: DefaultCacheSize,
+		ttl:       DefaultTTL,
+		now:       time.Now,
+	}
+	for _, o := range opts {
+		o.apply(e)
+	}
+	e.channels = lrucache.New(e.cacheSize, e.ttl, e.now)
+	e.users = lrucache.New(e.cacheSize, e.ttl, e.now)
+	e.teams = lrucache.New(e.cacheSize, e.ttl, e.now)
+	if e.teamSettings != nil && e.teamClients == nil {
+		tc, ok := client.(TeamClient)
+		if !ok {
+			panic("enrich: WithTeams requires a client that implements TeamClient")
+		}
+		e.teamClients = func(context.Context, string) (TeamClient, error) {
+			return tc, nil
+		}
+	}
+	e.messages = lrucache.New(e.cacheSize, e.ttl, e.now)
+	if e.resolveMessages {
+		mc, ok := client.(MessageClient)
+		if !ok {
+			panic("enrich: WithReactedMessages requires a client that implements MessageClient")
+		}
+		e.messageClient = mc
+	}
+	return e
+}
+
+type channelKey struct{}
+
+// ChannelFromContext returns the conversation where the event being processed happened.
+//
+// This is only available in contexts enriched by Enricher. Otherwise it returns nil.
+func ChannelFromContext(ctx context.Context) *slack.Channel {
+	ch, _ := ctx.Value(channelKey{}).(*slack.Channel)
+	return ch
+}
+
+// WithChannel returns a new context that holds `ch`. This is mainly intended to test handlers that use ChannelFromContext.
+func WithChannel(ctx context.Context, ch *slack.Channel) context.Context {
+	return context.WithValue(ctx, channelKey{}, ch)
+}
+
+type userKey struct{}
+
+// UserFromContext returns the user who triggered the event being processed.
+//
+// This is only available in contexts enriched by Enricher with WithUsers. Otherwise it returns nil.
+func UserFromContext(ctx context.Context) *slack.User {
+	u, _ := ctx.Value(userKey{}).(*slack.User)
+	return u
+}
+
+// WithUser returns a new context that holds `u`. This is mainly intended to test handlers that use UserFromContext.
+func WithUser(ctx context.Context, u *slack.User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// Enrich resolves information related to `e` and returns a new context that holds it.
+//
+// Information that cannot be resolved is left out of the context, unless WithRequired is given.
+func (e *Enricher) Enrich(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	if channelID := ChannelID(ev); channelID != "" {
+		ch, err := e.Channel(ctx, channelID)
+		if err != nil {
+			if err := e.lookupFailed(ctx, err); err != nil {
+				return nil, err
+			}
+		} else {
+			ctx = WithChannel(ctx, ch)
+		}
+	}
+	if userID := UserID(ev); e.resolveUsers && userID != "" {
+		u, err := e.User(ctx, userID)
+		if err != nil {
+			if err := e.lookupFailed(ctx, err); err != nil {
+				return nil, err
+			}
+		} else {
+			ctx = WithUser(ctx, u)
+		}
+	}
+	if teamID := TeamID(ev); e.teamSettings != nil && teamID != "" {
+		t, err := e.Team(ctx, teamID)
+		if err != nil {
+			if err := e.lookupFailed(ctx, err); err != nil {
+				return nil, err
+			}
+		} else {
+			ctx = WithTeam(ctx, t)
+		}
+	}
+	if e.resolveMessages {
+		return e.enrichReactedMessage(ctx, ev)
+	}
+	return ctx, nil
+}
+
+// lookupFailed returns `err` if the Enricher is required to resolve information. Otherwise it reports `err` to the hook and returns nil.
+func (e *Enricher) lookupFailed(ctx context.Context, err error) error {
+	if e.required {
+		return err
+	}
+	if e.lookupErrorHook != nil {
+		e.lookupErrorHook(ctx, err)
+	}
+	return nil
+}
+
+// Channel returns information of the conversation, using the cache if possible.
+func (e *Enricher) Channel(ctx context.Context, channelID string) (*slack.Channel, error) {
+	if v, ok := e.channels.Get(channelID); ok {
+		return v.(*slack.Channel), nil
+	}
+	ch, err := e.client.GetConversationInfoContext(ctx, channelID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation %s: %w", channelID, err)
+	}
+	e.channels.Add(channelID, ch)
+	return ch, nil
+}
+
+// ChannelID returns the ID of the conversation where the event happened, or an empty string if unknown.
+func ChannelID(ev *slackevents.EventsAPIEvent) string {
+	switch inner := ev.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		return inner.Channel
+	case *slackevents.AppMentionEvent:
+		return inner.Channel
+	case *slackevents.ReactionAddedEvent:
+		return inner.Item.Channel
+	case *slackevents.ReactionRemovedEvent:
+		return inner.Item.Channel
+	}
+	return ""
+}
+
+// User returns information of the user, using the cache if possible.
+func (e *Enricher) User(ctx context.Context, userID string) (*slack.User, error) {
+	if v, ok := e.users.Get(userID); ok {
+		return v.(*slack.User), nil
+	}
+	u, err := e.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", userID, err)
+	}
+	e.users.Add(userID, u)
+	return u, nil
+}
+
+// UserID returns the ID of the user who triggered the event, or an empty string if unknown.
+func UserID(ev *slackevents.EventsAPIEvent) string {
+	switch inner := ev.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		return inner.User
+	case *slackevents.AppMentionEvent:
+		return inner.User
+	case *slackevents.ReactionAddedEvent:
+		return inner.User
+	case *slackevents.ReactionRemovedEvent:
+		return inner.User
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+package enrich_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnrich(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Enrich Suite")
+}
@@ -0,0 +1,337 @@
+package enrich_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/enrich"
+)
+
+type fakeClient struct {
+	channels     map[string]*slack.Channel
+	users        map[string]*slack.User
+	numCalls     int
+	numUserCalls int
+}
+
+func (c *fakeClient) GetConversationInfoContext(_ context.Context, channelID string, _ bool) (*slack.Channel, error) {
+	c.numCalls++
+	ch, ok := c.channels[channelID]
+	if !ok {
+		return nil, errors.New("channel_not_found")
+	}
+	return ch, nil
+}
+
+func (c *fakeClient) GetUserInfoContext(_ context.Context, userID string) (*slack.User, error) {
+	c.numUserCalls++
+	u, ok := c.users[userID]
+	if !ok {
+		return nil, errors.New("user_not_found")
+	}
+	return u, nil
+}
+
+type fakeTeamClient struct {
+	*fakeClient
+	team         *slack.TeamInfo
+	numTeamCalls int
+}
+
+func (c *fakeTeamClient) GetTeamInfoContext(_ context.Context) (*slack.TeamInfo, error) {
+	c.numTeamCalls++
+	if c.team == nil {
+		return nil, errors.New("team_not_found")
+	}
+	return c.team, nil
+}
+
+type fakeMessageClient struct {
+	*fakeClient
+	messages        map[string][]slack.Message
+	numMessageCalls int
+}
+
+func (c *fakeMessageClient) GetConversationRepliesContext(_ context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	c.numMessageCalls++
+	msgs, ok := c.messages[params.ChannelID]
+	if !ok {
+		return nil, false, "", errors.New("channel_not_found")
+	}
+	return msgs, false, "", nil
+}
+
+func newMessage(ts, user, text string) slack.Message {
+	msg := slack.Message{}
+	msg.Timestamp = ts
+	msg.User = user
+	msg.Text = text
+	return msg
+}
+
+func reactionEvent(channel, ts string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.ReactionAdded,
+			Data: &slackevents.ReactionAddedEvent{Item: slackevents.Item{Type: "message", Channel: channel, Timestamp: ts}},
+		},
+	}
+}
+
+func newChannel(id, name string) *slack.Channel {
+	ch := &slack.Channel{}
+	ch.ID = id
+	ch.Name = name
+	return ch
+}
+
+func messageEvent(channel, user string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		TeamID: "T001",
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.Message,
+			Data: &slackevents.MessageEvent{Channel: channel, User: user},
+		},
+	}
+}
+
+var _ = Describe("Enrich", func() {
+	var (
+		ctx    = context.Background()
+		client *fakeClient
+		now    time.Time
+	)
+	BeforeEach(func() {
+		client = &fakeClient{channels: map[string]*slack.Channel{
+			"C001": newChannel("C001", "incidents"),
+			"C002": newChannel("C002", "random"),
+			"C003": newChannel("C003", "general"),
+		}, users: map[string]*slack.User{
+			"U001": {ID: "U001", Name: "alice"},
+		}}
+		now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	Describe("Enrich", func() {
+		It("stores the conversation in the context", func() {
+			e := enrich.New(client)
+			enriched, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.ChannelFromContext(enriched).Name).To(Equal("incidents"))
+			Expect(enrich.UserFromContext(enriched)).To(BeNil())
+			Expect(client.numUserCalls).To(Equal(0))
+		})
+
+		It("stores the user in the context when WithUsers is given", func() {
+			e := enrich.New(client, enrich.WithUsers())
+			enriched, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.UserFromContext(enriched).Name).To(Equal("alice"))
+			_, err = e.Enrich(ctx, messageEvent("C002", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numUserCalls).To(Equal(1))
+		})
+
+		It("leaves the user out of the context and reports the error when the user cannot be resolved", func() {
+			var hookErr error
+			e := enrich.New(client, enrich.WithUsers(), enrich.OnLookupError(func(_ context.Context, err error) {
+				hookErr = err
+			}))
+			enriched, err := e.Enrich(ctx, messageEvent("C001", "UUNKNOWN"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.UserFromContext(enriched)).To(BeNil())
+			Expect(enrich.ChannelFromContext(enriched).Name).To(Equal("incidents"))
+			Expect(hookErr).To(HaveOccurred())
+		})
+
+		It("returns an error when the user cannot be resolved and WithRequired is given", func() {
+			e := enrich.New(client, enrich.WithUsers(), enrich.WithRequired())
+			_, err := e.Enrich(ctx, messageEvent("C001", "UUNKNOWN"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("leaves the context as is when the event has no channel", func() {
+			e := enrich.New(client)
+			enriched, err := e.Enrich(ctx, &slackevents.EventsAPIEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.ChannelFromContext(enriched)).To(BeNil())
+			Expect(client.numCalls).To(Equal(0))
+		})
+
+		It("leaves the conversation out of the context when the API call fails", func() {
+			e := enrich.New(client)
+			enriched, err := e.Enrich(ctx, messageEvent("CUNKNOWN", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.ChannelFromContext(enriched)).To(BeNil())
+		})
+
+		It("returns an error when the API call fails and WithRequired is given", func() {
+			e := enrich.New(client, enrich.WithRequired())
+			_, err := e.Enrich(ctx, messageEvent("CUNKNOWN", "U001"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Channel", func() {
+		It("caches results until they expire", func() {
+			e := enrich.New(client, enrich.WithTTL(time.Minute), enrich.WithNowFunc(func() time.Time { return now }))
+			_, err := e.Channel(ctx, "C001")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = e.Channel(ctx, "C001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(1))
+
+			now = now.Add(time.Minute)
+			_, err = e.Channel(ctx, "C001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(2))
+		})
+
+		It("evicts the least recently used entry when the cache is full", func() {
+			e := enrich.New(client, enrich.WithCacheSize(2))
+			for _, id := range []string{"C001", "C002", "C001", "C003", "C001"} {
+				_, err := e.Channel(ctx, id)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(client.numCalls).To(Equal(3))
+			_, err := e.Channel(ctx, "C002")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(4))
+		})
+	})
+
+	Describe("Team", func() {
+		var (
+			teamClient *fakeTeamClient
+			tokyo      = time.FixedZone("Asia/Tokyo", 9*60*60)
+		)
+		BeforeEach(func() {
+			teamClient = &fakeTeamClient{fakeClient: client, team: &slack.TeamInfo{ID: "T001", Name: "example"}}
+		})
+
+		It("stores the team and its settings in the context when WithTeams is given", func() {
+			e := enrich.New(teamClient, enrich.WithTeams(enrich.StaticTeamSettings("ja-JP", tokyo)))
+			enriched, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			t := enrich.TeamFromContext(enriched)
+			Expect(t.Info.Name).To(Equal("example"))
+			Expect(t.Locale).To(Equal("ja-JP"))
+			Expect(t.Location).To(Equal(tokyo))
+			_, err = e.Enrich(ctx, messageEvent("C002", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(teamClient.numTeamCalls).To(Equal(1))
+		})
+
+		It("does not resolve the team unless WithTeams is given", func() {
+			e := enrich.New(teamClient)
+			enriched, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.TeamFromContext(enriched)).To(BeNil())
+			Expect(teamClient.numTeamCalls).To(Equal(0))
+		})
+
+		It("defaults to UTC when the settings have no location", func() {
+			e := enrich.New(teamClient, enrich.WithTeams(func(context.Context, *slack.TeamInfo) (*enrich.TeamSettings, error) {
+				return nil, nil
+			}))
+			t, err := e.Team(ctx, "T001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Location).To(Equal(time.UTC))
+		})
+
+		It("returns an error when the settings cannot be resolved and WithRequired is given", func() {
+			e := enrich.New(teamClient, enrich.WithRequired(), enrich.WithTeams(func(context.Context, *slack.TeamInfo) (*enrich.TeamSettings, error) {
+				return nil, errors.New("not configured")
+			}))
+			_, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("does not mistake the workspace of the client for other workspaces", func() {
+			e := enrich.New(teamClient, enrich.WithTeams(enrich.StaticTeamSettings("ja-JP", tokyo)))
+			_, err := e.Team(ctx, "T002")
+			Expect(err).To(HaveOccurred())
+			t, err := e.Team(ctx, "T001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Info.Name).To(Equal("example"))
+		})
+
+		It("resolves each workspace with its own client when WithTeamClients is given", func() {
+			other := &fakeTeamClient{fakeClient: client, team: &slack.TeamInfo{ID: "T002", Name: "another"}}
+			clients := map[string]*fakeTeamClient{"T001": teamClient, "T002": other}
+			e := enrich.New(client, enrich.WithTeams(enrich.StaticTeamSettings("ja-JP", tokyo)),
+				enrich.WithTeamClients(func(_ context.Context, teamID string) (enrich.TeamClient, error) {
+					c, ok := clients[teamID]
+					if !ok {
+						return nil, errors.New("not installed")
+					}
+					return c, nil
+				}))
+			t1, err := e.Team(ctx, "T001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t1.Info.Name).To(Equal("example"))
+			t2, err := e.Team(ctx, "T002")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t2.Info.Name).To(Equal("another"))
+			_, err = e.Team(ctx, "T001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(teamClient.numTeamCalls).To(Equal(1))
+			Expect(other.numTeamCalls).To(Equal(1))
+			_, err = e.Team(ctx, "T003")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("panics when the client cannot resolve teams", func() {
+			Expect(func() {
+				enrich.New(client, enrich.WithTeams(enrich.StaticTeamSettings("en-US", time.UTC)))
+			}).To(Panic())
+		})
+	})
+
+	Describe("ReactedMessage", func() {
+		var messageClient *fakeMessageClient
+		BeforeEach(func() {
+			messageClient = &fakeMessageClient{fakeClient: client, messages: map[string][]slack.Message{
+				"C001": {newMessage("1234.5678", "U001", "I ate an apple")},
+			}}
+		})
+
+		It("stores the reacted message in the context when WithReactedMessages is given", func() {
+			e := enrich.New(messageClient, enrich.WithReactedMessages())
+			enriched, err := e.Enrich(ctx, reactionEvent("C001", "1234.5678"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.ReactedMessageFromContext(enriched).Text).To(Equal("I ate an apple"))
+			_, err = e.Enrich(ctx, reactionEvent("C001", "1234.5678"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messageClient.numMessageCalls).To(Equal(1))
+		})
+
+		It("leaves the context as is when the message is not found", func() {
+			e := enrich.New(messageClient, enrich.WithReactedMessages())
+			enriched, err := e.Enrich(ctx, reactionEvent("C001", "9999.0000"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enrich.ReactedMessageFromContext(enriched)).To(BeNil())
+			_, err = e.Message(ctx, "C001", "9999.0000")
+			Expect(err).To(MatchError(enrich.ErrMessageNotFound))
+		})
+
+		It("returns an error when the API call fails and WithRequired is given", func() {
+			e := enrich.New(messageClient, enrich.WithReactedMessages(), enrich.WithRequired())
+			_, err := e.Enrich(ctx, reactionEvent("C002", "1234.5678"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("does not resolve messages of events other than reactions", func() {
+			e := enrich.New(messageClient, enrich.WithReactedMessages())
+			_, err := e.Enrich(ctx, messageEvent("C001", "U001"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messageClient.numMessageCalls).To(Equal(0))
+		})
+	})
+})
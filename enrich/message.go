@@ -0,0 +1,106 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// ErrMessageNotFound indicates that the message doesn't exist (e.g. it has already been deleted) or is not visible to the app.
+var ErrMessageNotFound = errors.New("message not found")
+
+// MessageClient is a subset of `slack.Client` that Enricher uses to resolve messages.
+type MessageClient interface {
+	GetConversationRepliesContext(ctx context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+}
+
+var _ MessageClient = &slack.Client{}
+
+// WithReactedMessages makes the Enricher also resolve messages that `reaction_*` events refer to via `conversations.replies`.
+//
+// `reaction_*` events only have the channel and the timestamp of reacted messages, so this is necessary to match on their texts or authors
+// (e.g. by `reaction.ResolvedMessageTextRegexp`).
+// This requires `channels:history` scope (and `groups:history`, `im:history` and `mpim:history` for other types of conversations),
+// and the client given to New must implement MessageClient.
+func WithReactedMessages() Option {
+	return optionFunc(func(e *Enricher) {
+		e.resolveMessages = true
+	})
+}
+
+type reactedMessageKey struct{}
+
+// ReactedMessageFromContext returns the message that the `reaction_*` event being processed refers to.
+//
+// This is only available in contexts enriched by Enricher with WithReactedMessages. Otherwise it returns nil.
+// It also returns nil if the message is not found or the reacted item is not a message (e.g. a file).
+func ReactedMessageFromContext(ctx context.Context) *slack.Message {
+	msg, _ := ctx.Value(reactedMessageKey{}).(*slack.Message)
+	return msg
+}
+
+// WithReactedMessage returns a new context that holds `msg`. This is mainly intended to test handlers that use ReactedMessageFromContext.
+func WithReactedMessage(ctx context.Context, msg *slack.Message) context.Context {
+	return context.WithValue(ctx, reactedMessageKey{}, msg)
+}
+
+// Message returns the message at `ts` in the conversation, using the cache if possible.
+//
+// Both messages in threads and those that are not can be resolved. It returns ErrMessageNotFound if there is no such message.
+func (e *Enricher) Message(ctx context.Context, channelID, ts string) (*slack.Message, error) {
+	key := channelID + "/" + ts
+	if v, ok := e.messages.Get(key); ok {
+		return v.(*slack.Message), nil
+	}
+	msgs, _, _, err := e.messageClient.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: ts,
+		Latest:    ts,
+		Oldest:    ts,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message %s in %s: %w", ts, channelID, err)
+	}
+	for i := range msgs {
+		if msgs[i].Timestamp == ts {
+			msg := &msgs[i]
+			e.messages.Add(key, msg)
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s in %s: %w", ts, channelID, ErrMessageNotFound)
+}
+
+// ReactedItem returns the item that the `reaction_*` event refers to, or nil if `ev` is not a `reaction_*` event.
+func ReactedItem(ev *slackevents.EventsAPIEvent) *slackevents.Item {
+	switch inner := ev.InnerEvent.Data.(type) {
+	case *slackevents.ReactionAddedEvent:
+		return &inner.Item
+	case *slackevents.ReactionRemovedEvent:
+		return &inner.Item
+	}
+	return nil
+}
+
+func (e *Enricher) enrichReactedMessage(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	item := ReactedItem(ev)
+	if item == nil || item.Type != "message" || item.Channel == "" || item.Timestamp == "" {
+		return ctx, nil
+	}
+	msg, err := e.Message(ctx, item.Channel, item.Timestamp)
+	if errors.Is(err, ErrMessageNotFound) {
+		return ctx, nil
+	}
+	if err != nil {
+		if err := e.lookupFailed(ctx, err); err != nil {
+			return nil, err
+		}
+		return ctx, nil
+	}
+	return WithReactedMessage(ctx, msg), nil
+}
@@ -0,0 +1,129 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// TeamClient is a subset of `slack.Client` that Enricher uses to resolve teams.
+type TeamClient interface {
+	GetTeamInfoContext(ctx context.Context) (*slack.TeamInfo, error)
+}
+
+var _ TeamClient = &slack.Client{}
+
+// TeamClientFunc returns a client that is authorized for the workspace `teamID` (e.g. from an `authctx.InstallationStore`).
+type TeamClientFunc func(ctx context.Context, teamID string) (TeamClient, error)
+
+// Team is the workspace where an event happened.
+type Team struct {
+	// Info is the result of `team.info`.
+	Info *slack.TeamInfo
+
+	// Locale is the locale of the workspace (e.g. "ja-JP"). This is empty if unknown.
+	Locale string
+
+	// Location is the time zone of the workspace. This is never nil; it defaults to UTC.
+	Location *time.Location
+}
+
+// TeamSettings are settings of a workspace that `team.info` doesn't provide.
+type TeamSettings struct {
+	Locale   string
+	Location *time.Location
+}
+
+// TeamSettingsFunc returns settings of the workspace described by `info`.
+//
+// Slack Web API doesn't expose the locale and the time zone of workspaces, so they must be supplied by applications
+// (e.g. from their own configurations or from the installing user).
+type TeamSettingsFunc func(ctx context.Context, info *slack.TeamInfo) (*TeamSettings, error)
+
+// StaticTeamSettings returns a TeamSettingsFunc that returns the same settings for all workspaces.
+func StaticTeamSettings(locale string, loc *time.Location) TeamSettingsFunc {
+	return func(context.Context, *slack.TeamInfo) (*TeamSettings, error) {
+		return &TeamSettings{Locale: locale, Location: loc}, nil
+	}
+}
+
+// WithTeams makes the Enricher also resolve the workspace where events happened via `team.info`, together with its settings given by `settings`.
+//
+// This requires `team:read` scope, and the client given to New must implement TeamClient unless WithTeamClients is given.
+// Since `team.info` only tells the workspace of the token, the client given to New can resolve only its own workspace;
+// multi-workspace apps should also give WithTeamClients.
+// Both results are cached in the same way as conversations, so `settings` is called at most once per TTL for each workspace.
+func WithTeams(settings TeamSettingsFunc) Option {
+	return optionFunc(func(e *Enricher) {
+		e.teamSettings = settings
+	})
+}
+
+// WithTeamClients makes the Enricher resolve workspaces with clients returned by `clients` instead of the client given to New.
+func WithTeamClients(clients TeamClientFunc) Option {
+	return optionFunc(func(e *Enricher) {
+		e.teamClients = clients
+	})
+}
+
+type teamKey struct{}
+
+// TeamFromContext returns the workspace where the event being processed happened.
+//
+// This is only available in contexts enriched by Enricher with WithTeams. Otherwise it returns nil.
+func TeamFromContext(ctx context.Context) *Team {
+	t, _ := ctx.Value(teamKey{}).(*Team)
+	return t
+}
+
+// WithTeam returns a new context that holds `t`. This is mainly intended to test handlers that use TeamFromContext.
+func WithTeam(ctx context.Context, t *Team) context.Context {
+	return context.WithValue(ctx, teamKey{}, t)
+}
+
+// Team returns information of the workspace, using the cache if possible.
+//
+// It returns an error if the client for `teamID` is authorized for another workspace.
+func (e *Enricher) Team(ctx context.Context, teamID string) (*Team, error) {
+	if v, ok := e.teams.Get(teamID); ok {
+		return v.(*Team), nil
+	}
+	client, err := e.teamClients(ctx, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a client for team %s: %w", teamID, err)
+	}
+	info, err := client.GetTeamInfoContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team %s: %w", teamID, err)
+	}
+	if info.ID != teamID {
+		return nil, fmt.Errorf("failed to get team %s: the client is authorized for team %s", teamID, info.ID)
+	}
+	settings, err := e.teamSettings(ctx, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings of team %s: %w", teamID, err)
+	}
+	t := &Team{Info: info, Location: time.UTC}
+	if settings != nil {
+		t.Locale = settings.Locale
+		if settings.Location != nil {
+			t.Location = settings.Location
+		}
+	}
+	e.teams.Add(teamID, t)
+	return t, nil
+}
+
+// TeamID returns the ID of the workspace where the event happened, or an empty string if unknown.
+func TeamID(ev *slackevents.EventsAPIEvent) string {
+	if ev.TeamID != "" {
+		return ev.TeamID
+	}
+	if cb, ok := ev.Data.(*slackevents.EventsAPICallbackEvent); ok {
+		return cb.TeamID
+	}
+	return ""
+}
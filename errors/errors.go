@@ -1,15 +1,24 @@
-// Package errors provides error values and types that are intended to be used to implement handlers.
+// Package errors provides error values and types that are intended to be used to implement handlers,
+// and ones that routers return when they are misconfigured.
 package errors
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 )
 
 // NotInterested indicates that the handler does not interested in the incoming events or actions.
 // When this error is returned from handlers, the processing event is falled back to another handler.
 var NotInterested = errors.New("not interested")
 
+// Mismatch indicates that the event was expected to be processed by the handler but didn't match its predicates (i.e. a misrouted event).
+// When this error is returned from handlers, the router reports the error without falling back to other handlers, and still acknowledges the event.
+//
+// Handlers usually don't return this directly. Use `FailOnMismatch` predicates instead.
+var Mismatch = errors.New("event did not match predicates")
+
 // HttpError represents errors that can be represented as http status codes.
 // When the router receives this error, the router responds with the corresponding status code.
 type HttpError int
@@ -19,3 +28,91 @@ func (e HttpError) Error() string {
 }
 
 var _ error = HttpError(0)
+
+// RetryLaterError indicates that the handler can't process the event for now and wants Slack to re-deliver it later.
+// When the router receives this error, the router responds with Service Unavailable and the `Retry-After` header.
+//
+// Note that Slack decides when to retry on its own, so `After` is merely a hint.
+type RetryLaterError struct {
+	After time.Duration
+}
+
+// RetryLater returns a RetryLaterError.
+func RetryLater(after time.Duration) error {
+	return &RetryLaterError{After: after}
+}
+
+func (e *RetryLaterError) Error() string {
+	return fmt.Sprintf("retry after %s", e.After)
+}
+
+var _ error = &RetryLaterError{}
+
+// NoRetryError indicates that Slack should not retry the event even though the handler failed.
+// When the router receives this error, the router responds with the `X-Slack-No-Retry: 1` header in addition to the status code determined by `Err`.
+type NoRetryError struct {
+	Err error
+}
+
+// NoRetry wraps `err` so that Slack does not retry the event.
+func NoRetry(err error) error {
+	return &NoRetryError{Err: err}
+}
+
+func (e *NoRetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NoRetryError) Unwrap() error {
+	return e.Err
+}
+
+var _ error = &NoRetryError{}
+
+type statusError struct {
+	err    error
+	status HttpError
+}
+
+// WithStatus returns an error that wraps `err` and makes the router respond with `status`.
+//
+// Unlike wrapping HttpError with `fmt.Errorf`, the message of the returned error is the same as `err`,
+// and `errors.Is` and `errors.As` still find errors wrapped by `err`. They also find `HttpError(status)`.
+func WithStatus(err error, status int) error {
+	return &statusError{err: err, status: HttpError(status)}
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func (e *statusError) Is(target error) bool {
+	status, ok := target.(HttpError)
+	return ok && status == e.status
+}
+
+func (e *statusError) As(target interface{}) bool {
+	if p, ok := target.(*HttpError); ok {
+		*p = e.status
+		return true
+	}
+	return false
+}
+
+// Errors returned by constructors of routers (e.g. `eventrouter.New`) when they are misconfigured.
+//
+// They are wrapped with messages that tell which options are wrong, so deployment tools should distinguish them by `errors.Is`.
+var (
+	// ErrMissingSigningSecret indicates that neither WithSigningSecret nor InsecureSkipVerification is given.
+	ErrMissingSigningSecret = errors.New("missing signing secret")
+
+	// ErrConflictingOptions indicates that options that can't be used together are given.
+	ErrConflictingOptions = errors.New("conflicting options")
+
+	// ErrInvalidOption indicates that an option is given an out-of-range value or lacks what it requires.
+	ErrInvalidOption = errors.New("invalid option")
+)
@@ -0,0 +1,171 @@
+// Package eventctx provides information about the message that triggered the event being processed, which is resolved lazily.
+//
+// Unlike the `enrich` package, nothing is resolved until handlers actually ask for it, so handlers that don't need it cost nothing:
+//
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(eventctx.New(slack.New(botToken))))
+//	r.OnReactionAdded(reaction.AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+//		link, err := eventctx.Permalink(ctx)
+//		...
+//	}), reaction.Name("rotating_light"))
+//
+// Results of API calls are cached in an LRU cache with TTL.
+package eventctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/internal/lrucache"
+)
+
+const (
+	// DefaultCacheSize is the default maximum number of cached entries.
+	DefaultCacheSize = 1000
+
+	// DefaultTTL is the default time to live of cached entries.
+	DefaultTTL = 10 * time.Minute
+)
+
+// ErrUnavailable indicates that the information is not available for the event being processed,
+// either because the event doesn't refer to a message or because the context is not enriched by Enricher.
+var ErrUnavailable = errors.New("not available for the event")
+
+// Client is a subset of `slack.Client` that Enricher uses.
+type Client interface {
+	GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (string, error)
+}
+
+var _ Client = &slack.Client{}
+
+// Option configures the Enricher.
+type Option interface {
+	apply(*Enricher)
+}
+
+type optionFunc func(*Enricher)
+
+func (f optionFunc) apply(e *Enricher) {
+	f(e)
+}
+
+// WithCacheSize sets the maximum number of cached entries.
+func WithCacheSize(size int) Option {
+	return optionFunc(func(e *Enricher) {
+		e.cacheSize = size
+	})
+}
+
+// WithTTL sets the time to live of cached entries.
+func WithTTL(ttl time.Duration) Option {
+	return optionFunc(func(e *Enricher) {
+		e.ttl = ttl
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(e *Enricher) {
+		e.now = now
+	})
+}
+
+// Enricher is an `eventrouter.Enricher` that makes the functions of this package available to handlers.
+type Enricher struct {
+	client     Client
+	cacheSize  int
+	ttl        time.Duration
+	now        func() time.Time
+	permalinks *lrucache.Cache
+}
+
+// New creates a new Enricher. Typically `client` is a `*slack.Client`.
+func New(client Client, opts ...Option) *Enricher {
+	e := &Enricher{
+		client:    client,
+		cacheSize: DefaultCacheSize,
+		ttl:       DefaultTTL,
+		now:       time.Now,
+	}
+	for _, o := range opts {
+		o.apply(e)
+	}
+	e.permalinks = lrucache.New(e.cacheSize, e.ttl, e.now)
+	return e
+}
+
+type messageKey struct{}
+
+type messageRef struct {
+	enricher *Enricher
+	channel  string
+	ts       string
+	link     string
+}
+
+// Enrich returns a new context that refers to the message that `ev` is about. It never calls Slack API by itself.
+func (e *Enricher) Enrich(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	channel, ts, ok := MessageOf(ev)
+	if !ok {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, messageKey{}, &messageRef{enricher: e, channel: channel, ts: ts}), nil
+}
+
+// Permalink returns the permalink of the message that triggered the event being processed.
+//
+// This is available for `message`, `app_mention` and `reaction_*` events (in the last case, the permalink of the reacted message).
+// It calls `chat.getPermalink` at the first time it is called for each message, and uses the cache afterwards.
+// It returns ErrUnavailable if the context is not enriched by Enricher or the event doesn't refer to a message.
+func Permalink(ctx context.Context) (string, error) {
+	ref, ok := ctx.Value(messageKey{}).(*messageRef)
+	if !ok {
+		return "", ErrUnavailable
+	}
+	if ref.enricher == nil {
+		return ref.link, nil
+	}
+	return ref.enricher.Permalink(ctx, ref.channel, ref.ts)
+}
+
+// WithPermalink returns a new context in which Permalink returns `link`. This is mainly intended to test handlers that use Permalink.
+func WithPermalink(ctx context.Context, link string) context.Context {
+	return context.WithValue(ctx, messageKey{}, &messageRef{link: link})
+}
+
+// Permalink returns the permalink of the message at `ts` in the conversation, using the cache if possible.
+func (e *Enricher) Permalink(ctx context.Context, channelID, ts string) (string, error) {
+	key := channelID + "/" + ts
+	if v, ok := e.permalinks.Get(key); ok {
+		return v.(string), nil
+	}
+	link, err := e.client.GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channelID, Ts: ts})
+	if err != nil {
+		return "", fmt.Errorf("failed to get permalink of %s in %s: %w", ts, channelID, err)
+	}
+	e.permalinks.Add(key, link)
+	return link, nil
+}
+
+// MessageOf returns the conversation and the timestamp of the message that `ev` is about, or false if there is no such message.
+func MessageOf(ev *slackevents.EventsAPIEvent) (channelID, ts string, ok bool) {
+	switch inner := ev.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		channelID, ts = inner.Channel, inner.TimeStamp
+	case *slackevents.AppMentionEvent:
+		channelID, ts = inner.Channel, inner.TimeStamp
+	case *slackevents.ReactionAddedEvent:
+		if inner.Item.Type == "message" {
+			channelID, ts = inner.Item.Channel, inner.Item.Timestamp
+		}
+	case *slackevents.ReactionRemovedEvent:
+		if inner.Item.Type == "message" {
+			channelID, ts = inner.Item.Channel, inner.Item.Timestamp
+		}
+	}
+	return channelID, ts, channelID != "" && ts != ""
+}
@@ -0,0 +1,13 @@
+package eventctx_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEventctx(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Eventctx Suite")
+}
@@ -0,0 +1,117 @@
+package eventctx_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/eventctx"
+)
+
+type fakeClient struct {
+	numCalls int
+}
+
+func (c *fakeClient) GetPermalinkContext(_ context.Context, params *slack.PermalinkParameters) (string, error) {
+	c.numCalls++
+	if params.Channel == "CUNKNOWN" {
+		return "", errors.New("channel_not_found")
+	}
+	return "https://example.slack.com/archives/" + params.Channel + "/p" + params.Ts, nil
+}
+
+func event(data interface{}) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Data: data}}
+}
+
+var _ = Describe("Eventctx", func() {
+	var (
+		ctx    = context.Background()
+		client *fakeClient
+		now    time.Time
+	)
+	BeforeEach(func() {
+		client = &fakeClient{}
+		now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	Describe("Permalink", func() {
+		It("resolves the permalink lazily", func() {
+			e := eventctx.New(client)
+			enriched, err := e.Enrich(ctx, event(&slackevents.MessageEvent{Channel: "C001", TimeStamp: "1234.5678"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(0))
+
+			link, err := eventctx.Permalink(enriched)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("https://example.slack.com/archives/C001/p1234.5678"))
+			_, err = eventctx.Permalink(enriched)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(1))
+		})
+
+		It("resolves the permalink of the reacted message", func() {
+			e := eventctx.New(client)
+			enriched, err := e.Enrich(ctx, event(&slackevents.ReactionAddedEvent{
+				Item: slackevents.Item{Type: "message", Channel: "C001", Timestamp: "1234.5678"},
+			}))
+			Expect(err).NotTo(HaveOccurred())
+			link, err := eventctx.Permalink(enriched)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("https://example.slack.com/archives/C001/p1234.5678"))
+		})
+
+		It("caches permalinks until they expire", func() {
+			e := eventctx.New(client, eventctx.WithTTL(time.Minute), eventctx.WithNowFunc(func() time.Time { return now }))
+			mention := event(&slackevents.AppMentionEvent{Channel: "C001", TimeStamp: "1234.5678"})
+			for i := 0; i < 2; i++ {
+				enriched, err := e.Enrich(ctx, mention)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = eventctx.Permalink(enriched)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(client.numCalls).To(Equal(1))
+
+			now = now.Add(time.Minute)
+			_, err := e.Permalink(ctx, "C001", "1234.5678")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.numCalls).To(Equal(2))
+		})
+
+		It("returns ErrUnavailable when the event doesn't refer to a message", func() {
+			e := eventctx.New(client)
+			enriched, err := e.Enrich(ctx, event(&slackevents.ReactionAddedEvent{
+				Item: slackevents.Item{Type: "file"},
+			}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = eventctx.Permalink(enriched)
+			Expect(err).To(MatchError(eventctx.ErrUnavailable))
+		})
+
+		It("returns ErrUnavailable when the context is not enriched", func() {
+			_, err := eventctx.Permalink(ctx)
+			Expect(err).To(MatchError(eventctx.ErrUnavailable))
+		})
+
+		It("returns an error when the API call fails", func() {
+			e := eventctx.New(client)
+			enriched, err := e.Enrich(ctx, event(&slackevents.MessageEvent{Channel: "CUNKNOWN", TimeStamp: "1234.5678"}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = eventctx.Permalink(enriched)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("WithPermalink", func() {
+		It("makes Permalink return the given link", func() {
+			link, err := eventctx.Permalink(eventctx.WithPermalink(ctx, "https://example.com"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("https://example.com"))
+		})
+	})
+})
@@ -2,25 +2,64 @@
 package eventrouter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"time"
 
-	"github.com/pkg/errors"
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/adminapps"
 	"github.com/genkami/go-slack-event-router/appmention"
 	"github.com/genkami/go-slack-event-router/appratelimited"
+	"github.com/genkami/go-slack-event-router/attempt"
+	"github.com/genkami/go-slack-event-router/bookmark"
 	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/filecomment"
+	"github.com/genkami/go-slack-event-router/huddle"
 	"github.com/genkami/go-slack-event-router/internal/routerutils"
 	"github.com/genkami/go-slack-event-router/message"
 	"github.com/genkami/go-slack-event-router/reaction"
-	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/routeinfo"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/routeroptions"
+	"github.com/genkami/go-slack-event-router/sharedchannel"
 	"github.com/genkami/go-slack-event-router/urlverification"
 )
 
+var (
+	// ErrBodyTooLarge indicates that the request body exceeds the limit set by WithMaxBodySize.
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	// ErrTruncatedBody indicates that the request body ends in the middle of a JSON value.
+	// This usually means that the body is truncated by proxies.
+	ErrTruncatedBody = errors.New("request body is truncated")
+
+	// ErrInvalidBody indicates that the request body is not a valid JSON value.
+	ErrInvalidBody = errors.New("request body is not a valid JSON")
+
+	// ErrUnknownEventType indicates that the type of the request (i.e. `type` in the outer event) is not supported.
+	ErrUnknownEventType = errors.New("unknown event type")
+
+	// ErrUnsupportedInMinimalMode is returned by New when an option that is not available in the minimal mode
+	// (i.e. when built with the `slackrouter_minimal` build tag) is given.
+	ErrUnsupportedInMinimalMode = errors.New("not supported in the minimal mode")
+
+	// ErrInnerEventTypeMismatch indicates that `InnerEvent.Data` of an event is not of the type that the handler expects.
+	// This happens when slack-go parses new variants of known events into different types.
+	//
+	// Handlers registered by On may return errors that wrap this to be treated in the same way as typed handlers like OnMessage.
+	// See FallbackToRawOnTypeMismatch for how the Router responds to such events.
+	ErrInnerEventTypeMismatch = errors.New("unexpected type of inner event")
+)
+
 // Handler is a handler that processes events from Slack.
 // Usually you don't need to use this directly. Instead, you might want to use event-specific handler types like `appmention.Handler`.
 //
@@ -28,6 +67,8 @@ import (
 //
 // Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
 //
+// Handlers can control whether Slack retries the event by returning `routererrors.RetryLater` or errors wrapped by `routererrors.NoRetry`.
+//
 // If any other errors are returned, the Router responds with Internal Server Error.
 type Handler interface {
 	HandleEventsAPIEvent(context.Context, *slackevents.EventsAPIEvent) error
@@ -39,6 +80,18 @@ func (f HandlerFunc) HandleEventsAPIEvent(ctx context.Context, e *slackevents.Ev
 	return f(ctx, e)
 }
 
+// HandlerProvider constructs Handlers lazily. See `router.HandlerProvider`.
+type HandlerProvider = router.HandlerProvider[Handler]
+
+type HandlerProviderFunc = router.HandlerProviderFunc[Handler]
+
+// FromProvider returns a Handler that obtains a Handler from `p` every time it processes an event and delegates the event to it.
+//
+// Errors returned from `p` are returned as is.
+func FromProvider(p HandlerProvider) Handler {
+	return HandlerFunc(router.FromProvider(p, Handler.HandleEventsAPIEvent).Handle)
+}
+
 // Option configures the Router.
 type Option interface {
 	apply(*Router)
@@ -50,11 +103,18 @@ func (f optionFunc) apply(r *Router) {
 	f(r)
 }
 
+// WithShared applies options shared by all routers. See the `routeroptions` package for details.
+func WithShared(opts ...routeroptions.Option) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(opts...)
+	})
+}
+
 // InsecureSkipVerification skips verifying request signatures.
 // This is useful to test your handlers, but do not use this in production environments.
 func InsecureSkipVerification() Option {
 	return optionFunc(func(r *Router) {
-		r.skipVerification = true
+		r.shared.Apply(routeroptions.InsecureSkipVerification())
 	})
 }
 
@@ -63,14 +123,304 @@ func InsecureSkipVerification() Option {
 // For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
 func WithSigningSecret(token string) Option {
 	return optionFunc(func(r *Router) {
-		r.signingSecret = token
+		r.shared.Apply(routeroptions.WithSigningSecret(token))
+	})
+}
+
+// WithPreviousSigningSecrets sets signing secrets that are accepted in addition to the one set by WithSigningSecret.
+//
+// This is useful to rotate signing secrets without downtime. Handlers can find which secret matched by `signature.VerificationFromContext`.
+func WithPreviousSigningSecrets(secrets ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithPreviousSigningSecrets(secrets...))
 	})
 }
 
 // If VerboseResponse is set, the Router shows error details when it fails to process requests.
 func VerboseResponse() Option {
 	return optionFunc(func(r *Router) {
-		r.verboseResponse = true
+		r.shared.Apply(routeroptions.VerboseResponse())
+	})
+}
+
+// WithRedaction makes the Router redact error details by `policy` before showing them (see VerboseResponse) or passing them to hooks.
+//
+// Errors given to the hooks set by OnError and OnBackgroundError are redacted as well. They still wrap the original errors,
+// so hooks can distinguish them by `errors.Is` as usual.
+func WithRedaction(policy *redact.Policy) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithRedaction(policy))
+	})
+}
+
+// WithRequestFilter sets a filter that is evaluated for every incoming request before its body is read.
+//
+// This can be used to enforce IP allowlists, mTLS header checks, and so on.
+// If the filter returns a non-nil error, the Router rejects the request without reading its body.
+// The response status is determined in the same way as errors returned from handlers,
+// i.e. `routererrors.HttpError` (or its equivalents in the sense of `errors.As`) results in the corresponding status code and any other errors result in Internal Server Error.
+func WithRequestFilter(f func(*http.Request) error) Option {
+	return optionFunc(func(r *Router) {
+		r.requestFilter = f
+	})
+}
+
+// AcceptHealthProbes makes the Router respond to GET and HEAD requests with 200 (OK), so that load balancers can probe the path of the Router.
+//
+// Regardless of this option, the Router responds to requests with other methods with 405 (Method Not Allowed).
+func AcceptHealthProbes() Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.AcceptHealthProbes())
+	})
+}
+
+// WithHTTPMiddleware adds middlewares that wrap the Router. The first middleware is the outermost one.
+//
+// Requests pass through layers in the following order:
+//
+//  1. the filter set by WithRequestFilter
+//  2. middlewares set by this option (bodies they read are already limited by WithMaxBodySize)
+//  3. responses to requests with methods other than POST (see AcceptHealthProbes)
+//  4. the batch endpoint set by WithBatchEndpoint
+//  5. BodyTransformers with BeforeVerification
+//  6. signature verification
+//  7. enrichers and handlers
+//
+// Middlewares may read request bodies without breaking verification. See `routeroptions.WithHTTPMiddleware` for details.
+func WithHTTPMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithHTTPMiddleware(mw...))
+	})
+}
+
+// DisableURLVerification makes the Router reject every `url_verification` event with Not Found.
+//
+// This is equivalent to calling `SetURLVerificationHandler(urlverification.DisabledHandler)`.
+func DisableURLVerification() Option {
+	return optionFunc(func(r *Router) {
+		r.urlVerificationHandler = urlverification.DisabledHandler
+	})
+}
+
+// WithURLVerificationResponder sets a Responder that writes responses to `url_verification` events.
+//
+// If not set, the Router uses `urlverification.JSONResponder`.
+func WithURLVerificationResponder(resp urlverification.Responder) Option {
+	return optionFunc(func(r *Router) {
+		r.urlVerificationResponder = resp
+	})
+}
+
+// PreserveUnknownFields makes the Router retain fields of inner events that are not known to slack-go.
+//
+// Handlers can access them by UnknownFields. This is useful to access new fields before slack-go supports them.
+func PreserveUnknownFields() Option {
+	return optionFunc(func(r *Router) {
+		r.preserveUnknownFields = true
+	})
+}
+
+// WithNowFunc sets a function that returns the current time.
+//
+// The Router uses it wherever it depends on the current time (e.g. checking whether request timestamps are too old).
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithNowFunc(now))
+	})
+}
+
+// WithMirror makes the Router asynchronously forward copies of verified requests to `url`.
+//
+// Only `percent` (0-100) percent of requests are forwarded. Original headers including signatures are preserved,
+// so the destination can verify requests with the same signing secret.
+// Responses from the destination are ignored. This is useful when migrating to another environment.
+func WithMirror(url string, percent float64) Option {
+	return optionFunc(func(r *Router) {
+		r.mirrorTarget = &mirrorTarget{url: url, percent: percent}
+	})
+}
+
+type mirrorTarget struct {
+	url     string
+	percent float64
+}
+
+// requestMirror forwards copies of requests. This is implemented only outside the minimal mode (see newMirror),
+// so that the minimal build doesn't depend on the HTTP client.
+type requestMirror interface {
+	Forward(req *http.Request, body []byte)
+}
+
+// WithUnmatchedStatus sets the HTTP status code that the Router responds with when no handler (including the fallback handler) processes an event.
+//
+// The default is 200 (OK). Setting other status codes is useful to find misconfigured subscriptions in staging environments,
+// but note that Slack retries events when the Router responds with non-2xx status codes.
+func WithUnmatchedStatus(code int) Option {
+	return optionFunc(func(r *Router) {
+		r.unmatchedStatus = code
+	})
+}
+
+// OnUnmatched sets a hook that is called when no handler (including the fallback handler) processes an event.
+//
+// This is useful to record metrics or logs of unmatched events.
+func OnUnmatched(hook func(context.Context, *slackevents.EventsAPIEvent)) Option {
+	return optionFunc(func(r *Router) {
+		r.unmatchedHook = hook
+	})
+}
+
+// StrictRegistration makes the Router panic when it detects registrations that are likely to be mistakes.
+//
+// This includes registering more than one handlers for the same event type with equal predicates (regardless of their order),
+// and calling SetFallback more than once. Note that handlers registered by On are not checked since they don't have predicates.
+func StrictRegistration() Option {
+	return optionFunc(func(r *Router) {
+		r.registry = routerutils.NewRegistry()
+	})
+}
+
+// AckBefore makes the Router respond with 200 (OK) when handlers don't finish within `d`, and let them finish in the background.
+//
+// Slack expects responses within 3 seconds, otherwise it retries the event. This is a middle ground between
+// processing events synchronously and processing all of them asynchronously: fast handlers can still respond with
+// their own status codes, while slow ones don't cause retries.
+//
+// When AckBefore is set, handlers are given a context that is not canceled even after the Router responds.
+// Errors returned from handlers after the Router responded are passed to the hook set by OnBackgroundError.
+func AckBefore(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.ackTimeout = d
+	})
+}
+
+// OnBackgroundError sets a hook that is called when a handler returns an error after the Router responded to the request due to AckBefore.
+//
+// `routererrors.Mismatch` is not passed to this hook but to the hook set by OnError, in the same way as when the Router waits for handlers.
+func OnBackgroundError(hook func(context.Context, *slackevents.EventsAPIEvent, error)) Option {
+	return optionFunc(func(r *Router) {
+		r.backgroundErrorHook = hook
+	})
+}
+
+// NoRetryOnClientErrors makes the Router set `X-Slack-No-Retry: 1` when handlers fail with 4xx status codes.
+//
+// Such errors are usually permanent, so retrying them is just a waste. Handlers can still explicitly control retries
+// by `routererrors.RetryLater` and `routererrors.NoRetry`.
+func NoRetryOnClientErrors() Option {
+	return optionFunc(func(r *Router) {
+		r.noRetryOnClientErrors = true
+	})
+}
+
+// WithMaxBodySize limits the size of request bodies to `n` bytes.
+//
+// The Router responds with Request Entity Too Large to requests whose bodies exceed the limit.
+// Requests whose Content-Length exceeds the limit are rejected before signature verification,
+// and signature verification stops reading other bodies once they exceed the limit.
+// The limit also applies to bodies read by middlewares set by WithHTTPMiddleware, and to bodies replaced by them.
+// If not set (or `n` is not positive), the size is not limited.
+func WithMaxBodySize(n int64) Option {
+	return optionFunc(func(r *Router) {
+		r.maxBodySize = n
+	})
+}
+
+// OnMalformedBody sets a hook that is called when the Router rejects a request because of its body.
+//
+// `body` is the raw request body (only the first WithMaxBodySize bytes if the body is too large,
+// or nil if a body without Content-Length turns out to be too large while it is read),
+// and `err` is (or wraps) one of ErrBodyTooLarge, ErrTruncatedBody or ErrInvalidBody.
+// This is useful to find proxies that truncate large payloads.
+func OnMalformedBody(hook func(ctx context.Context, body []byte, err error)) Option {
+	return optionFunc(func(r *Router) {
+		r.malformedBodyHook = hook
+	})
+}
+
+// OnError sets a hook that is called every time the Router responds with an error, including verification failures.
+//
+// Errors given to the hook wrap sentinel errors such as `signature.ErrBadSignature` and ErrUnknownEventType when applicable,
+// so the hook can distinguish reasons by `errors.Is`.
+// The hook is also called with `routererrors.Mismatch` when predicates marked by FailOnMismatch don't match, even though the Router responds with 200 (OK) in such case.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.OnError(hook))
+	})
+}
+
+// WithMaxEventAge makes the Router drop events whose `event_time` is older than `d`.
+//
+// Slack may deliver events minutes after they happened (e.g. when it retries), and acting on such stale events can be harmful.
+// Stale events are responded with 200 without calling any handlers unless OnStaleEvent is specified.
+func WithMaxEventAge(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.maxEventAge = d
+	})
+}
+
+// OnStaleEvent sets a handler that processes events considered to be stale by WithMaxEventAge instead of dropping them.
+func OnStaleEvent(h Handler) Option {
+	return optionFunc(func(r *Router) {
+		r.staleEventHandler = h
+	})
+}
+
+// Enricher adds information related to events to contexts before they are passed to handlers.
+//
+// See the `enrich` package for the default implementation.
+type Enricher interface {
+	Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error)
+}
+
+type EnricherFunc func(context.Context, *slackevents.EventsAPIEvent) (context.Context, error)
+
+func (f EnricherFunc) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	return f(ctx, e)
+}
+
+// WithEnricher adds an Enricher that is called before handlers and predicates are evaluated.
+//
+// If this option is specified more than once, enrichers are called in the order they are given.
+// If an enricher returns an error, the Router responds with the error without calling any handlers.
+func WithEnricher(e Enricher) Option {
+	return optionFunc(func(r *Router) {
+		r.enrichers = append(r.enrichers, e)
+	})
+}
+
+// BodyTransformer rewrites a request body before the Router parses it.
+//
+// `req` is the original request, which can be used to inspect headers. Its body must not be read.
+// If a BodyTransformer returns an error, the Router responds in the same way as when handlers return the error.
+// That is, it responds with the corresponding status if the error is (or wraps) `routererrors.HttpError`, and otherwise with Internal Server Error.
+type BodyTransformer func(body []byte, req *http.Request) ([]byte, error)
+
+// TransformStage specifies when a BodyTransformer runs.
+type TransformStage int
+
+const (
+	// AfterVerification makes a BodyTransformer run after the signature of the original body is verified.
+	AfterVerification TransformStage = iota
+
+	// BeforeVerification makes a BodyTransformer run before signature verification.
+	// The signature is then verified against the transformed body.
+	BeforeVerification
+)
+
+// WithBodyTransformer adds a BodyTransformer that runs at `stage`.
+//
+// This is useful when requests from Slack are wrapped by proxies (e.g. enveloped or encoded).
+// If more than one transformers are added to the same stage, they run in the order in which they are given.
+func WithBodyTransformer(stage TransformStage, t BodyTransformer) Option {
+	return optionFunc(func(r *Router) {
+		switch stage {
+		case BeforeVerification:
+			r.preVerificationTransformers = append(r.preVerificationTransformers, t)
+		default:
+			r.bodyTransformers = append(r.bodyTransformers, t)
+		}
 	})
 }
 
@@ -78,14 +428,42 @@ func VerboseResponse() Option {
 //
 // For more details, see https://api.slack.com/apis/connections/events-api.
 type Router struct {
-	signingSecret          string
-	skipVerification       bool
-	verboseResponse        bool
-	callbackHandlers       map[string][]Handler
-	urlVerificationHandler urlverification.Handler
-	appRateLimitedHandler  appratelimited.Handler
-	fallbackHandler        Handler
-	httpHandler            http.Handler
+	shared                      routeroptions.Config
+	requestFilter               func(*http.Request) error
+	preserveUnknownFields       bool
+	mirrorTarget                *mirrorTarget
+	mirror                      requestMirror
+	callbackHandlers            map[string][]namedHandler
+	urlVerificationHandler      urlverification.Handler
+	urlVerificationResponder    urlverification.Responder
+	appRateLimitedHandler       appratelimited.Handler
+	fallbackHandlers            []namedHandler
+	registry                    *routerutils.Registry
+	routes                      []routeinfo.Route
+	unmatchedStatus             int
+	unmatchedHook               func(context.Context, *slackevents.EventsAPIEvent)
+	noRetryOnClientErrors       bool
+	maxBodySize                 int64
+	malformedBodyHook           func(context.Context, []byte, error)
+	ackTimeout                  time.Duration
+	backgroundErrorHook         func(context.Context, *slackevents.EventsAPIEvent, error)
+	enrichers                   []Enricher
+	maxEventAge                 time.Duration
+	staleEventHandler           Handler
+	staleEventHandlerName       string
+	rawHandler                  Handler
+	rawHandlerName              string
+	rawTypes                    map[string]bool
+	rawOnTypeMismatch           bool
+	typeMismatchHook            func(context.Context, *slackevents.EventsAPIEvent, error)
+	bodyTransformers            []BodyTransformer
+	lifetimeAuditHook           func(context.Context, *LifetimeViolation)
+	batchPath                   string
+	batchAuthorizer             func(*http.Request) error
+	resultSink                  ResultSink
+	preflightCheckers           []namedChecker
+	preVerificationTransformers []BodyTransformer
+	httpHandler                 http.Handler
 }
 
 // New creates a new Router.
@@ -93,31 +471,76 @@ type Router struct {
 // At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
 func New(options ...Option) (*Router, error) {
 	r := &Router{
-		callbackHandlers:       make(map[string][]Handler),
-		urlVerificationHandler: urlverification.DefaultHandler,
-		appRateLimitedHandler:  appratelimited.DefaultHandler,
+		callbackHandlers:         make(map[string][]namedHandler),
+		rawTypes:                 make(map[string]bool),
+		urlVerificationHandler:   urlverification.DefaultHandler,
+		urlVerificationResponder: urlverification.JSONResponder,
+		appRateLimitedHandler:    appratelimited.DefaultHandler,
+		unmatchedStatus:          http.StatusOK,
+		shared:                   routeroptions.NewConfig(),
 	}
 	for _, o := range options {
 		o.apply(r)
 	}
-	if r.signingSecret == "" && !r.skipVerification {
-		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
+	if err := r.shared.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateOptions(r); err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), routererrors.ErrInvalidOption)
+	}
+	if err := checkMode(r); err != nil {
+		return nil, err
 	}
-	if r.signingSecret != "" && r.skipVerification {
-		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
+	if r.mirrorTarget != nil {
+		r.mirror = newMirror(r.mirrorTarget)
+	}
+	if r.staleEventHandler != nil {
+		r.staleEventHandlerName = r.handlerName(r.staleEventHandler)
 	}
 
-	r.httpHandler = http.HandlerFunc(r.serveHTTP)
-	if !r.skipVerification {
-		r.httpHandler = &signature.Middleware{
-			SigningSecret:   r.signingSecret,
-			VerboseResponse: r.verboseResponse,
-			Handler:         r.httpHandler,
-		}
+	r.httpHandler = r.shared.Verify(http.HandlerFunc(r.serveHTTP))
+	if len(r.preVerificationTransformers) > 0 {
+		r.httpHandler = r.transformBeforeVerification(r.httpHandler)
+	}
+	if r.batchPath != "" {
+		r.httpHandler = r.routeBatch(r.httpHandler)
+	}
+	if r.maxBodySize > 0 {
+		r.httpHandler = r.limitBody(r.httpHandler)
+	}
+	r.httpHandler = r.shared.CheckMethod(r.httpHandler)
+	r.httpHandler = r.shared.WrapHTTP(r.httpHandler)
+	if r.maxBodySize > 0 && len(r.shared.HTTPMiddlewares) > 0 {
+		// Middlewares may read whole bodies (which are also buffered to be replayed), so they are limited as well.
+		// The inner limit still applies to bodies replaced by middlewares (e.g. decompressed ones).
+		r.httpHandler = r.limitBody(r.httpHandler)
+	}
+	if r.requestFilter != nil {
+		r.httpHandler = r.filterRequest(r.httpHandler)
 	}
 	return r, nil
 }
 
+// validateOptions returns an error that tells which option is invalid, if any.
+func validateOptions(r *Router) error {
+	if r.batchPath != "" && r.batchAuthorizer == nil {
+		return errors.New("WithBatchEndpoint requires an authorizer")
+	}
+	if r.mirrorTarget != nil && (r.mirrorTarget.percent < 0 || 100 < r.mirrorTarget.percent) {
+		return fmt.Errorf("WithMirror: percent must be in [0, 100], but got %g", r.mirrorTarget.percent)
+	}
+	if http.StatusText(r.unmatchedStatus) == "" {
+		return fmt.Errorf("WithUnmatchedStatus: invalid status code %d", r.unmatchedStatus)
+	}
+	if r.ackTimeout < 0 {
+		return fmt.Errorf("AckBefore: negative timeout %s", r.ackTimeout)
+	}
+	if r.maxEventAge < 0 {
+		return fmt.Errorf("WithMaxEventAge: negative age %s", r.maxEventAge)
+	}
+	return nil
+}
+
 // On registers a handler for a specific event type.
 //
 // If more than one handlers are registered, the first ones take precedence.
@@ -131,14 +554,24 @@ func New(options ...Option) (*Router, error) {
 // This can be useful if you have a general-purpose event handlers that can process arbitrary types of events,
 // but, in the most cases it would be better option to use event-specfic `OnEVENT_NAME` methods instead.
 func (r *Router) On(eventType string, h Handler) {
+	name := r.addRoute(eventType, h, nil)
+	r.on(eventType, name, h)
+}
+
+func (r *Router) on(eventType, name string, h Handler) {
 	handlers, ok := r.callbackHandlers[eventType]
 	if !ok {
-		handlers = make([]Handler, 0)
+		handlers = make([]namedHandler, 0)
 	}
-	handlers = append(handlers, h)
+	handlers = append(handlers, namedHandler{name: name, handler: h})
 	r.callbackHandlers[eventType] = handlers
 }
 
+type namedHandler struct {
+	name    string
+	handler Handler
+}
+
 // OnMessage registers a handler that processes `message` events.
 //
 // If more than one handlers are registered, the first ones take precedence.
@@ -146,11 +579,14 @@ func (r *Router) On(eventType string, h Handler) {
 // Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
 // The handler `h` will be called only when all of given Predicates are true.
 func (r *Router) OnMessage(h message.Handler, preds ...message.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(slackevents.Message, ps)
+	name := r.addRoute(slackevents.Message, h, ps)
 	h = message.Build(h, preds...)
-	r.On(slackevents.Message, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	r.on(slackevents.Message, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
 		inner, ok := e.InnerEvent.Data.(*slackevents.MessageEvent)
 		if !ok {
-			return routererrors.HttpError(http.StatusBadRequest)
+			return typeMismatchError(e, inner)
 		}
 		return h.HandleMessageEvent(ctx, inner)
 	}))
@@ -163,11 +599,14 @@ func (r *Router) OnMessage(h message.Handler, preds ...message.Predicate) {
 // Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
 // The handler `h` will be called only when all of given Predicates are true.
 func (r *Router) OnAppMention(h appmention.Handler, preds ...appmention.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(slackevents.AppMention, ps)
+	name := r.addRoute(slackevents.AppMention, h, ps)
 	h = appmention.Build(h, preds...)
-	r.On(slackevents.AppMention, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	r.on(slackevents.AppMention, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
 		inner, ok := e.InnerEvent.Data.(*slackevents.AppMentionEvent)
 		if !ok {
-			return routererrors.HttpError(http.StatusBadRequest)
+			return typeMismatchError(e, inner)
 		}
 		return h.HandleAppMentionEvent(ctx, inner)
 	}))
@@ -180,11 +619,14 @@ func (r *Router) OnAppMention(h appmention.Handler, preds ...appmention.Predicat
 // Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
 // The handler `h` will be called only when all of given Predicates are true.
 func (r *Router) OnReactionAdded(h reaction.AddedHandler, preds ...reaction.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(slackevents.ReactionAdded, ps)
+	name := r.addRoute(slackevents.ReactionAdded, h, ps)
 	h = reaction.BuildAdded(h, preds...)
-	r.On(slackevents.ReactionAdded, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	r.on(slackevents.ReactionAdded, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
 		inner, ok := e.InnerEvent.Data.(*slackevents.ReactionAddedEvent)
 		if !ok {
-			return routererrors.HttpError(http.StatusBadRequest)
+			return typeMismatchError(e, inner)
 		}
 		return h.HandleReactionAddedEvent(ctx, inner)
 	}))
@@ -197,16 +639,212 @@ func (r *Router) OnReactionAdded(h reaction.AddedHandler, preds ...reaction.Pred
 // Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
 // The handler `h` will be called only when all of given Predicates are true.
 func (r *Router) OnReactionRemoved(h reaction.RemovedHandler, preds ...reaction.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(slackevents.ReactionRemoved, ps)
+	name := r.addRoute(slackevents.ReactionRemoved, h, ps)
 	h = reaction.BuildRemoved(h, preds...)
-	r.On(slackevents.ReactionRemoved, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	r.on(slackevents.ReactionRemoved, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
 		inner, ok := e.InnerEvent.Data.(*slackevents.ReactionRemovedEvent)
 		if !ok {
-			return routererrors.HttpError(http.StatusBadRequest)
+			return typeMismatchError(e, inner)
 		}
 		return h.HandleReactionRemovedEvent(ctx, inner)
 	}))
 }
 
+// OnAppRequested registers a handler that processes `app_requested` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnAppRequested(h adminapps.Handler, preds ...adminapps.Predicate) {
+	r.onAdminApps(adminapps.AppRequested, h, preds)
+}
+
+// OnAppApproved registers a handler that processes `app_approved` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnAppApproved(h adminapps.Handler, preds ...adminapps.Predicate) {
+	r.onAdminApps(adminapps.AppApproved, h, preds)
+}
+
+// OnAppRestricted registers a handler that processes `app_restricted` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnAppRestricted(h adminapps.Handler, preds ...adminapps.Predicate) {
+	r.onAdminApps(adminapps.AppRestricted, h, preds)
+}
+
+func (r *Router) onAdminApps(eventType string, h adminapps.Handler, preds []adminapps.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(eventType, ps)
+	name := r.addRoute(eventType, h, ps)
+	h = adminapps.Build(h, preds...)
+	r.onRaw(eventType, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner adminapps.Event
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleAppRequestEvent(ctx, &inner)
+	}))
+}
+
+// OnChannelShared registers a handler that processes `channel_shared` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnChannelShared(h sharedchannel.SharedHandler, preds ...sharedchannel.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(sharedchannel.ChannelShared, ps)
+	name := r.addRoute(sharedchannel.ChannelShared, h, ps)
+	h = sharedchannel.BuildShared(h, preds...)
+	r.onRaw(sharedchannel.ChannelShared, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner sharedchannel.SharedEvent
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleChannelSharedEvent(ctx, &inner)
+	}))
+}
+
+// OnChannelUnshared registers a handler that processes `channel_unshared` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnChannelUnshared(h sharedchannel.UnsharedHandler, preds ...sharedchannel.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(sharedchannel.ChannelUnshared, ps)
+	name := r.addRoute(sharedchannel.ChannelUnshared, h, ps)
+	h = sharedchannel.BuildUnshared(h, preds...)
+	r.onRaw(sharedchannel.ChannelUnshared, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner sharedchannel.UnsharedEvent
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleChannelUnsharedEvent(ctx, &inner)
+	}))
+}
+
+// OnBookmarkAdded registers a handler that processes `bookmark_added` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnBookmarkAdded(h bookmark.Handler, preds ...bookmark.Predicate) {
+	r.onBookmark(bookmark.BookmarkAdded, h, preds)
+}
+
+// OnBookmarkChanged registers a handler that processes `bookmark_changed` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnBookmarkChanged(h bookmark.Handler, preds ...bookmark.Predicate) {
+	r.onBookmark(bookmark.BookmarkChanged, h, preds)
+}
+
+// OnBookmarkRemoved registers a handler that processes `bookmark_removed` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnBookmarkRemoved(h bookmark.Handler, preds ...bookmark.Predicate) {
+	r.onBookmark(bookmark.BookmarkRemoved, h, preds)
+}
+
+func (r *Router) onBookmark(eventType string, h bookmark.Handler, preds []bookmark.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(eventType, ps)
+	name := r.addRoute(eventType, h, ps)
+	h = bookmark.Build(h, preds...)
+	r.onRaw(eventType, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner bookmark.Event
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleBookmarkEvent(ctx, &inner)
+	}))
+}
+
+// OnUserHuddleChanged registers a handler that processes `user_huddle_changed` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnUserHuddleChanged(h huddle.Handler, preds ...huddle.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(huddle.UserHuddleChanged, ps)
+	name := r.addRoute(huddle.UserHuddleChanged, h, ps)
+	h = huddle.Build(h, preds...)
+	r.onRaw(huddle.UserHuddleChanged, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner huddle.Event
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleUserHuddleChangedEvent(ctx, &inner)
+	}))
+}
+
+// OnFileCommentAdded registers a handler that processes `file_comment_added` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnFileCommentAdded(h filecomment.Handler, preds ...filecomment.Predicate) {
+	r.onFileComment(filecomment.FileCommentAdded, h, preds)
+}
+
+// OnFileCommentEdited registers a handler that processes `file_comment_edited` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnFileCommentEdited(h filecomment.Handler, preds ...filecomment.Predicate) {
+	r.onFileComment(filecomment.FileCommentEdited, h, preds)
+}
+
+// OnFileCommentDeleted registers a handler that processes `file_comment_deleted` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnFileCommentDeleted(h filecomment.Handler, preds ...filecomment.Predicate) {
+	r.onFileComment(filecomment.FileCommentDeleted, h, preds)
+}
+
+func (r *Router) onFileComment(eventType string, h filecomment.Handler, preds []filecomment.Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(eventType, ps)
+	name := r.addRoute(eventType, h, ps)
+	h = filecomment.Build(h, preds...)
+	// slack-go parses these events into types for the RTM API, so they are decoded again from JSON.
+	r.on(eventType, name, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		var inner filecomment.Event
+		if err := decodeInnerEvent(e, &inner); err != nil {
+			return err
+		}
+		return h.HandleFileCommentEvent(ctx, &inner)
+	}))
+}
+
 // SetURLVerificationHandler sets a handler to process `url_verification` events.
 //
 // If more than one handlers are registered, the last one will be used.
@@ -232,30 +870,137 @@ func (r *Router) SetAppRateLimitedHandler(h appratelimited.Handler) {
 // SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming event.
 //
 // If more than one handlers are registered, the last one will be used.
+// Note that this discards all fallback handlers registered so far, including ones registered by AddFallback.
+// If you want to register more than one fallback handlers, use AddFallback instead.
 func (r *Router) SetFallback(h Handler) {
-	r.fallbackHandler = h
+	r.checkDuplicate("SetFallback", nil)
+	r.fallbackHandlers = []namedHandler{{name: r.handlerName(h), handler: h}}
+}
+
+// AddFallback adds a fallback handler that is called when none of the registered handlers matches to a coming event.
+//
+// Fallback handlers form a chain in the order of registration. If a fallback handler returns `routererrors.NotInterested`
+// (or its equivalents in the sense of `errors.Is`), the Router falls back to the next one.
+func (r *Router) AddFallback(h Handler) {
+	r.fallbackHandlers = append(r.fallbackHandlers, namedHandler{name: r.handlerName(h), handler: h})
+}
+
+// RawBody returns the raw request body of the event that is being processed.
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RawBody(ctx context.Context) []byte {
+	return routerutils.RawBody(ctx)
+}
+
+// RequestHeader returns the HTTP headers of the event that is being processed (e.g. `X-Slack-Retry-Num`).
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RequestHeader(ctx context.Context) http.Header {
+	return routerutils.Header(ctx)
+}
+
+type unknownFieldsKey struct{}
+
+// UnknownFields returns fields of the inner event that are not known to slack-go (i.e. not mapped to any field of `InnerEvent.Data`).
+//
+// This is only available in the context given to handlers when PreserveUnknownFields is set. Otherwise it returns nil.
+func UnknownFields(ctx context.Context) map[string]json.RawMessage {
+	unknown, _ := ctx.Value(unknownFieldsKey{}).(map[string]json.RawMessage)
+	return unknown
+}
+
+// Routes returns all routes registered to the Router in the order of registration.
+//
+// Routes registered by On don't have predicates since the Router can't know them.
+func (r *Router) Routes() []routeinfo.Route {
+	routes := make([]routeinfo.Route, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+// addRoute records a route and returns the name of the handler.
+func (r *Router) addRoute(eventType string, h interface{}, preds []interface{}) string {
+	route := routerutils.NewRoute(eventType, h, preds)
+	r.routes = append(r.routes, route)
+	return route.Handler
+}
+
+func (r *Router) checkDuplicate(key string, preds []interface{}) {
+	if r.registry == nil {
+		return
+	}
+	if err := r.registry.Register(key, preds); err != nil {
+		panic(err)
+	}
 }
 
 func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	router.httpHandler.ServeHTTP(w, req)
 }
 
+func (r *Router) filterRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := r.requestFilter(req); err != nil {
+			r.respondWithError(req.Context(), w, err)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
 func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
+	body, err := router.readBody(req)
 	if err != nil {
-		router.respondWithError(w, err)
+		router.respondWithError(req.Context(), w, err)
+		return
+	}
+	body, err = transformBody(router.bodyTransformers, body, req)
+	if err != nil {
+		router.respondWithError(req.Context(), w, err)
+		return
+	}
+	if router.mirror != nil {
+		router.mirror.Forward(req, body)
+	}
+
+	if ev, ok := parseFormURLVerification(req, body); ok {
+		router.respondToURLVerification(req.Context(), w, ev)
 		return
 	}
 
 	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	isRaw := false
 	if err != nil {
-		router.respondWithError(
-			w,
-			errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), err.Error()))
-		return
+		if cause := classifyBody(body); cause != nil {
+			router.rejectMalformedBody(req.Context(), w, body, cause, http.StatusBadRequest)
+			return
+		}
+		raw, ok := router.parseRawEvent(body, err)
+		if !ok {
+			router.respondWithError(
+				req.Context(),
+				w,
+				fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(http.StatusBadRequest)))
+			return
+		}
+		eventsAPIEvent, isRaw = *raw, true
+	}
+	if eventsAPIEvent.Type == slackevents.CallbackEvent && eventsAPIEvent.EnterpriseID == "" {
+		eventsAPIEvent.EnterpriseID = enterpriseID(body)
 	}
 
-	ctx := req.Context()
+	ctx := routerutils.WithRequest(req.Context(), body, req.Header)
+	if router.preserveUnknownFields && eventsAPIEvent.Type == slackevents.CallbackEvent && !isRaw {
+		unknown, err := findUnknownFields(body, eventsAPIEvent.InnerEvent.Data)
+		if err != nil {
+			router.respondWithError(
+				ctx,
+				w,
+				fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(http.StatusBadRequest)))
+			return
+		}
+		ctx = context.WithValue(ctx, unknownFieldsKey{}, unknown)
+	}
 	switch eventsAPIEvent.Type {
 	case slackevents.URLVerification:
 		router.handleURLVerification(ctx, w, &eventsAPIEvent)
@@ -268,73 +1013,376 @@ func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		err := json.Unmarshal(body, &appRateLimited)
 		if err != nil {
 			router.respondWithError(
+				ctx,
 				w,
-				errors.WithMessage(err, "failed to parse app_rate_limited event"))
+				fmt.Errorf("failed to parse app_rate_limited event: %w", err))
 		}
 		router.handleAppRateLimited(ctx, w, &appRateLimited)
 	default:
 		router.respondWithError(
+			ctx,
 			w,
-			errors.WithMessagef(routererrors.HttpError(http.StatusBadRequest),
-				"unknown event type: %s", eventsAPIEvent.Type))
+			routererrors.WithStatus(
+				fmt.Errorf("%s: %w", eventsAPIEvent.Type, ErrUnknownEventType),
+				http.StatusBadRequest))
+	}
+}
+
+func (r *Router) transformBeforeVerification(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := r.readBody(req)
+		if err != nil {
+			r.respondWithError(req.Context(), w, err)
+			return
+		}
+		body, err = transformBody(r.preVerificationTransformers, body, req)
+		if err != nil {
+			r.respondWithError(req.Context(), w, err)
+			return
+		}
+		req.Body = routerutils.NewMemoryBody(body)
+		req.ContentLength = int64(len(body))
+		next.ServeHTTP(w, req)
+	})
+}
+
+func transformBody(transformers []BodyTransformer, body []byte, req *http.Request) ([]byte, error) {
+	for _, t := range transformers {
+		var err error
+		body, err = t(body, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform body: %w", err)
+		}
 	}
+	return body, nil
+}
+
+// limitBody rejects requests whose Content-Length exceeds WithMaxBodySize before their bodies are read,
+// and limits the bodies of other requests so that signature verification doesn't read more than that.
+func (r *Router) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ContentLength > r.maxBodySize {
+			prefix, _ := io.ReadAll(io.LimitReader(req.Body, r.maxBodySize))
+			err := fmt.Errorf("Content-Length %d exceeds %d bytes: %w", req.ContentLength, r.maxBodySize, ErrBodyTooLarge)
+			r.rejectMalformedBody(req.Context(), w, prefix, err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		ctx := req.Context()
+		req.Body = &limitedBody{ReadCloser: req.Body, remaining: r.maxBodySize, onExceeded: func(err error) {
+			if r.malformedBodyHook != nil {
+				r.malformedBodyHook(ctx, nil, err)
+			}
+		}}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// limitedBody is a request body that fails with ErrBodyTooLarge (and 413) once more than `remaining` bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining  int64
+	err        error
+	onExceeded func(err error)
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.err = routererrors.WithStatus(fmt.Errorf("exceeds the limit: %w", ErrBodyTooLarge), http.StatusRequestEntityTooLarge)
+		b.onExceeded(b.err)
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// readBody reads the request body. It doesn't copy the body if it has already been read into memory by signature verification.
+//
+// Bodies are limited by limitBody, so this returns an error that wraps ErrBodyTooLarge if the body exceeds WithMaxBodySize.
+func (r *Router) readBody(req *http.Request) ([]byte, error) {
+	return routerutils.ReadBody(req.Body, req.ContentLength)
+}
+
+// enterpriseID returns `enterprise_id` of the envelope. slack-go drops it when it parses callback events.
+func enterpriseID(body []byte) string {
+	var envelope struct {
+		EnterpriseID string `json:"enterprise_id"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	return envelope.EnterpriseID
+}
+
+// classifyBody returns ErrTruncatedBody or ErrInvalidBody if the body is not a valid JSON value.
+func classifyBody(body []byte) error {
+	var v json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(body)).Decode(&v)
+	if err == io.EOF {
+		return fmt.Errorf("empty body: %w", ErrInvalidBody)
+	}
+	if err == io.ErrUnexpectedEOF {
+		return ErrTruncatedBody
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", err.Error(), ErrInvalidBody)
+	}
+	return nil
+}
+
+func (r *Router) rejectMalformedBody(ctx context.Context, w http.ResponseWriter, body []byte, err error, status int) {
+	if r.malformedBodyHook != nil {
+		r.malformedBodyHook(ctx, body, err)
+	}
+	r.respondWithError(ctx, w, fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(status)))
 }
 
 func (r *Router) handleURLVerification(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIEvent) {
 	ev, ok := e.Data.(*slackevents.EventsAPIURLVerificationEvent)
 	if !ok {
-		r.respondWithError(w, fmt.Errorf("expected EventsAPIURLVerificationEvent but got %T", e.Data))
+		r.respondWithError(ctx, w, fmt.Errorf("expected EventsAPIURLVerificationEvent but got %T", e.Data))
 		return
 	}
+	r.respondToURLVerification(ctx, w, ev)
+}
+
+func (r *Router) respondToURLVerification(ctx context.Context, w http.ResponseWriter, ev *slackevents.EventsAPIURLVerificationEvent) {
 	resp, err := r.urlVerificationHandler.HandleURLVerification(ctx, ev)
 	if err != nil {
-		r.respondWithError(w, err)
+		r.respondWithError(ctx, w, err)
 		return
 	}
-	w.Header().Add("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
-	_ = enc.Encode(resp)
+	_ = r.urlVerificationResponder.RespondURLVerification(w, resp)
+}
+
+// parseFormURLVerification parses a `url_verification` event that is delivered as form-encoded parameters instead of JSON.
+// Some proxies re-encode the body in this way, or even move the parameters to the query string.
+func parseFormURLVerification(req *http.Request, body []byte) (*slackevents.EventsAPIURLVerificationEvent, bool) {
+	var values url.Values
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err == nil && mediaType == "application/x-www-form-urlencoded" {
+		values, err = url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+	} else if len(bytes.TrimSpace(body)) == 0 {
+		values = req.URL.Query()
+	} else {
+		return nil, false
+	}
+	if values.Get("type") != slackevents.URLVerification || values.Get("challenge") == "" {
+		return nil, false
+	}
+	return &slackevents.EventsAPIURLVerificationEvent{
+		Token:     values.Get("token"),
+		Challenge: values.Get("challenge"),
+		Type:      slackevents.URLVerification,
+	}, true
 }
 
 func (r *Router) handleCallbackEvent(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIEvent) {
-	var err error = routererrors.NotInterested
-	handlers, ok := r.callbackHandlers[e.InnerEvent.Type]
-	if ok {
-		for _, h := range handlers {
-			err = h.HandleEventsAPIEvent(ctx, e)
-			if !errors.Is(err, routererrors.NotInterested) {
-				break
-			}
+	if cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent); ok && cb.EventID != "" {
+		ctx = attempt.NewContext(ctx, attempt.New(cb.EventID, routerutils.Header(ctx)))
+	}
+	if r.lifetimeAuditHook != nil {
+		var state *lifetimeState
+		ctx, state = withLifetimeState(ctx, r.lifetimeAuditHook, e)
+		if r.ackTimeout <= 0 {
+			// In the asynchronous mode, handlers are allowed to outlive requests.
+			defer state.markAcked()
+		}
+	}
+	var err error
+	if r.ackTimeout > 0 {
+		var acked bool
+		acked, err = r.dispatchBefore(ctx, e, r.ackTimeout)
+		if acked {
+			w.WriteHeader(http.StatusOK)
+			return
 		}
+	} else {
+		err = r.dispatch(ctx, e)
 	}
 
 	if errors.Is(err, routererrors.NotInterested) {
-		err = r.handleFallback(ctx, e)
+		r.handleUnmatched(ctx, w, e)
+		return
 	}
 
-	if err != nil && !errors.Is(err, routererrors.NotInterested) {
-		r.respondWithError(w, err)
+	if errors.Is(err, routererrors.Mismatch) {
+		// Misrouted events are reported, but retrying them doesn't help.
+		r.shared.ReportError(ctx, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err != nil {
+		if r.noRetryOnClientErrors {
+			err = markNoRetryIfClientError(err)
+		}
+		r.respondWithError(ctx, w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+func markNoRetryIfClientError(err error) error {
+	var retryErr *routererrors.RetryLaterError
+	if errors.As(err, &retryErr) {
+		return err
+	}
+	status := routerutils.StatusCode(err)
+	if 400 <= status && status < 500 {
+		return routererrors.NoRetry(err)
+	}
+	return err
+}
+
+func (r *Router) dispatch(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	if r.resultSink == nil {
+		_, err := r.dispatchHandlers(ctx, e)
+		return err
+	}
+	start := r.shared.Now()
+	name, err := r.dispatchHandlers(ctx, e)
+	result := &Result{
+		EventType: e.InnerEvent.Type,
+		Handler:   name,
+		Duration:  r.shared.Now().Sub(start),
+		Err:       err,
+	}
+	if cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent); ok {
+		result.EventID = cb.EventID
+	}
+	result.Attempt, _ = attempt.FromContext(ctx)
+	r.resultSink.Record(ctx, result)
+	return err
+}
+
+// dispatchHandlers calls handlers and returns the name of the handler that processed the event, if any.
+func (r *Router) dispatchHandlers(ctx context.Context, e *slackevents.EventsAPIEvent) (string, error) {
+	if r.isStale(e) {
+		if r.staleEventHandler == nil {
+			return "", nil
+		}
+		return r.staleEventHandlerName, r.staleEventHandler.HandleEventsAPIEvent(ctx, e)
+	}
+
+	for _, enricher := range r.enrichers {
+		var err error
+		ctx, err = enricher.Enrich(ctx, e)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, ok := e.InnerEvent.Data.(*RawInnerEvent); ok && r.rawHandler != nil && !r.rawTypes[e.InnerEvent.Type] {
+		return r.rawHandlerName, r.rawHandler.HandleEventsAPIEvent(ctx, e)
+	}
+
+	for _, h := range r.callbackHandlers[e.InnerEvent.Type] {
+		err := h.handler.HandleEventsAPIEvent(ctx, e)
+		if errors.Is(err, ErrInnerEventTypeMismatch) {
+			return r.handleTypeMismatch(ctx, e, h.name, err)
+		}
+		if !errors.Is(err, routererrors.NotInterested) {
+			return h.name, err
+		}
+	}
+	return r.handleFallback(ctx, e)
+}
+
+// handlerName returns the name of `h` only if it is needed, since it may be expensive.
+// Names are computed when handlers are registered, so that the Router doesn't compute them for every event.
+func (r *Router) handlerName(h Handler) string {
+	if r.resultSink == nil {
+		return ""
+	}
+	return routerutils.HandlerName(h)
+}
+
+func (r *Router) isStale(e *slackevents.EventsAPIEvent) bool {
+	if r.maxEventAge <= 0 {
+		return false
+	}
+	cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent)
+	if !ok || cb.EventTime == 0 {
+		return false
+	}
+	return r.shared.Now().Sub(time.Unix(int64(cb.EventTime), 0)) > r.maxEventAge
+}
+
+// dispatchBefore calls handlers in the background and waits for them at most `timeout`.
+// If the handlers don't finish in time, it returns true and leaves the result to handleBackgroundResult.
+func (r *Router) dispatchBefore(ctx context.Context, e *slackevents.EventsAPIEvent, timeout time.Duration) (bool, error) {
+	ctx = routerutils.Detach(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.dispatch(ctx, e)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return false, err
+	case <-timer.C:
+		go func() {
+			r.handleBackgroundResult(ctx, e, <-done)
+		}()
+		return true, nil
+	}
+}
+
+func (r *Router) handleBackgroundResult(ctx context.Context, e *slackevents.EventsAPIEvent, err error) {
+	if errors.Is(err, routererrors.NotInterested) {
+		if r.unmatchedHook != nil {
+			r.unmatchedHook(ctx, e)
+		}
+		return
+	}
+	if errors.Is(err, routererrors.Mismatch) {
+		// Reported in the same way as when the Router waits for handlers.
+		r.shared.ReportError(ctx, err)
+		return
+	}
+	if err != nil && r.backgroundErrorHook != nil {
+		r.backgroundErrorHook(ctx, e, r.shared.Redaction.Error(err))
+	}
+}
+
+func (r *Router) handleUnmatched(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIEvent) {
+	if r.unmatchedHook != nil {
+		r.unmatchedHook(ctx, e)
+	}
+	w.WriteHeader(r.unmatchedStatus)
+}
+
 func (r *Router) handleAppRateLimited(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIAppRateLimited) {
 	err := r.appRateLimitedHandler.HandleAppRateLimited(ctx, e)
 	if err != nil {
-		r.respondWithError(w, err)
+		r.respondWithError(ctx, w, err)
 		return
 	}
 	_, _ = w.Write([]byte("OK"))
 }
 
-func (r *Router) handleFallback(ctx context.Context, e *slackevents.EventsAPIEvent) error {
-	if r.fallbackHandler == nil {
-		return routererrors.NotInterested
+func (r *Router) handleFallback(ctx context.Context, e *slackevents.EventsAPIEvent) (string, error) {
+	for _, h := range r.fallbackHandlers {
+		err := h.handler.HandleEventsAPIEvent(ctx, e)
+		if !errors.Is(err, routererrors.NotInterested) {
+			return h.name, err
+		}
 	}
-	return r.fallbackHandler.HandleEventsAPIEvent(ctx, e)
+	return "", routererrors.NotInterested
 }
 
-func (r *Router) respondWithError(w http.ResponseWriter, err error) {
-	routerutils.RespondWithError(w, err, r.verboseResponse)
+func (r *Router) respondWithError(ctx context.Context, w http.ResponseWriter, err error) {
+	r.shared.RespondWithError(ctx, w, err)
 }
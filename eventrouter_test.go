@@ -3,21 +3,37 @@ package eventrouter_test
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/pkg/errors"
 	"github.com/slack-go/slack/slackevents"
 
 	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/adminapps"
+	"github.com/genkami/go-slack-event-router/attempt"
+	"github.com/genkami/go-slack-event-router/bookmark"
+	"github.com/genkami/go-slack-event-router/bridge"
 	routererrors "github.com/genkami/go-slack-event-router/errors"
-	"github.com/genkami/go-slack-event-router/internal/testutils"
+	"github.com/genkami/go-slack-event-router/filecomment"
+	"github.com/genkami/go-slack-event-router/huddle"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/routeroptions"
+	"github.com/genkami/go-slack-event-router/sharedchannel"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+	"github.com/genkami/go-slack-event-router/urlverification"
 )
 
 var _ = Describe("EventRouter", func() {
@@ -26,6 +42,7 @@ var _ = Describe("EventRouter", func() {
 			It("returns an error", func() {
 				_, err := eventrouter.New()
 				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+				Expect(errors.Is(err, routererrors.ErrMissingSigningSecret)).To(BeTrue())
 			})
 		})
 
@@ -49,172 +66,2681 @@ var _ = Describe("EventRouter", func() {
 			It("returns an error", func() {
 				_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithSigningSecret("THE_TOKEN"))
 				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+				Expect(errors.Is(err, routererrors.ErrConflictingOptions)).To(BeTrue())
 			})
 		})
+
+		Context("when both WithPreviousSigningSecrets and InsecureSkipVerification are given", func() {
+			It("returns an error", func() {
+				_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithPreviousSigningSecrets("THE_OLD_TOKEN"))
+				Expect(errors.Is(err, routererrors.ErrConflictingOptions)).To(BeTrue())
+			})
+		})
+
+		Context("when options are given invalid values", func() {
+			It("returns an error", func() {
+				for _, o := range []eventrouter.Option{
+					eventrouter.WithBatchEndpoint("/batch", nil),
+					eventrouter.WithMirror("http://example.com", 120),
+					eventrouter.WithUnmatchedStatus(999),
+					eventrouter.AckBefore(-time.Second),
+					eventrouter.WithMaxEventAge(-time.Minute),
+				} {
+					_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), o)
+					Expect(errors.Is(err, routererrors.ErrInvalidOption)).To(BeTrue(), "%v", err)
+				}
+			})
+		})
+	})
+
+	Describe("WithSigningSecret", func() {
+		var (
+			r       *eventrouter.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is valid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with Unauthorized", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.Signature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("when the timestamp is too old", func() {
+			It("responds with BadRequest", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithNowFunc", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `{"type": "url_verification", "challenge": "hello"}`
+			ts      = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		)
+
+		Context("when the timestamp is close to the time returned by the function", func() {
+			It("responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithNowFunc(func() time.Time { return ts.Add(1 * time.Minute) }))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is too old compared to the time returned by the function", func() {
+			It("responds with BadRequest", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithNowFunc(func() time.Time { return ts.Add(1 * time.Hour) }))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithPreviousSigningSecrets", func() {
+		It("accepts requests signed with previous secrets", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret("NEW_TOKEN"), eventrouter.WithPreviousSigningSecrets("OLD_TOKEN"))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := NewSignedRequest("OLD_TOKEN", `{"type": "url_verification", "challenge": "xxx"}`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("InsecureSkipVerification", func() {
+		var (
+			r       *eventrouter.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is valid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.Signature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is too old", func() {
+			It("responds with 200", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("WithRequestFilter", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+			filterError error
+			numCalled   int
+			filter      = func(_ *http.Request) error {
+				numCalled++
+				return filterError
+			}
+		)
+		BeforeEach(func() {
+			filterError = nil
+			numCalled = 0
+		})
+
+		Context("when the filter returns nil", func() {
+			It("processes the request", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithRequestFilter(filter))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the filter returns an HttpError", func() {
+			It("responds with a corresponding status code", func() {
+				filterError = fmt.Errorf("forbidden IP: %w", routererrors.HttpError(http.StatusForbidden))
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithRequestFilter(filter))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the filter returns an error", func() {
+			It("responds with InternalServerError", func() {
+				filterError = errors.New("something wrong happened")
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithRequestFilter(filter))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("when the filter rejects the request", func() {
+			It("does not verify the signature", func() {
+				filterError = routererrors.HttpError(http.StatusForbidden)
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithRequestFilter(filter))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.Signature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("URL Verification", func() {
+		var (
+			r *eventrouter.Router
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the given challenge in JSON", func() {
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "THE_SECRET_CHALLENGE_VALUE",
+				"type": "url_verification"
+			}
+			`)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			dec := json.NewDecoder(resp.Body)
+			body := slackevents.ChallengeResponse{}
+			err = dec.Decode(&body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+		})
+
+		Context("when the challenge is sent as form-encoded parameters", func() {
+			It("returns the given challenge in JSON", func() {
+				form := url.Values{}
+				form.Set("token", "Jhj5dZrVaK7ZwHHjRyZWjbDl")
+				form.Set("challenge", "THE_SECRET_CHALLENGE_VALUE")
+				form.Set("type", "url_verification")
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(form.Encode())))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				dec := json.NewDecoder(resp.Body)
+				body := slackevents.ChallengeResponse{}
+				err = dec.Decode(&body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+			})
+		})
+
+		Context("when the challenge is sent in the query string", func() {
+			It("returns the given challenge in JSON", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path?type=url_verification&challenge=THE_SECRET_CHALLENGE_VALUE", bytes.NewReader(nil))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				dec := json.NewDecoder(resp.Body)
+				body := slackevents.ChallengeResponse{}
+				err = dec.Decode(&body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+			})
+		})
+
+		Context("when the form-encoded parameters are not url_verification", func() {
+			It("responds with BadRequest", func() {
+				form := url.Values{}
+				form.Set("type", "event_callback")
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(form.Encode())))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithURLVerificationResponder", func() {
+		It("responds using the given responder", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+				eventrouter.WithURLVerificationResponder(urlverification.PlainTextResponder))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "THE_SECRET_CHALLENGE_VALUE",
+				"type": "url_verification"
+			}
+			`)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain"))
+			Expect(w.Body.String()).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+		})
+	})
+
+	Describe("DisableURLVerification", func() {
+		It("responds with NotFound", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.DisableURLVerification())
+			Expect(err).NotTo(HaveOccurred())
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "THE_SECRET_CHALLENGE_VALUE",
+				"type": "url_verification"
+			}
+			`)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("App Rate Limited", func() {
+		var (
+			r *eventrouter.Router
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("responds with 200", func() {
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"type": "app_rate_limited",
+				"team_id": "T123456",
+				"minute_rate_limited": 1518467820,
+				"api_app_id": "A123456"
+			}
+			`)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("WithMirror", func() {
+		var (
+			token    = "THE_TOKEN"
+			content  = `{"type": "url_verification", "challenge": "hello"}`
+			server   *httptest.Server
+			received chan http.Header
+		)
+		BeforeEach(func() {
+			if eventrouter.MinimalMode {
+				Skip("WithMirror is not available in the minimal mode")
+			}
+			received = make(chan http.Header, 1)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				received <- req.Header
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when percent is 100", func() {
+			It("forwards a copy of the request with its signature", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithMirror(server.URL, 100))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				var header http.Header
+				Eventually(received).Should(Receive(&header))
+				Expect(header.Get(slackheaders.Signature)).To(Equal(req.Header.Get(slackheaders.Signature)))
+			})
+		})
+
+		Context("when percent is 0", func() {
+			It("does not forward the request", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithMirror(server.URL, 0))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("does not forward the request", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithMirror(server.URL, 100))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest("WRONG_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+			})
+		})
+	})
+
+	Describe("PreserveUnknownFields", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005",
+					"brand_new_field": {"foo": "bar"}
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			unknown map[string]json.RawMessage
+			handler = eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+				unknown = eventrouter.UnknownFields(ctx)
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			unknown = nil
+		})
+
+		Context("when PreserveUnknownFields is set", func() {
+			It("provides unknown fields of the inner event", func() {
+				if eventrouter.MinimalMode {
+					Skip("PreserveUnknownFields is not available in the minimal mode")
+				}
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.PreserveUnknownFields())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(unknown).To(HaveLen(1))
+				Expect(unknown["brand_new_field"]).To(MatchJSON(`{"foo": "bar"}`))
+			})
+		})
+
+		Context("when PreserveUnknownFields is not set", func() {
+			It("does not provide unknown fields", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(unknown).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Enterprise ID", func() {
+		It("passes enterprise_id of the envelope to handlers", func() {
+			content := `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"enterprise_id": "EXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			var enterpriseID string
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, e *slackevents.EventsAPIEvent) error {
+				enterpriseID = e.EnterpriseID
+				return nil
+			}))
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(enterpriseID).To(Equal("EXXXXXXXX"))
+		})
+	})
+
+	Describe("Unmatched events", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			numHookCalled int
+			hook          = func(_ context.Context, _ *slackevents.EventsAPIEvent) {
+				numHookCalled++
+			}
+		)
+		BeforeEach(func() {
+			numHookCalled = 0
+		})
+
+		Context("when no handler processes the event", func() {
+			It("calls the hook and responds with the given status", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithUnmatchedStatus(http.StatusInternalServerError), eventrouter.OnUnmatched(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.NotInterested
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(numHookCalled).To(Equal(1))
+			})
+		})
+
+		Context("when a handler processes the event", func() {
+			It("does not call the hook and responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithUnmatchedStatus(http.StatusInternalServerError), eventrouter.OnUnmatched(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHookCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the fallback handler processes the event", func() {
+			It("does not call the hook and responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithUnmatchedStatus(http.StatusInternalServerError), eventrouter.OnUnmatched(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.SetFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHookCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("AckBefore", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			errCh chan error
+			hook  = func(_ context.Context, _ *slackevents.EventsAPIEvent, err error) {
+				errCh <- err
+			}
+		)
+		BeforeEach(func() {
+			errCh = make(chan error, 1)
+		})
+
+		Context("when the handler finishes in time", func() {
+			It("responds with the result of the handler", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.AckBefore(time.Second), eventrouter.OnBackgroundError(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusTeapot))
+				Consistently(errCh).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the handler does not finish in time", func() {
+			It("responds with 200 and lets the handler finish in the background", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.AckBefore(10*time.Millisecond), eventrouter.OnBackgroundError(hook))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+					<-release
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				ctx, cancel := context.WithCancel(context.Background())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				cancel()
+				close(release)
+				Eventually(errCh).Should(Receive(MatchError(routererrors.HttpError(http.StatusTeapot))))
+			})
+		})
+	})
+
+	Describe("Retry behavior", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			r          *eventrouter.Router
+			handlerErr error
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				return handlerErr
+			}))
+		})
+
+		Context("when the handler returns RetryLater", func() {
+			It("responds with 503 and Retry-After", func() {
+				handlerErr = fmt.Errorf("database is busy: %w", routererrors.RetryLater(1500*time.Millisecond))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(resp.Header.Get("Retry-After")).To(Equal("2"))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal(""))
+			})
+		})
+
+		Context("when the handler returns an error wrapped by NoRetry", func() {
+			It("responds with the status code of the error and X-Slack-No-Retry", func() {
+				handlerErr = routererrors.NoRetry(routererrors.HttpError(http.StatusBadRequest))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal("1"))
+			})
+		})
+
+		Context("when the handler returns other errors", func() {
+			It("does not set X-Slack-No-Retry", func() {
+				handlerErr = errors.New("oops")
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal(""))
+			})
+		})
+	})
+
+	Describe("NoRetryOnClientErrors", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			r          *eventrouter.Router
+			handlerErr error
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.NoRetryOnClientErrors())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				return handlerErr
+			}))
+		})
+
+		serve := func() *http.Response {
+			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result()
+		}
+
+		Context("when the handler returns a 4xx error", func() {
+			It("sets X-Slack-No-Retry", func() {
+				handlerErr = fmt.Errorf("forbidden: %w", routererrors.HttpError(http.StatusForbidden))
+				resp := serve()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal("1"))
+			})
+		})
+
+		Context("when the handler returns an error wrapped by WithStatus", func() {
+			It("responds with the status and keeps the original error", func() {
+				sentinel := errors.New("invalid input")
+				var reported error
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.NoRetryOnClientErrors(),
+					eventrouter.OnError(func(_ context.Context, err error) { reported = err }))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.WithStatus(fmt.Errorf("title is too long: %w", sentinel), http.StatusUnprocessableEntity)
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnprocessableEntity))
+				Expect(w.Result().Header.Get(slackheaders.NoRetry)).To(Equal("1"))
+				Expect(reported).To(MatchError("title is too long: invalid input"))
+				Expect(errors.Is(reported, sentinel)).To(BeTrue())
+				Expect(errors.Is(reported, routererrors.HttpError(http.StatusUnprocessableEntity))).To(BeTrue())
+				Expect(errors.Is(reported, routererrors.HttpError(http.StatusBadRequest))).To(BeFalse())
+			})
+		})
+
+		Context("when the handler returns a 5xx error", func() {
+			It("does not set X-Slack-No-Retry", func() {
+				handlerErr = routererrors.HttpError(http.StatusBadGateway)
+				resp := serve()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal(""))
+			})
+		})
+
+		Context("when the handler returns RetryLater", func() {
+			It("does not set X-Slack-No-Retry", func() {
+				handlerErr = routererrors.RetryLater(time.Second)
+				resp := serve()
+				Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal(""))
+			})
+		})
+	})
+
+	Describe("Malformed bodies", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			hookBody []byte
+			hookErr  error
+			hook     = func(_ context.Context, body []byte, err error) {
+				hookBody = body
+				hookErr = err
+			}
+			serve = func(r *eventrouter.Router, body string) *http.Response {
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(body)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result()
+			}
+		)
+		BeforeEach(func() {
+			hookBody = nil
+			hookErr = nil
+		})
+
+		Context("when the body exceeds WithMaxBodySize", func() {
+			It("responds with 413 and calls the hook", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithMaxBodySize(16), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				resp := serve(r, content)
+				Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(hookErr).To(MatchError(eventrouter.ErrBodyTooLarge))
+				Expect(hookBody).To(Equal([]byte(content[:16])))
+			})
+		})
+
+		Context("when Content-Length exceeds WithMaxBodySize", func() {
+			It("responds with 413 before verifying signatures", func() {
+				var verificationErrs []error
+				r, err := eventrouter.New(eventrouter.WithSigningSecret("THE_TOKEN"),
+					eventrouter.WithMaxBodySize(16), eventrouter.OnMalformedBody(hook),
+					eventrouter.OnError(func(_ context.Context, err error) { verificationErrs = append(verificationErrs, err) }))
+				Expect(err).NotTo(HaveOccurred())
+				resp := serve(r, content)
+				Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(hookErr).To(MatchError(eventrouter.ErrBodyTooLarge))
+				Expect(verificationErrs).To(HaveLen(1))
+				Expect(errors.Is(verificationErrs[0], routererrors.HttpError(http.StatusRequestEntityTooLarge))).To(BeTrue())
+			})
+		})
+
+		Context("when the body without Content-Length exceeds WithMaxBodySize", func() {
+			It("responds with 413 without reading the rest of the body", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret("THE_TOKEN"),
+					eventrouter.WithMaxBodySize(16), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest("THE_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				body := bytes.NewReader([]byte(content))
+				req.Body = io.NopCloser(body)
+				req.ContentLength = -1
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(hookErr).To(MatchError(eventrouter.ErrBodyTooLarge))
+				Expect(body.Len()).To(BeNumerically(">", 0))
+			})
+		})
+
+		Context("when the body does not exceed WithMaxBodySize", func() {
+			It("responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithMaxBodySize(int64(len(content))), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				resp := serve(r, content)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(hookErr).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when the body is truncated", func() {
+			It("responds with 400 and calls the hook with ErrTruncatedBody", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				truncated := content[:len(content)/2]
+				resp := serve(r, truncated)
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(hookErr).To(MatchError(eventrouter.ErrTruncatedBody))
+				Expect(hookBody).To(Equal([]byte(truncated)))
+			})
+		})
+
+		Context("when the body is empty", func() {
+			It("responds with 400 and calls the hook with ErrInvalidBody", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				resp := serve(r, "")
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errors.Is(hookErr, eventrouter.ErrInvalidBody)).To(BeTrue())
+				Expect(errors.Is(hookErr, eventrouter.ErrTruncatedBody)).To(BeFalse())
+			})
+		})
+
+		Context("when the body is not a valid JSON", func() {
+			It("responds with 400 and calls the hook with ErrInvalidBody", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.OnMalformedBody(hook))
+				Expect(err).NotTo(HaveOccurred())
+				resp := serve(r, `{"type": event_callback}`)
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errors.Is(hookErr, eventrouter.ErrInvalidBody)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("OnError", func() {
+		var (
+			token    = "THE_TOKEN"
+			hookErrs []error
+			hook     = func(_ context.Context, err error) {
+				hookErrs = append(hookErrs, err)
+			}
+		)
+		BeforeEach(func() {
+			hookErrs = nil
+		})
+
+		Context("when the signature is invalid", func() {
+			It("calls the hook with ErrBadSignature", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.OnError(hook))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest("WRONG_TOKEN", `{"type": "url_verification", "challenge": "xxx"}`, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(signature.ErrBadSignature))
+			})
+		})
+
+		Context("when the event type is unknown", func() {
+			It("responds with 400 and calls the hook with ErrUnknownEventType", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.OnError(hook))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`{"type": "no_such_type"}`)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(eventrouter.ErrUnknownEventType))
+			})
+		})
+
+		Context("when predicates marked by FailOnMismatch don't match", func() {
+			It("responds with 200 and calls the hook with Mismatch without falling back", func() {
+				numFallbackCalled := 0
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.OnError(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					return nil
+				}), message.FailOnMismatch(), message.Channel("CNOTMATCH"))
+				r.SetFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numFallbackCalled++
+					return nil
+				}))
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": {"type": "message", "channel": "C2147483705", "user": "U2147483697", "text": "Hello world", "ts": "1355517523.000005"},
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(routererrors.Mismatch))
+				Expect(numFallbackCalled).To(Equal(0))
+			})
+		})
+
+		Context("when predicates marked by FailOnMismatch don't match after the Router responded", func() {
+			It("calls the hook with Mismatch", func() {
+				errCh := make(chan error, 1)
+				backgroundErrCh := make(chan error, 1)
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.AckBefore(10*time.Millisecond),
+					eventrouter.OnError(func(_ context.Context, err error) { errCh <- err }),
+					eventrouter.OnBackgroundError(func(_ context.Context, _ *slackevents.EventsAPIEvent, err error) { backgroundErrCh <- err }))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				slow := router.Filter(func(context.Context, *slackevents.MessageEvent) bool {
+					<-release
+					return false
+				})
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					return nil
+				}), message.FailOnMismatch(), message.FromGenericPredicate(slow))
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": {"type": "message", "channel": "C2147483705", "user": "U2147483697", "text": "Hello world", "ts": "1355517523.000005"},
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+
+				close(release)
+				Eventually(errCh).Should(Receive(MatchError(routererrors.Mismatch)))
+				Consistently(backgroundErrCh).ShouldNot(Receive())
+			})
+		})
+	})
+
+	Describe("WithEnricher", func() {
+		type enrichedKey struct{}
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			enriched []interface{}
+			handler  = message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				enriched = append(enriched, ctx.Value(enrichedKey{}))
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			enriched = nil
+		})
+
+		Context("when the enricher succeeds", func() {
+			It("calls the handler with the enriched context", func() {
+				enricher := eventrouter.EnricherFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+					return context.WithValue(ctx, enrichedKey{}, e.InnerEvent.Data.(*slackevents.MessageEvent).Channel), nil
+				})
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(enricher))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(enriched).To(Equal([]interface{}{"C2147483705"}))
+			})
+		})
+
+		Context("when the enricher fails", func() {
+			It("responds with an error without calling the handler", func() {
+				enricher := eventrouter.EnricherFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+					return nil, routererrors.HttpError(http.StatusServiceUnavailable)
+				})
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(enricher))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(enriched).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WithMaxEventAge", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1234567890.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			eventTime        = time.Unix(1234567890, 0)
+			numHandlerCalled int
+			numStaleCalled   int
+			handler          = message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numHandlerCalled++
+				return nil
+			})
+			staleHandler = eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				numStaleCalled++
+				return nil
+			})
+			serve = func(r *eventrouter.Router) int {
+				r.OnMessage(handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			numStaleCalled = 0
+		})
+
+		Context("when the event is fresh", func() {
+			It("calls the handler", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithMaxEventAge(time.Minute),
+					eventrouter.WithNowFunc(func() time.Time { return eventTime.Add(30 * time.Second) }))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the event is stale", func() {
+			It("drops the event", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithMaxEventAge(time.Minute),
+					eventrouter.WithNowFunc(func() time.Time { return eventTime.Add(2 * time.Minute) }))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+
+			It("calls the stale event handler if specified", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithMaxEventAge(time.Minute),
+					eventrouter.OnStaleEvent(staleHandler),
+					eventrouter.WithNowFunc(func() time.Time { return eventTime.Add(2 * time.Minute) }))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+				Expect(numStaleCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("Dispatch", func() {
+		var (
+			signingSecret = "THE_SIGNING_SECRET"
+			content       = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			numHandlerCalled int
+			r                *eventrouter.Router
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecret(signingSecret))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numHandlerCalled++
+				return nil
+			}))
+		})
+
+		Context("when the signature is valid", func() {
+			It("calls the handler", func() {
+				req, err := NewSignedRequest(signingSecret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				headers := map[string]string{}
+				for k := range req.Header {
+					headers[k] = req.Header.Get(k)
+				}
+				resp := r.Dispatch([]byte(content), headers)
+				Expect(resp.Status).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("accepts lower-case header names", func() {
+				req, err := NewSignedRequest(signingSecret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				headers := map[string]string{
+					"x-slack-request-timestamp": req.Header.Get(slackheaders.RequestTimestamp),
+					"x-slack-signature":         req.Header.Get(slackheaders.Signature),
+				}
+				resp := r.Dispatch([]byte(content), headers)
+				Expect(resp.Status).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with Unauthorized", func() {
+				req, err := NewSignedRequest("WRONG_SECRET", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				headers := map[string]string{
+					slackheaders.RequestTimestamp: req.Header.Get(slackheaders.RequestTimestamp),
+					slackheaders.Signature:        req.Header.Get(slackheaders.Signature),
+				}
+				resp := r.Dispatch([]byte(content), headers)
+				Expect(resp.Status).To(Equal(http.StatusUnauthorized))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the request is a URL verification", func() {
+			It("returns the challenge in the body", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				body := `{"token": "XXYYZZ", "challenge": "THE_CHALLENGE", "type": "url_verification"}`
+				resp := r.Dispatch([]byte(body), map[string]string{"Content-Type": "application/json"})
+				Expect(resp.Status).To(Equal(http.StatusOK))
+				Expect(string(resp.Body)).To(ContainSubstring("THE_CHALLENGE"))
+				Expect(resp.Header).To(HaveKey("Content-Type"))
+			})
+		})
+	})
+
+	Describe("WithBodyTransformer", func() {
+		var (
+			signingSecret = "THE_SIGNING_SECRET"
+			content       = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			encoded          = base64.StdEncoding.EncodeToString([]byte(content))
+			numHandlerCalled int
+			decode           = func(body []byte, _ *http.Request) ([]byte, error) {
+				decoded, err := base64.StdEncoding.DecodeString(string(body))
+				if err != nil {
+					return nil, routererrors.HttpError(http.StatusBadRequest)
+				}
+				return decoded, nil
+			}
+			newRouter = func(opts ...eventrouter.Option) *eventrouter.Router {
+				r, err := eventrouter.New(append([]eventrouter.Option{eventrouter.WithSigningSecret(signingSecret)}, opts...)...)
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					numHandlerCalled++
+					return nil
+				}))
+				return r
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		Context("when the stage is AfterVerification", func() {
+			It("verifies the original body and passes the transformed body to handlers", func() {
+				r := newRouter(eventrouter.WithBodyTransformer(eventrouter.AfterVerification, decode))
+				req, err := NewSignedRequest(signingSecret, encoded, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("rejects requests whose signature is computed over the transformed body", func() {
+				r := newRouter(eventrouter.WithBodyTransformer(eventrouter.AfterVerification, decode))
+				req, err := NewSignedRequest(signingSecret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Body = io.NopCloser(bytes.NewReader([]byte(encoded)))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the stage is BeforeVerification", func() {
+			It("verifies the transformed body", func() {
+				r := newRouter(eventrouter.WithBodyTransformer(eventrouter.BeforeVerification, decode))
+				req, err := NewSignedRequest(signingSecret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Body = io.NopCloser(bytes.NewReader([]byte(encoded)))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when more than one transformers are given", func() {
+			It("runs them in order", func() {
+				var calls []string
+				record := func(name string) eventrouter.BodyTransformer {
+					return func(body []byte, _ *http.Request) ([]byte, error) {
+						calls = append(calls, name)
+						return body, nil
+					}
+				}
+				r := newRouter(
+					eventrouter.WithBodyTransformer(eventrouter.AfterVerification, record("after-1")),
+					eventrouter.WithBodyTransformer(eventrouter.BeforeVerification, record("before-1")),
+					eventrouter.WithBodyTransformer(eventrouter.AfterVerification, record("after-2")),
+					eventrouter.WithBodyTransformer(eventrouter.BeforeVerification, record("before-2")),
+				)
+				req, err := NewSignedRequest(signingSecret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(calls).To(Equal([]string{"before-1", "before-2", "after-1", "after-2"}))
+			})
+		})
+
+		Context("when the transformer fails", func() {
+			It("responds with the status of the error", func() {
+				r := newRouter(eventrouter.WithBodyTransformer(eventrouter.AfterVerification, decode))
+				req, err := NewSignedRequest(signingSecret, "not base64!", nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("WithLifetimeAudit", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			server     *httptest.Server
+			client     *http.Client
+			violations chan *eventrouter.LifetimeViolation
+			hook       = func(_ context.Context, v *eventrouter.LifetimeViolation) {
+				violations <- v
+			}
+			call = func(ctx context.Context) {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+				Expect(err).NotTo(HaveOccurred())
+				resp, err := client.Do(req)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}
+			serve = func(r *eventrouter.Router, ctx context.Context) int {
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+			client = &http.Client{Transport: eventrouter.AuditTransport(nil)}
+			violations = make(chan *eventrouter.LifetimeViolation, 10)
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when a handler makes calls while processing the event", func() {
+			It("does not report anything", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithLifetimeAudit(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					call(ctx)
+					return nil
+				}))
+				Expect(serve(r, context.Background())).To(Equal(http.StatusOK))
+				Expect(violations).NotTo(Receive())
+			})
+		})
+
+		Context("when a handler makes calls with a canceled context", func() {
+			It("reports CallAfterCancel", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithLifetimeAudit(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					cancel()
+					call(ctx)
+					return nil
+				}))
+				serve(r, ctx)
+				var v *eventrouter.LifetimeViolation
+				Expect(violations).To(Receive(&v))
+				Expect(v.Kind).To(Equal(eventrouter.CallAfterCancel))
+				Expect(v.Event.InnerEvent.Type).To(Equal("message"))
+			})
+		})
+
+		Context("when a handler makes calls after the Router responded in the synchronous mode", func() {
+			It("reports CallAfterAck", func() {
+				leaked := make(chan context.Context, 1)
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithLifetimeAudit(hook))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					leaked <- ctx
+					return nil
+				}))
+				Expect(serve(r, context.Background())).To(Equal(http.StatusOK))
+				call(<-leaked)
+				var v *eventrouter.LifetimeViolation
+				Expect(violations).To(Receive(&v))
+				Expect(v.Kind).To(Equal(eventrouter.CallAfterAck))
+				Expect(v.Request.URL.String()).To(Equal(server.URL))
+			})
+		})
+
+		Context("when a handler makes calls after the Router responded in the asynchronous mode", func() {
+			It("does not report anything", func() {
+				release := make(chan struct{})
+				done := make(chan struct{})
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithLifetimeAudit(hook),
+					eventrouter.AckBefore(10*time.Millisecond))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					<-release
+					call(ctx)
+					close(done)
+					return nil
+				}))
+				Expect(serve(r, context.Background())).To(Equal(http.StatusOK))
+				close(release)
+				<-done
+				Expect(violations).NotTo(Receive())
+			})
+		})
+	})
+
+	Describe("WithBatchEndpoint", func() {
+		var (
+			signingSecret = "THE_SIGNING_SECRET"
+			event         = func(text string) []byte {
+				return []byte(fmt.Sprintf(`
+				{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": {
+						"type": "message",
+						"channel": "C2147483705",
+						"user": "U2147483697",
+						"text": %q,
+						"ts": "1355517523.000005"
+					},
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`, text))
+			}
+			authorize = func(req *http.Request) error {
+				if req.Header.Get("Authorization") != "Bearer replay" {
+					return routererrors.HttpError(http.StatusUnauthorized)
+				}
+				return nil
+			}
+			texts []string
+			r     *eventrouter.Router
+			post  = func(path string, body []byte, authorized bool) *http.Response {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com"+path, bytes.NewReader(body))
+				Expect(err).NotTo(HaveOccurred())
+				if authorized {
+					req.Header.Set("Authorization", "Bearer replay")
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result()
+			}
+		)
+		BeforeEach(func() {
+			texts = nil
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecret(signingSecret), eventrouter.WithBatchEndpoint("/batch", authorize))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, e *slackevents.MessageEvent) error {
+				texts = append(texts, e.Text)
+				if e.Text == "bad" {
+					return routererrors.HttpError(http.StatusBadRequest)
+				}
+				return nil
+			}))
+		})
+
+		It("requires an authorizer", func() {
+			_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithBatchEndpoint("/batch", nil))
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("when the request is authorized", func() {
+			It("dispatches every envelope and returns the results", func() {
+				envelopes := []*bridge.Envelope{
+					{Body: event("first")},
+					{Body: event("bad")},
+					{Body: event("third")},
+				}
+				body, err := json.Marshal(envelopes)
+				Expect(err).NotTo(HaveOccurred())
+				resp := post("/batch", body, true)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				var results []eventrouter.BatchResult
+				Expect(json.NewDecoder(resp.Body).Decode(&results)).To(Succeed())
+				Expect(results).To(HaveLen(3))
+				Expect(results[0].Status).To(Equal(http.StatusOK))
+				Expect(results[1].Status).To(Equal(http.StatusBadRequest))
+				Expect(results[2].Index).To(Equal(2))
+				Expect(results[2].Status).To(Equal(http.StatusOK))
+				Expect(texts).To(Equal([]string{"first", "bad", "third"}))
+			})
+
+			It("responds with Bad Request if the batch is malformed", func() {
+				resp := post("/batch", []byte(`{"not": "an array"}`), true)
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the request is not authorized", func() {
+			It("responds with the error from the authorizer", func() {
+				body, err := json.Marshal([]*bridge.Envelope{{Body: event("first")}})
+				Expect(err).NotTo(HaveOccurred())
+				resp := post("/batch", body, false)
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(texts).To(BeEmpty())
+			})
+		})
+
+		Context("when the request is sent to other paths", func() {
+			It("verifies the signature as usual", func() {
+				resp := post("/events", event("first"), true)
+				Expect(resp.StatusCode).NotTo(Equal(http.StatusOK))
+				Expect(texts).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WithResultSink", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			results chan *eventrouter.Result
+			sink    = eventrouter.ResultSinkFunc(func(_ context.Context, result *eventrouter.Result) {
+				results <- result
+			})
+			serve = func(r *eventrouter.Router) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+		BeforeEach(func() {
+			results = make(chan *eventrouter.Result, 10)
+		})
+
+		Context("when a handler processes the event", func() {
+			It("records the result with the name of the handler", func() {
+				if eventrouter.MinimalMode {
+					Skip("handler names are reduced to their types in the minimal mode")
+				}
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithResultSink(sink))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(handleMessageForResultSink))
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				var result *eventrouter.Result
+				Expect(results).To(Receive(&result))
+				Expect(result.EventID).To(Equal("Ev08MFMKH6"))
+				Expect(result.Attempt).To(Equal(attempt.Key{EventID: "Ev08MFMKH6"}))
+				Expect(result.EventType).To(Equal("message"))
+				Expect(result.Handler).To(HaveSuffix("handleMessageForResultSink"))
+				Expect(result.Err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when a fallback handler processes the event", func() {
+			It("records the result with the name of the fallback handler", func() {
+				if eventrouter.MinimalMode {
+					Skip("handler names are reduced to their types in the minimal mode")
+				}
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithResultSink(sink))
+				Expect(err).NotTo(HaveOccurred())
+				r.AddFallback(eventrouter.HandlerFunc(handleFallbackForResultSink))
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				var result *eventrouter.Result
+				Expect(results).To(Receive(&result))
+				Expect(result.Handler).To(HaveSuffix("handleFallbackForResultSink"))
+			})
+		})
+
+		Context("when no handler processes the event", func() {
+			It("records NotInterested", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithResultSink(sink))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				var result *eventrouter.Result
+				Expect(results).To(Receive(&result))
+				Expect(result.Handler).To(BeEmpty())
+				Expect(result.Err).To(MatchError(routererrors.NotInterested))
+			})
+		})
+
+		Context("when the event is processed in the background", func() {
+			It("records the result after the handler finishes", func() {
+				release := make(chan struct{})
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(),
+					eventrouter.WithResultSink(sink),
+					eventrouter.AckBefore(10*time.Millisecond))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					<-release
+					return errors.New("failed in the background")
+				}))
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(results).NotTo(Receive())
+				close(release)
+				var result *eventrouter.Result
+				Eventually(results).Should(Receive(&result))
+				Expect(result.EventID).To(Equal("Ev08MFMKH6"))
+				Expect(result.Err).To(MatchError("failed in the background"))
+			})
+		})
+	})
+
+	Describe("SetRawHandler", func() {
+		var (
+			serve = func(r *eventrouter.Router, content string) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+			envelope = func(inner string) string {
+				return `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": ` + inner + `,
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890,
+					"authorizations": [{"team_id": "TXXXXXXXX", "user_id": "UXXXXXXXX", "is_bot": true}]
+				}`
+			}
+			unknownType = envelope(`{"type": "brand_new_event", "channel": "C2147483705"}`)
+			changedType = envelope(`{"type": "message", "channel": "C2147483705", "text": {"rich": "Hello world"}}`)
+			received    []*slackevents.EventsAPIEvent
+			rawHandler  = eventrouter.HandlerFunc(func(_ context.Context, e *slackevents.EventsAPIEvent) error {
+				received = append(received, e)
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			received = nil
+		})
+
+		Context("when no raw handler is set", func() {
+			It("responds with 400", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve(r, unknownType)).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the type of the inner event is unknown to slack-go", func() {
+			It("passes the event to the raw handler with the envelope", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.SetRawHandler(rawHandler)
+				Expect(serve(r, unknownType)).To(Equal(http.StatusOK))
+				Expect(received).To(HaveLen(1))
+				e := received[0]
+				Expect(e.TeamID).To(Equal("TXXXXXXXX"))
+				Expect(e.APIAppID).To(Equal("AXXXXXXXXX"))
+				Expect(e.InnerEvent.Type).To(Equal("brand_new_event"))
+				Expect(e.Data.(*slackevents.EventsAPICallbackEvent).EventID).To(Equal("Ev08MFMKH6"))
+				raw, ok := e.InnerEvent.Data.(*eventrouter.RawInnerEvent)
+				Expect(ok).To(BeTrue())
+				Expect(raw.Type).To(Equal("brand_new_event"))
+				Expect(raw.JSON).To(MatchJSON(`{"type": "brand_new_event", "channel": "C2147483705"}`))
+				Expect(raw.Err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the inner event has fields that slack-go can't parse", func() {
+			It("passes the event to the raw handler instead of typed handlers", func() {
+				numMessageHandled := 0
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+					numMessageHandled++
+					return nil
+				}))
+				r.SetRawHandler(rawHandler)
+				Expect(serve(r, changedType)).To(Equal(http.StatusOK))
+				Expect(numMessageHandled).To(Equal(0))
+				Expect(received).To(HaveLen(1))
+				Expect(received[0].InnerEvent.Type).To(Equal("message"))
+			})
+		})
+
+		Context("when the raw handler is not interested in the event", func() {
+			It("treats the event as unmatched", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithUnmatchedStatus(http.StatusAccepted))
+				Expect(err).NotTo(HaveOccurred())
+				r.SetRawHandler(eventrouter.HandlerFunc(func(context.Context, *slackevents.EventsAPIEvent) error {
+					return routererrors.NotInterested
+				}))
+				Expect(serve(r, unknownType)).To(Equal(http.StatusAccepted))
+			})
+		})
+
+		Context("when the envelope itself is broken", func() {
+			It("responds with 400", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.SetRawHandler(rawHandler)
+				Expect(serve(r, `{"token": "XXYYZZ", "event": {"type": "message"}}`)).To(Equal(http.StatusBadRequest))
+				Expect(received).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("FallbackToRawOnTypeMismatch", func() {
+		var (
+			content = `{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {"type": "message", "channel": "C2147483705", "text": "Hello world"},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			serve = func(r *eventrouter.Router) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+			// replaceInnerEvent simulates slack-go parsing a new variant of `message` events into a different type.
+			replaceInnerEvent = eventrouter.WithEnricher(eventrouter.EnricherFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+				e.InnerEvent.Data = &slackevents.ChannelCreatedEvent{}
+				return ctx, nil
+			}))
+			mismatches     []error
+			recordMismatch = eventrouter.OnTypeMismatch(func(_ context.Context, _ *slackevents.EventsAPIEvent, err error) {
+				mismatches = append(mismatches, err)
+			})
+			numMessageHandled int
+			messageHandler    = message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+				numMessageHandled++
+				return nil
+			})
+			received   []*slackevents.EventsAPIEvent
+			rawHandler = eventrouter.HandlerFunc(func(_ context.Context, e *slackevents.EventsAPIEvent) error {
+				received = append(received, e)
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			mismatches = nil
+			numMessageHandled = 0
+			received = nil
+		})
+
+		Context("when the option is not specified", func() {
+			It("responds with 400 and calls the hook", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), replaceInnerEvent, recordMismatch)
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(messageHandler)
+				r.SetRawHandler(rawHandler)
+				Expect(serve(r)).To(Equal(http.StatusBadRequest))
+				Expect(numMessageHandled).To(Equal(0))
+				Expect(received).To(BeEmpty())
+				Expect(mismatches).To(HaveLen(1))
+				Expect(mismatches[0]).To(MatchError(eventrouter.ErrInnerEventTypeMismatch))
+			})
+		})
+
+		Context("when the option is specified", func() {
+			It("passes the event to the raw handler", func() {
+				r, err := eventrouter.New(
+					eventrouter.InsecureSkipVerification(),
+					eventrouter.FallbackToRawOnTypeMismatch(),
+					replaceInnerEvent,
+					recordMismatch,
+				)
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(messageHandler)
+				r.OnMessage(messageHandler, message.Channel("C2147483705"))
+				r.SetRawHandler(rawHandler)
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(numMessageHandled).To(Equal(0))
+				Expect(mismatches).To(HaveLen(1))
+				Expect(received).To(HaveLen(1))
+				raw, ok := received[0].InnerEvent.Data.(*eventrouter.RawInnerEvent)
+				Expect(ok).To(BeTrue())
+				Expect(raw.Type).To(Equal("message"))
+				Expect(raw.JSON).To(MatchJSON(`{"type": "message", "channel": "C2147483705", "text": "Hello world"}`))
+				Expect(raw.Err).To(MatchError(eventrouter.ErrInnerEventTypeMismatch))
+			})
+
+			It("treats the event as unmatched when no raw handler is set", func() {
+				r, err := eventrouter.New(
+					eventrouter.InsecureSkipVerification(),
+					eventrouter.FallbackToRawOnTypeMismatch(),
+					eventrouter.WithUnmatchedStatus(http.StatusAccepted),
+					replaceInnerEvent,
+				)
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(messageHandler)
+				Expect(serve(r)).To(Equal(http.StatusAccepted))
+			})
+
+			It("passes the event to the fallback handler when no raw handler is set", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.FallbackToRawOnTypeMismatch(), replaceInnerEvent)
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(messageHandler)
+				r.SetFallback(rawHandler)
+				Expect(serve(r)).To(Equal(http.StatusOK))
+				Expect(received).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("WithRedaction", func() {
+		var (
+			content = `{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {"type": "message", "channel": "C2147483705", "text": "Hello world"},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			sentinel = errors.New("failed")
+		)
+
+		It("redacts verbose responses and errors given to hooks", func() {
+			var hookErr error
+			r, err := eventrouter.New(
+				eventrouter.InsecureSkipVerification(),
+				eventrouter.VerboseResponse(),
+				eventrouter.WithRedaction(redact.New(redact.StripTokens(), redact.MaskEmails())),
+				eventrouter.OnError(func(_ context.Context, err error) {
+					hookErr = err
+				}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+				return fmt.Errorf("posting as xoxb-1234-abcd to alice@example.com: %w", sentinel)
+			}))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+			body := w.Body.String()
+			Expect(body).To(ContainSubstring("[TOKEN]"))
+			Expect(body).To(ContainSubstring("***@example.com"))
+			Expect(body).NotTo(ContainSubstring("xoxb-1234-abcd"))
+			Expect(body).NotTo(ContainSubstring("alice@"))
+			Expect(hookErr.Error()).To(Equal(body))
+			Expect(errors.Is(hookErr, sentinel)).To(BeTrue())
+		})
+	})
+
+	Describe("Preflight", func() {
+		const nonHexSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+		var ctx = context.Background()
+		validSecret := hex.EncodeToString([]byte("0123456789abcdef"))
+
+		It("returns nil if there are no problems", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(validSecret))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.Preflight(ctx)).To(Succeed())
+		})
+
+		It("reports implausible signing secrets", func() {
+			for _, secret := range []string{nonHexSecret, "xoxb-1234", " " + validSecret} {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(secret))
+				Expect(err).NotTo(HaveOccurred())
+				err = r.Preflight(ctx)
+				Expect(errors.Is(err, eventrouter.ErrImplausibleSigningSecret)).To(BeTrue(), secret)
+			}
+		})
+
+		It("reports all the problems at once", func() {
+			r, err := eventrouter.New(
+				eventrouter.WithSigningSecret(validSecret),
+				eventrouter.WithPreviousSigningSecrets("THE_SIGNING_SECRET"),
+				eventrouter.OnStaleEvent(eventrouter.HandlerFunc(func(context.Context, *slackevents.EventsAPIEvent) error { return nil })),
+				eventrouter.OnBackgroundError(func(context.Context, *slackevents.EventsAPIEvent, error) {}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			err = r.Preflight(ctx)
+			var preflightErr *eventrouter.PreflightError
+			Expect(errors.As(err, &preflightErr)).To(BeTrue())
+			Expect(preflightErr.Errors).To(HaveLen(3))
+			Expect(err).To(MatchError(ContainSubstring("WithPreviousSigningSecrets[0]")))
+			Expect(err).To(MatchError(ContainSubstring("OnStaleEvent has no effect without WithMaxEventAge")))
+			Expect(err).To(MatchError(ContainSubstring("OnBackgroundError has no effect without AckBefore")))
+		})
+
+		It("calls PreflightCheckers", func() {
+			type checkingEnricher struct {
+				eventrouter.EnricherFunc
+				eventrouter.PreflightCheckerFunc
+			}
+			enricher := checkingEnricher{
+				EnricherFunc: func(ctx context.Context, _ *slackevents.EventsAPIEvent) (context.Context, error) { return ctx, nil },
+				PreflightCheckerFunc: func(context.Context) error {
+					return errors.New("installation store unreachable")
+				},
+			}
+			r, err := eventrouter.New(
+				eventrouter.InsecureSkipVerification(),
+				eventrouter.WithEnricher(enricher),
+				eventrouter.WithPreflightCheck("database", eventrouter.PreflightCheckerFunc(func(context.Context) error {
+					return errors.New("connection refused")
+				})),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			err = r.Preflight(ctx)
+			Expect(err).To(MatchError(ContainSubstring("database: connection refused")))
+			Expect(err).To(MatchError(ContainSubstring("installation store unreachable")))
+		})
+	})
+
+	Describe("WithHTTPMiddleware", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+			calls  []string
+			record = func(name string) func(http.Handler) http.Handler {
+				return func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+						calls = append(calls, name)
+						next.ServeHTTP(w, req)
+					})
+				}
+			}
+		)
+		BeforeEach(func() {
+			calls = nil
+		})
+
+		It("applies middlewares in the documented order", func() {
+			filter := func(*http.Request) error {
+				calls = append(calls, "filter")
+				return nil
+			}
+			r, err := eventrouter.New(
+				eventrouter.WithSigningSecret(token),
+				eventrouter.WithRequestFilter(filter),
+				eventrouter.WithHTTPMiddleware(record("first"), record("second")),
+				eventrouter.WithBodyTransformer(eventrouter.BeforeVerification, func(body []byte, _ *http.Request) ([]byte, error) {
+					calls = append(calls, "transformer")
+					return body, nil
+				}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal([]string{"filter", "first", "second", "transformer"}))
+		})
+
+		It("verifies the original body even if middlewares read it", func() {
+			var seen []byte
+			peek := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					var err error
+					seen, err = io.ReadAll(req.Body)
+					Expect(err).NotTo(HaveOccurred())
+					next.ServeHTTP(w, req)
+				})
+			}
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithHTTPMiddleware(peek))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(string(seen)).To(Equal(content))
+		})
+
+		It("limits bodies read by middlewares to WithMaxBodySize", func() {
+			var readErr error
+			peek := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					_, readErr = io.ReadAll(req.Body)
+					next.ServeHTTP(w, req)
+				})
+			}
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token),
+				eventrouter.WithMaxBodySize(16), eventrouter.WithHTTPMiddleware(peek))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			body := bytes.NewReader([]byte(content))
+			req.Body = io.NopCloser(body)
+			req.ContentLength = -1
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(readErr).To(MatchError(eventrouter.ErrBodyTooLarge))
+			Expect(body.Len()).To(BeNumerically(">", 0))
+		})
+
+		It("lets middlewares reject requests before verification", func() {
+			deny := func(http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				})
+			}
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithHTTPMiddleware(deny))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusForbidden))
+		})
 	})
 
-	Describe("WithSigningSecret", func() {
-		var (
-			r       *eventrouter.Router
-			token   = "THE_TOKEN"
-			content = `
-			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
-				"type": "url_verification"
-			}`
-		)
-		BeforeEach(func() {
-			var err error
-			r, err = eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.VerboseResponse())
+	Describe("AcceptHealthProbes", func() {
+		serve := func(r *eventrouter.Router, method string) *http.Response {
+			req, err := http.NewRequest(method, "http://example.com/path", nil)
 			Expect(err).NotTo(HaveOccurred())
-		})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result()
+		}
 
-		Context("when the signature is valid", func() {
-			It("responds with 200", func() {
-				req, err := NewSignedRequest(token, content, nil)
+		Context("when AcceptHealthProbes is not given", func() {
+			It("responds to requests other than POST with Method Not Allowed", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret("THE_TOKEN"))
 				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPut} {
+					resp := serve(r, method)
+					Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+					Expect(resp.Header.Get("Allow")).To(Equal("POST"))
+				}
 			})
 		})
 
-		Context("when the signature is invalid", func() {
-			It("responds with Unauthorized", func() {
-				req, err := NewSignedRequest(token, content, nil)
+		Context("when AcceptHealthProbes is given", func() {
+			It("responds to GET and HEAD requests with OK", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret("THE_TOKEN"), eventrouter.AcceptHealthProbes())
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(serve(r, http.MethodGet).StatusCode).To(Equal(http.StatusOK))
+				Expect(serve(r, http.MethodHead).StatusCode).To(Equal(http.StatusOK))
+				resp := serve(r, http.MethodDelete)
+				Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+				Expect(resp.Header.Get("Allow")).To(Equal("GET, HEAD, POST"))
 			})
 		})
+	})
 
-		Context("when the timestamp is too old", func() {
-			It("responds with BadRequest", func() {
-				ts := time.Now().Add(-1 * time.Hour)
-				req, err := NewSignedRequest(token, content, &ts)
-				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
-			})
+	Describe("Precompile", func() {
+		It("doesn't call handlers, and keeps the Router working", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithRedaction(redact.Default))
+			Expect(err).NotTo(HaveOccurred())
+			var texts []string
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, e *slackevents.MessageEvent) error {
+				texts = append(texts, e.Text)
+				return nil
+			}))
+			r.Precompile()
+			r.Precompile()
+			Expect(texts).To(BeEmpty())
+
+			resp := r.Dispatch([]byte(`{"type": "event_callback", "event": {"type": "message", "text": "hello"}}`), nil)
+			Expect(resp.Status).To(Equal(http.StatusOK))
+			Expect(texts).To(Equal([]string{"hello"}))
 		})
 	})
 
-	Describe("InsecureSkipVerification", func() {
+	Describe("OnAppRequested", func() {
 		var (
-			r       *eventrouter.Router
-			token   = "THE_TOKEN"
-			content = `
-			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
-				"type": "url_verification"
+			serve = func(r *eventrouter.Router, content string) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+			envelope = func(eventType, appRequest string) string {
+				return `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": {"type": "` + eventType + `", "app_request": ` + appRequest + `},
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+			}
+			appRequest = `{
+				"id": "1234",
+				"app": {"id": "A5678", "name": "Brent's app", "is_app_directory_approved": true},
+				"user": {"id": "U1234", "name": "Bill Jones", "email": "bill@example.com"},
+				"team": {"id": "T1234", "name": "Acme Corp", "domain": "acme-corp"},
+				"scopes": [{"name": "app_mentions:read", "is_sensitive": false, "token_type": "bot"}],
+				"message": "please"
 			}`
+			received []*adminapps.Event
+			handler  = adminapps.HandlerFunc(func(_ context.Context, e *adminapps.Event) error {
+				received = append(received, e)
+				return nil
+			})
 		)
 		BeforeEach(func() {
-			var err error
-			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			received = nil
+		})
+
+		It("passes the decoded event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
 			Expect(err).NotTo(HaveOccurred())
+			r.OnAppRequested(handler, adminapps.InTeam("T1234"))
+			Expect(serve(r, envelope(adminapps.AppRequested, appRequest))).To(Equal(http.StatusOK))
+			Expect(received).To(HaveLen(1))
+			Expect(received[0].Type).To(Equal(adminapps.AppRequested))
+			Expect(received[0].AppRequest.ID).To(Equal("1234"))
+			Expect(received[0].AppRequest.App.ID).To(Equal("A5678"))
+			Expect(received[0].AppRequest.User.Email).To(Equal("bill@example.com"))
+			Expect(received[0].AppRequest.Scopes).To(HaveLen(1))
+			Expect(received[0].AppRequest.Message).To(Equal("please"))
 		})
 
-		Context("when the signature is valid", func() {
-			It("responds with 200", func() {
-				req, err := NewSignedRequest(token, content, nil)
+		It("does not call the handler if predicates are not satisfied", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithUnmatchedStatus(http.StatusAccepted))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnAppRequested(handler, adminapps.RequestedBy("U9999"))
+			Expect(serve(r, envelope(adminapps.AppRequested, appRequest))).To(Equal(http.StatusAccepted))
+			Expect(received).To(BeEmpty())
+		})
+
+		It("routes each type of events to its own handlers", func() {
+			var approved, restricted []*adminapps.Event
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnAppRequested(handler)
+			r.OnAppApproved(adminapps.HandlerFunc(func(_ context.Context, e *adminapps.Event) error {
+				approved = append(approved, e)
+				return nil
+			}))
+			r.OnAppRestricted(adminapps.HandlerFunc(func(_ context.Context, e *adminapps.Event) error {
+				restricted = append(restricted, e)
+				return nil
+			}))
+			Expect(serve(r, envelope(adminapps.AppApproved, appRequest))).To(Equal(http.StatusOK))
+			Expect(serve(r, envelope(adminapps.AppRestricted, appRequest))).To(Equal(http.StatusOK))
+			Expect(received).To(BeEmpty())
+			Expect(approved).To(HaveLen(1))
+			Expect(restricted).To(HaveLen(1))
+		})
+
+		It("takes precedence over the raw handler", func() {
+			numRawHandled := 0
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.SetRawHandler(eventrouter.HandlerFunc(func(context.Context, *slackevents.EventsAPIEvent) error {
+				numRawHandled++
+				return nil
+			}))
+			r.OnAppRequested(handler)
+			Expect(serve(r, envelope(adminapps.AppRequested, appRequest))).To(Equal(http.StatusOK))
+			Expect(serve(r, envelope("brand_new_event", appRequest))).To(Equal(http.StatusOK))
+			Expect(received).To(HaveLen(1))
+			Expect(numRawHandled).To(Equal(1))
+		})
+
+		It("responds with 400 if the event is malformed", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnAppRequested(handler)
+			Expect(serve(r, envelope(adminapps.AppRequested, `"oops"`))).To(Equal(http.StatusBadRequest))
+			Expect(received).To(BeEmpty())
+		})
+
+		It("responds with 400 to events of other unknown types", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnAppRequested(handler)
+			Expect(serve(r, envelope("brand_new_event", appRequest))).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("OnChannelShared", func() {
+		var (
+			serve = func(r *eventrouter.Router, inner string) int {
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": ` + inner + `,
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
 				Expect(err).NotTo(HaveOccurred())
 				w := httptest.NewRecorder()
 				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
-			})
+				return w.Result().StatusCode
+			}
+			shared   = `{"type": "channel_shared", "connected_team_id": "E163Q94DX", "channel": "C123ABC456", "event_ts": "1561064063.001100"}`
+			unshared = `{"type": "channel_unshared", "previously_connected_team_id": "E163Q94DX", "channel": "C123ABC456", "is_ext_shared": true, "event_ts": "1561064063.001100"}`
+		)
+
+		It("passes shared and unshared events to their handlers", func() {
+			var (
+				sharedEvents   []*sharedchannel.SharedEvent
+				unsharedEvents []*sharedchannel.UnsharedEvent
+			)
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnChannelShared(sharedchannel.SharedHandlerFunc(func(_ context.Context, e *sharedchannel.SharedEvent) error {
+				sharedEvents = append(sharedEvents, e)
+				return nil
+			}), sharedchannel.ConnectedTeam("E163Q94DX"))
+			r.OnChannelUnshared(sharedchannel.UnsharedHandlerFunc(func(_ context.Context, e *sharedchannel.UnsharedEvent) error {
+				unsharedEvents = append(unsharedEvents, e)
+				return nil
+			}), sharedchannel.ConnectedTeam("E163Q94DX"))
+			Expect(serve(r, shared)).To(Equal(http.StatusOK))
+			Expect(serve(r, unshared)).To(Equal(http.StatusOK))
+			Expect(sharedEvents).To(Equal([]*sharedchannel.SharedEvent{{
+				Type:            sharedchannel.ChannelShared,
+				Channel:         "C123ABC456",
+				ConnectedTeamID: "E163Q94DX",
+				EventTimestamp:  "1561064063.001100",
+			}}))
+			Expect(unsharedEvents).To(Equal([]*sharedchannel.UnsharedEvent{{
+				Type:                      sharedchannel.ChannelUnshared,
+				Channel:                   "C123ABC456",
+				PreviouslyConnectedTeamID: "E163Q94DX",
+				IsExtShared:               true,
+				EventTimestamp:            "1561064063.001100",
+			}}))
 		})
 
-		Context("when the signature is invalid", func() {
-			It("responds with 200", func() {
-				req, err := NewSignedRequest(token, content, nil)
+		It("does not call the handler if the channel is shared with other teams", func() {
+			numHandlerCalled := 0
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithUnmatchedStatus(http.StatusAccepted))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnChannelShared(sharedchannel.SharedHandlerFunc(func(context.Context, *sharedchannel.SharedEvent) error {
+				numHandlerCalled++
+				return nil
+			}), sharedchannel.ConnectedTeam("T0THER"))
+			Expect(serve(r, shared)).To(Equal(http.StatusAccepted))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("OnBookmarkAdded", func() {
+		var (
+			serve = func(r *eventrouter.Router, eventType string) int {
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": {
+						"type": "` + eventType + `",
+						"channel_id": "C123ABC456",
+						"bookmark": {
+							"id": "Bk123",
+							"channel_id": "C123ABC456",
+							"title": "Runbook",
+							"link": "https://wiki.example.com/runbook",
+							"type": "link",
+							"date_created": 1561064063,
+							"last_updated_by_user_id": "U123"
+						},
+						"event_ts": "1561064063.001100"
+					},
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
 				w := httptest.NewRecorder()
 				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				return w.Result().StatusCode
+			}
+			received map[string][]*bookmark.Event
+			handler  = bookmark.HandlerFunc(func(_ context.Context, e *bookmark.Event) error {
+				received[e.Type] = append(received[e.Type], e)
+				return nil
 			})
+		)
+		BeforeEach(func() {
+			received = make(map[string][]*bookmark.Event)
 		})
 
-		Context("when the timestamp is too old", func() {
-			It("responds with 200", func() {
-				ts := time.Now().Add(-1 * time.Hour)
-				req, err := NewSignedRequest(token, content, &ts)
+		It("passes each type of events to its handlers", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnBookmarkAdded(handler, bookmark.Channel("C123ABC456"))
+			r.OnBookmarkChanged(handler, bookmark.Channel("C123ABC456"))
+			r.OnBookmarkRemoved(handler, bookmark.Channel("C123ABC456"))
+			for _, eventType := range []string{bookmark.BookmarkAdded, bookmark.BookmarkChanged, bookmark.BookmarkRemoved} {
+				Expect(serve(r, eventType)).To(Equal(http.StatusOK))
+				Expect(received[eventType]).To(HaveLen(1))
+			}
+			e := received[bookmark.BookmarkAdded][0]
+			Expect(e.Channel()).To(Equal("C123ABC456"))
+			Expect(e.Bookmark.ID).To(Equal("Bk123"))
+			Expect(e.Bookmark.Link).To(Equal("https://wiki.example.com/runbook"))
+			Expect(e.Bookmark.DateCreated).To(Equal(int64(1561064063)))
+		})
+
+		It("does not call the handler for bookmarks of other channels", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithUnmatchedStatus(http.StatusAccepted))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnBookmarkAdded(handler, bookmark.Channel("C0THER"))
+			Expect(serve(r, bookmark.BookmarkAdded)).To(Equal(http.StatusAccepted))
+			Expect(received).To(BeEmpty())
+		})
+	})
+
+	Describe("OnUserHuddleChanged", func() {
+		var (
+			serve = func(r *eventrouter.Router, inner string) int {
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": ` + inner + `,
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
 				Expect(err).NotTo(HaveOccurred())
 				w := httptest.NewRecorder()
 				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
-			})
+				return w.Result().StatusCode
+			}
+		)
+
+		It("passes the decoded event to the handler", func() {
+			var received []*huddle.Event
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnUserHuddleChanged(huddle.HandlerFunc(func(_ context.Context, e *huddle.Event) error {
+				received = append(received, e)
+				return nil
+			}), huddle.State(huddle.StateInHuddle))
+			Expect(serve(r, `{
+				"type": "user_huddle_changed",
+				"user": {"id": "U123", "team_id": "T123", "name": "alice", "profile": {"huddle_state": "in_a_huddle", "huddle_state_expiration_ts": 0}},
+				"cache_ts": 1643043212,
+				"event_ts": "1643043212.002700"
+			}`)).To(Equal(http.StatusOK))
+			Expect(received).To(HaveLen(1))
+			Expect(received[0].User.ID).To(Equal("U123"))
+			Expect(received[0].InHuddle()).To(BeTrue())
+		})
+
+		It("makes huddles of huddle_thread messages available to message handlers", func() {
+			var room *huddle.Room
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				var err error
+				room, err = huddle.ThreadRoom(ctx)
+				return err
+			}), huddle.Threads())
+			Expect(serve(r, `{
+				"type": "message",
+				"subtype": "huddle_thread",
+				"channel": "C123",
+				"ts": "1643043212.002700",
+				"room": {"id": "R123", "created_by": "U123", "date_start": 1643043212, "participants": ["U123"], "channels": ["C123"]}
+			}`)).To(Equal(http.StatusOK))
+			Expect(room).NotTo(BeNil())
+			Expect(room.ID).To(Equal("R123"))
+			Expect(room.Participants).To(Equal([]string{"U123"}))
 		})
 	})
 
-	Describe("URL Verification", func() {
+	Describe("OnFileCommentAdded", func() {
 		var (
-			r *eventrouter.Router
+			serve = func(r *eventrouter.Router, inner string) int {
+				content := `{
+					"token": "XXYYZZ",
+					"team_id": "TXXXXXXXX",
+					"api_app_id": "AXXXXXXXXX",
+					"event": ` + inner + `,
+					"type": "event_callback",
+					"event_id": "Ev08MFMKH6",
+					"event_time": 1234567890
+				}`
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+			received map[string][]*filecomment.Event
+			handler  = filecomment.HandlerFunc(func(_ context.Context, e *filecomment.Event) error {
+				received[e.Type] = append(received[e.Type], e)
+				return nil
+			})
 		)
 		BeforeEach(func() {
-			var err error
-			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
-			Expect(err).NotTo(HaveOccurred())
+			received = make(map[string][]*filecomment.Event)
 		})
 
-		It("returns the given challenge in JSON", func() {
-			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
-			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"challenge": "THE_SECRET_CHALLENGE_VALUE",
-				"type": "url_verification"
-			}
-			`)))
+		It("passes each type of events to its handlers", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
 			Expect(err).NotTo(HaveOccurred())
-			w := httptest.NewRecorder()
-			r.ServeHTTP(w, req)
-			resp := w.Result()
-			Expect(resp.StatusCode).To(Equal(http.StatusOK))
-			dec := json.NewDecoder(resp.Body)
-			body := slackevents.ChallengeResponse{}
-			err = dec.Decode(&body)
+			r.OnFileCommentAdded(handler, filecomment.File("F2147483862"))
+			r.OnFileCommentEdited(handler, filecomment.File("F2147483862"))
+			r.OnFileCommentDeleted(handler, filecomment.File("F2147483862"))
+			comment := `{"id": "Fc1234567890", "created": 1356032811, "timestamp": 1356032811, "user": "U123", "comment": "LGTM"}`
+			Expect(serve(r, `{"type": "file_comment_added", "comment": `+comment+`, "file_id": "F2147483862", "file": {"id": "F2147483862"}}`)).To(Equal(http.StatusOK))
+			Expect(serve(r, `{"type": "file_comment_edited", "comment": `+comment+`, "file_id": "F2147483862", "file": {"id": "F2147483862"}}`)).To(Equal(http.StatusOK))
+			Expect(serve(r, `{"type": "file_comment_deleted", "comment": "Fc1234567890", "file_id": "F2147483862", "file": {"id": "F2147483862"}}`)).To(Equal(http.StatusOK))
+			Expect(received[filecomment.FileCommentAdded]).To(HaveLen(1))
+			Expect(received[filecomment.FileCommentAdded][0].Comment.Comment).To(Equal("LGTM"))
+			Expect(received[filecomment.FileCommentEdited]).To(HaveLen(1))
+			Expect(received[filecomment.FileCommentDeleted]).To(HaveLen(1))
+			Expect(received[filecomment.FileCommentDeleted][0].Comment.ID).To(Equal("Fc1234567890"))
+		})
+
+		It("does not call the handler for comments on other files", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithUnmatchedStatus(http.StatusAccepted))
 			Expect(err).NotTo(HaveOccurred())
-			Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+			r.OnFileCommentAdded(handler, filecomment.File("F0THER"))
+			Expect(serve(r, `{"type": "file_comment_added", "comment": {"id": "Fc1", "user": "U123"}, "file_id": "F2147483862"}`)).To(Equal(http.StatusAccepted))
+			Expect(received).To(BeEmpty())
 		})
 	})
 
-	Describe("App Rate Limited", func() {
+	Describe("StrictRegistration", func() {
 		var (
-			r *eventrouter.Router
+			r       *eventrouter.Router
+			handler = message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				return nil
+			})
 		)
 		BeforeEach(func() {
 			var err error
-			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.StrictRegistration())
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("responds with 200", func() {
-			req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(`
-			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"type": "app_rate_limited",
-				"team_id": "T123456",
-				"minute_rate_limited": 1518467820,
-				"api_app_id": "A123456"
-			}
-			`)))
-			Expect(err).NotTo(HaveOccurred())
-			w := httptest.NewRecorder()
-			r.ServeHTTP(w, req)
-			resp := w.Result()
-			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Context("when handlers with equal predicates are registered", func() {
+			It("panics", func() {
+				r.OnMessage(handler, message.Channel("C123"), message.SubType("bot_message"))
+				Expect(func() {
+					r.OnMessage(handler, message.SubType("bot_message"), message.Channel("C123"))
+				}).To(Panic())
+			})
+		})
+
+		Context("when handlers with different predicates are registered", func() {
+			It("does not panic", func() {
+				r.OnMessage(handler, message.Channel("C123"))
+				Expect(func() {
+					r.OnMessage(handler, message.Channel("C456"))
+				}).NotTo(Panic())
+			})
+		})
+
+		Context("when SetFallback is called twice", func() {
+			It("panics", func() {
+				fallback := eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return nil
+				})
+				r.SetFallback(fallback)
+				Expect(func() {
+					r.SetFallback(fallback)
+				}).To(Panic())
+			})
+		})
+
+		Context("when StrictRegistration is not given", func() {
+			It("does not panic", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.OnMessage(handler, message.Channel("C123"))
+				Expect(func() {
+					r.OnMessage(handler, message.Channel("C123"))
+				}).NotTo(Panic())
+			})
 		})
 	})
 
@@ -276,6 +2802,59 @@ var _ = Describe("EventRouter", func() {
 			})
 		})
 
+		Context("when a handler accesses the raw request", func() {
+			It("provides the raw body and headers", func() {
+				var (
+					rawBody []byte
+					header  http.Header
+				)
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+					rawBody = eventrouter.RawBody(ctx)
+					header = eventrouter.RequestHeader(ctx)
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.RetryNum, "1")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(rawBody).To(Equal([]byte(content)))
+				Expect(header.Get(slackheaders.RetryNum)).To(Equal("1"))
+			})
+		})
+
+		Context("when Slack retries the event", func() {
+			It("provides the attempt key to handlers and the error hook", func() {
+				var (
+					handlerKey, hookKey attempt.Key
+					handlerOK, hookOK   bool
+				)
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithShared(
+					routeroptions.OnError(func(ctx context.Context, _ error) {
+						hookKey, hookOK = attempt.FromContext(ctx)
+					}),
+				))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+					handlerKey, handlerOK = attempt.FromContext(ctx)
+					return errors.New("temporary failure")
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.RetryNum, "2")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				expected := attempt.Key{EventID: "Ev08MFMKH6", RetryNum: 2}
+				Expect(handlerOK).To(BeTrue())
+				Expect(handlerKey).To(Equal(expected))
+				Expect(hookOK).To(BeTrue())
+				Expect(hookKey).To(Equal(expected))
+			})
+		})
+
 		Context("when a matching handler is registered to a different type of events", func() {
 			It("does not call the handler and responds with 200", func() {
 				r.On("other_type", handler)
@@ -320,7 +2899,7 @@ var _ = Describe("EventRouter", func() {
 		Context("when a handler returned an error that equals to NotInterested using errors.Is", func() {
 			It("responds with 200", func() {
 				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
-					return errors.WithMessage(routererrors.NotInterested, "not interested")
+					return fmt.Errorf("not interested: %w", routererrors.NotInterested)
 				}))
 				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
 				Expect(err).NotTo(HaveOccurred())
@@ -350,7 +2929,7 @@ var _ = Describe("EventRouter", func() {
 			It("responds with a corresponding status code", func() {
 				code := http.StatusUnauthorized
 				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
-					return errors.WithMessage(routererrors.HttpError(code), "you ain't authorized")
+					return fmt.Errorf("you ain't authorized: %w", routererrors.HttpError(code))
 				}))
 				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
 				Expect(err).NotTo(HaveOccurred())
@@ -441,7 +3020,7 @@ var _ = Describe("EventRouter", func() {
 
 			Context("when a first handler returned an error that equals to NotInterested using errors.Is", func() {
 				It("falls back to another handler", func() {
-					firstError = errors.WithMessage(routererrors.NotInterested, "not interested")
+					firstError = fmt.Errorf("not interested: %w", routererrors.NotInterested)
 					secondError = nil
 					fallbackError = nil
 					req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
@@ -476,7 +3055,7 @@ var _ = Describe("EventRouter", func() {
 			Context("when the last handler returned an error that equals to NotInterested using errors.Is", func() {
 				It("falls back to fallback handler", func() {
 					firstError = routererrors.NotInterested
-					secondError = errors.WithMessage(routererrors.NotInterested, "not interested")
+					secondError = fmt.Errorf("not interested: %w", routererrors.NotInterested)
 					fallbackError = nil
 					req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
 					Expect(err).NotTo(HaveOccurred())
@@ -508,6 +3087,50 @@ var _ = Describe("EventRouter", func() {
 			})
 		})
 
+		Context("when more than one fallback handlers are added by AddFallback", func() {
+			It("falls back to the next one when a fallback handler returned NotInterested", func() {
+				numFirstHandlerCalled := 0
+				r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numFirstHandlerCalled++
+					return routererrors.NotInterested
+				}))
+				numSecondHandlerCalled := 0
+				r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numSecondHandlerCalled++
+					return nil
+				}))
+				numThirdHandlerCalled := 0
+				r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numThirdHandlerCalled++
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numFirstHandlerCalled).To(Equal(1))
+				Expect(numSecondHandlerCalled).To(Equal(1))
+				Expect(numThirdHandlerCalled).To(Equal(0))
+			})
+
+			It("responds with the error returned by a fallback handler", func() {
+				r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.NotInterested
+				}))
+				r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusTeapot))
+			})
+		})
+
 		Context("when more than one fallback handlers are registered", func() {
 			It("uses the last one", func() {
 				numFirstHandlerCalled := 0
@@ -544,8 +3167,16 @@ func NewSignedRequest(signingSecret string, body string, ts *time.Time) (*http.R
 	if err != nil {
 		return nil, err
 	}
-	if err := testutils.AddSignature(req.Header, []byte(signingSecret), []byte(body), now); err != nil {
+	if err := signature.AddSignature(req.Header, []byte(signingSecret), []byte(body), now); err != nil {
 		return nil, err
 	}
 	return req, nil
 }
+
+func handleMessageForResultSink(_ context.Context, _ *slackevents.MessageEvent) error {
+	return nil
+}
+
+func handleFallbackForResultSink(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+	return nil
+}
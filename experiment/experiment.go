@@ -0,0 +1,187 @@
+// Package experiment routes events to one of several handlers deterministically, so that new behaviors of bots can be A/B tested.
+//
+// Each event is assigned to a variant by its key (e.g. the user who triggered it), so the same user always sees the same variant:
+//
+//	r.On(slackevents.Message, experiment.Split(experiment.ByUser, map[string]eventrouter.Handler{
+//		"control":   currentHandler,
+//		"treatment": newHandler,
+//	}, experiment.WithName("new-greeting"), experiment.WithWeights(map[string]int{"control": 90, "treatment": 10})))
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/enrich"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// KeyFunc returns the key that determines the variant of the event, or an empty string if the event has no key.
+type KeyFunc func(ctx context.Context, e *slackevents.EventsAPIEvent) string
+
+// ByUser assigns variants per user who triggered events.
+func ByUser(_ context.Context, e *slackevents.EventsAPIEvent) string {
+	return enrich.UserID(e)
+}
+
+// ByChannel assigns variants per conversation where events happened.
+func ByChannel(_ context.Context, e *slackevents.EventsAPIEvent) string {
+	return enrich.ChannelID(e)
+}
+
+// Exposure records that an event is routed to a variant of an experiment.
+type Exposure struct {
+	// Experiment is the name of the experiment given by WithName.
+	Experiment string
+
+	// Key is the key returned by KeyFunc.
+	Key string
+
+	// Variant is the name of the variant that the event is routed to.
+	Variant string
+}
+
+// Option configures the Experiment.
+type Option interface {
+	apply(*Experiment)
+}
+
+type optionFunc func(*Experiment)
+
+func (f optionFunc) apply(x *Experiment) {
+	f(x)
+}
+
+// WithName sets the name of the experiment.
+//
+// The name is also used as a seed of assignment, so different experiments split the same keys differently.
+func WithName(name string) Option {
+	return optionFunc(func(x *Experiment) {
+		x.name = name
+	})
+}
+
+// WithWeights sets relative weights of variants (e.g. percentages). If not set, all variants have the same weight.
+//
+// Every variant must have a weight. Note that changing weights may move keys to other variants.
+func WithWeights(weights map[string]int) Option {
+	return optionFunc(func(x *Experiment) {
+		x.weights = weights
+	})
+}
+
+// WithControl sets the variant that handles events without keys. Such events are not reported by OnExposure.
+//
+// If not set, events without keys are ignored.
+func WithControl(variant string) Option {
+	return optionFunc(func(x *Experiment) {
+		x.control = variant
+	})
+}
+
+// OnExposure sets a hook that is called before an event is passed to a variant.
+func OnExposure(hook func(ctx context.Context, exp *Exposure)) Option {
+	return optionFunc(func(x *Experiment) {
+		x.exposureHook = hook
+	})
+}
+
+type variant struct {
+	name  string
+	upper uint64 // exclusive upper bound of buckets assigned to this variant
+}
+
+// Experiment is an eventrouter.Handler that passes events to one of variants.
+type Experiment struct {
+	keyFunc      KeyFunc
+	handlers     map[string]eventrouter.Handler
+	name         string
+	weights      map[string]int
+	control      string
+	exposureHook func(context.Context, *Exposure)
+	variants     []variant
+	total        uint64
+}
+
+// New creates a new Experiment that splits events into `variants` by keys returned by `keyFunc`.
+func New(keyFunc KeyFunc, variants map[string]eventrouter.Handler, opts ...Option) (*Experiment, error) {
+	if len(variants) == 0 {
+		return nil, errors.New("experiment: no variants")
+	}
+	x := &Experiment{keyFunc: keyFunc, handlers: variants}
+	for _, o := range opts {
+		o.apply(x)
+	}
+	if _, ok := variants[x.control]; x.control != "" && !ok {
+		return nil, fmt.Errorf("experiment: unknown control variant %q", x.control)
+	}
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		weight := 1
+		if x.weights != nil {
+			w, ok := x.weights[name]
+			if !ok {
+				return nil, fmt.Errorf("experiment: no weight for variant %q", name)
+			}
+			if w < 0 {
+				return nil, fmt.Errorf("experiment: negative weight for variant %q", name)
+			}
+			weight = w
+		}
+		x.total += uint64(weight)
+		x.variants = append(x.variants, variant{name: name, upper: x.total})
+	}
+	if x.total == 0 {
+		return nil, errors.New("experiment: total weight must be positive")
+	}
+	return x, nil
+}
+
+// Split is the same as New except that it panics if the configuration is invalid.
+func Split(keyFunc KeyFunc, variants map[string]eventrouter.Handler, opts ...Option) *Experiment {
+	x, err := New(keyFunc, variants, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// Variant returns the name of the variant assigned to `key`.
+func (x *Experiment) Variant(key string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(x.name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	bucket := h.Sum64() % x.total
+	for _, v := range x.variants {
+		if bucket < v.upper {
+			return v.name
+		}
+	}
+	return x.variants[len(x.variants)-1].name
+}
+
+func (x *Experiment) HandleEventsAPIEvent(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+	key := x.keyFunc(ctx, e)
+	if key == "" {
+		if x.control == "" {
+			return routererrors.NotInterested
+		}
+		return x.handlers[x.control].HandleEventsAPIEvent(ctx, e)
+	}
+	name := x.Variant(key)
+	if x.exposureHook != nil {
+		x.exposureHook(ctx, &Exposure{Experiment: x.name, Key: key, Variant: name})
+	}
+	return x.handlers[name].HandleEventsAPIEvent(ctx, e)
+}
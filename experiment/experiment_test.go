@@ -0,0 +1,141 @@
+package experiment_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/experiment"
+)
+
+var _ = Describe("Experiment", func() {
+	var (
+		calls    []string
+		variants map[string]eventrouter.Handler
+		event    = func(user string) *slackevents.EventsAPIEvent {
+			return &slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: slackevents.Message,
+					Data: &slackevents.MessageEvent{User: user, Channel: "C0123"},
+				},
+			}
+		}
+	)
+	BeforeEach(func() {
+		calls = nil
+		variants = map[string]eventrouter.Handler{}
+		for _, name := range []string{"control", "treatment"} {
+			name := name
+			variants[name] = eventrouter.HandlerFunc(func(context.Context, *slackevents.EventsAPIEvent) error {
+				calls = append(calls, name)
+				return nil
+			})
+		}
+	})
+
+	Describe("New", func() {
+		It("returns an error if there are no variants", func() {
+			_, err := experiment.New(experiment.ByUser, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if a variant has no weight", func() {
+			_, err := experiment.New(experiment.ByUser, variants, experiment.WithWeights(map[string]int{"control": 100}))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the total weight is zero", func() {
+			_, err := experiment.New(experiment.ByUser, variants, experiment.WithWeights(map[string]int{"control": 0, "treatment": 0}))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the control variant does not exist", func() {
+			_, err := experiment.New(experiment.ByUser, variants, experiment.WithControl("unknown"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Variant", func() {
+		It("assigns the same variant to the same key", func() {
+			x := experiment.Split(experiment.ByUser, variants, experiment.WithName("exp"))
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("U%04d", i)
+				Expect(x.Variant(key)).To(Equal(x.Variant(key)))
+			}
+		})
+
+		It("splits keys roughly by weights", func() {
+			x := experiment.Split(experiment.ByUser, variants, experiment.WithName("exp"),
+				experiment.WithWeights(map[string]int{"control": 90, "treatment": 10}))
+			numTreatment := 0
+			for i := 0; i < 10000; i++ {
+				if x.Variant(fmt.Sprintf("U%04d", i)) == "treatment" {
+					numTreatment++
+				}
+			}
+			Expect(numTreatment).To(BeNumerically("~", 1000, 200))
+		})
+
+		It("never assigns variants with zero weight", func() {
+			x := experiment.Split(experiment.ByUser, variants, experiment.WithWeights(map[string]int{"control": 100, "treatment": 0}))
+			for i := 0; i < 100; i++ {
+				Expect(x.Variant(fmt.Sprintf("U%04d", i))).To(Equal("control"))
+			}
+		})
+
+		It("splits keys differently in different experiments", func() {
+			x := experiment.Split(experiment.ByUser, variants, experiment.WithName("exp1"))
+			y := experiment.Split(experiment.ByUser, variants, experiment.WithName("exp2"))
+			numDifferent := 0
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("U%04d", i)
+				if x.Variant(key) != y.Variant(key) {
+					numDifferent++
+				}
+			}
+			Expect(numDifferent).To(BeNumerically(">", 0))
+		})
+	})
+
+	Describe("HandleEventsAPIEvent", func() {
+		It("passes events to the assigned variant and reports exposures", func() {
+			var exposures []*experiment.Exposure
+			x := experiment.Split(experiment.ByUser, variants, experiment.WithName("exp"),
+				experiment.OnExposure(func(_ context.Context, exp *experiment.Exposure) {
+					exposures = append(exposures, exp)
+				}))
+			Expect(x.HandleEventsAPIEvent(context.Background(), event("U0001"))).To(Succeed())
+			Expect(calls).To(Equal([]string{x.Variant("U0001")}))
+			Expect(exposures).To(Equal([]*experiment.Exposure{{Experiment: "exp", Key: "U0001", Variant: x.Variant("U0001")}}))
+		})
+
+		It("can split events by channels", func() {
+			x := experiment.Split(experiment.ByChannel, variants)
+			Expect(x.HandleEventsAPIEvent(context.Background(), event("U0001"))).To(Succeed())
+			Expect(calls).To(Equal([]string{x.Variant("C0123")}))
+		})
+
+		Context("when the event has no key", func() {
+			It("ignores the event", func() {
+				x := experiment.Split(experiment.ByUser, variants)
+				err := x.HandleEventsAPIEvent(context.Background(), event(""))
+				Expect(err).To(MatchError(routererrors.NotInterested))
+				Expect(calls).To(BeEmpty())
+			})
+
+			It("passes the event to the control variant without reporting exposures", func() {
+				numExposures := 0
+				x := experiment.Split(experiment.ByUser, variants, experiment.WithControl("control"),
+					experiment.OnExposure(func(context.Context, *experiment.Exposure) { numExposures++ }))
+				Expect(x.HandleEventsAPIEvent(context.Background(), event(""))).To(Succeed())
+				Expect(calls).To(Equal([]string{"control"}))
+				Expect(numExposures).To(Equal(0))
+			})
+		})
+	})
+})
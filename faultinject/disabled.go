@@ -0,0 +1,7 @@
+//go:build !slackrouter_faultinject
+// +build !slackrouter_faultinject
+
+package faultinject
+
+// Enabled reports whether faults are injected, i.e. whether the package is built with the `slackrouter_faultinject` build tag.
+const Enabled = false
@@ -0,0 +1,133 @@
+// Package faultinject injects artificial latency and errors into event processing.
+//
+// This is intended to verify retry and dead-letter handling against realistic failure modes.
+// Faults are only injected in builds with the `slackrouter_faultinject` build tag; otherwise Injector does nothing,
+// so it is safe to leave it configured in production code:
+//
+//	go test -tags slackrouter_faultinject ./...
+package faultinject
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Fault is a kind of fault to inject.
+type Fault struct {
+	// Latency is an artificial delay before handlers are called.
+	Latency time.Duration
+
+	// Err is an error that makes the Router fail without calling handlers. If nil, handlers are called as usual.
+	Err error
+
+	// Percent is the percentage (0-100) of events to inject the fault into.
+	Percent float64
+
+	// EventTypes are the types of inner events to inject the fault into. If empty, any events are affected.
+	EventTypes []string
+}
+
+// Latency returns a Fault that delays `percent` percent of events of `eventTypes` by `d`.
+func Latency(d time.Duration, percent float64, eventTypes ...string) *Fault {
+	return &Fault{Latency: d, Percent: percent, EventTypes: eventTypes}
+}
+
+// Error returns a Fault that makes `percent` percent of events of `eventTypes` fail with `err`.
+//
+// Use `routererrors.HttpError` to control the status code that the Router responds with.
+func Error(err error, percent float64, eventTypes ...string) *Fault {
+	return &Fault{Err: err, Percent: percent, EventTypes: eventTypes}
+}
+
+func (f *Fault) appliesTo(eventType string) bool {
+	if len(f.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range f.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Option configures the Injector.
+type Option interface {
+	apply(*Injector)
+}
+
+type optionFunc func(*Injector)
+
+func (f optionFunc) apply(i *Injector) {
+	f(i)
+}
+
+// WithRandFunc sets a function that returns a random number in [0, 1). This is mainly intended to make tests deterministic.
+func WithRandFunc(rand func() float64) Option {
+	return optionFunc(func(i *Injector) {
+		i.rand = rand
+	})
+}
+
+// OnInject sets a hook that is called every time a fault is injected.
+func OnInject(hook func(ctx context.Context, e *slackevents.EventsAPIEvent, f *Fault)) Option {
+	return optionFunc(func(i *Injector) {
+		i.injectHook = hook
+	})
+}
+
+// Injector injects Faults into events. It implements `eventrouter.Enricher`, so it can be set by `eventrouter.WithEnricher`.
+//
+// Faults are evaluated independently in the order they are given.
+type Injector struct {
+	faults     []*Fault
+	rand       func() float64
+	injectHook func(context.Context, *slackevents.EventsAPIEvent, *Fault)
+}
+
+// New creates a new Injector.
+func New(faults []*Fault, opts ...Option) *Injector {
+	i := &Injector{faults: faults, rand: rand.Float64}
+	for _, o := range opts {
+		o.apply(i)
+	}
+	return i
+}
+
+// Enrich injects faults into `e` if Enabled. It returns ctx as is.
+func (i *Injector) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	if !Enabled {
+		return ctx, nil
+	}
+	for _, f := range i.faults {
+		if !f.appliesTo(e.InnerEvent.Type) || f.Percent <= 0 || i.rand()*100 >= f.Percent {
+			continue
+		}
+		if i.injectHook != nil {
+			i.injectHook(ctx, e, f)
+		}
+		if f.Latency > 0 {
+			if err := sleep(ctx, f.Latency); err != nil {
+				return ctx, err
+			}
+		}
+		if f.Err != nil {
+			return ctx, f.Err
+		}
+	}
+	return ctx, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,13 @@
+package faultinject_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFaultinject(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Faultinject Suite")
+}
@@ -0,0 +1,129 @@
+package faultinject_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/faultinject"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+var _ = Describe("Faultinject", func() {
+	var (
+		messageEvent = &slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Type: slackevents.Message}}
+		mention      = &slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Type: slackevents.AppMention}}
+		always       = faultinject.WithRandFunc(func() float64 { return 0 })
+		never        = faultinject.WithRandFunc(func() float64 { return 0.999 })
+	)
+
+	Context("when built without the build tag", func() {
+		BeforeEach(func() {
+			if faultinject.Enabled {
+				Skip("faults are enabled")
+			}
+		})
+
+		It("does nothing", func() {
+			i := faultinject.New([]*faultinject.Fault{faultinject.Error(errors.New("injected"), 100)}, always)
+			_, err := i.Enrich(context.Background(), messageEvent)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when built with the build tag", func() {
+		BeforeEach(func() {
+			if !faultinject.Enabled {
+				Skip("faults are disabled; run with -tags slackrouter_faultinject")
+			}
+		})
+
+		Describe("Error", func() {
+			It("injects the error into sampled events", func() {
+				i := faultinject.New([]*faultinject.Fault{faultinject.Error(errors.New("injected"), 50)}, always)
+				_, err := i.Enrich(context.Background(), messageEvent)
+				Expect(err).To(MatchError("injected"))
+			})
+
+			It("does not inject the error into events that are not sampled", func() {
+				i := faultinject.New([]*faultinject.Fault{faultinject.Error(errors.New("injected"), 50)}, never)
+				_, err := i.Enrich(context.Background(), messageEvent)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("only affects the given event types", func() {
+				i := faultinject.New([]*faultinject.Fault{faultinject.Error(errors.New("injected"), 100, slackevents.AppMention)}, always)
+				_, err := i.Enrich(context.Background(), messageEvent)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = i.Enrich(context.Background(), mention)
+				Expect(err).To(MatchError("injected"))
+			})
+		})
+
+		Describe("Latency", func() {
+			It("delays events", func() {
+				i := faultinject.New([]*faultinject.Fault{faultinject.Latency(20*time.Millisecond, 100)}, always)
+				start := time.Now()
+				_, err := i.Enrich(context.Background(), messageEvent)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+			})
+
+			It("stops waiting when the context is canceled", func() {
+				i := faultinject.New([]*faultinject.Fault{faultinject.Latency(time.Hour, 100)}, always)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := i.Enrich(ctx, messageEvent)
+				Expect(err).To(MatchError(context.Canceled))
+			})
+		})
+
+		Describe("OnInject", func() {
+			It("calls the hook with the injected fault", func() {
+				var injected []*faultinject.Fault
+				fault := faultinject.Latency(time.Millisecond, 100)
+				i := faultinject.New([]*faultinject.Fault{fault}, always, faultinject.OnInject(func(_ context.Context, _ *slackevents.EventsAPIEvent, f *faultinject.Fault) {
+					injected = append(injected, f)
+				}))
+				_, err := i.Enrich(context.Background(), messageEvent)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(injected).To(Equal([]*faultinject.Fault{fault}))
+			})
+		})
+
+		It("makes the Router respond with the injected error", func() {
+			numCalled := 0
+			i := faultinject.New([]*faultinject.Fault{faultinject.Error(routererrors.HttpError(http.StatusServiceUnavailable), 100)}, always)
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithEnricher(i))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numCalled++
+				return nil
+			}))
+			content := `{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {"type": "message", "channel": "C2147483705", "user": "U2147483697", "text": "Hello world", "ts": "1355517523.000005"},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(numCalled).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,167 @@
+// Package filecomment provides handlers to process `file_comment_*` events, which are sent when comments on files are added, edited or deleted.
+//
+// Canvases are files, so comments on canvases can be selected by File and FileIn with their IDs as well.
+//
+// slack-go parses these events into types for the RTM API, which lack some fields of the Events API,
+// so the Router decodes them into the types in this package instead.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/file_comment_added
+//   - https://api.slack.com/events/file_comment_edited
+//   - https://api.slack.com/events/file_comment_deleted
+package filecomment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// Types of events that this package processes.
+const (
+	// FileCommentAdded is the type of events that are sent when a comment is added to a file.
+	FileCommentAdded = "file_comment_added"
+
+	// FileCommentEdited is the type of events that are sent when a comment on a file is edited.
+	FileCommentEdited = "file_comment_edited"
+
+	// FileCommentDeleted is the type of events that are sent when a comment on a file is deleted.
+	FileCommentDeleted = "file_comment_deleted"
+)
+
+// Event is an inner event of any of FileCommentAdded, FileCommentEdited and FileCommentDeleted.
+type Event struct {
+	Type string `json:"type"`
+
+	// Comment is the comment after the change. For FileCommentDeleted, only `Comment.ID` is available.
+	Comment Comment `json:"comment"`
+
+	// FileID is the ID of the file (or the canvas) that the comment belongs to.
+	FileID string `json:"file_id"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// Comment is a comment on a file.
+type Comment struct {
+	ID        string `json:"id"`
+	Created   int64  `json:"created"`
+	Timestamp int64  `json:"timestamp"`
+
+	// User is the ID of the user who wrote the comment.
+	User string `json:"user"`
+
+	// Comment is the text of the comment.
+	Comment string `json:"comment"`
+}
+
+// UnmarshalJSON decodes a comment, which is either an object or an ID (in FileCommentDeleted events).
+func (c *Comment) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*c = Comment{}
+		return json.Unmarshal(data, &c.ID)
+	}
+	type comment Comment
+	return json.Unmarshal(data, (*comment)(c))
+}
+
+// Handler processes `file_comment_*` events.
+type Handler interface {
+	HandleFileCommentEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleFileCommentEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type filePredicate struct {
+	ids []string
+}
+
+// File is a predicate that is considered to be "true" if and only if the comment belongs to any of the given files.
+func File(ids ...string) Predicate {
+	return &filePredicate{ids: ids}
+}
+
+func (p *filePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		for _, id := range p.ids {
+			if e.FileID == id {
+				return h.HandleFileCommentEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *filePredicate) String() string {
+	return fmt.Sprintf("File(%s)", strings.Join(p.ids, ", "))
+}
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+type fileInPredicate struct {
+	set Set
+}
+
+// FileIn is a predicate that is considered to be "true" if and only if the comment belongs to a file in `set`
+// (e.g. documents under review).
+func FileIn(set Set) Predicate {
+	return &fileInPredicate{set: set}
+}
+
+func (p *fileInPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if !p.set.Contains(ctx, e.FileID) {
+			return errors.NotInterested
+		}
+		return h.HandleFileCommentEvent(ctx, e)
+	})
+}
+
+func (p *fileInPredicate) String() string {
+	return fmt.Sprintf("FileIn(%v)", p.set)
+}
+
+type userPredicate struct {
+	id string
+}
+
+// User is a predicate that is considered to be "true" if and only if the comment is written by the given user.
+//
+// It is never "true" for FileCommentDeleted events, since they don't tell who wrote the comment.
+func User(id string) Predicate {
+	return &userPredicate{id: id}
+}
+
+func (p *userPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.Comment.User != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleFileCommentEvent(ctx, e)
+	})
+}
+
+func (p *userPredicate) String() string {
+	return fmt.Sprintf("User(%s)", p.id)
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	return routerutils.Build(h, preds, Predicate.Wrap, isOutermost)
+}
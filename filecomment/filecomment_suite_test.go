@@ -0,0 +1,13 @@
+package filecomment_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFilecomment(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Filecomment Suite")
+}
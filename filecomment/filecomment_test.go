@@ -0,0 +1,95 @@
+package filecomment_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/filecomment"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+type staticSet map[string]bool
+
+func (s staticSet) Contains(_ context.Context, value string) bool {
+	return s[value]
+}
+
+var _ = Describe("Filecomment", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = filecomment.HandlerFunc(func(_ context.Context, _ *filecomment.Event) error {
+			numHandlerCalled++
+			return nil
+		})
+		event = &filecomment.Event{FileID: "F001", Comment: filecomment.Comment{ID: "Fc001", User: "U001"}}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Comment", func() {
+		It("decodes comments", func() {
+			var c filecomment.Comment
+			Expect(json.Unmarshal([]byte(`{"id": "Fc001", "created": 1356032811, "timestamp": 1356032811, "user": "U001", "comment": "LGTM"}`), &c)).To(Succeed())
+			Expect(c).To(Equal(filecomment.Comment{ID: "Fc001", Created: 1356032811, Timestamp: 1356032811, User: "U001", Comment: "LGTM"}))
+		})
+
+		It("decodes IDs of deleted comments", func() {
+			var c filecomment.Comment
+			Expect(json.Unmarshal([]byte(`"Fc001"`), &c)).To(Succeed())
+			Expect(c).To(Equal(filecomment.Comment{ID: "Fc001"}))
+		})
+	})
+
+	Describe("File", func() {
+		It("calls the inner handler if the comment belongs to any of the files", func() {
+			h := filecomment.Build(innerHandler, filecomment.File("F002", "F001"))
+			Expect(h.HandleFileCommentEvent(ctx, event)).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := filecomment.Build(innerHandler, filecomment.File("F002"))
+			Expect(h.HandleFileCommentEvent(ctx, event)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FileIn", func() {
+		It("calls the inner handler only if the file is in the set", func() {
+			Expect(filecomment.Build(innerHandler, filecomment.FileIn(staticSet{"F001": true})).HandleFileCommentEvent(ctx, event)).To(Succeed())
+			Expect(filecomment.Build(innerHandler, filecomment.FileIn(staticSet{"F002": true})).HandleFileCommentEvent(ctx, event)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("User", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := filecomment.Build(innerHandler, filecomment.User("U001"))
+			Expect(h.HandleFileCommentEvent(ctx, event)).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := filecomment.Build(innerHandler, filecomment.User("U002"))
+			Expect(h.HandleFileCommentEvent(ctx, event)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("applies generic predicates", func() {
+			h := filecomment.Build(innerHandler, filecomment.FromGenericPredicate(router.Filter(func(context.Context, *filecomment.Event) bool {
+				return false
+			})))
+			Expect(h.HandleFileCommentEvent(ctx, event)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+})
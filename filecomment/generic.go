@@ -0,0 +1,53 @@
+package filecomment
+
+import (
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// ToGeneric converts `h` to a generic `router.Handler`.
+func ToGeneric(h Handler) router.Handler[*Event] {
+	return router.HandlerFunc[*Event](h.HandleFileCommentEvent)
+}
+
+// FromGeneric converts a generic `router.Handler` to a Handler.
+func FromGeneric(h router.Handler[*Event]) Handler {
+	return HandlerFunc(h.Handle)
+}
+
+type genericPredicate struct {
+	pred router.Predicate[*Event]
+}
+
+// FromGenericPredicate converts a generic `router.Predicate` to a Predicate, so that generic middleware can be used with this package.
+func FromGenericPredicate(p router.Predicate[*Event]) Predicate {
+	return &genericPredicate{pred: p}
+}
+
+func (p *genericPredicate) Wrap(h Handler) Handler {
+	return FromGeneric(p.pred.Wrap(ToGeneric(h)))
+}
+
+func (p *genericPredicate) String() string {
+	if s, ok := p.pred.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p.pred)
+}
+
+// Unwrap returns the generic predicate, so that route introspection can see through the adapter.
+func (p *genericPredicate) Unwrap() interface{} {
+	return p.pred
+}
+
+func isOutermost(p Predicate) bool {
+	switch p := p.(type) {
+	case router.Outermost:
+		return true
+	case *genericPredicate:
+		_, ok := p.pred.(router.Outermost)
+		return ok
+	}
+	return false
+}
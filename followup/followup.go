@@ -0,0 +1,316 @@
+// Package followup escalates mentions of bots that nobody answers within a certain time.
+//
+// Handlers of `app_mention` mark mentions as pending, and a Tracker resolves them when someone reacts to or replies to them.
+// Mentions that are still pending after the window are escalated (e.g. by posting a reminder to the thread):
+//
+//	tracker := followup.New(followup.NewMemoryStore(), 30*time.Minute,
+//		followup.PostReply(slack.New(botToken), "Nobody has answered this yet. <!subteam^S0123|oncall>, could you take a look?"))
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(tracker))
+//	r.OnAppMention(appmention.HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+//		return tracker.MarkPending(ctx, e)
+//	}))
+//	go tracker.Run(ctx, time.Minute)
+//
+// The Tracker observes reactions and replies as an Enricher, so it doesn't prevent other handlers from processing them.
+// Pending mentions are kept in a Store rather than in timers, so escalations survive restarts when the Store is persistent.
+package followup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Item is a mention that is waiting for an answer.
+type Item struct {
+	Channel string
+
+	// Timestamp is the timestamp of the mention.
+	Timestamp string
+
+	// ThreadTimestamp is the timestamp of the parent message if the mention is posted in a thread.
+	ThreadTimestamp string
+
+	// User is the ID of the user who mentioned the bot.
+	User string
+
+	// Deadline is the time when the mention is escalated unless it is answered.
+	// It is pushed forward when the escalation fails.
+	Deadline time.Time
+
+	// Attempts is the number of times the escalation has failed.
+	Attempts int
+}
+
+// Thread returns the timestamp of the thread that answers to the mention are posted to.
+func (i *Item) Thread() string {
+	if i.ThreadTimestamp != "" {
+		return i.ThreadTimestamp
+	}
+	return i.Timestamp
+}
+
+// Store persists pending mentions.
+type Store interface {
+	// Put saves `item`, replacing the item of the same mention if any.
+	Put(ctx context.Context, item *Item) error
+
+	// Resolve removes the item of the mention `ts` in `channel`, as well as items of mentions in the thread `ts`,
+	// unless they are mentioned by `user` (i.e. users can't answer their own mentions).
+	// It does nothing if there are no such items.
+	Resolve(ctx context.Context, channel, ts, user string) error
+
+	// TakeDue removes items whose deadlines are not after `now` and returns them.
+	TakeDue(ctx context.Context, now time.Time) ([]*Item, error)
+}
+
+// MemoryStore is a Store that keeps pending mentions in memory.
+//
+// Since it doesn't survive restarts, it is mainly intended for tests.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]*Item
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]*Item)}
+}
+
+func itemKey(channel, ts string) string {
+	return channel + "/" + ts
+}
+
+func (s *MemoryStore) Put(_ context.Context, item *Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[itemKey(item.Channel, item.Timestamp)] = item
+	return nil
+}
+
+func (s *MemoryStore) Resolve(_ context.Context, channel, ts, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, item := range s.items {
+		if item.Channel == channel && (item.Timestamp == ts || item.ThreadTimestamp == ts) && item.User != user {
+			delete(s.items, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) TakeDue(_ context.Context, now time.Time) ([]*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]*Item, 0)
+	for key, item := range s.items {
+		if !item.Deadline.After(now) {
+			due = append(due, item)
+			delete(s.items, key)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Deadline.Before(due[j].Deadline)
+	})
+	return due, nil
+}
+
+// EscalateFunc is called when a pending mention is not answered within the window.
+type EscalateFunc func(ctx context.Context, item *Item) error
+
+// Poster is a subset of `slack.Client` that PostReply uses.
+type Poster interface {
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+}
+
+var _ Poster = &slack.Client{}
+
+// PostReply returns an EscalateFunc that posts `text` to the thread of the mention.
+func PostReply(client Poster, text string) EscalateFunc {
+	return func(ctx context.Context, item *Item) error {
+		_, _, err := client.PostMessageContext(ctx, item.Channel, slack.MsgOptionText(text, false), slack.MsgOptionTS(item.Thread()))
+		if err != nil {
+			return fmt.Errorf("failed to post a follow-up to %s/%s: %w", item.Channel, item.Thread(), err)
+		}
+		return nil
+	}
+}
+
+// Option configures the Tracker.
+type Option interface {
+	apply(*Tracker)
+}
+
+type optionFunc func(*Tracker)
+
+func (f optionFunc) apply(t *Tracker) {
+	f(t)
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(t *Tracker) {
+		t.now = now
+	})
+}
+
+// Default values of WithRetry.
+const (
+	DefaultRetryBackoff = time.Minute
+	DefaultMaxAttempts  = 5
+)
+
+// WithRetry sets how the Tracker retries escalations that fail.
+//
+// A failed escalation is retried after `backoff`, which doubles on every failure, and the mention is dropped after `maxAttempts` failures.
+// If not set (or the values are not positive), DefaultRetryBackoff and DefaultMaxAttempts are used.
+func WithRetry(backoff time.Duration, maxAttempts int) Option {
+	return optionFunc(func(t *Tracker) {
+		if backoff > 0 {
+			t.retryBackoff = backoff
+		}
+		if maxAttempts > 0 {
+			t.maxAttempts = maxAttempts
+		}
+	})
+}
+
+// OnError sets a hook that is called when Run fails to check or escalate pending mentions.
+//
+// Mentions dropped after too many failed escalations are reported to this hook by Check, with errors that wrap ErrGaveUp.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(t *Tracker) {
+		t.errorHook = hook
+	})
+}
+
+// Tracker keeps track of pending mentions and escalates unanswered ones.
+type Tracker struct {
+	store        Store
+	window       time.Duration
+	escalate     EscalateFunc
+	now          func() time.Time
+	errorHook    func(ctx context.Context, err error)
+	retryBackoff time.Duration
+	maxAttempts  int
+}
+
+// New creates a new Tracker that calls `escalate` for mentions that are not answered within `window`.
+func New(store Store, window time.Duration, escalate EscalateFunc, opts ...Option) *Tracker {
+	t := &Tracker{
+		store:        store,
+		window:       window,
+		escalate:     escalate,
+		now:          time.Now,
+		retryBackoff: DefaultRetryBackoff,
+		maxAttempts:  DefaultMaxAttempts,
+	}
+	for _, o := range opts {
+		o.apply(t)
+	}
+	return t
+}
+
+// MarkPending marks the mention `e` as pending, so that it is escalated unless answered within the window.
+//
+// Marking the same mention more than once (e.g. when Slack retries the event) extends its deadline.
+func (t *Tracker) MarkPending(ctx context.Context, e *slackevents.AppMentionEvent) error {
+	item := &Item{
+		Channel:         e.Channel,
+		Timestamp:       e.TimeStamp,
+		ThreadTimestamp: e.ThreadTimeStamp,
+		User:            e.User,
+		Deadline:        t.now().Add(t.window),
+	}
+	if err := t.store.Put(ctx, item); err != nil {
+		return fmt.Errorf("failed to mark %s/%s as pending: %w", item.Channel, item.Timestamp, err)
+	}
+	return nil
+}
+
+// Enrich resolves pending mentions if `e` answers them, which is either a reaction to a mention or a reply in its thread
+// by someone other than the user who mentioned. Messages posted by bots (including the escalations) are not considered as answers.
+//
+// It never changes the context.
+func (t *Tracker) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	var channel, ts, user string
+	switch ev := e.InnerEvent.Data.(type) {
+	case *slackevents.ReactionAddedEvent:
+		if ev.Item.Type == "message" {
+			channel, ts, user = ev.Item.Channel, ev.Item.Timestamp, ev.User
+		}
+	case *slackevents.MessageEvent:
+		if ev.ThreadTimeStamp != "" && ev.ThreadTimeStamp != ev.TimeStamp && ev.BotID == "" {
+			channel, ts, user = ev.Channel, ev.ThreadTimeStamp, ev.User
+		}
+	}
+	if channel == "" {
+		return ctx, nil
+	}
+	if err := t.store.Resolve(ctx, channel, ts, user); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s/%s: %w", channel, ts, err)
+	}
+	return ctx, nil
+}
+
+// ErrGaveUp indicates that the Tracker dropped a mention because its escalation failed too many times. See WithRetry.
+var ErrGaveUp = errors.New("gave up escalating")
+
+// Check escalates pending mentions whose deadlines have passed.
+//
+// Mentions that fail to be escalated are put back to the Store with their deadlines pushed forward (see WithRetry),
+// so that they are retried by later checks. It returns the first error that occurs, except for mentions that are dropped,
+// which are reported to the hook set by OnError instead.
+func (t *Tracker) Check(ctx context.Context) error {
+	now := t.now()
+	due, err := t.store.TakeDue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to take pending mentions: %w", err)
+	}
+	var firstErr error
+	for _, item := range due {
+		err := t.escalate(ctx, item)
+		if err == nil {
+			continue
+		}
+		item.Attempts++
+		if item.Attempts >= t.maxAttempts {
+			if t.errorHook != nil {
+				t.errorHook(ctx, fmt.Errorf("%w %s/%s after %d attempts: %s", ErrGaveUp, item.Channel, item.Timestamp, item.Attempts, err.Error()))
+			}
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		item.Deadline = now.Add(t.retryBackoff << (item.Attempts - 1))
+		if err := t.store.Put(ctx, item); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to put back %s/%s: %w", item.Channel, item.Timestamp, err)
+		}
+	}
+	return firstErr
+}
+
+// Run calls Check every `interval` until `ctx` is done. Errors are reported to the hook set by OnError.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Check(ctx); err != nil && t.errorHook != nil {
+				t.errorHook(ctx, err)
+			}
+		}
+	}
+}
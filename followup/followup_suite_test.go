@@ -0,0 +1,13 @@
+package followup_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFollowup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Followup Suite")
+}
@@ -0,0 +1,179 @@
+package followup_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/followup"
+)
+
+type fakePoster struct {
+	channels []string
+	err      error
+}
+
+func (p *fakePoster) PostMessageContext(_ context.Context, channelID string, _ ...slack.MsgOption) (string, string, error) {
+	if p.err != nil {
+		return "", "", p.err
+	}
+	p.channels = append(p.channels, channelID)
+	return channelID, "9999.0000", nil
+}
+
+func mention(user, ts, threadTS string) *slackevents.AppMentionEvent {
+	return &slackevents.AppMentionEvent{Type: slackevents.AppMention, User: user, Channel: "C001", TimeStamp: ts, ThreadTimeStamp: threadTS}
+}
+
+func reply(user, ts, threadTS string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.Message,
+			Data: &slackevents.MessageEvent{User: user, Channel: "C001", TimeStamp: ts, ThreadTimeStamp: threadTS},
+		},
+	}
+}
+
+func reactionAdded(user, ts string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.ReactionAdded,
+			Data: &slackevents.ReactionAddedEvent{User: user, Reaction: "eyes", Item: slackevents.Item{Type: "message", Channel: "C001", Timestamp: ts}},
+		},
+	}
+}
+
+var _ = Describe("Followup", func() {
+	var (
+		ctx       = context.Background()
+		now       time.Time
+		escalated []*followup.Item
+		failing   bool
+		tracker   *followup.Tracker
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+		escalated = nil
+		failing = false
+		tracker = followup.New(followup.NewMemoryStore(), 30*time.Minute, func(_ context.Context, item *followup.Item) error {
+			if failing {
+				return errors.New("failed to post")
+			}
+			escalated = append(escalated, item)
+			return nil
+		}, followup.WithNowFunc(func() time.Time { return now }))
+	})
+
+	enrich := func(e *slackevents.EventsAPIEvent) {
+		_, err := tracker.Enrich(ctx, e)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("escalates mentions that are not answered within the window", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		now = now.Add(29 * time.Minute)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(BeEmpty())
+		now = now.Add(time.Minute)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(HaveLen(1))
+		Expect(escalated[0].Timestamp).To(Equal("1111.0000"))
+		Expect(escalated[0].User).To(Equal("U001"))
+	})
+
+	It("escalates each mention only once", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(HaveLen(1))
+	})
+
+	It("does not escalate mentions that have reactions", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		enrich(reactionAdded("U002", "1111.0000"))
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(BeEmpty())
+	})
+
+	It("does not escalate mentions that have replies", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		enrich(reply("U002", "1112.0000", "1111.0000"))
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(BeEmpty())
+	})
+
+	It("does not escalate mentions in threads that have later replies", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", "1000.0000"))).To(Succeed())
+		enrich(reply("U002", "1112.0000", "1000.0000"))
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(BeEmpty())
+	})
+
+	It("does not consider replies by the user who mentioned as answers", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		enrich(reply("U001", "1112.0000", "1111.0000"))
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(HaveLen(1))
+	})
+
+	It("retries escalations that fail", func() {
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		now = now.Add(time.Hour)
+		failing = true
+		Expect(tracker.Check(ctx)).To(MatchError(ContainSubstring("failed to post")))
+		failing = false
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(BeEmpty())
+		now = now.Add(followup.DefaultRetryBackoff)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(escalated).To(HaveLen(1))
+	})
+
+	It("backs off and drops mentions whose escalations keep failing", func() {
+		var reported []error
+		tracker = followup.New(followup.NewMemoryStore(), 30*time.Minute, func(_ context.Context, _ *followup.Item) error {
+			return errors.New("failed to post")
+		}, followup.WithNowFunc(func() time.Time { return now }), followup.WithRetry(time.Minute, 3),
+			followup.OnError(func(_ context.Context, err error) { reported = append(reported, err) }))
+		Expect(tracker.MarkPending(ctx, mention("U001", "1111.0000", ""))).To(Succeed())
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(HaveOccurred())
+		now = now.Add(time.Minute - time.Second)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		now = now.Add(time.Second)
+		Expect(tracker.Check(ctx)).To(HaveOccurred())
+		now = now.Add(2 * time.Minute)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(reported).To(HaveLen(1))
+		Expect(reported[0]).To(MatchError(followup.ErrGaveUp))
+		now = now.Add(time.Hour)
+		Expect(tracker.Check(ctx)).To(Succeed())
+		Expect(reported).To(HaveLen(1))
+	})
+
+	Describe("PostReply", func() {
+		It("posts to the channel of the mention", func() {
+			poster := &fakePoster{}
+			escalate := followup.PostReply(poster, "ping")
+			Expect(escalate(ctx, &followup.Item{Channel: "C001", Timestamp: "1111.0000"})).To(Succeed())
+			Expect(poster.channels).To(Equal([]string{"C001"}))
+		})
+
+		It("returns an error when posting fails", func() {
+			escalate := followup.PostReply(&fakePoster{err: errors.New("channel_not_found")}, "ping")
+			err := escalate(ctx, &followup.Item{Channel: "C001", Timestamp: "1111.0000"})
+			Expect(err).To(MatchError(ContainSubstring("channel_not_found")))
+		})
+	})
+})
@@ -0,0 +1,148 @@
+// Package grpcadapter feeds requests from Slack that are forwarded via gRPC into routers.
+//
+// This is useful when Slack traffic is terminated at an edge gateway that forwards requests to internal services via gRPC.
+// The gateway forwards headers and bodies as is (see `grpcadapterpb/envelope.proto`),
+// so the router verifies request signatures in exactly the same way as it does for HTTP requests.
+//
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret))
+//	s := grpc.NewServer()
+//	grpcadapterpb.RegisterEventIngestionServer(s, grpcadapter.NewServer(r))
+//
+// This package is a separate module so that users of other packages don't depend on gRPC.
+package grpcadapter
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/genkami/go-slack-event-router/grpcadapter/grpcadapterpb"
+)
+
+// Server is an EventIngestionServer that dispatches requests to an http.Handler (typically `*eventrouter.Router`).
+type Server struct {
+	grpcadapterpb.UnimplementedEventIngestionServer
+
+	handler http.Handler
+	path    string
+}
+
+// Option configures the Server.
+type Option interface {
+	apply(*Server)
+}
+
+type optionFunc func(*Server)
+
+func (f optionFunc) apply(s *Server) {
+	f(s)
+}
+
+// WithPath sets the URL path of requests passed to the handler. The default is "/".
+//
+// This is useful when the handler is a mux that serves multiple routers.
+func WithPath(path string) Option {
+	return optionFunc(func(s *Server) {
+		s.path = path
+	})
+}
+
+// NewServer creates a new Server.
+func NewServer(h http.Handler, opts ...Option) *Server {
+	s := &Server{
+		handler: h,
+		path:    "/",
+	}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	return s
+}
+
+var _ grpcadapterpb.EventIngestionServer = &Server{}
+
+// Dispatch passes the forwarded request to the handler and returns its response.
+//
+// Errors from the handler (including verification failures) are reported by DispatchResponse.Status
+// rather than gRPC errors, so the gateway can respond to Slack with the same status code.
+func (s *Server) Dispatch(ctx context.Context, ev *grpcadapterpb.SignedEvent) (*grpcadapterpb.DispatchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.path, bytes.NewReader(ev.GetBody()))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range ev.GetHeaders() {
+		for _, v := range h.GetValues() {
+			req.Header.Add(h.GetName(), v)
+		}
+	}
+	req.ContentLength = int64(len(ev.GetBody()))
+	req.Body = ioutil.NopCloser(bytes.NewReader(ev.GetBody()))
+
+	w := newResponseWriter()
+	s.handler.ServeHTTP(w, req)
+	return w.response(), nil
+}
+
+// responseWriter is an http.ResponseWriter that buffers the response.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: make(http.Header)}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) response() *grpcadapterpb.DispatchResponse {
+	w.WriteHeader(http.StatusOK)
+	names := make([]string, 0, len(w.header))
+	for name := range w.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := make([]*grpcadapterpb.Header, 0, len(names))
+	for _, name := range names {
+		headers = append(headers, &grpcadapterpb.Header{Name: name, Values: w.header[name]})
+	}
+	return &grpcadapterpb.DispatchResponse{
+		Status:  int32(w.status),
+		Headers: headers,
+		Body:    w.body.Bytes(),
+	}
+}
+
+// ToSignedEvent converts an HTTP request from Slack into a SignedEvent. This is intended to be used by gateways.
+func ToSignedEvent(req *http.Request) (*grpcadapterpb.SignedEvent, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ev := &grpcadapterpb.SignedEvent{Body: body}
+	for _, name := range names {
+		ev.Headers = append(ev.Headers, &grpcadapterpb.Header{Name: name, Values: req.Header[name]})
+	}
+	return ev, nil
+}
@@ -0,0 +1,13 @@
+package grpcadapter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGrpcadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Grpcadapter Suite")
+}
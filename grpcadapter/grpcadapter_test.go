@@ -0,0 +1,103 @@
+package grpcadapter_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/grpcadapter"
+	"github.com/genkami/go-slack-event-router/grpcadapter/grpcadapterpb"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+const content = `
+{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+
+var _ = Describe("Grpcadapter", func() {
+	var (
+		token            = "THE_TOKEN"
+		numHandlerCalled int
+		server           *grpc.Server
+		conn             *grpc.ClientConn
+		client           grpcadapterpb.EventIngestionClient
+	)
+	BeforeEach(func() {
+		numHandlerCalled = 0
+		r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+		Expect(err).NotTo(HaveOccurred())
+		r.OnMessage(message.HandlerFunc(func(_ context.Context, e *slackevents.MessageEvent) error {
+			numHandlerCalled++
+			return nil
+		}))
+
+		lis := bufconn.Listen(1024 * 1024)
+		server = grpc.NewServer()
+		grpcadapterpb.RegisterEventIngestionServer(server, grpcadapter.NewServer(r))
+		go func() {
+			_ = server.Serve(lis)
+		}()
+		conn, err = grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		client = grpcadapterpb.NewEventIngestionClient(conn)
+	})
+	AfterEach(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+
+	signedEvent := func(secret string) *grpcadapterpb.SignedEvent {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/slack/events", bytes.NewReader([]byte(content)))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		err = signature.AddSignature(req.Header, []byte(secret), []byte(content), time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		ev, err := grpcadapter.ToSignedEvent(req)
+		Expect(err).NotTo(HaveOccurred())
+		return ev
+	}
+
+	Context("when the signature is valid", func() {
+		It("dispatches the event to the router", func() {
+			resp, err := client.Dispatch(context.Background(), signedEvent(token))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetStatus()).To(Equal(int32(http.StatusOK)))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Context("when the signature is invalid", func() {
+		It("responds with 401 without calling handlers", func() {
+			resp, err := client.Dispatch(context.Background(), signedEvent("WRONG_TOKEN"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetStatus()).To(Equal(int32(http.StatusUnauthorized)))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+})
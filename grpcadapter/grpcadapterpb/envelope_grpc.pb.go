@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: envelope.proto
+
+package grpcadapterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	EventIngestion_Dispatch_FullMethodName = "/slackeventrouter.grpcadapter.v1.EventIngestion/Dispatch"
+)
+
+// EventIngestionClient is the client API for EventIngestion service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EventIngestionClient interface {
+	Dispatch(ctx context.Context, in *SignedEvent, opts ...grpc.CallOption) (*DispatchResponse, error)
+}
+
+type eventIngestionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventIngestionClient(cc grpc.ClientConnInterface) EventIngestionClient {
+	return &eventIngestionClient{cc}
+}
+
+func (c *eventIngestionClient) Dispatch(ctx context.Context, in *SignedEvent, opts ...grpc.CallOption) (*DispatchResponse, error) {
+	out := new(DispatchResponse)
+	err := c.cc.Invoke(ctx, EventIngestion_Dispatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EventIngestionServer is the server API for EventIngestion service.
+// All implementations must embed UnimplementedEventIngestionServer
+// for forward compatibility
+type EventIngestionServer interface {
+	Dispatch(context.Context, *SignedEvent) (*DispatchResponse, error)
+	mustEmbedUnimplementedEventIngestionServer()
+}
+
+// UnimplementedEventIngestionServer must be embedded to have forward compatible implementations.
+type UnimplementedEventIngestionServer struct {
+}
+
+func (UnimplementedEventIngestionServer) Dispatch(context.Context, *SignedEvent) (*DispatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Dispatch not implemented")
+}
+func (UnimplementedEventIngestionServer) mustEmbedUnimplementedEventIngestionServer() {}
+
+// UnsafeEventIngestionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventIngestionServer will
+// result in compilation errors.
+type UnsafeEventIngestionServer interface {
+	mustEmbedUnimplementedEventIngestionServer()
+}
+
+func RegisterEventIngestionServer(s grpc.ServiceRegistrar, srv EventIngestionServer) {
+	s.RegisterService(&EventIngestion_ServiceDesc, srv)
+}
+
+func _EventIngestion_Dispatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignedEvent)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventIngestionServer).Dispatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventIngestion_Dispatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventIngestionServer).Dispatch(ctx, req.(*SignedEvent))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EventIngestion_ServiceDesc is the grpc.ServiceDesc for EventIngestion service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EventIngestion_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "slackeventrouter.grpcadapter.v1.EventIngestion",
+	HandlerType: (*EventIngestionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Dispatch",
+			Handler:    _EventIngestion_Dispatch_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "envelope.proto",
+}
@@ -0,0 +1,193 @@
+// Package huddle provides handlers to process events about huddles.
+//
+// Changes of users' huddle states are sent as `user_huddle_changed` events. slack-go doesn't know them,
+// so the Router decodes them into the types in this package:
+//
+//	r.OnUserHuddleChanged(huddle.HandlerFunc(func(ctx context.Context, e *huddle.Event) error {
+//		dashboard.SetInHuddle(e.User.ID, e.InHuddle())
+//		return nil
+//	}))
+//
+// Huddles in channels are also posted as messages of the `huddle_thread` subtype, which can be processed by `message.Handler`s with Threads.
+// slack-go drops the huddle (`room`) from such messages, so ThreadRoom provides it from the raw request.
+//
+// For more details, see https://api.slack.com/events/user_huddle_changed.
+package huddle
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+const (
+	// UserHuddleChanged is the type of events that are sent when a user joins or leaves a huddle.
+	UserHuddleChanged = "user_huddle_changed"
+
+	// ThreadSubType is the subtype of messages that are posted when huddles start in channels.
+	ThreadSubType = "huddle_thread"
+)
+
+// Huddle states of users.
+const (
+	// StateInHuddle means that the user is in a huddle.
+	StateInHuddle = "in_a_huddle"
+
+	// StateNone means that the user is not in any huddle.
+	StateNone = "default_unset"
+)
+
+// ErrNoRoom indicates that the message being processed is not a `huddle_thread` message, or the raw request is not available.
+var ErrNoRoom = stderrors.New("no huddle in the event")
+
+// Event is an inner event of UserHuddleChanged.
+type Event struct {
+	Type string `json:"type"`
+
+	// User is the user whose huddle state is changed.
+	User User `json:"user"`
+
+	EventTimestamp string `json:"event_ts"`
+	CacheTimestamp int64  `json:"cache_ts"`
+}
+
+// InHuddle returns true if and only if the user is in a huddle after the change.
+func (e *Event) InHuddle() bool {
+	return e.User.Profile.HuddleState == StateInHuddle
+}
+
+// User is a user whose huddle state is changed. Fields irrelevant to huddles are omitted.
+type User struct {
+	ID      string  `json:"id"`
+	TeamID  string  `json:"team_id"`
+	Name    string  `json:"name"`
+	Profile Profile `json:"profile"`
+}
+
+type Profile struct {
+	// HuddleState is either StateInHuddle or StateNone.
+	HuddleState string `json:"huddle_state"`
+
+	// HuddleStateExpirationTimestamp is the time (in UNIX seconds) when HuddleState expires, or 0 if it doesn't.
+	HuddleStateExpirationTimestamp int64 `json:"huddle_state_expiration_ts"`
+}
+
+// Room is a huddle.
+type Room struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedBy string `json:"created_by"`
+
+	// DateStart and DateEnd are the times (in UNIX seconds) when the huddle started and ended. DateEnd is 0 while the huddle continues.
+	DateStart int64 `json:"date_start"`
+	DateEnd   int64 `json:"date_end"`
+
+	// Participants are IDs of users who are in the huddle.
+	Participants []string `json:"participants"`
+
+	// ParticipantHistory are IDs of users who have ever joined the huddle.
+	ParticipantHistory []string `json:"participant_history"`
+
+	Channels []string `json:"channels"`
+	HasEnded bool     `json:"has_ended"`
+}
+
+// ThreadRoom returns the huddle of the `huddle_thread` message being processed.
+//
+// It returns ErrNoRoom if the message is of another subtype, or if the raw request is not available
+// (e.g. when handlers are called directly rather than by the Router).
+func ThreadRoom(ctx context.Context) (*Room, error) {
+	body := routerutils.RawBody(ctx)
+	if body == nil {
+		return nil, ErrNoRoom
+	}
+	var outer struct {
+		Event struct {
+			SubType string `json:"subtype"`
+			Room    *Room  `json:"room"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &outer); err != nil {
+		return nil, fmt.Errorf("failed to parse huddle: %w", err)
+	}
+	if outer.Event.SubType != ThreadSubType || outer.Event.Room == nil {
+		return nil, ErrNoRoom
+	}
+	return outer.Event.Room, nil
+}
+
+// Threads returns a predicate for `message.Handler`s that is considered to be "true" if and only if a message is a `huddle_thread` message.
+func Threads() message.Predicate {
+	return message.SubType(ThreadSubType)
+}
+
+// Handler processes `user_huddle_changed` events.
+type Handler interface {
+	HandleUserHuddleChangedEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleUserHuddleChangedEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type userPredicate struct {
+	id string
+}
+
+// UserID is a predicate that is considered to be "true" if and only if the huddle state of the given user is changed.
+func UserID(id string) Predicate {
+	return &userPredicate{id: id}
+}
+
+func (p *userPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.User.ID != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleUserHuddleChangedEvent(ctx, e)
+	})
+}
+
+func (p *userPredicate) String() string {
+	return fmt.Sprintf("UserID(%s)", p.id)
+}
+
+type statePredicate struct {
+	state string
+}
+
+// State is a predicate that is considered to be "true" if and only if the huddle state of the user is changed to the given one
+// (either StateInHuddle or StateNone).
+func State(state string) Predicate {
+	return &statePredicate{state: state}
+}
+
+func (p *statePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.User.Profile.HuddleState != p.state {
+			return errors.NotInterested
+		}
+		return h.HandleUserHuddleChangedEvent(ctx, e)
+	})
+}
+
+func (p *statePredicate) String() string {
+	return fmt.Sprintf("State(%s)", p.state)
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	return routerutils.Build(h, preds, Predicate.Wrap, isOutermost)
+}
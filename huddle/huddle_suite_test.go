@@ -0,0 +1,13 @@
+package huddle_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHuddle(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Huddle Suite")
+}
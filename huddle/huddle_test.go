@@ -0,0 +1,92 @@
+package huddle_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/huddle"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+var _ = Describe("Huddle", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = huddle.HandlerFunc(func(_ context.Context, _ *huddle.Event) error {
+			numHandlerCalled++
+			return nil
+		})
+		joined = &huddle.Event{User: huddle.User{ID: "U001", Profile: huddle.Profile{HuddleState: huddle.StateInHuddle}}}
+		left   = &huddle.Event{User: huddle.User{ID: "U001", Profile: huddle.Profile{HuddleState: huddle.StateNone}}}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Event", func() {
+		It("tells whether the user is in a huddle", func() {
+			Expect(joined.InHuddle()).To(BeTrue())
+			Expect(left.InHuddle()).To(BeFalse())
+		})
+	})
+
+	Describe("UserID", func() {
+		It("calls the inner handler if the event matches", func() {
+			h := huddle.Build(innerHandler, huddle.UserID("U001"))
+			Expect(h.HandleUserHuddleChangedEvent(ctx, joined)).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			h := huddle.Build(innerHandler, huddle.UserID("U002"))
+			Expect(h.HandleUserHuddleChangedEvent(ctx, joined)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("State", func() {
+		It("calls the inner handler only if the user is changed to the given state", func() {
+			h := huddle.Build(innerHandler, huddle.State(huddle.StateInHuddle))
+			Expect(h.HandleUserHuddleChangedEvent(ctx, joined)).To(Succeed())
+			Expect(h.HandleUserHuddleChangedEvent(ctx, left)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("applies generic predicates", func() {
+			h := huddle.Build(innerHandler, huddle.FromGenericPredicate(router.Filter(func(context.Context, *huddle.Event) bool {
+				return false
+			})))
+			Expect(h.HandleUserHuddleChangedEvent(ctx, joined)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("ThreadRoom", func() {
+		It("returns the huddle of the message", func() {
+			body := []byte(`{"type": "event_callback", "event": {"type": "message", "subtype": "huddle_thread", "room": {"id": "R001", "participants": ["U001", "U002"], "has_ended": false}}}`)
+			room, err := huddle.ThreadRoom(routerutils.WithRequest(ctx, body, http.Header{}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(room.ID).To(Equal("R001"))
+			Expect(room.Participants).To(Equal([]string{"U001", "U002"}))
+		})
+
+		It("returns ErrNoRoom for other messages", func() {
+			body := []byte(`{"type": "event_callback", "event": {"type": "message", "text": "hello"}}`)
+			_, err := huddle.ThreadRoom(routerutils.WithRequest(ctx, body, http.Header{}))
+			Expect(err).To(MatchError(huddle.ErrNoRoom))
+		})
+
+		It("returns ErrNoRoom if the raw request is not available", func() {
+			_, err := huddle.ThreadRoom(ctx)
+			Expect(err).To(MatchError(huddle.ErrNoRoom))
+		})
+	})
+})
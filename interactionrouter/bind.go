@@ -0,0 +1,143 @@
+package interactionrouter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// BindTag is the name of the struct tag that Bind uses.
+const BindTag = "slack"
+
+var (
+	stringType      = reflect.TypeOf("")
+	stringSliceType = reflect.TypeOf([]string{})
+	blockActionType = reflect.TypeOf(slack.BlockAction{})
+)
+
+// Bind maps values of block actions (in `block_actions`) or view states (in `view_submission` and so on) onto `v`.
+//
+// `v` must be a pointer to a struct. Each field to bind must have a struct tag in the form of `slack:"BLOCK_ID.ACTION_ID"`.
+// The following field types are supported:
+//   - string: the value of the action (e.g. text inputs, buttons, static selects, user selects, date pickers)
+//   - []string: the selected values of multi-select menus
+//   - slack.BlockAction and *slack.BlockAction: the action itself
+//
+// Fields whose actions are not found in the callback are left untouched.
+//
+//	type DeployRequest struct {
+//		Service string   `slack:"service_block.service_select"`
+//		Targets []string `slack:"target_block.target_select"`
+//	}
+//
+//	req := DeployRequest{}
+//	if err := interactionrouter.Bind(callback, &req); err != nil {
+//		return err
+//	}
+func Bind(callback *slack.InteractionCallback, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a non-nil pointer to a struct but got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(BindTag)
+		if !ok || tag == "-" {
+			continue
+		}
+		blockID, actionID, err := parseBindTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		action := findAction(callback, blockID, actionID)
+		if action == nil {
+			continue
+		}
+		if err := bindField(rv.Field(i), action); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseBindTag(tag string) (string, string, error) {
+	idx := strings.LastIndex(tag, ".")
+	if idx <= 0 || idx == len(tag)-1 {
+		return "", "", fmt.Errorf("invalid tag %q: expected BLOCK_ID.ACTION_ID", tag)
+	}
+	return tag[:idx], tag[idx+1:], nil
+}
+
+func findAction(callback *slack.InteractionCallback, blockID, actionID string) *slack.BlockAction {
+	if callback.View.State != nil {
+		if action, ok := callback.View.State.Values[blockID][actionID]; ok {
+			return &action
+		}
+	}
+	if callback.BlockActionState != nil {
+		if action, ok := callback.BlockActionState.Values[blockID][actionID]; ok {
+			return &action
+		}
+	}
+	return FindBlockAction(callback, blockID, actionID)
+}
+
+func bindField(fv reflect.Value, action *slack.BlockAction) error {
+	if !fv.CanSet() {
+		return errors.New("cannot set value to unexported field")
+	}
+	switch fv.Type() {
+	case stringType:
+		fv.SetString(actionValue(action))
+	case stringSliceType:
+		fv.Set(reflect.ValueOf(actionValues(action)))
+	case blockActionType:
+		fv.Set(reflect.ValueOf(*action))
+	case reflect.PtrTo(blockActionType):
+		fv.Set(reflect.ValueOf(action))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func actionValue(action *slack.BlockAction) string {
+	candidates := []string{
+		action.Value,
+		action.SelectedOption.Value,
+		action.SelectedUser,
+		action.SelectedChannel,
+		action.SelectedConversation,
+		action.SelectedDate,
+		action.SelectedTime,
+	}
+	for _, c := range candidates {
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+func actionValues(action *slack.BlockAction) []string {
+	switch {
+	case len(action.SelectedOptions) > 0:
+		values := make([]string, 0, len(action.SelectedOptions))
+		for _, o := range action.SelectedOptions {
+			values = append(values, o.Value)
+		}
+		return values
+	case len(action.SelectedUsers) > 0:
+		return action.SelectedUsers
+	case len(action.SelectedChannels) > 0:
+		return action.SelectedChannels
+	case len(action.SelectedConversations) > 0:
+		return action.SelectedConversations
+	}
+	return []string{}
+}
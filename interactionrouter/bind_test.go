@@ -0,0 +1,101 @@
+package interactionrouter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+)
+
+var _ = Describe("Bind", func() {
+	type request struct {
+		Service  string             `slack:"service_block.service_select"`
+		Targets  []string           `slack:"target_block.target_select"`
+		Assignee string             `slack:"assignee_block.assignee_select"`
+		Action   *slack.BlockAction `slack:"service_block.service_select"`
+		Missing  string             `slack:"missing_block.missing_action"`
+		Ignored  string
+	}
+
+	Context("when the callback is a view submission", func() {
+		It("binds view state values", func() {
+			callback := &slack.InteractionCallback{
+				Type: slack.InteractionTypeViewSubmission,
+				View: slack.View{
+					State: &slack.ViewState{
+						Values: map[string]map[string]slack.BlockAction{
+							"service_block": {
+								"service_select": {SelectedOption: slack.OptionBlockObject{Value: "api"}},
+							},
+							"target_block": {
+								"target_select": {SelectedOptions: []slack.OptionBlockObject{{Value: "prod"}, {Value: "staging"}}},
+							},
+							"assignee_block": {
+								"assignee_select": {SelectedUser: "U123"},
+							},
+						},
+					},
+				},
+			}
+			req := request{Missing: "untouched"}
+			err := ir.Bind(callback, &req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.Service).To(Equal("api"))
+			Expect(req.Targets).To(Equal([]string{"prod", "staging"}))
+			Expect(req.Assignee).To(Equal("U123"))
+			Expect(req.Action).NotTo(BeNil())
+			Expect(req.Action.SelectedOption.Value).To(Equal("api"))
+			Expect(req.Missing).To(Equal("untouched"))
+		})
+	})
+
+	Context("when the callback is a block action", func() {
+		It("binds action values", func() {
+			callback := &slack.InteractionCallback{
+				Type: slack.InteractionTypeBlockActions,
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{
+						{BlockID: "service_block", ActionID: "service_select", Value: "web"},
+					},
+				},
+			}
+			req := request{}
+			err := ir.Bind(callback, &req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.Service).To(Equal("web"))
+		})
+	})
+
+	Context("when the given value is not a pointer to a struct", func() {
+		It("returns an error", func() {
+			err := ir.Bind(&slack.InteractionCallback{}, request{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the tag is malformed", func() {
+		It("returns an error", func() {
+			req := struct {
+				Value string `slack:"no_action_id"`
+			}{}
+			err := ir.Bind(&slack.InteractionCallback{}, &req)
+			Expect(err).To(MatchError(MatchRegexp("Value")))
+		})
+	})
+
+	Context("when the field type is not supported", func() {
+		It("returns an error", func() {
+			req := struct {
+				Value int `slack:"block.action"`
+			}{}
+			callback := &slack.InteractionCallback{
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{{BlockID: "block", ActionID: "action", Value: "1"}},
+				},
+			}
+			err := ir.Bind(callback, &req)
+			Expect(err).To(MatchError(MatchRegexp("unsupported type")))
+		})
+	})
+})
@@ -4,16 +4,25 @@
 package interactionrouter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"time"
 
-	"github.com/pkg/errors"
 	"github.com/slack-go/slack"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
 	"github.com/genkami/go-slack-event-router/internal/routerutils"
-	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/routeinfo"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/routeroptions"
+	"github.com/genkami/go-slack-event-router/validation"
 )
 
 // Handler processes interaction callbacks sent from Slack.
@@ -27,6 +36,18 @@ func (f HandlerFunc) HandleInteraction(ctx context.Context, callback *slack.Inte
 	return f(ctx, callback)
 }
 
+// HandlerProvider constructs Handlers lazily. See `router.HandlerProvider`.
+type HandlerProvider = router.HandlerProvider[Handler]
+
+type HandlerProviderFunc = router.HandlerProviderFunc[Handler]
+
+// FromProvider returns a Handler that obtains a Handler from `p` every time it processes an event and delegates the event to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func FromProvider(p HandlerProvider) Handler {
+	return FromGeneric(router.FromProvider(p, Handler.HandleInteraction))
+}
+
 // Predicate disthinguishes whether or not a certain handler should process coming events.
 type Predicate interface {
 	Wrap(Handler) Handler
@@ -50,6 +71,66 @@ func (p *typePredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *typePredicate) String() string {
+	return fmt.Sprintf("Type(%s)", p.typeName)
+}
+
+type enterprisePredicate struct {
+	id string
+}
+
+// Enterprise is a predicate that is considered to be "true" if and only if the InteractionCallback comes from the given Enterprise Grid organization.
+//
+// The ID is exposed as the label `enterprise` in route introspection.
+func Enterprise(id string) Predicate {
+	return &enterprisePredicate{id: id}
+}
+
+func (p *enterprisePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Enterprise.ID != p.id {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+func (p *enterprisePredicate) String() string {
+	return fmt.Sprintf("Enterprise(%s)", p.id)
+}
+
+func (p *enterprisePredicate) Labels() map[string]string {
+	return map[string]string{"enterprise": p.id}
+}
+
+type apiAppIDPredicate struct {
+	id string
+}
+
+// APIAppID is a predicate that is considered to be "true" if and only if the InteractionCallback is sent to the given app.
+//
+// The ID is exposed as the label `api_app_id` in route introspection.
+func APIAppID(id string) Predicate {
+	return &apiAppIDPredicate{id: id}
+}
+
+func (p *apiAppIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.APIAppID != p.id {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+func (p *apiAppIDPredicate) String() string {
+	return fmt.Sprintf("APIAppID(%s)", p.id)
+}
+
+func (p *apiAppIDPredicate) Labels() map[string]string {
+	return map[string]string{"api_app_id": p.id}
+}
+
 type blockActionPredicate struct {
 	blockID  string
 	actionID string
@@ -69,6 +150,10 @@ func (p *blockActionPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *blockActionPredicate) String() string {
+	return fmt.Sprintf("BlockAction(%s, %s)", p.blockID, p.actionID)
+}
+
 type callbackIDPredicate struct {
 	id string
 }
@@ -87,6 +172,10 @@ func (p *callbackIDPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *callbackIDPredicate) String() string {
+	return fmt.Sprintf("CallbackID(%s)", p.id)
+}
+
 type channelPredicate struct {
 	id string
 }
@@ -105,12 +194,42 @@ func (p *channelPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *channelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.id)
+}
+
+// Condition is a condition that does not depend on events. See `router.Condition`.
+type Condition = router.Condition
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When(cond Condition) Predicate {
+	return FromGenericPredicate(router.When[*slack.InteractionCallback](cond))
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description(text string) Predicate {
+	return FromGenericPredicate(router.Description[*slack.InteractionCallback](text))
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue(key, value interface{}) Predicate {
+	return FromGenericPredicate(router.WithValue[*slack.InteractionCallback](key, value))
+}
+
 // Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func Build(h Handler, preds ...Predicate) Handler {
-	for _, p := range preds {
-		h = p.Wrap(h)
-	}
-	return h
+	return routerutils.BuildMarked(h, preds, Predicate.Wrap, isOutermost, markHandler)
+}
+
+// markHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markHandler(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		return routerutils.MarkHandlerError(h.HandleInteraction(ctx, callback))
+	})
 }
 
 // Option configures the Router.
@@ -124,11 +243,18 @@ func (f optionFunc) apply(r *Router) {
 	f(r)
 }
 
+// WithShared applies options shared by all routers. See the `routeroptions` package for details.
+func WithShared(opts ...routeroptions.Option) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(opts...)
+	})
+}
+
 // InsecureSkipVerification skips verifying request signatures.
 // This is useful to test your handlers, but do not use this in production environments.
 func InsecureSkipVerification() Option {
 	return optionFunc(func(r *Router) {
-		r.skipVerification = true
+		r.shared.Apply(routeroptions.InsecureSkipVerification())
 	})
 }
 
@@ -137,14 +263,155 @@ func InsecureSkipVerification() Option {
 // For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
 func WithSigningSecret(token string) Option {
 	return optionFunc(func(r *Router) {
-		r.signingSecret = token
+		r.shared.Apply(routeroptions.WithSigningSecret(token))
+	})
+}
+
+// WithPreviousSigningSecrets sets signing secrets that are accepted in addition to the one set by WithSigningSecret.
+//
+// This is useful to rotate signing secrets without downtime. Handlers can find which secret matched by `signature.VerificationFromContext`.
+func WithPreviousSigningSecrets(secrets ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithPreviousSigningSecrets(secrets...))
 	})
 }
 
 // If VerboseResponse is set, the Router shows error details when it fails to process requests.
 func VerboseResponse() Option {
 	return optionFunc(func(r *Router) {
-		r.verboseResponse = true
+		r.shared.Apply(routeroptions.VerboseResponse())
+	})
+}
+
+// WithRedaction makes the Router redact error details by `policy` before showing them (see VerboseResponse) or passing them to the hook set by OnError.
+func WithRedaction(policy *redact.Policy) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithRedaction(policy))
+	})
+}
+
+// AcceptHealthProbes makes the Router respond to GET and HEAD requests with 200 (OK), so that load balancers can probe the path of the Router.
+//
+// Regardless of this option, the Router responds to requests with other methods with 405 (Method Not Allowed).
+func AcceptHealthProbes() Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.AcceptHealthProbes())
+	})
+}
+
+// WithHTTPMiddleware adds middlewares that wrap the Router. The first middleware is the outermost one.
+//
+// Middlewares are applied outside of signature verification, and may read request bodies without breaking it.
+// See `routeroptions.WithHTTPMiddleware` for details.
+func WithHTTPMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithHTTPMiddleware(mw...))
+	})
+}
+
+// OnError sets a hook that is called every time the Router responds with an error, including verification failures.
+//
+// Errors given to the hook wrap sentinel errors such as `signature.ErrBadSignature` when applicable,
+// so the hook can distinguish reasons by `errors.Is`.
+// The hook is also called with `routererrors.Mismatch` when predicates marked by `router.FailOnMismatch` don't match, even though the Router responds with 200 (OK) in such case.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.OnError(hook))
+	})
+}
+
+// WithHTTPClient sets an HTTP client that is used to post messages to `response_url`.
+//
+// If not set, the Router uses `http.DefaultClient`.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(r *Router) {
+		r.httpClient = client
+	})
+}
+
+// WithStrictParsing makes the Router report payloads that contain fields unknown to `slack.InteractionCallback`
+// to the hook set by OnError. Errors given to the hook wrap ErrUnknownField and describe the offending field.
+//
+// This helps you to notice changes in payload shapes instead of silently ignoring new fields.
+// The Router still processes such payloads, since Slack sends fields that `slack-go/slack` doesn't know about
+// (e.g. `user.username` and `is_enterprise_install` of `block_actions`) even in ordinary payloads.
+func WithStrictParsing() Option {
+	return optionFunc(func(r *Router) {
+		r.strictParsing = true
+	})
+}
+
+// WithNowFunc sets a function that returns the current time.
+//
+// The Router uses it wherever it depends on the current time (e.g. checking whether request timestamps are too old).
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Router) {
+		r.shared.Apply(routeroptions.WithNowFunc(now))
+	})
+}
+
+// StrictRegistration makes the Router panic when it detects registrations that are likely to be mistakes.
+//
+// This includes registering more than one handlers for the same interaction type with equal predicates
+// (e.g. the same block action or callback ID, regardless of the order of predicates), and calling SetFallback more than once.
+func StrictRegistration() Option {
+	return optionFunc(func(r *Router) {
+		r.registry = routerutils.NewRegistry()
+	})
+}
+
+// WithPrivateMetadataCodec sets functions that encode and decode `private_metadata` of views.
+//
+// The Router decodes `private_metadata` of incoming callbacks by `dec` before passing them to handlers, so handlers always see decoded values.
+// If `dec` fails, the Router responds with Unauthorized since it usually means that the value is tampered.
+// Handlers can encode values to put into views by EncodePrivateMetadata, which uses `enc`.
+//
+// This is useful to encrypt or sign states stored in `private_metadata`.
+func WithPrivateMetadataCodec(enc, dec func(string) (string, error)) Option {
+	return optionFunc(func(r *Router) {
+		r.privateMetadataCodec = &privateMetadataCodec{encode: enc, decode: dec}
+	})
+}
+
+// DefaultAckBudget is the default time budget used by WithAckWatchdog.
+//
+// Slack shows "operation timed out" errors to users if apps don't respond within 3 seconds.
+const DefaultAckBudget = 2500 * time.Millisecond
+
+// WithAckWatchdog makes the Router measure time from receipt of requests to completion of handlers,
+// and call `onSlow` when it exceeds `budget`. If `budget` is zero, DefaultAckBudget is used. New fails if `budget` is negative.
+//
+// This is useful to find handlers that cause timeouts on the user side.
+func WithAckWatchdog(budget time.Duration, onSlow func(ctx context.Context, callback *slack.InteractionCallback, elapsed time.Duration)) Option {
+	return optionFunc(func(r *Router) {
+		if budget == 0 {
+			budget = DefaultAckBudget
+		}
+		r.ackBudget = budget
+		r.slowHook = onSlow
+	})
+}
+
+// ForceAck makes the Router respond with 200 (OK) when handlers exceed the budget set by WithAckWatchdog, and let them finish in the background.
+//
+// Handlers are given a context that is not canceled even after the Router responds.
+// `*MessageResponse`s returned after the Router responded are still posted to `response_url`,
+// but other results (e.g. `validation.Errors`) cannot be delivered. Such results, errors returned from handlers
+// and failures to post to `response_url` are passed to the hook set by OnBackgroundError.
+func ForceAck() Option {
+	return optionFunc(func(r *Router) {
+		r.forceAck = true
+	})
+}
+
+// OnBackgroundError sets a hook that is called when a handler returns an error after the Router responded to the request due to ForceAck,
+// or when the Router fails to post its `*MessageResponse` to `response_url`.
+//
+// `routererrors.Mismatch` is not passed to this hook but to the hook set by OnError, in the same way as when the Router waits for handlers.
+func OnBackgroundError(hook func(context.Context, *slack.InteractionCallback, error)) Option {
+	return optionFunc(func(r *Router) {
+		r.backgroundErrorHook = hook
 	})
 }
 
@@ -152,12 +419,19 @@ func VerboseResponse() Option {
 //
 // For more details, see https://api.slack.com/interactivity/handling.
 type Router struct {
-	signingSecret    string
-	skipVerification bool
-	handlers         map[slack.InteractionType][]Handler
-	fallbackHandler  Handler
-	verboseResponse  bool
-	httpHandler      http.Handler
+	shared               routeroptions.Config
+	handlers             map[slack.InteractionType][]Handler
+	fallbackHandlers     []Handler
+	registry             *routerutils.Registry
+	routes               []routeinfo.Route
+	strictParsing        bool
+	httpClient           *http.Client
+	privateMetadataCodec *privateMetadataCodec
+	ackBudget            time.Duration
+	slowHook             func(context.Context, *slack.InteractionCallback, time.Duration)
+	forceAck             bool
+	backgroundErrorHook  func(context.Context, *slack.InteractionCallback, error)
+	httpHandler          http.Handler
 }
 
 // New creates a new Router.
@@ -165,29 +439,41 @@ type Router struct {
 // At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
 func New(opts ...Option) (*Router, error) {
 	r := &Router{
-		handlers: make(map[slack.InteractionType][]Handler),
+		handlers:   make(map[slack.InteractionType][]Handler),
+		httpClient: http.DefaultClient,
+		shared:     routeroptions.NewConfig(),
 	}
 	for _, o := range opts {
 		o.apply(r)
 	}
-	if r.signingSecret == "" && !r.skipVerification {
-		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
+	if err := r.shared.Validate(); err != nil {
+		return nil, err
 	}
-	if r.signingSecret != "" && r.skipVerification {
-		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
+	if err := validateOptions(r); err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), routererrors.ErrInvalidOption)
 	}
 
-	r.httpHandler = http.HandlerFunc(r.serveHTTP)
-	if !r.skipVerification {
-		r.httpHandler = &signature.Middleware{
-			SigningSecret:   r.signingSecret,
-			VerboseResponse: r.verboseResponse,
-			Handler:         r.httpHandler,
-		}
-	}
+	r.httpHandler = r.shared.WrapHTTP(r.shared.CheckMethod(r.shared.Verify(http.HandlerFunc(r.serveHTTP))))
 	return r, nil
 }
 
+// validateOptions returns an error that tells which option is invalid, if any.
+func validateOptions(r *Router) error {
+	if r.forceAck && r.ackBudget == 0 {
+		return errors.New("ForceAck requires WithAckWatchdog")
+	}
+	if r.ackBudget < 0 {
+		return fmt.Errorf("WithAckWatchdog: negative budget %s", r.ackBudget)
+	}
+	if r.httpClient == nil {
+		return errors.New("WithHTTPClient: client must not be nil")
+	}
+	if r.privateMetadataCodec != nil && (r.privateMetadataCodec.encode == nil || r.privateMetadataCodec.decode == nil) {
+		return errors.New("WithPrivateMetadataCodec: both enc and dec are required")
+	}
+	return nil
+}
+
 // On registers a handler for a specific event type.
 //
 // Unlike `eventrouter.Router`, the Router does not have type-specific `OnXXX` methods because all types of
@@ -199,8 +485,17 @@ func New(opts ...Option) (*Router, error) {
 //
 // Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
 //
+// Handlers of `view_submission` may return `validation.Errors` (or its equivalents in the sense of `errors.As`).
+// In such case the Router responds with `response_action: errors` so that Slack displays the error messages in the modal.
+//
+// Handlers may also return `*MessageResponse` (or its equivalents in the sense of `errors.As`), which can be created by `Update` or `ReplaceEphemeral`.
+// In such case the Router posts the message to the `response_url` of the callback.
+//
 // If any other errors are returned, the Router responds with Internal Server Error.
 func (r *Router) On(typeName slack.InteractionType, h Handler, preds ...Predicate) {
+	ps := routerutils.Predicates(preds)
+	r.checkDuplicate(string(typeName), ps)
+	r.routes = append(r.routes, routerutils.NewRoute(string(typeName), h, ps))
 	h = Build(h, preds...)
 	handlers, ok := r.handlers[typeName]
 	if !ok {
@@ -213,36 +508,160 @@ func (r *Router) On(typeName slack.InteractionType, h Handler, preds ...Predicat
 // SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming event.
 //
 // If more than one handlers are registered, the last one will be used.
+// Note that this discards all fallback handlers registered so far, including ones registered by AddFallback.
+// If you want to register more than one fallback handlers, use AddFallback instead.
 func (r *Router) SetFallback(h Handler) {
-	r.fallbackHandler = h
+	r.checkDuplicate("SetFallback", nil)
+	r.fallbackHandlers = []Handler{h}
+}
+
+// AddFallback adds a fallback handler that is called when none of the registered handlers matches to a coming event.
+//
+// Fallback handlers form a chain in the order of registration. If a fallback handler returns `routererrors.NotInterested`
+// (or its equivalents in the sense of `errors.Is`), the Router falls back to the next one.
+func (r *Router) AddFallback(h Handler) {
+	r.fallbackHandlers = append(r.fallbackHandlers, h)
+}
+
+// Routes returns all routes registered to the Router in the order of registration.
+func (r *Router) Routes() []routeinfo.Route {
+	routes := make([]routeinfo.Route, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+func (r *Router) checkDuplicate(key string, preds []interface{}) {
+	if r.registry == nil {
+		return
+	}
+	if err := r.registry.Register(key, preds); err != nil {
+		panic(err)
+	}
 }
 
+type receivedAtKey struct{}
+
 func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if router.ackBudget > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), receivedAtKey{}, router.shared.Now()))
+	}
 	router.httpHandler.ServeHTTP(w, req)
 }
 
 func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	callback := slack.InteractionCallback{}
-	if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
-		router.respondWithError(w,
-			errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "unexpected Content-Type"))
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		router.shared.RespondWithError(req.Context(), w,
+			fmt.Errorf("unexpected Content-Type: %w", routererrors.HttpError(http.StatusBadRequest)))
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		router.shared.RespondWithError(req.Context(), w, err)
 		return
 	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	payload := req.FormValue("payload")
 	if payload == "" {
-		router.respondWithError(w,
-			errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "missing payload"))
+		router.shared.RespondWithError(req.Context(), w,
+			fmt.Errorf("missing payload: %w", routererrors.HttpError(http.StatusBadRequest)))
 		return
 	}
+	if router.strictParsing {
+		if err := validateStrictly([]byte(payload)); err != nil {
+			router.shared.ReportError(req.Context(), fmt.Errorf("%s: %w", err.Error(), ErrUnknownField))
+		}
+	}
 	if err := json.Unmarshal([]byte(payload), &callback); err != nil {
-		router.respondWithError(w, err)
+		router.shared.RespondWithError(req.Context(), w, err)
 		return
 	}
 
-	router.handleInteractionCallback(req.Context(), w, &callback)
+	ctx := routerutils.WithRequest(req.Context(), body, req.Header)
+	if router.privateMetadataCodec != nil {
+		ctx = context.WithValue(ctx, privateMetadataCodecKey{}, router.privateMetadataCodec)
+		if err := router.privateMetadataCodec.decodeView(&callback.View); err != nil {
+			router.shared.RespondWithError(req.Context(), w, err)
+			return
+		}
+	}
+	router.handleInteractionCallback(ctx, w, &callback)
+}
+
+// ErrUnknownField indicates that a payload contains fields unknown to `slack.InteractionCallback`. See WithStrictParsing.
+var ErrUnknownField = errors.New("unknown field in the payload")
+
+// validateStrictly checks that the payload does not contain unknown fields.
+// Since `slack.InteractionCallback` implements json.Unmarshaler, we have to decode it into a type without methods to make DisallowUnknownFields work.
+func validateStrictly(payload []byte) error {
+	type strictInteractionCallback slack.InteractionCallback
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	return dec.Decode(&strictInteractionCallback{})
 }
 
 func (r *Router) handleInteractionCallback(ctx context.Context, w http.ResponseWriter, callback *slack.InteractionCallback) {
+	if r.ackBudget == 0 {
+		r.respond(ctx, w, callback, r.dispatch(ctx, callback))
+		return
+	}
+
+	receivedAt, ok := ctx.Value(receivedAtKey{}).(time.Time)
+	if !ok {
+		receivedAt = r.shared.Now()
+	}
+	if !r.forceAck {
+		err := r.dispatch(ctx, callback)
+		r.checkElapsed(ctx, callback, receivedAt)
+		r.respond(ctx, w, callback, err)
+		return
+	}
+
+	ctx = routerutils.Detach(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.dispatch(ctx, callback)
+	}()
+	timer := time.NewTimer(r.ackBudget - r.shared.Now().Sub(receivedAt))
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		r.checkElapsed(ctx, callback, receivedAt)
+		r.respond(ctx, w, callback, err)
+	case <-timer.C:
+		w.WriteHeader(http.StatusOK)
+		go func() {
+			err := <-done
+			r.checkElapsed(ctx, callback, receivedAt)
+			r.handleBackgroundResult(ctx, callback, err)
+		}()
+	}
+}
+
+func (r *Router) handleBackgroundResult(ctx context.Context, callback *slack.InteractionCallback, err error) {
+	var msgResp *MessageResponse
+	if errors.As(err, &msgResp) {
+		err = r.postToResponseURL(ctx, callback, msgResp)
+	}
+	if errors.Is(err, routererrors.Mismatch) {
+		// Reported in the same way as when the Router waits for handlers.
+		r.shared.ReportError(ctx, err)
+		return
+	}
+	if err != nil && !errors.Is(err, routererrors.NotInterested) && r.backgroundErrorHook != nil {
+		r.backgroundErrorHook(ctx, callback, r.shared.Redaction.Error(err))
+	}
+}
+
+func (r *Router) checkElapsed(ctx context.Context, callback *slack.InteractionCallback, receivedAt time.Time) {
+	elapsed := r.shared.Now().Sub(receivedAt)
+	if elapsed > r.ackBudget && r.slowHook != nil {
+		r.slowHook(ctx, callback, elapsed)
+	}
+}
+
+func (r *Router) dispatch(ctx context.Context, callback *slack.InteractionCallback) error {
 	var err error = routererrors.NotInterested
 	handlers, ok := r.handlers[callback.Type]
 	if ok {
@@ -257,23 +676,107 @@ func (r *Router) handleInteractionCallback(ctx context.Context, w http.ResponseW
 	if errors.Is(err, routererrors.NotInterested) {
 		err = r.handleFallback(ctx, callback)
 	}
+	return err
+}
+
+func (r *Router) respond(ctx context.Context, w http.ResponseWriter, callback *slack.InteractionCallback, err error) {
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		r.respondWithJSON(w, slack.NewErrorsViewSubmissionResponse(validationErrs))
+		return
+	}
+
+	var msgResp *MessageResponse
+	if errors.As(err, &msgResp) {
+		err = r.postToResponseURL(ctx, callback, msgResp)
+	}
+
+	if errors.Is(err, routererrors.Mismatch) {
+		// Misrouted interactions are reported, but retrying them doesn't help.
+		r.shared.ReportError(ctx, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	if err != nil && !errors.Is(err, routererrors.NotInterested) {
-		r.respondWithError(w, err)
+		r.shared.RespondWithError(ctx, w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+func (r *Router) postToResponseURL(ctx context.Context, callback *slack.InteractionCallback, resp *MessageResponse) error {
+	if callback.ResponseURL == "" {
+		return errors.New("the callback does not have response_url")
+	}
+	if err := slack.PostWebhookCustomHTTPContext(ctx, callback.ResponseURL, r.httpClient, resp.Message); err != nil {
+		return fmt.Errorf("failed to post a message to response_url: %w", err)
+	}
+	return nil
+}
+
+func (r *Router) respondWithJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
 func (r *Router) handleFallback(ctx context.Context, callback *slack.InteractionCallback) error {
-	if r.fallbackHandler == nil {
-		return routererrors.NotInterested
+	var err error = routererrors.NotInterested
+	for _, h := range r.fallbackHandlers {
+		err = h.HandleInteraction(ctx, callback)
+		if !errors.Is(err, routererrors.NotInterested) {
+			break
+		}
 	}
-	return r.fallbackHandler.HandleInteraction(ctx, callback)
+	return err
 }
 
-func (r *Router) respondWithError(w http.ResponseWriter, err error) {
-	routerutils.RespondWithError(w, err, r.verboseResponse)
+// ErrInvalidPrivateMetadata indicates that the Router failed to decode `private_metadata` by the codec set by WithPrivateMetadataCodec.
+var ErrInvalidPrivateMetadata = errors.New("invalid private_metadata")
+
+type privateMetadataCodec struct {
+	encode func(string) (string, error)
+	decode func(string) (string, error)
+}
+
+type privateMetadataCodecKey struct{}
+
+func (c *privateMetadataCodec) decodeView(view *slack.View) error {
+	if view.PrivateMetadata == "" {
+		return nil
+	}
+	decoded, err := c.decode(view.PrivateMetadata)
+	if err != nil {
+		return routererrors.WithStatus(fmt.Errorf("%s: %w", err.Error(), ErrInvalidPrivateMetadata), http.StatusUnauthorized)
+	}
+	view.PrivateMetadata = decoded
+	return nil
+}
+
+// EncodePrivateMetadata encodes `value` to put it into `private_metadata` of views, using the codec set by WithPrivateMetadataCodec.
+//
+// If the codec is not set, or `ctx` is not given by the Router, it returns `value` as is.
+func EncodePrivateMetadata(ctx context.Context, value string) (string, error) {
+	codec, ok := ctx.Value(privateMetadataCodecKey{}).(*privateMetadataCodec)
+	if !ok {
+		return value, nil
+	}
+	return codec.encode(value)
+}
+
+// RawBody returns the raw request body of the interaction callback that is being processed.
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RawBody(ctx context.Context) []byte {
+	return routerutils.RawBody(ctx)
+}
+
+// RequestHeader returns the HTTP headers of the interaction callback that is being processed (e.g. `X-Slack-Retry-Num`).
+//
+// This is only available in the context given to handlers. Otherwise it returns nil.
+func RequestHeader(ctx context.Context) http.Header {
+	return routerutils.Header(ctx)
 }
 
 // FindBlockAction finds a block action whose blockID and actionID equal to the given ones.
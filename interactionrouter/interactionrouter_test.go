@@ -4,22 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/pkg/errors"
 	"github.com/slack-go/slack"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
 	ir "github.com/genkami/go-slack-event-router/interactionrouter"
-	"github.com/genkami/go-slack-event-router/internal/testutils"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+	"github.com/genkami/go-slack-event-router/validation"
 )
 
+// staticCondition is a Condition that always returns the same result.
+type staticCondition bool
+
+func (c staticCondition) Match(context.Context) bool {
+	return bool(c)
+}
+
 var _ = Describe("InteractionRouter", func() {
 	Describe("Type", func() {
 		var (
@@ -176,6 +188,41 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
+	Describe("Enterprise and APIAppID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx = context.Background()
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		It("calls the inner handler only when the enterprise and the app match", func() {
+			h := ir.Build(innerHandler, ir.Enterprise("E123"), ir.APIAppID("A123"))
+			callback := &slack.InteractionCallback{APIAppID: "A123", Enterprise: slack.Enterprise{ID: "E123"}}
+			Expect(h.HandleInteraction(ctx, callback)).To(Succeed())
+			callback = &slack.InteractionCallback{APIAppID: "A123", Enterprise: slack.Enterprise{ID: "E456"}}
+			Expect(h.HandleInteraction(ctx, callback)).To(Equal(routererrors.NotInterested))
+			callback = &slack.InteractionCallback{APIAppID: "A456", Enterprise: slack.Enterprise{ID: "E123"}}
+			Expect(h.HandleInteraction(ctx, callback)).To(Equal(routererrors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("exposes the IDs as labels of routes", func() {
+			r, err := ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeBlockActions, innerHandler, ir.Enterprise("E123"), ir.APIAppID("A123"))
+			routes := r.Routes()
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Labels).To(Equal(map[string]string{"enterprise": "E123", "api_app_id": "A123"}))
+			Expect(routes[0].Predicates).To(Equal([]string{"Enterprise(E123)", "APIAppID(A123)"}))
+		})
+	})
+
 	Describe("CallbackID", func() {
 		var (
 			numHandlerCalled int
@@ -222,6 +269,7 @@ var _ = Describe("InteractionRouter", func() {
 			It("returns an error", func() {
 				_, err := ir.New()
 				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+				Expect(errors.Is(err, routererrors.ErrMissingSigningSecret)).To(BeTrue())
 			})
 		})
 
@@ -245,6 +293,7 @@ var _ = Describe("InteractionRouter", func() {
 			It("returns an error", func() {
 				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithSigningSecret("THE_TOKEN"))
 				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+				Expect(errors.Is(err, routererrors.ErrConflictingOptions)).To(BeTrue())
 			})
 		})
 	})
@@ -292,7 +341,7 @@ var _ = Describe("InteractionRouter", func() {
 			It("responds with Unauthorized", func() {
 				req, err := NewSignedRequest(token, content, nil)
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				req.Header.Set(slackheaders.Signature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
 				w := httptest.NewRecorder()
 				r.ServeHTTP(w, req)
 				resp := w.Result()
@@ -313,6 +362,40 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
+	Describe("WithNowFunc", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `{"type": "url_verification", "challenge": "hello"}`
+			ts      = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		)
+
+		Context("when the timestamp is close to the time returned by the function", func() {
+			It("responds with 200", func() {
+				r, err := ir.New(ir.WithSigningSecret(token), ir.WithNowFunc(func() time.Time { return ts.Add(1 * time.Minute) }))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is too old compared to the time returned by the function", func() {
+			It("responds with BadRequest", func() {
+				r, err := ir.New(ir.WithSigningSecret(token), ir.WithNowFunc(func() time.Time { return ts.Add(1 * time.Hour) }))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
 	Describe("InsecureSkipVerification", func() {
 		var (
 			r       *ir.Router
@@ -356,7 +439,7 @@ var _ = Describe("InteractionRouter", func() {
 			It("responds with 200", func() {
 				req, err := NewSignedRequest(token, content, nil)
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				req.Header.Set(slackheaders.Signature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
 				w := httptest.NewRecorder()
 				r.ServeHTTP(w, req)
 				resp := w.Result()
@@ -377,6 +460,362 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
+	Describe("Content-Type", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the Content-Type has a charset parameter", func() {
+			It("responds with 200", func() {
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the Content-Type is in a different case", func() {
+			It("responds with 200", func() {
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "Application/X-WWW-Form-Urlencoded;charset=UTF-8")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the Content-Type is not form-encoded", func() {
+			It("responds with BadRequest", func() {
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the Content-Type is malformed", func() {
+			It("responds with BadRequest", func() {
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithStrictParsing", func() {
+		var (
+			r                *ir.Router
+			hookErrs         []error
+			numHandlerCalled int
+		)
+		BeforeEach(func() {
+			hookErrs = nil
+			numHandlerCalled = 0
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.WithStrictParsing(), ir.OnError(func(_ context.Context, err error) {
+				hookErrs = append(hookErrs, err)
+			}))
+			Expect(err).NotTo(HaveOccurred())
+			handler := ir.HandlerFunc(func(context.Context, *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			r.On(slack.InteractionTypeShortcut, handler)
+			r.On(slack.InteractionTypeBlockActions, handler)
+			r.On(slack.InteractionTypeViewSubmission, handler)
+		})
+
+		Context("when the payload consists of known fields", func() {
+			It("responds with 200 without calling the hook", func() {
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(hookErrs).To(BeEmpty())
+			})
+		})
+
+		Context("when the payload contains unknown fields", func() {
+			It("processes the payload and calls the hook with an error that describes the field", func() {
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task", "brand_new_field": 1}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(ir.ErrUnknownField))
+				Expect(hookErrs[0].Error()).To(ContainSubstring("brand_new_field"))
+			})
+		})
+
+		Context("when the payload is an ordinary one sent by Slack", func() {
+			It("processes block_actions", func() {
+				req, err := NewRequest(blockActionsPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("processes view_submission", func() {
+				req, err := NewRequest(viewSubmissionPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("WithPrivateMetadataCodec", func() {
+		var (
+			r       *ir.Router
+			decoded string
+			encoded string
+			enc     = func(v string) (string, error) {
+				return "signed:" + v, nil
+			}
+			dec = func(v string) (string, error) {
+				if !strings.HasPrefix(v, "signed:") {
+					return "", errors.New("not signed")
+				}
+				return strings.TrimPrefix(v, "signed:"), nil
+			}
+		)
+		BeforeEach(func() {
+			var err error
+			decoded = ""
+			encoded = ""
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.WithPrivateMetadataCodec(enc, dec))
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeViewSubmission, ir.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+				decoded = callback.View.PrivateMetadata
+				var err error
+				encoded, err = ir.EncodePrivateMetadata(ctx, "next state")
+				return err
+			}))
+		})
+
+		Context("when private_metadata is valid", func() {
+			It("passes the decoded value to the handler", func() {
+				req, err := NewRequest(`{"type": "view_submission", "view": {"private_metadata": "signed:the state"}}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(decoded).To(Equal("the state"))
+				Expect(encoded).To(Equal("signed:next state"))
+			})
+		})
+
+		Context("when private_metadata is invalid", func() {
+			It("responds with Unauthorized without calling the handler", func() {
+				req, err := NewRequest(`{"type": "view_submission", "view": {"private_metadata": "forged state"}}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(decoded).To(Equal(""))
+			})
+		})
+
+		Context("when the codec is not set", func() {
+			It("EncodePrivateMetadata returns the value as is", func() {
+				encoded, err := ir.EncodePrivateMetadata(context.Background(), "the state")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(encoded).To(Equal("the state"))
+			})
+		})
+	})
+
+	Describe("WithAckWatchdog", func() {
+		var (
+			slowCh chan time.Duration
+			onSlow = func(_ context.Context, _ *slack.InteractionCallback, elapsed time.Duration) {
+				slowCh <- elapsed
+			}
+		)
+		BeforeEach(func() {
+			slowCh = make(chan time.Duration, 1)
+		})
+
+		Context("when the handler finishes within the budget", func() {
+			It("does not call the hook", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(time.Second, onSlow))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				req, err := NewRequest(`{"type": "shortcut"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Consistently(slowCh).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the handler exceeds the budget", func() {
+			It("calls the hook and responds with the result of the handler", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(10*time.Millisecond, onSlow))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					time.Sleep(30 * time.Millisecond)
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := NewRequest(`{"type": "shortcut"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusTeapot))
+				Expect(slowCh).To(Receive(BeNumerically(">=", 30*time.Millisecond)))
+			})
+		})
+
+		Context("when ForceAck is given", func() {
+			It("responds with 200 before the handler finishes", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(10*time.Millisecond, onSlow), ir.ForceAck())
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := NewRequest(`{"type": "shortcut"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				close(release)
+				Eventually(slowCh).Should(Receive())
+			})
+
+			It("passes errors returned after the response to the hook set by OnBackgroundError", func() {
+				errCh := make(chan error, 1)
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(10*time.Millisecond, onSlow), ir.ForceAck(),
+					ir.OnBackgroundError(func(_ context.Context, _ *slack.InteractionCallback, err error) { errCh <- err }))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := NewRequest(`{"type": "shortcut"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				close(release)
+				Eventually(errCh).Should(Receive(MatchError(routererrors.HttpError(http.StatusTeapot))))
+			})
+
+			It("passes failures to post to response_url to the hook set by OnBackgroundError", func() {
+				errCh := make(chan error, 1)
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(10*time.Millisecond, onSlow), ir.ForceAck(),
+					ir.OnBackgroundError(func(_ context.Context, _ *slack.InteractionCallback, err error) { errCh <- err }))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					return ir.Update(slack.NewDividerBlock())
+				}))
+				req, err := NewRequest(`{"type": "block_actions"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				close(release)
+				Eventually(errCh).Should(Receive(HaveOccurred()))
+			})
+		})
+
+		Context("when ForceAck is given without WithAckWatchdog", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.ForceAck())
+				Expect(err).To(MatchError(MatchRegexp("WithAckWatchdog")))
+				Expect(err).To(MatchError(routererrors.ErrInvalidOption))
+			})
+		})
+
+		Context("when the budget is negative", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithAckWatchdog(-time.Second, nil))
+				Expect(err).To(MatchError(routererrors.ErrInvalidOption))
+			})
+		})
+	})
+
+	Describe("StrictRegistration", func() {
+		var (
+			r       *ir.Router
+			handler = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.StrictRegistration())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when handlers for the same block action are registered", func() {
+			It("panics", func() {
+				r.On(slack.InteractionTypeBlockActions, handler, ir.BlockAction("BLOCK_ID", "ACTION_ID"))
+				Expect(func() {
+					r.On(slack.InteractionTypeBlockActions, handler, ir.BlockAction("BLOCK_ID", "ACTION_ID"))
+				}).To(Panic())
+			})
+		})
+
+		Context("when handlers for the same callback ID but different types are registered", func() {
+			It("does not panic", func() {
+				r.On(slack.InteractionTypeViewSubmission, handler, ir.CallbackID("CALLBACK_ID"))
+				Expect(func() {
+					r.On(slack.InteractionTypeViewClosed, handler, ir.CallbackID("CALLBACK_ID"))
+				}).NotTo(Panic())
+			})
+		})
+
+		Context("when SetFallback is called twice", func() {
+			It("panics", func() {
+				r.SetFallback(handler)
+				Expect(func() {
+					r.SetFallback(handler)
+				}).To(Panic())
+			})
+		})
+	})
+
 	Describe("On", func() {
 		var (
 			r       *ir.Router
@@ -435,6 +874,29 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 
+		Context("when a handler accesses the raw request", func() {
+			It("provides the raw body and headers", func() {
+				var (
+					rawBody []byte
+					header  http.Header
+				)
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					rawBody = ir.RawBody(ctx)
+					header = ir.RequestHeader(ctx)
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(slackheaders.RetryNum, "1")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(rawBody).To(Equal(buildRequestBody(content)))
+				Expect(header.Get(slackheaders.RetryNum)).To(Equal("1"))
+			})
+		})
+
 		Context("when a matching handler is registered to a different type of events", func() {
 			It("does not call the handler and responds with 200", func() {
 				r.On("other_interaction_type", handler)
@@ -479,7 +941,7 @@ var _ = Describe("InteractionRouter", func() {
 		Context("when a handler returned an error that equals to NotInterested using errors.Is", func() {
 			It("responds with 200", func() {
 				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
-					return errors.WithMessage(routererrors.NotInterested, "not interested")
+					return fmt.Errorf("not interested: %w", routererrors.NotInterested)
 				}))
 				req, err := NewRequest(content)
 				Expect(err).NotTo(HaveOccurred())
@@ -509,7 +971,7 @@ var _ = Describe("InteractionRouter", func() {
 			It("responds with a corresponding status code", func() {
 				code := http.StatusUnauthorized
 				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
-					return errors.WithMessage(routererrors.HttpError(code), "you ain't authorized")
+					return fmt.Errorf("you ain't authorized: %w", routererrors.HttpError(code))
 				}))
 				req, err := NewRequest(content)
 				Expect(err).NotTo(HaveOccurred())
@@ -520,6 +982,77 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 
+		Context("when a handler returned validation.Errors", func() {
+			It("responds with response_action: errors", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return fmt.Errorf("invalid input: %w", validation.Errors{"title_block": "too long"})
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(w.Body.String()).To(MatchJSON(`{"response_action": "errors", "errors": {"title_block": "too long"}}`))
+			})
+		})
+
+		Context("when a handler returned a MessageResponse", func() {
+			var (
+				server   *httptest.Server
+				received []byte
+			)
+			BeforeEach(func() {
+				received = nil
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					received, _ = ioutil.ReadAll(req.Body)
+					w.WriteHeader(http.StatusOK)
+				}))
+			})
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("posts the message to response_url", func() {
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return ir.Update(slack.NewDividerBlock())
+				}))
+				req, err := NewRequest(fmt.Sprintf(`{"type": "block_actions", "response_url": %q}`, server.URL))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(received).To(MatchJSON(`{"blocks": [{"type": "divider"}], "replace_original": true}`))
+			})
+
+			It("posts an ephemeral message to response_url", func() {
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return ir.ReplaceEphemeral("done")
+				}))
+				req, err := NewRequest(fmt.Sprintf(`{"type": "block_actions", "response_url": %q}`, server.URL))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(received).To(MatchJSON(`{"text": "done", "response_type": "ephemeral", "replace_original": true}`))
+			})
+
+			It("responds with InternalServerError when response_url is missing", func() {
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return ir.Update(slack.NewDividerBlock())
+				}))
+				req, err := NewRequest(`{"type": "block_actions"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
 		Describe("Fallback", func() {
 			var (
 				numFirstHandlerCalled  int
@@ -600,7 +1133,7 @@ var _ = Describe("InteractionRouter", func() {
 
 			Context("when a first handler returned an error that equals to NotInterested using errors.Is", func() {
 				It("falls back to another handler", func() {
-					firstError = errors.WithMessage(routererrors.NotInterested, "not interested")
+					firstError = fmt.Errorf("not interested: %w", routererrors.NotInterested)
 					secondError = nil
 					fallbackError = nil
 					req, err := NewRequest(content)
@@ -635,7 +1168,7 @@ var _ = Describe("InteractionRouter", func() {
 			Context("when the last handler returned an error that equals to NotInterested using errors.Is", func() {
 				It("falls back to fallback handler", func() {
 					firstError = routererrors.NotInterested
-					secondError = errors.WithMessage(routererrors.NotInterested, "not interested")
+					secondError = fmt.Errorf("not interested: %w", routererrors.NotInterested)
 					fallbackError = nil
 					req, err := NewRequest(content)
 					Expect(err).NotTo(HaveOccurred())
@@ -667,6 +1200,50 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 
+		Context("when more than one fallback handlers are added by AddFallback", func() {
+			It("falls back to the next one when a fallback handler returned NotInterested", func() {
+				numFirstHandlerCalled := 0
+				r.AddFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numFirstHandlerCalled++
+					return routererrors.NotInterested
+				}))
+				numSecondHandlerCalled := 0
+				r.AddFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numSecondHandlerCalled++
+					return nil
+				}))
+				numThirdHandlerCalled := 0
+				r.AddFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numThirdHandlerCalled++
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numFirstHandlerCalled).To(Equal(1))
+				Expect(numSecondHandlerCalled).To(Equal(1))
+				Expect(numThirdHandlerCalled).To(Equal(0))
+			})
+
+			It("responds with the error returned by a fallback handler", func() {
+				r.AddFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return routererrors.NotInterested
+				}))
+				r.AddFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return routererrors.HttpError(http.StatusTeapot)
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusTeapot))
+			})
+		})
+
 		Context("when more than one fallback handlers are registered", func() {
 			It("uses the last one", func() {
 				numFirstHandlerCalled := 0
@@ -690,8 +1267,133 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 	})
+
+	Describe("When", func() {
+		It("calls the inner handler only when the condition matches", func() {
+			numHandlerCalled := 0
+			innerHandler := ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx := context.Background()
+			callback := &slack.InteractionCallback{}
+			Expect(ir.Build(innerHandler, ir.When(staticCondition(true))).HandleInteraction(ctx, callback)).To(Succeed())
+			Expect(ir.Build(innerHandler, ir.When(staticCondition(false))).HandleInteraction(ctx, callback)).To(MatchError(routererrors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		var (
+			r        *ir.Router
+			hookErrs []error
+			payload  = `{"type": "block_actions", "actions": [{"block_id": "other_block", "action_id": "other_action"}]}`
+		)
+		BeforeEach(func() {
+			hookErrs = nil
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.OnError(func(_ context.Context, err error) {
+				hookErrs = append(hookErrs, err)
+			}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the other predicates don't match", func() {
+			It("responds with 200 and calls the hook with Mismatch", func() {
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(context.Context, *slack.InteractionCallback) error {
+					return nil
+				}), ir.FromGenericPredicate(router.FailOnMismatch[*slack.InteractionCallback]()), ir.BlockAction("block_id", "action_id"))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(hookErrs).To(HaveLen(1))
+				Expect(hookErrs[0]).To(MatchError(routererrors.Mismatch))
+			})
+		})
+
+		Context("when the handler itself is not interested", func() {
+			It("falls back to other handlers", func() {
+				numFallbackCalled := 0
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(context.Context, *slack.InteractionCallback) error {
+					return routererrors.NotInterested
+				}), ir.FromGenericPredicate(router.FailOnMismatch[*slack.InteractionCallback]()))
+				r.SetFallback(ir.HandlerFunc(func(context.Context, *slack.InteractionCallback) error {
+					numFallbackCalled++
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numFallbackCalled).To(Equal(1))
+				Expect(hookErrs).To(BeEmpty())
+			})
+		})
+	})
 })
 
+// blockActionsPayload is a block_actions payload in the shape that Slack sends today.
+const blockActionsPayload = `{
+	"type": "block_actions",
+	"user": {"id": "U0CA5", "username": "amy.mcgee", "name": "amy.mcgee", "team_id": "T0CA5"},
+	"api_app_id": "A0CA5",
+	"token": "Shh_its_a_seekrit",
+	"container": {"type": "message", "message_ts": "1548426417.840180", "channel_id": "C0CA5", "is_ephemeral": false},
+	"trigger_id": "12466734323.1395872398",
+	"team": {"id": "T0CA5", "domain": "pocket-calculator"},
+	"enterprise": null,
+	"is_enterprise_install": false,
+	"channel": {"id": "C0CA5", "name": "pocket-calculator"},
+	"message": {"type": "message", "user": "U0CA5", "ts": "1548426417.840180", "text": "Deploy?", "blocks": []},
+	"state": {"values": {}},
+	"response_url": "https://hooks.slack.com/actions/T0CA5/123/abc",
+	"actions": [{
+		"action_id": "deploy",
+		"block_id": "deploy_block",
+		"text": {"type": "plain_text", "text": "Deploy", "emoji": true},
+		"value": "production",
+		"type": "button",
+		"action_ts": "1548426417.840180"
+	}]
+}`
+
+// viewSubmissionPayload is a view_submission payload in the shape that Slack sends today.
+const viewSubmissionPayload = `{
+	"type": "view_submission",
+	"team": {"id": "T0CA5", "domain": "pocket-calculator"},
+	"user": {"id": "U0CA5", "username": "amy.mcgee", "name": "amy.mcgee", "team_id": "T0CA5"},
+	"api_app_id": "A0CA5",
+	"token": "Shh_its_a_seekrit",
+	"trigger_id": "12466734323.1395872398",
+	"view": {
+		"id": "V0CA5",
+		"team_id": "T0CA5",
+		"type": "modal",
+		"blocks": [],
+		"private_metadata": "",
+		"callback_id": "create_task",
+		"state": {"values": {"title": {"title_input": {"type": "plain_text_input", "value": "Write tests"}}}},
+		"hash": "156772938.1827394",
+		"title": {"type": "plain_text", "text": "Create a task", "emoji": true},
+		"clear_on_close": false,
+		"notify_on_close": false,
+		"close": null,
+		"submit": {"type": "plain_text", "text": "Create", "emoji": true},
+		"previous_view_id": null,
+		"root_view_id": "V0CA5",
+		"app_id": "A0CA5",
+		"external_id": "",
+		"app_installed_team_id": "T0CA5",
+		"bot_id": "B0CA5"
+	},
+	"response_urls": [],
+	"is_enterprise_install": false,
+	"enterprise": null
+}`
+
 func NewRequest(payload string) (*http.Request, error) {
 	body := buildRequestBody(payload)
 	req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader([]byte(body)))
@@ -714,7 +1416,7 @@ func NewSignedRequest(signingSecret string, payload string, ts *time.Time) (*htt
 		return nil, err
 	}
 	body := buildRequestBody(payload)
-	if err := testutils.AddSignature(req.Header, []byte(signingSecret), []byte(body), now); err != nil {
+	if err := signature.AddSignature(req.Header, []byte(signingSecret), []byte(body), now); err != nil {
 		return nil, err
 	}
 	return req, nil
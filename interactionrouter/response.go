@@ -0,0 +1,44 @@
+package interactionrouter
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// MessageResponse is a directive that handlers can return (as an error) to make the Router post a message to the `response_url` of the callback.
+//
+// This is typically used to update the message that contains the clicked button in `block_actions` handlers.
+// Use Update or ReplaceEphemeral to create one.
+//
+// For more details, see https://api.slack.com/interactivity/handling#message_responses.
+type MessageResponse struct {
+	Message *slack.WebhookMessage
+}
+
+func (r *MessageResponse) Error() string {
+	return "message response to response_url"
+}
+
+var _ error = &MessageResponse{}
+
+// Update returns a MessageResponse that replaces the source message with the given blocks.
+func Update(blocks ...slack.Block) *MessageResponse {
+	return &MessageResponse{
+		Message: &slack.WebhookMessage{
+			Blocks:          &slack.Blocks{BlockSet: blocks},
+			ReplaceOriginal: true,
+		},
+	}
+}
+
+// ReplaceEphemeral returns a MessageResponse that replaces the source message with an ephemeral message that consists of the given text and blocks.
+func ReplaceEphemeral(text string, blocks ...slack.Block) *MessageResponse {
+	msg := &slack.WebhookMessage{
+		Text:            text,
+		ResponseType:    slack.ResponseTypeEphemeral,
+		ReplaceOriginal: true,
+	}
+	if len(blocks) > 0 {
+		msg.Blocks = &slack.Blocks{BlockSet: blocks}
+	}
+	return &MessageResponse{Message: msg}
+}
@@ -0,0 +1,80 @@
+// Package envelope parses outer envelopes of Events API requests without depending on the structs of slack-go.
+//
+// slack-go fails to parse the whole request if it doesn't know the inner event or Slack changes fields of it,
+// even though the envelope itself is still readable. This package makes it possible to route such events anyway.
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Envelope is the outer part of an Events API request.
+type Envelope struct {
+	Token          string
+	TeamID         string
+	APIAppID       string
+	EnterpriseID   string
+	Type           string
+	EventID        string
+	EventTime      int
+	EventContext   string
+	Authorizations []Authorization
+
+	// Event is the inner event as is.
+	Event json.RawMessage
+
+	// InnerType is the `type` of Event, if any.
+	InnerType string
+}
+
+// Authorization is an installation that the event is visible to.
+type Authorization struct {
+	EnterpriseID        string `json:"enterprise_id"`
+	TeamID              string `json:"team_id"`
+	UserID              string `json:"user_id"`
+	IsBot               bool   `json:"is_bot"`
+	IsEnterpriseInstall bool   `json:"is_enterprise_install"`
+}
+
+// Parse parses the envelope in `body`.
+//
+// Fields are parsed one by one, so a field whose type has changed doesn't prevent the others from being parsed.
+// Unknown fields are ignored, and the inner event is parsed only as far as its `type`.
+func Parse(body []byte) (*Envelope, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse envelope: %w", err)
+	}
+	env := &Envelope{}
+	if err := json.Unmarshal(fields["type"], &env.Type); err != nil || env.Type == "" {
+		return nil, errors.New("failed to parse envelope: no type")
+	}
+	targets := map[string]interface{}{
+		"token":          &env.Token,
+		"team_id":        &env.TeamID,
+		"api_app_id":     &env.APIAppID,
+		"enterprise_id":  &env.EnterpriseID,
+		"event_id":       &env.EventID,
+		"event_time":     &env.EventTime,
+		"event_context":  &env.EventContext,
+		"authorizations": &env.Authorizations,
+	}
+	for name, target := range targets {
+		if raw, ok := fields[name]; ok {
+			_ = json.Unmarshal(raw, target)
+		}
+	}
+	env.Event = fields["event"]
+	if len(env.Event) > 0 {
+		var inner struct {
+			Type string `json:"type"`
+		}
+		// The inner event may not be an object if its schema has changed, but the envelope is still usable.
+		if err := json.Unmarshal(env.Event, &inner); err == nil {
+			env.InnerType = inner.Type
+		}
+	}
+	return env, nil
+}
@@ -0,0 +1,61 @@
+// Package jsonfields provides helpers to find JSON fields that are not mapped to Go structs.
+package jsonfields
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Unknown returns fields in `raw` that encoding/json would not map to any field of `v`.
+// `v` must be a struct or a pointer to a struct. Otherwise Unknown returns nil.
+func Unknown(raw json.RawMessage, v interface{}) (map[string]json.RawMessage, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	known := map[string]struct{}{}
+	collectKnown(t, known)
+	unknown := map[string]json.RawMessage{}
+	for name, value := range fields {
+		if _, ok := known[strings.ToLower(name)]; !ok {
+			unknown[name] = value
+		}
+	}
+	return unknown, nil
+}
+
+func collectKnown(t reflect.Type, known map[string]struct{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectKnown(ft, known)
+				continue
+			}
+		}
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		known[strings.ToLower(name)] = struct{}{}
+	}
+}
@@ -0,0 +1,75 @@
+// Package mirror provides a way to forward copies of requests to another endpoint.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+// DefaultTimeout is the timeout of mirrored requests.
+const DefaultTimeout = 10 * time.Second
+
+var mirroredHeaders = []string{
+	"Content-Type",
+	slackheaders.RequestTimestamp,
+	slackheaders.Signature,
+	slackheaders.RetryNum,
+	slackheaders.RetryReason,
+}
+
+// Mirror forwards copies of requests to URL.
+type Mirror struct {
+	// URL is an endpoint to forward requests to.
+	URL string
+
+	// Percent is the percentage (0-100) of requests to forward.
+	Percent float64
+
+	// Client is an HTTP client that is used to forward requests.
+	Client *http.Client
+}
+
+// New creates a new Mirror.
+func New(url string, percent float64) *Mirror {
+	return &Mirror{
+		URL:     url,
+		Percent: percent,
+		Client:  &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// Forward asynchronously sends a copy of the request if the request is sampled.
+// The response and errors are discarded.
+func (m *Mirror) Forward(req *http.Request, body []byte) {
+	if m.Percent <= 0 || rand.Float64()*100 >= m.Percent {
+		return
+	}
+	header := http.Header{}
+	for _, name := range mirroredHeaders {
+		if v := req.Header.Get(name); v != "" {
+			header.Set(name, v)
+		}
+	}
+	go m.send(header, body)
+}
+
+func (m *Mirror) send(header http.Header, body []byte) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, m.URL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header = header
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+}
@@ -0,0 +1,62 @@
+package routerutils
+
+import (
+	"bytes"
+	"io"
+)
+
+// MemoryBody is a request body that has already been read into memory (e.g. for signature verification).
+//
+// Routers use Bytes instead of reading it again, which saves copying the whole body on every request.
+type MemoryBody struct {
+	bytes.Reader
+	data []byte
+}
+
+// NewMemoryBody returns a MemoryBody that reads `data`.
+func NewMemoryBody(data []byte) *MemoryBody {
+	b := &MemoryBody{data: data}
+	b.Reader.Reset(data)
+	return b
+}
+
+// Bytes returns the part of the body that has not been read yet. The returned slice must not be modified.
+func (b *MemoryBody) Bytes() []byte {
+	return b.data[len(b.data)-b.Reader.Len():]
+}
+
+func (b *MemoryBody) Close() error {
+	return nil
+}
+
+// ReadBody reads `body` until EOF. If `body` is a MemoryBody, it returns its content without copying.
+//
+// `sizeHint` (e.g. `http.Request.ContentLength`) is used to allocate the buffer at once. It is ignored if it is not positive.
+func ReadBody(body io.Reader, sizeHint int64) ([]byte, error) {
+	if b, ok := body.(*MemoryBody); ok {
+		data := b.Bytes()
+		_, _ = b.Seek(0, io.SeekEnd)
+		return data, nil
+	}
+	if sizeHint <= 0 || sizeHint > maxSizeHint {
+		return io.ReadAll(body)
+	}
+	// This is the same as io.ReadAll except for the initial size of the buffer. The extra byte avoids growing it just to find EOF.
+	buf := make([]byte, 0, sizeHint+1)
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n, err := body.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+}
+
+// maxSizeHint prevents clients from making servers allocate huge buffers by sending fake Content-Length headers.
+const maxSizeHint = 1 << 20
@@ -0,0 +1,32 @@
+package routerutils
+
+// Build decorates `h` with `preds` by `wrap`.
+//
+// Predicates for which `outermost` returns true are applied after all the others regardless of their positions,
+// so that they can observe results of the other predicates.
+func Build[H, P any](h H, preds []P, wrap func(P, H) H, outermost func(P) bool) H {
+	return BuildMarked(h, preds, wrap, outermost, nil)
+}
+
+// BuildMarked is the same as Build, except that `h` is decorated by `mark` if there are any outermost predicates,
+// so that they can tell errors of `h` from ones of the other predicates (see MarkHandlerError).
+func BuildMarked[H, P any](h H, preds []P, wrap func(P, H) H, outermost func(P) bool, mark func(H) H) H {
+	var inner, outer []P
+	for _, p := range preds {
+		if outermost(p) {
+			outer = append(outer, p)
+			continue
+		}
+		inner = append(inner, p)
+	}
+	if len(outer) > 0 && mark != nil {
+		h = mark(h)
+	}
+	for _, p := range inner {
+		h = wrap(p, h)
+	}
+	for _, p := range outer {
+		h = wrap(p, h)
+	}
+	return h
+}
@@ -0,0 +1,59 @@
+package routerutils
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type requestKey struct{}
+
+// requestValue is stored as a single value so that WithRequest allocates as little as possible.
+type requestValue struct {
+	body   []byte
+	header http.Header
+}
+
+// WithRequest returns a new context that holds the raw body and the headers of the request.
+func WithRequest(ctx context.Context, body []byte, header http.Header) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestValue{body: body, header: header.Clone()})
+}
+
+// RawBody returns the raw body stored by WithRequest.
+func RawBody(ctx context.Context) []byte {
+	if v, ok := ctx.Value(requestKey{}).(*requestValue); ok {
+		return v.body
+	}
+	return nil
+}
+
+// Header returns the headers stored by WithRequest.
+func Header(ctx context.Context) http.Header {
+	if v, ok := ctx.Value(requestKey{}).(*requestValue); ok {
+		return v.header
+	}
+	return nil
+}
+
+type detachedContext struct {
+	context.Context
+}
+
+// Detach returns a new context that holds the same values as `ctx` but is never canceled and has no deadline.
+//
+// This is used to keep handlers running after the Router responded to the request.
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{Context: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !slackrouter_minimal
+// +build !slackrouter_minimal
+
+package routerutils
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// HandlerName returns a human-readable name of a handler.
+func HandlerName(h interface{}) string {
+	v := reflect.ValueOf(h)
+	if v.Kind() == reflect.Func {
+		if f := runtime.FuncForPC(v.Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,13 @@
+//go:build slackrouter_minimal
+// +build slackrouter_minimal
+
+package routerutils
+
+import "fmt"
+
+// HandlerName returns a human-readable name of a handler.
+//
+// In the minimal mode, function names are not resolved since runtime.FuncForPC is not available on every target.
+func HandlerName(h interface{}) string {
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,42 @@
+package routerutils
+
+import (
+	"errors"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// handlerError marks errors returned by handlers themselves, as opposed to ones returned by predicates.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *handlerError) Unwrap() error {
+	return e.err
+}
+
+// MarkHandlerError marks `err` returned by a handler itself, so that ToMismatch doesn't mistake it for a mismatch of predicates.
+// Marked errors still satisfy `errors.Is(err, routererrors.NotInterested)`.
+func MarkHandlerError(err error) error {
+	if errors.Is(err, routererrors.NotInterested) {
+		return &handlerError{err: err}
+	}
+	return err
+}
+
+// ToMismatch converts `routererrors.NotInterested` returned by predicates into `routererrors.Mismatch`.
+// NotInterested returned by handlers themselves (i.e. ones marked by MarkHandlerError) is returned as is.
+func ToMismatch(err error) error {
+	var he *handlerError
+	if errors.As(err, &he) {
+		return he.err
+	}
+	if errors.Is(err, routererrors.NotInterested) {
+		return routererrors.Mismatch
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package routerutils
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Registry records registrations of handlers to detect duplicates.
+type Registry struct {
+	entries map[string][][]interface{}
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string][][]interface{})}
+}
+
+// Register records a registration identified by `key` and `preds`.
+// It returns an error if there is already a registration with the same key and equal predicates (regardless of their order).
+func (r *Registry) Register(key string, preds []interface{}) error {
+	for _, registered := range r.entries[key] {
+		if equalPredicates(registered, preds) {
+			return fmt.Errorf("duplicate registration for %s with predicates %v", key, describePredicates(preds))
+		}
+	}
+	r.entries[key] = append(r.entries[key], preds)
+	return nil
+}
+
+func equalPredicates(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for _, pa := range a {
+		found := false
+		for i, pb := range b {
+			if !used[i] && reflect.DeepEqual(pa, pb) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func describePredicates(preds []interface{}) []string {
+	descs := make([]string, 0, len(preds))
+	for _, p := range preds {
+		descs = append(descs, fmt.Sprintf("%T%+v", p, p))
+	}
+	return descs
+}
+
+// Predicates converts a slice of predicates of any type into []interface{}.
+func Predicates(preds interface{}) []interface{} {
+	v := reflect.ValueOf(preds)
+	ps := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		ps = append(ps, v.Index(i).Interface())
+	}
+	return ps
+}
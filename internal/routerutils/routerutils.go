@@ -2,19 +2,42 @@ package routerutils
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/slackheaders"
 )
 
 func RespondWithError(w http.ResponseWriter, err error, verboseResponse bool) {
-	var httpErr routererrors.HttpError
-	if errors.As(err, &httpErr) {
-		w.WriteHeader(int(httpErr))
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
+	var (
+		retryErr   *routererrors.RetryLaterError
+		noRetryErr *routererrors.NoRetryError
+	)
+	if errors.As(err, &retryErr) && retryErr.After > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.After.Seconds()))))
+	}
+	if errors.As(err, &noRetryErr) {
+		w.Header().Set(slackheaders.NoRetry, "1")
 	}
+	w.WriteHeader(StatusCode(err))
 	if verboseResponse {
 		_, _ = w.Write([]byte(err.Error()))
 	}
 }
+
+// StatusCode returns the HTTP status code that RespondWithError responds with.
+func StatusCode(err error) int {
+	var (
+		httpErr  routererrors.HttpError
+		retryErr *routererrors.RetryLaterError
+	)
+	if errors.As(err, &retryErr) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.As(err, &httpErr) {
+		return int(httpErr)
+	}
+	return http.StatusInternalServerError
+}
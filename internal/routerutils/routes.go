@@ -0,0 +1,51 @@
+package routerutils
+
+import (
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/routeinfo"
+)
+
+// NewRoute builds a Route from a handler and its predicates.
+func NewRoute(typeName string, h interface{}, preds []interface{}) routeinfo.Route {
+	route := routeinfo.Route{
+		Type:    typeName,
+		Handler: HandlerName(h),
+	}
+	for _, p := range preds {
+		p = unwrapPredicate(p)
+		if d, ok := p.(routeinfo.Describer); ok {
+			route.Description = d.Description()
+			continue
+		}
+		if l, ok := p.(routeinfo.Labeler); ok {
+			for k, v := range l.Labels() {
+				if route.Labels == nil {
+					route.Labels = make(map[string]string)
+				}
+				route.Labels[k] = v
+			}
+		}
+		route.Predicates = append(route.Predicates, DescribePredicate(p))
+	}
+	return route
+}
+
+// unwrapPredicate returns the innermost predicate of adapters (e.g. predicates converted from generic ones).
+func unwrapPredicate(p interface{}) interface{} {
+	for {
+		u, ok := p.(interface{ Unwrap() interface{} })
+		if !ok {
+			return p
+		}
+		p = u.Unwrap()
+	}
+}
+
+// DescribePredicate returns a human-readable description of a predicate.
+func DescribePredicate(p interface{}) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
@@ -0,0 +1,107 @@
+package eventrouter
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// LifetimeViolationKind describes why an outbound call violates the lifetime of a request.
+type LifetimeViolationKind int
+
+const (
+	// CallAfterCancel indicates that an outbound call is made with a context that is already canceled.
+	CallAfterCancel LifetimeViolationKind = iota + 1
+
+	// CallAfterAck indicates that an outbound call is made after the Router responded to Slack in the synchronous mode.
+	// This usually means that a handler leaks the context to goroutines that outlive the handler.
+	CallAfterAck
+)
+
+func (k LifetimeViolationKind) String() string {
+	switch k {
+	case CallAfterCancel:
+		return "call after cancel"
+	case CallAfterAck:
+		return "call after ack"
+	default:
+		return "unknown"
+	}
+}
+
+// LifetimeViolation is reported to the hook set by WithLifetimeAudit.
+type LifetimeViolation struct {
+	Kind LifetimeViolationKind
+
+	// Event is the event that the handler was processing.
+	Event *slackevents.EventsAPIEvent
+
+	// Request is the outbound request.
+	Request *http.Request
+}
+
+// WithLifetimeAudit makes the Router report outbound calls that outlive requests to `hook`.
+//
+// This is intended to catch lifetime bugs introduced when switching between the synchronous mode and the asynchronous mode (see AckBefore).
+// Outbound calls are only observed if they are made via AuditTransport with contexts given to handlers
+// (e.g. by `slack.OptionHTTPClient(&http.Client{Transport: eventrouter.AuditTransport(nil)})` and `*Context` methods of `slack.Client`).
+// Calls are never blocked; `hook` is just called before they are sent.
+func WithLifetimeAudit(hook func(context.Context, *LifetimeViolation)) Option {
+	return optionFunc(func(r *Router) {
+		r.lifetimeAuditHook = hook
+	})
+}
+
+type lifetimeKey struct{}
+
+type lifetimeState struct {
+	hook  func(context.Context, *LifetimeViolation)
+	event *slackevents.EventsAPIEvent
+	acked int32
+}
+
+func withLifetimeState(ctx context.Context, hook func(context.Context, *LifetimeViolation), e *slackevents.EventsAPIEvent) (context.Context, *lifetimeState) {
+	state := &lifetimeState{hook: hook, event: e}
+	return context.WithValue(ctx, lifetimeKey{}, state), state
+}
+
+func (s *lifetimeState) markAcked() {
+	atomic.StoreInt32(&s.acked, 1)
+}
+
+func (s *lifetimeState) check(req *http.Request) {
+	ctx := req.Context()
+	var kind LifetimeViolationKind
+	switch {
+	case atomic.LoadInt32(&s.acked) != 0:
+		kind = CallAfterAck
+	case ctx.Err() != nil:
+		kind = CallAfterCancel
+	default:
+		return
+	}
+	s.hook(ctx, &LifetimeViolation{Kind: kind, Event: s.event, Request: req})
+}
+
+// AuditTransport wraps `base` so that outbound calls are checked by WithLifetimeAudit. If `base` is nil, `http.DefaultTransport` is used.
+//
+// Requests whose contexts don't come from Routers with WithLifetimeAudit are passed to `base` as is.
+func AuditTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if state, ok := req.Context().Value(lifetimeKey{}).(*lifetimeState); ok {
+			state.check(req)
+		}
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,365 @@
+// Package loopdetect detects conversations in which our bot and another bot keep replying to each other,
+// and routes messages of such conversations to a quarantine handler.
+//
+// Ignoring messages of our own bot is not enough to prevent loops: two bots that reply to each other's messages can flood a channel forever.
+// A Detector observes messages as an Enricher, and considers a conversation (a channel or a thread) to be in a loop
+// when messages of our bot and the same other bot alternate rapidly:
+//
+//	detector := loopdetect.New(selfBotID, loopdetect.OnLoop(func(ctx context.Context, loop *loopdetect.Loop) {
+//		log.Printf("loop with %s in %s", loop.Bot, loop.Channel)
+//	}))
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(detector))
+//	r.OnMessage(detector.Quarantine(handleMessage, quarantine))
+//
+// Messages of the other bot in the conversation are quarantined until the conversation cools down.
+// Messages of humans are never quarantined. Stats reports numbers of detected loops and quarantined messages for metrics.
+package loopdetect
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/internal/lrucache"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+const (
+	// DefaultMinExchanges is the default number of round trips between two bots that is considered to be a loop.
+	DefaultMinExchanges = 3
+
+	// DefaultMaxInterval is the default maximum interval between messages of a loop.
+	DefaultMaxInterval = 10 * time.Second
+
+	// DefaultCooldown is the default time for which conversations stay quarantined after the last message of a loop.
+	DefaultCooldown = 5 * time.Minute
+
+	// DefaultMaxConversations is the default maximum number of conversations that a Detector keeps track of.
+	DefaultMaxConversations = 10000
+)
+
+// Loop is a conversation in which our bot and another bot keep replying to each other.
+type Loop struct {
+	Channel string
+
+	// ThreadTimestamp is the timestamp of the thread, or empty if the loop is not in a thread.
+	ThreadTimestamp string
+
+	// Bot is the bot ID (or the user ID if the bot ID is not available) of the other bot.
+	Bot string
+}
+
+// Stats is a snapshot of the numbers of loops and quarantined messages.
+type Stats struct {
+	// Detected is the number of loops detected so far.
+	Detected int64
+
+	// Quarantined is the number of messages of other bots that are considered to be in loops.
+	Quarantined int64
+}
+
+// Option configures the Detector.
+type Option interface {
+	apply(*Detector)
+}
+
+type optionFunc func(*Detector)
+
+func (f optionFunc) apply(d *Detector) {
+	f(d)
+}
+
+// WithMinExchanges sets the number of round trips between our bot and another bot that is considered to be a loop.
+//
+// For example, 3 means that a conversation is in a loop when the last 6 messages alternate between the two bots.
+func WithMinExchanges(n int) Option {
+	return optionFunc(func(d *Detector) {
+		d.minExchanges = n
+	})
+}
+
+// WithMaxInterval sets the maximum interval between consecutive messages of a loop. Slower exchanges are not considered to be loops.
+func WithMaxInterval(interval time.Duration) Option {
+	return optionFunc(func(d *Detector) {
+		d.maxInterval = interval
+	})
+}
+
+// WithCooldown sets the time for which messages of the other bot stay quarantined after the last message of a loop.
+func WithCooldown(cooldown time.Duration) Option {
+	return optionFunc(func(d *Detector) {
+		d.cooldown = cooldown
+	})
+}
+
+// WithMaxConversations sets the maximum number of conversations that the Detector keeps track of.
+// The least recently active conversations are forgotten first.
+func WithMaxConversations(n int) Option {
+	return optionFunc(func(d *Detector) {
+		d.maxConversations = n
+	})
+}
+
+// OnLoop sets a hook that is called every time the Detector detects a new loop.
+func OnLoop(hook func(ctx context.Context, loop *Loop)) Option {
+	return optionFunc(func(d *Detector) {
+		d.loopHook = hook
+	})
+}
+
+// WithNowFunc sets a function that returns the current time, which is used when messages have malformed timestamps
+// and to expire conversations. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(d *Detector) {
+		d.now = now
+	})
+}
+
+// Detector detects loops between our bot and other bots.
+type Detector struct {
+	self             string
+	minExchanges     int
+	maxInterval      time.Duration
+	cooldown         time.Duration
+	maxConversations int
+	loopHook         func(ctx context.Context, loop *Loop)
+	now              func() time.Time
+
+	mu            sync.Mutex
+	conversations *lrucache.Cache
+	stats         Stats
+}
+
+type post struct {
+	ts     string
+	at     time.Time
+	author string
+	isBot  bool
+}
+
+type conversation struct {
+	posts []post
+
+	// bot and until are set while the conversation is quarantined.
+	bot   string
+	until time.Time
+}
+
+// New creates a new Detector. `self` is the bot ID or the user ID of our bot.
+func New(self string, opts ...Option) *Detector {
+	d := &Detector{
+		self:             self,
+		minExchanges:     DefaultMinExchanges,
+		maxInterval:      DefaultMaxInterval,
+		cooldown:         DefaultCooldown,
+		maxConversations: DefaultMaxConversations,
+		now:              time.Now,
+	}
+	for _, o := range opts {
+		o.apply(d)
+	}
+	d.conversations = lrucache.New(d.maxConversations, d.ttl(), d.now)
+	return d
+}
+
+func (d *Detector) ttl() time.Duration {
+	window := d.maxInterval * time.Duration(2*d.minExchanges)
+	if d.cooldown > window {
+		return d.cooldown
+	}
+	return window
+}
+
+type loopKey struct{}
+
+// InLoop returns true if and only if the event being processed is a message of another bot in a conversation that is in a loop.
+//
+// This is only available in contexts enriched by Detector. Otherwise it returns false.
+func InLoop(ctx context.Context) bool {
+	inLoop, _ := ctx.Value(loopKey{}).(bool)
+	return inLoop
+}
+
+// WithInLoop returns a new context in which InLoop returns `inLoop`. This is mainly intended to test handlers that use InLoop.
+func WithInLoop(ctx context.Context, inLoop bool) context.Context {
+	return context.WithValue(ctx, loopKey{}, inLoop)
+}
+
+// Enrich records `e` if it is a message, and returns a new context in which InLoop tells whether the message is in a loop.
+//
+// Since a mention is sent as both `message` and `app_mention` events, each message is recorded only once.
+func (d *Detector) Enrich(ctx context.Context, e *slackevents.EventsAPIEvent) (context.Context, error) {
+	var p post
+	var channel, thread string
+	switch ev := e.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		channel, thread = ev.Channel, ev.ThreadTimeStamp
+		p = d.newPost(ev.TimeStamp, ev.User, ev.BotID)
+	case *slackevents.AppMentionEvent:
+		channel, thread = ev.Channel, ev.ThreadTimeStamp
+		p = d.newPost(ev.TimeStamp, ev.User, ev.BotID)
+	default:
+		return ctx, nil
+	}
+	if p.ts == "" {
+		return ctx, nil
+	}
+	loop, inLoop := d.record(channel, thread, p)
+	if loop != nil && d.loopHook != nil {
+		d.loopHook(ctx, loop)
+	}
+	return WithInLoop(ctx, inLoop), nil
+}
+
+func (d *Detector) newPost(ts, user, botID string) post {
+	p := post{ts: ts, author: user, isBot: botID != ""}
+	if botID != "" {
+		p.author = botID
+	}
+	if user == d.self || botID == d.self {
+		p.author = d.self
+		p.isBot = true
+	}
+	if sec, err := strconv.ParseFloat(ts, 64); err == nil {
+		whole, frac := math.Modf(sec)
+		p.at = time.Unix(int64(whole), int64(frac*1e9))
+	} else {
+		p.at = d.now()
+	}
+	return p
+}
+
+// record adds `p` to the conversation and returns a new Loop if it is detected by `p`, and whether `p` is in a loop.
+func (d *Detector) record(channel, thread string, p post) (*Loop, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := channel + "/" + thread
+	var c *conversation
+	if v, ok := d.conversations.Get(key); ok {
+		c = v.(*conversation)
+	} else {
+		c = &conversation{}
+	}
+	for _, q := range c.posts {
+		if q.ts == p.ts {
+			// Retries and mentions that are already recorded.
+			return nil, p.isBot && p.author != d.self && p.author == c.bot && !p.at.After(c.until)
+		}
+	}
+	n := 2 * d.minExchanges
+	c.posts = append(c.posts, p)
+	if len(c.posts) > n {
+		c.posts = c.posts[len(c.posts)-n:]
+	}
+	d.conversations.Add(key, c)
+
+	if !p.isBot || p.author == d.self {
+		// Our own messages extend the quarantine, but are never quarantined since handlers ignore them anyway.
+		if c.bot != "" && p.author == d.self && !p.at.After(c.until) {
+			c.until = p.at.Add(d.cooldown)
+		}
+		return nil, false
+	}
+	if c.bot == p.author && !p.at.After(c.until) {
+		c.until = p.at.Add(d.cooldown)
+		d.stats.Quarantined++
+		return nil, true
+	}
+	other, ok := d.alternating(c.posts)
+	if !ok {
+		return nil, false
+	}
+	c.bot = other
+	c.until = p.at.Add(d.cooldown)
+	d.stats.Detected++
+	d.stats.Quarantined++
+	return &Loop{Channel: channel, ThreadTimestamp: thread, Bot: other}, true
+}
+
+// alternating returns the other bot if `posts` are exactly minExchanges round trips between our bot and the other bot,
+// each of which is posted rapidly.
+func (d *Detector) alternating(posts []post) (string, bool) {
+	if len(posts) < 2*d.minExchanges {
+		return "", false
+	}
+	var other string
+	for i, p := range posts {
+		if !p.isBot {
+			return "", false
+		}
+		if i > 0 {
+			prev := posts[i-1]
+			if prev.author == p.author || p.at.Sub(prev.at) > d.maxInterval {
+				return "", false
+			}
+		}
+		if p.author == d.self {
+			continue
+		}
+		if other != "" && other != p.author {
+			return "", false
+		}
+		other = p.author
+	}
+	return other, other != ""
+}
+
+// Stats returns the current Stats, which can be exported by metrics libraries.
+func (d *Detector) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// LoopCondition is a condition that matches messages in loops. See Looping.
+type LoopCondition struct{}
+
+// Looping returns a condition that matches if and only if the event being processed is in a loop (see InLoop).
+//
+// It can be used with predicates named `When` (e.g. `message.When`), so that the quarantine handler takes precedence:
+//
+//	r.OnMessage(quarantine, message.When(loopdetect.Looping()))
+//	r.OnMessage(handleMessage)
+func Looping() *LoopCondition {
+	return &LoopCondition{}
+}
+
+func (c *LoopCondition) Match(ctx context.Context) bool {
+	return InLoop(ctx)
+}
+
+func (c *LoopCondition) String() string {
+	return "Looping()"
+}
+
+// Quarantine returns a handler that calls `quarantine` for messages in loops, and `h` for others.
+// If `quarantine` is nil, messages in loops are just dropped.
+func (d *Detector) Quarantine(h, quarantine message.Handler) message.Handler {
+	return message.HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if !InLoop(ctx) {
+			return h.HandleMessageEvent(ctx, e)
+		}
+		if quarantine == nil {
+			return nil
+		}
+		return quarantine.HandleMessageEvent(ctx, e)
+	})
+}
+
+// QuarantineAppMention is the same as Quarantine, but for `app_mention` events.
+func (d *Detector) QuarantineAppMention(h, quarantine appmention.Handler) appmention.Handler {
+	return appmention.HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		if !InLoop(ctx) {
+			return h.HandleAppMentionEvent(ctx, e)
+		}
+		if quarantine == nil {
+			return nil
+		}
+		return quarantine.HandleAppMentionEvent(ctx, e)
+	})
+}
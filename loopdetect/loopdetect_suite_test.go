@@ -0,0 +1,13 @@
+package loopdetect_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLoopdetect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Loopdetect Suite")
+}
@@ -0,0 +1,174 @@
+package loopdetect_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/loopdetect"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+const self = "BSELF"
+
+// post returns a message posted `sec` seconds after the epoch of the test by `author`, which is a bot if it starts with "B".
+func post(author string, sec int) *slackevents.EventsAPIEvent {
+	ev := &slackevents.MessageEvent{Channel: "C001", TimeStamp: fmt.Sprintf("%d.000100", 1600000000+sec)}
+	if author[0] == 'B' {
+		ev.BotID = author
+	} else {
+		ev.User = author
+	}
+	return &slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{Type: slackevents.Message, Data: ev}}
+}
+
+var _ = Describe("Loopdetect", func() {
+	var (
+		ctx      = context.Background()
+		detector *loopdetect.Detector
+		loops    []*loopdetect.Loop
+	)
+
+	BeforeEach(func() {
+		loops = nil
+		detector = loopdetect.New(self, loopdetect.OnLoop(func(_ context.Context, loop *loopdetect.Loop) {
+			loops = append(loops, loop)
+		}))
+	})
+
+	enrich := func(e *slackevents.EventsAPIEvent) bool {
+		enriched, err := detector.Enrich(ctx, e)
+		Expect(err).NotTo(HaveOccurred())
+		return loopdetect.InLoop(enriched)
+	}
+
+	pingPong := func(other string, start, interval, n int) []bool {
+		results := make([]bool, 0, n)
+		for i := 0; i < n; i++ {
+			author := self
+			if i%2 == 0 {
+				author = other
+			}
+			results = append(results, enrich(post(author, start+i*interval)))
+		}
+		return results
+	}
+
+	It("detects rapid alternating messages between two bots", func() {
+		results := pingPong("BOTHER", 0, 1, 7)
+		Expect(results).To(Equal([]bool{false, false, false, false, false, false, true}))
+		Expect(loops).To(HaveLen(1))
+		Expect(loops[0].Channel).To(Equal("C001"))
+		Expect(loops[0].Bot).To(Equal("BOTHER"))
+		Expect(detector.Stats()).To(Equal(loopdetect.Stats{Detected: 1, Quarantined: 1}))
+	})
+
+	It("keeps quarantining messages of the other bot until the conversation cools down", func() {
+		pingPong("BOTHER", 0, 1, 7)
+		Expect(enrich(post("BOTHER", 60))).To(BeTrue())
+		Expect(enrich(post("BOTHER", 60+301))).To(BeFalse())
+		Expect(loops).To(HaveLen(1))
+		Expect(detector.Stats().Quarantined).To(Equal(int64(2)))
+	})
+
+	It("does not quarantine messages of humans", func() {
+		pingPong("BOTHER", 0, 1, 7)
+		Expect(enrich(post("U001", 8))).To(BeFalse())
+	})
+
+	It("does not consider slow exchanges as loops", func() {
+		results := pingPong("BOTHER", 0, 30, 7)
+		Expect(results).NotTo(ContainElement(BeTrue()))
+		Expect(loops).To(BeEmpty())
+	})
+
+	It("does not consider exchanges with humans as loops", func() {
+		results := pingPong("U001", 0, 1, 7)
+		Expect(results).NotTo(ContainElement(BeTrue()))
+	})
+
+	It("does not consider conversations among more than two bots as loops", func() {
+		authors := []string{"BOTHER", self, "BTHIRD", self, "BOTHER", self, "BTHIRD"}
+		for i, author := range authors {
+			Expect(enrich(post(author, i))).To(BeFalse())
+		}
+	})
+
+	It("respects WithMinExchanges", func() {
+		detector = loopdetect.New(self, loopdetect.WithMinExchanges(1))
+		Expect(pingPong("BOTHER", 0, 1, 3)).To(Equal([]bool{false, false, true}))
+	})
+
+	It("records the same message only once", func() {
+		for i := 0; i < 6; i++ {
+			Expect(enrich(post("BOTHER", 0))).To(BeFalse())
+		}
+		Expect(loops).To(BeEmpty())
+	})
+
+	It("tells whether app_mention events are in loops", func() {
+		pingPong("BOTHER", 0, 1, 6)
+		inLoop := enrich(&slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.AppMention,
+			Data: &slackevents.AppMentionEvent{Channel: "C001", BotID: "BOTHER", TimeStamp: "1600000006.000100"},
+		}})
+		Expect(inLoop).To(BeTrue())
+		Expect(enrich(post("BOTHER", 6))).To(BeTrue())
+		Expect(detector.Stats().Quarantined).To(Equal(int64(1)))
+	})
+
+	Describe("Quarantine", func() {
+		var handled, quarantined int
+
+		BeforeEach(func() {
+			handled, quarantined = 0, 0
+		})
+
+		h := message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+			handled++
+			return nil
+		})
+		q := message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+			quarantined++
+			return nil
+		})
+
+		It("routes messages in loops to the quarantine handler", func() {
+			wrapped := detector.Quarantine(h, q)
+			Expect(wrapped.HandleMessageEvent(loopdetect.WithInLoop(ctx, true), &slackevents.MessageEvent{})).To(Succeed())
+			Expect(wrapped.HandleMessageEvent(ctx, &slackevents.MessageEvent{})).To(Succeed())
+			Expect(handled).To(Equal(1))
+			Expect(quarantined).To(Equal(1))
+		})
+
+		It("drops messages in loops when the quarantine handler is nil", func() {
+			wrapped := detector.Quarantine(h, nil)
+			Expect(wrapped.HandleMessageEvent(loopdetect.WithInLoop(ctx, true), &slackevents.MessageEvent{})).To(Succeed())
+			Expect(handled).To(Equal(0))
+		})
+
+		It("routes mentions in loops to the quarantine handler", func() {
+			wrapped := detector.QuarantineAppMention(appmention.HandlerFunc(func(context.Context, *slackevents.AppMentionEvent) error {
+				handled++
+				return nil
+			}), appmention.HandlerFunc(func(context.Context, *slackevents.AppMentionEvent) error {
+				quarantined++
+				return nil
+			}))
+			Expect(wrapped.HandleAppMentionEvent(loopdetect.WithInLoop(ctx, true), &slackevents.AppMentionEvent{})).To(Succeed())
+			Expect(handled).To(Equal(0))
+			Expect(quarantined).To(Equal(1))
+		})
+	})
+
+	Describe("Looping", func() {
+		It("matches contexts in loops", func() {
+			Expect(loopdetect.Looping().Match(loopdetect.WithInLoop(ctx, true))).To(BeTrue())
+			Expect(loopdetect.Looping().Match(ctx)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/errors"
+)
+
+// TextDiff is the difference between texts of a message before and after an edit.
+type TextDiff struct {
+	// Old is the text before the edit.
+	Old string
+
+	// New is the text after the edit.
+	New string
+
+	// Added are words that appear in New more times than in Old, in the order in which they appear in New.
+	Added []string
+
+	// Removed are words that appear in Old more times than in New, in the order in which they appear in Old.
+	Removed []string
+}
+
+// Changed returns true if the text has been changed by the edit.
+func (d *TextDiff) Changed() bool {
+	return d.Old != d.New
+}
+
+// Diff computes the difference between texts of the message before and after the edit that `e` notifies.
+//
+// Words are separated by whitespace, and their positions are not taken into account: moving words doesn't count as a change.
+// It returns false if `e` is not `message_changed` or lacks either of the messages.
+func Diff(e *slackevents.MessageEvent) (*TextDiff, bool) {
+	if e.SubType != SubTypeMessageChanged || e.Message == nil || e.PreviousMessage == nil {
+		return nil, false
+	}
+	d := &TextDiff{Old: e.PreviousMessage.Text, New: e.Message.Text}
+	oldWords, newWords := strings.Fields(d.Old), strings.Fields(d.New)
+	d.Added = subtract(newWords, oldWords)
+	d.Removed = subtract(oldWords, newWords)
+	return d, true
+}
+
+// subtract returns items of `a` that remain after removing each item of `b` once, preserving the order in `a`.
+func subtract(a, b []string) []string {
+	counts := make(map[string]int, len(b))
+	for _, s := range b {
+		counts[s]++
+	}
+	var result []string
+	for _, s := range a {
+		if counts[s] > 0 {
+			counts[s]--
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+type textChangedMatchingPredicate struct {
+	re *regexp.Regexp
+}
+
+// TextChangedMatching is a predicate that is considered to be "true" if and only if an edit of a message (i.e. `message_changed`)
+// introduces text that matches to the given regexp.
+//
+// That is, it is "true" if the edited text has a match that the original text doesn't have, so edits that merely keep
+// matching text as is are not matched. This is useful to catch banned content that is added after a message is posted.
+func TextChangedMatching(re *regexp.Regexp) Predicate {
+	return &textChangedMatchingPredicate{re: re}
+}
+
+func (p *textChangedMatchingPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		d, ok := Diff(e)
+		if !ok {
+			return errors.NotInterested
+		}
+		added := subtract(p.re.FindAllString(d.New, -1), p.re.FindAllString(d.Old, -1))
+		if len(added) == 0 {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func (p *textChangedMatchingPredicate) String() string {
+	return fmt.Sprintf("TextChangedMatching(%s)", p.re)
+}
@@ -5,11 +5,24 @@ package message
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/enrich"
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/slacktext"
 )
 
 // Handler processes `message` events.
@@ -23,6 +36,18 @@ func (f HandlerFunc) HandleMessageEvent(ctx context.Context, e *slackevents.Mess
 	return f(ctx, e)
 }
 
+// HandlerProvider constructs Handlers lazily. See `router.HandlerProvider`.
+type HandlerProvider = router.HandlerProvider[Handler]
+
+type HandlerProviderFunc = router.HandlerProviderFunc[Handler]
+
+// FromProvider returns a Handler that obtains a Handler from `p` every time it processes an event and delegates the event to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func FromProvider(p HandlerProvider) Handler {
+	return FromGeneric(router.FromProvider(p, Handler.HandleMessageEvent))
+}
+
 // Predicate disthinguishes whether or not a certain handler should process coming events.
 type Predicate interface {
 	Wrap(h Handler) Handler
@@ -47,6 +72,310 @@ func (p *textRegexpPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *textRegexpPredicate) String() string {
+	return fmt.Sprintf("TextRegexp(%s)", p.re)
+}
+
+type keywordPredicate struct {
+	keywords []string
+}
+
+// Keyword is a predicate that is considered to be "true" if and only if a text of a message contains one of the given words.
+//
+// Words are matched as a whole and case-insensitively, and Slack markup such as `<@U123>` and `<#C123|name>` is ignored.
+// For example, `Keyword("deploy")` matches "<@U123> Deploy now!" but neither "deployment" nor "<#C123|deploy>".
+func Keyword(keywords ...string) Predicate {
+	return &keywordPredicate{keywords: keywords}
+}
+
+func (p *keywordPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, token := range slacktext.Words(e.Text) {
+			for _, k := range p.keywords {
+				if strings.EqualFold(token, k) {
+					return h.HandleMessageEvent(ctx, e)
+				}
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *keywordPredicate) String() string {
+	return fmt.Sprintf("Keyword(%s)", strings.Join(p.keywords, ", "))
+}
+
+type hasBlocksOfTypePredicate struct {
+	types []string
+}
+
+// HasBlocksOfType is a predicate that is considered to be "true" if and only if a message has at least one block of the given types (e.g. "section").
+//
+// Blocks of an edited message (i.e. `message_changed`) are also taken into account.
+// Since `slackevents.MessageEvent` does not hold blocks, they are read from the raw request body,
+// which is available only when the handler is called by `eventrouter.Router`. Otherwise the predicate is always considered to be "false".
+func HasBlocksOfType(types ...string) Predicate {
+	return &hasBlocksOfTypePredicate{types: types}
+}
+
+func (p *hasBlocksOfTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, blockType := range blockTypes(routerutils.RawBody(ctx)) {
+			for _, t := range p.types {
+				if blockType == t {
+					return h.HandleMessageEvent(ctx, e)
+				}
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *hasBlocksOfTypePredicate) String() string {
+	return fmt.Sprintf("HasBlocksOfType(%s)", strings.Join(p.types, ", "))
+}
+
+type rawBlocks struct {
+	Blocks []struct {
+		Type string `json:"type"`
+	} `json:"blocks"`
+	Message *rawBlocks `json:"message"`
+}
+
+// blockTypes returns types of blocks in the message event contained in `body`.
+func blockTypes(body []byte) []string {
+	var payload struct {
+		Event rawBlocks `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	var types []string
+	for m := &payload.Event; m != nil; m = m.Message {
+		for _, b := range m.Blocks {
+			types = append(types, b.Type)
+		}
+	}
+	return types
+}
+
+type attachmentTextRegexpPredicate struct {
+	re *regexp.Regexp
+}
+
+// AttachmentTextRegexp is a predicate that is considered to be "true" if and only if any of the attachments of a message
+// has `text`, `pretext`, `title` or `fallback` that matches to the given regexp.
+func AttachmentTextRegexp(re *regexp.Regexp) Predicate {
+	return &attachmentTextRegexpPredicate{re: re}
+}
+
+func (p *attachmentTextRegexpPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, a := range e.Attachments {
+			for _, text := range []string{a.Text, a.Pretext, a.Title, a.Fallback} {
+				if p.re.MatchString(text) {
+					return h.HandleMessageEvent(ctx, e)
+				}
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *attachmentTextRegexpPredicate) String() string {
+	return fmt.Sprintf("AttachmentTextRegexp(%s)", p.re)
+}
+
+type eventAgePredicate struct {
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// EventAgeOption configures EventAge.
+type EventAgeOption interface {
+	apply(*eventAgePredicate)
+}
+
+type eventAgeOptionFunc func(*eventAgePredicate)
+
+func (f eventAgeOptionFunc) apply(p *eventAgePredicate) {
+	f(p)
+}
+
+// WithNowFunc sets a function that returns the current time.
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) EventAgeOption {
+	return eventAgeOptionFunc(func(p *eventAgePredicate) {
+		p.now = now
+	})
+}
+
+// EventAge is a predicate that is considered to be "true" if and only if a message was posted within `maxAge`, judging from its `ts`.
+//
+// This is useful to avoid acting on stale messages (e.g. paging someone) delivered late by retries.
+// To drop stale events regardless of their types, see `eventrouter.WithMaxEventAge`.
+func EventAge(maxAge time.Duration, opts ...EventAgeOption) Predicate {
+	p := &eventAgePredicate{maxAge: maxAge, now: time.Now}
+	for _, o := range opts {
+		o.apply(p)
+	}
+	return p
+}
+
+func (p *eventAgePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		ts, err := strconv.ParseFloat(e.TimeStamp, 64)
+		if err != nil {
+			return errors.NotInterested
+		}
+		postedAt := time.Unix(0, int64(ts*float64(time.Second)))
+		if p.now().Sub(postedAt) > p.maxAge {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func (p *eventAgePredicate) String() string {
+	return fmt.Sprintf("EventAge(%s)", p.maxAge)
+}
+
+type samplePredicate struct {
+	rate    float64
+	keyFunc func(*slackevents.MessageEvent) string
+	name    string
+}
+
+// Sample is a predicate that is considered to be "true" for a deterministic subset of messages whose proportion is `rate` (0.0-1.0).
+//
+// Messages are identified by their channels and timestamps, so retries of the same message are always sampled (or not sampled) in the same way.
+func Sample(rate float64) Predicate {
+	return &samplePredicate{rate: rate, keyFunc: messageKey, name: "Sample"}
+}
+
+// SampleByKey is the same as Sample except that messages are identified by `keyFunc`.
+//
+// For example, using `func(e *slackevents.MessageEvent) string { return e.User }` samples all messages from a certain subset of users.
+func SampleByKey(rate float64, keyFunc func(*slackevents.MessageEvent) string) Predicate {
+	return &samplePredicate{rate: rate, keyFunc: keyFunc, name: "SampleByKey"}
+}
+
+func messageKey(e *slackevents.MessageEvent) string {
+	return e.Channel + "/" + e.TimeStamp
+}
+
+func (p *samplePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		sum := sha256.Sum256([]byte(p.keyFunc(e)))
+		if float64(binary.BigEndian.Uint64(sum[:8]))/math.MaxUint64 >= p.rate {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func (p *samplePredicate) String() string {
+	return fmt.Sprintf("%s(%g)", p.name, p.rate)
+}
+
+// LocaleResolver returns the locale (e.g. "en-US") of the user who sent a message, or an empty string if it is unknown.
+type LocaleResolver func(ctx context.Context, e *slackevents.MessageEvent) string
+
+// UserLocale is a LocaleResolver that returns the locale of the user stored by `enrich.Enricher` with `enrich.WithUsers()`.
+func UserLocale(ctx context.Context, _ *slackevents.MessageEvent) string {
+	if u := enrich.UserFromContext(ctx); u != nil {
+		return u.Locale
+	}
+	return ""
+}
+
+type textRegexpAnyPredicate struct {
+	resolver LocaleResolver
+	res      map[string]*regexp.Regexp
+}
+
+// TextRegexpAny is a predicate that matches a text of a message against the regexp for the language of the sender.
+//
+// Keys of `res` are either locales (e.g. "en-US") or languages (e.g. "en").
+// The regexp for the exact locale is preferred to the one for its language.
+// If the locale is unknown or there is no regexp for it, the predicate is considered to be "true" if any of the regexps matches.
+//
+// Locales are resolved by UserLocale. Use TextRegexpAnyWith to resolve them in a different way.
+func TextRegexpAny(res map[string]*regexp.Regexp) Predicate {
+	return TextRegexpAnyWith(UserLocale, res)
+}
+
+// TextRegexpAnyWith is the same as TextRegexpAny except that it resolves locales by `resolver`.
+func TextRegexpAnyWith(resolver LocaleResolver, res map[string]*regexp.Regexp) Predicate {
+	return &textRegexpAnyPredicate{resolver: resolver, res: res}
+}
+
+func (p *textRegexpAnyPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if re, ok := p.lookup(p.resolver(ctx, e)); ok {
+			if !re.MatchString(e.Text) {
+				return errors.NotInterested
+			}
+			return h.HandleMessageEvent(ctx, e)
+		}
+		for _, re := range p.res {
+			if re.MatchString(e.Text) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *textRegexpAnyPredicate) lookup(locale string) (*regexp.Regexp, bool) {
+	if locale == "" {
+		return nil, false
+	}
+	if re, ok := p.res[locale]; ok {
+		return re, true
+	}
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		re, ok := p.res[locale[:i]]
+		return re, ok
+	}
+	return nil, false
+}
+
+func (p *textRegexpAnyPredicate) String() string {
+	langs := make([]string, 0, len(p.res))
+	for lang := range p.res {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for i, lang := range langs {
+		langs[i] = fmt.Sprintf("%s: %s", lang, p.res[lang])
+	}
+	return fmt.Sprintf("TextRegexpAny(%s)", strings.Join(langs, ", "))
+}
+
+type normalizedTextRegexpPredicate struct {
+	re *regexp.Regexp
+}
+
+// NormalizedTextRegexp is a predicate that is considered to be "true" if and only if a text of a message matches to the given regexp
+// after it is normalized by `slacktext.Normalize`.
+func NormalizedTextRegexp(re *regexp.Regexp) Predicate {
+	return &normalizedTextRegexpPredicate{re: re}
+}
+
+func (p *normalizedTextRegexpPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if !p.re.MatchString(slacktext.Normalize(e.Text)) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func (p *normalizedTextRegexpPredicate) String() string {
+	return fmt.Sprintf("NormalizedTextRegexp(%s)", p.re)
+}
+
 type channelPredicate struct {
 	id string
 }
@@ -65,6 +394,10 @@ func (p *channelPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *channelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.id)
+}
+
 type subTypePredicate struct {
 	subType string
 }
@@ -85,10 +418,170 @@ func (p *subTypePredicate) Wrap(h Handler) Handler {
 	})
 }
 
+func (p *subTypePredicate) String() string {
+	return fmt.Sprintf("SubType(%s)", p.subType)
+}
+
+type channelTypePredicate struct {
+	types []string
+}
+
+// ChannelType is a predicate that is considered to be "true" if and only if `channel_type` of a message is one of the given ones.
+//
+// See the package `channeltype` for possible values.
+func ChannelType(types ...string) Predicate {
+	return &channelTypePredicate{types: types}
+}
+
+func (p *channelTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, t := range p.types {
+			if e.ChannelType == t {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *channelTypePredicate) String() string {
+	return fmt.Sprintf("ChannelType(%s)", strings.Join(p.types, ", "))
+}
+
+type channelNamedPredicate struct {
+	names []string
+}
+
+// ChannelNamed is a predicate that is considered to be "true" if and only if a message is posted to a conversation with one of the given names.
+//
+// This requires the context to be enriched by `enrich.Enricher` (see `eventrouter.WithEnricher`).
+// Otherwise the predicate is always considered to be "false".
+func ChannelNamed(names ...string) Predicate {
+	return &channelNamedPredicate{names: names}
+}
+
+func (p *channelNamedPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		ch := enrich.ChannelFromContext(ctx)
+		if ch == nil {
+			return errors.NotInterested
+		}
+		for _, name := range p.names {
+			if ch.Name == name {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *channelNamedPredicate) String() string {
+	return fmt.Sprintf("ChannelNamed(%s)", strings.Join(p.names, ", "))
+}
+
+type fromUserWithDomainPredicate struct {
+	domains []string
+}
+
+// FromUserWithDomain is a predicate that is considered to be "true" if and only if a message is sent by a user
+// whose email address belongs to one of the given domains (e.g. "example.com"). Domains are compared case-insensitively.
+//
+// This requires the context to be enriched by `enrich.Enricher` with `enrich.WithUsers()`.
+// Otherwise the predicate is always considered to be "false".
+func FromUserWithDomain(domains ...string) Predicate {
+	return &fromUserWithDomainPredicate{domains: domains}
+}
+
+func (p *fromUserWithDomainPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		u := enrich.UserFromContext(ctx)
+		if u == nil {
+			return errors.NotInterested
+		}
+		at := strings.LastIndex(u.Profile.Email, "@")
+		if at < 0 {
+			return errors.NotInterested
+		}
+		domain := u.Profile.Email[at+1:]
+		for _, d := range p.domains {
+			if strings.EqualFold(domain, d) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+func (p *fromUserWithDomainPredicate) String() string {
+	return fmt.Sprintf("FromUserWithDomain(%s)", strings.Join(p.domains, ", "))
+}
+
+// Condition is a condition that does not depend on events. See `router.Condition`.
+type Condition = router.Condition
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When(cond Condition) Predicate {
+	return FromGenericPredicate(router.When[*slackevents.MessageEvent](cond))
+}
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+// ChannelIn is a predicate that is considered to be "true" if and only if the channel where a message is posted is in `set`.
+func ChannelIn(set Set) Predicate {
+	return FromGenericPredicate(router.In("ChannelIn", set, func(e *slackevents.MessageEvent) string { return e.Channel }))
+}
+
+// UserIn is a predicate that is considered to be "true" if and only if the user who sent a message is in `set`.
+func UserIn(set Set) Predicate {
+	return FromGenericPredicate(router.In("UserIn", set, func(e *slackevents.MessageEvent) string { return e.User }))
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description(text string) Predicate {
+	return FromGenericPredicate(router.Description[*slackevents.MessageEvent](text))
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue(key, value interface{}) Predicate {
+	return FromGenericPredicate(router.WithValue[*slackevents.MessageEvent](key, value))
+}
+
+type failOnMismatchPredicate struct{}
+
+// FailOnMismatch is a predicate that turns mismatches of the other predicates into `errors.Mismatch`.
+//
+// By default, the Router silently falls back to other handlers when predicates don't match.
+// With FailOnMismatch, the Router stops there and reports the mismatch (e.g. to the hook set by `eventrouter.OnError`) as a misrouted event,
+// while still acknowledging the event. Unlike other predicates, it takes effect regardless of its position in the predicates.
+func FailOnMismatch() Predicate {
+	return &failOnMismatchPredicate{}
+}
+
+func (p *failOnMismatchPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		return routerutils.ToMismatch(h.HandleMessageEvent(ctx, e))
+	})
+}
+
+func (p *failOnMismatchPredicate) Outermost() {}
+
+func (p *failOnMismatchPredicate) String() string {
+	return "FailOnMismatch()"
+}
+
 // Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func Build(h Handler, preds ...Predicate) Handler {
-	for _, p := range preds {
-		h = p.Wrap(h)
-	}
-	return h
+	return routerutils.BuildMarked(h, preds, Predicate.Wrap, isOutermost, markHandler)
+}
+
+// markHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markHandler(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		return routerutils.MarkHandlerError(h.HandleMessageEvent(ctx, e))
+	})
 }
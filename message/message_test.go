@@ -2,14 +2,23 @@ package message_test
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/channeltype"
+	"github.com/genkami/go-slack-event-router/dynamic"
+	"github.com/genkami/go-slack-event-router/enrich"
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
 	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/schedule"
 )
 
 var _ = Describe("Message", func() {
@@ -180,4 +189,470 @@ var _ = Describe("Message", func() {
 			})
 		})
 	})
+
+	Describe("IsThreadBroadcast", func() {
+		It("calls the inner handler only for broadcast replies", func() {
+			h := message.IsThreadBroadcast().Wrap(innerHandler)
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{SubType: message.SubTypeThreadBroadcast})).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{SubType: message.SubTypeReplyBroadcast})).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{ThreadTimeStamp: "1.0", TimeStamp: "2.0"})).To(MatchError(errors.NotInterested))
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{
+				SubType: message.SubTypeMessageChanged,
+				Message: &slackevents.MessageEvent{SubType: message.SubTypeThreadBroadcast},
+			})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+	})
+
+	Describe("Thread helpers", func() {
+		var (
+			parent    = &slackevents.MessageEvent{TimeStamp: "1.0", ThreadTimeStamp: "1.0"}
+			reply     = &slackevents.MessageEvent{TimeStamp: "2.0", ThreadTimeStamp: "1.0"}
+			broadcast = &slackevents.MessageEvent{TimeStamp: "3.0", ThreadTimeStamp: "1.0", SubType: message.SubTypeThreadBroadcast}
+			plain     = &slackevents.MessageEvent{TimeStamp: "4.0"}
+			edited    = &slackevents.MessageEvent{TimeStamp: "5.0", SubType: message.SubTypeMessageChanged, Message: broadcast}
+		)
+
+		It("detects broadcast replies including edits of them", func() {
+			Expect(message.IsBroadcast(broadcast)).To(BeTrue())
+			Expect(message.IsBroadcast(edited)).To(BeTrue())
+			Expect(message.IsBroadcast(reply)).To(BeFalse())
+			Expect(message.IsBroadcast(plain)).To(BeFalse())
+		})
+
+		It("detects replies in threads", func() {
+			Expect(message.IsThreadReply(reply)).To(BeTrue())
+			Expect(message.IsThreadReply(broadcast)).To(BeTrue())
+			Expect(message.IsThreadReply(edited)).To(BeTrue())
+			Expect(message.IsThreadReply(parent)).To(BeFalse())
+			Expect(message.IsThreadReply(plain)).To(BeFalse())
+		})
+
+		It("returns the timestamp of the thread", func() {
+			Expect(message.ThreadTimeStamp(edited)).To(Equal("1.0"))
+			Expect(message.ThreadTimeStamp(plain)).To(BeEmpty())
+		})
+	})
+
+	Describe("Diff", func() {
+		It("computes words added and removed by the edit", func() {
+			d, ok := message.Diff(&slackevents.MessageEvent{
+				SubType:         message.SubTypeMessageChanged,
+				Message:         &slackevents.MessageEvent{Text: "deploy the api to prod now"},
+				PreviousMessage: &slackevents.MessageEvent{Text: "deploy the web to prod"},
+			})
+			Expect(ok).To(BeTrue())
+			Expect(d.Changed()).To(BeTrue())
+			Expect(d.Added).To(Equal([]string{"api", "now"}))
+			Expect(d.Removed).To(Equal([]string{"web"}))
+		})
+
+		It("returns false for messages that are not edits", func() {
+			_, ok := message.Diff(&slackevents.MessageEvent{Text: "hello"})
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("TextChangedMatching", func() {
+		var (
+			h    message.Handler
+			edit = func(before, after string) *slackevents.MessageEvent {
+				return &slackevents.MessageEvent{
+					SubType:         message.SubTypeMessageChanged,
+					Message:         &slackevents.MessageEvent{Text: after},
+					PreviousMessage: &slackevents.MessageEvent{Text: before},
+				}
+			}
+		)
+		BeforeEach(func() {
+			h = message.TextChangedMatching(regexp.MustCompile(`\d{4}-\d{4}`)).Wrap(innerHandler)
+		})
+
+		It("calls the inner handler when the edit introduces a match", func() {
+			Expect(h.HandleMessageEvent(ctx, edit("my card is", "my card is 1234-5678"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, edit("1234-5678", "1234-5678 and 8765-4321"))).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("does not call the inner handler when the match has been there", func() {
+			Expect(h.HandleMessageEvent(ctx, edit("card 1234-5678", "my card: 1234-5678"))).To(MatchError(errors.NotInterested))
+			Expect(h.HandleMessageEvent(ctx, edit("card 1234-5678", "card"))).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("does not call the inner handler for messages that are not edits", func() {
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "1234-5678"})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("WithValue", func() {
+		type key struct{}
+		It("passes the value to the inner handler", func() {
+			var got interface{}
+			h := message.Build(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				got = ctx.Value(key{})
+				return nil
+			}), message.WithValue(key{}, "the value"))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal("the value"))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		It("returns Mismatch when the other predicates don't match", func() {
+			h := message.Build(innerHandler, message.FailOnMismatch(), message.TextRegexp(regexp.MustCompile("deploy")))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})
+			Expect(err).To(MatchError(errors.Mismatch))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("calls the inner handler when the other predicates match", func() {
+			h := message.Build(innerHandler, message.FailOnMismatch(), message.TextRegexp(regexp.MustCompile("deploy")))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "deploy now"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("wraps the handler with the generic predicate", func() {
+			hello := router.Filter(func(_ context.Context, e *slackevents.MessageEvent) bool {
+				return e.Text == "hello world"
+			})
+			h := message.Build(innerHandler, message.FromGenericPredicate(hello))
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "bye"})).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("applies generic FailOnMismatch after the other predicates", func() {
+			h := message.Build(innerHandler,
+				message.FromGenericPredicate(router.FailOnMismatch[*slackevents.MessageEvent]()),
+				message.TextRegexp(regexp.MustCompile("deploy")))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})
+			Expect(err).To(MatchError(errors.Mismatch))
+		})
+	})
+
+	Describe("ToGeneric", func() {
+		It("converts the handler to a generic one and back", func() {
+			h := message.FromGeneric(router.Build(message.ToGeneric(innerHandler)))
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FromProvider", func() {
+		var numProvided int
+		BeforeEach(func() {
+			numProvided = 0
+		})
+
+		It("obtains a handler from the provider for every event", func() {
+			h := message.FromProvider(message.HandlerProviderFunc(func(_ context.Context) (message.Handler, error) {
+				numProvided++
+				return innerHandler, nil
+			}))
+			e := &slackevents.MessageEvent{Text: "hello world"}
+			Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+			Expect(numProvided).To(Equal(2))
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("does not call the provider when the predicates are false", func() {
+			h := message.Build(message.FromProvider(message.HandlerProviderFunc(func(_ context.Context) (message.Handler, error) {
+				numProvided++
+				return innerHandler, nil
+			})), message.TextRegexp(regexp.MustCompile(`BYE`)))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numProvided).To(Equal(0))
+		})
+
+		It("returns the error from the provider", func() {
+			h := message.FromProvider(message.HandlerProviderFunc(func(_ context.Context) (message.Handler, error) {
+				return nil, errors.HttpError(503)
+			}))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello world"})
+			Expect(err).To(Equal(errors.HttpError(503)))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("ChannelType", func() {
+		It("calls the inner handler only when channel_type is one of the given ones", func() {
+			h := message.Build(innerHandler, message.ChannelType(channeltype.IM, channeltype.MPIM))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{ChannelType: channeltype.MPIM})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{ChannelType: channeltype.Channel})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("ChannelNamed", func() {
+		It("calls the inner handler only when the enriched channel has one of the given names", func() {
+			h := message.Build(innerHandler, message.ChannelNamed("incidents", "alerts"))
+			err := h.HandleMessageEvent(enrich.WithChannel(ctx, &slack.Channel{GroupConversation: slack.GroupConversation{Name: "alerts"}}), &slackevents.MessageEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(enrich.WithChannel(ctx, &slack.Channel{GroupConversation: slack.GroupConversation{Name: "random"}}), &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("is not interested in messages when the context is not enriched", func() {
+			h := message.Build(innerHandler, message.ChannelNamed("incidents"))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FromUserWithDomain", func() {
+		withEmail := func(email string) context.Context {
+			return enrich.WithUser(ctx, &slack.User{Profile: slack.UserProfile{Email: email}})
+		}
+
+		It("calls the inner handler only when the user's email belongs to one of the given domains", func() {
+			h := message.Build(innerHandler, message.FromUserWithDomain("example.com"))
+			err := h.HandleMessageEvent(withEmail("alice@EXAMPLE.com"), &slackevents.MessageEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(withEmail("bob@example.com.evil.org"), &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(withEmail(""), &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("is not interested in messages when the context is not enriched", func() {
+			h := message.Build(innerHandler, message.FromUserWithDomain("example.com"))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("TextRegexpAny", func() {
+		var (
+			res = map[string]*regexp.Regexp{
+				"en":    regexp.MustCompile(`^hello`),
+				"ja":    regexp.MustCompile(`^こんにちは`),
+				"en-GB": regexp.MustCompile(`^good day`),
+			}
+			withLocale = func(locale string) context.Context {
+				return enrich.WithUser(ctx, &slack.User{Locale: locale})
+			}
+		)
+
+		It("uses the regexp for the language of the user", func() {
+			h := message.Build(innerHandler, message.TextRegexpAny(res))
+			err := h.HandleMessageEvent(withLocale("ja-JP"), &slackevents.MessageEvent{Text: "こんにちは"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(withLocale("ja-JP"), &slackevents.MessageEvent{Text: "hello"})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(withLocale("en-US"), &slackevents.MessageEvent{Text: "hello"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("prefers the regexp for the exact locale", func() {
+			h := message.Build(innerHandler, message.TextRegexpAny(res))
+			err := h.HandleMessageEvent(withLocale("en-GB"), &slackevents.MessageEvent{Text: "hello"})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(withLocale("en-GB"), &slackevents.MessageEvent{Text: "good day"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("accepts any of the regexps when the locale is unknown", func() {
+			h := message.Build(innerHandler, message.TextRegexpAny(res))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "こんにちは"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(withLocale("fr-FR"), &slackevents.MessageEvent{Text: "hello"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "bonjour"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("resolves locales by the given resolver", func() {
+			resolver := func(_ context.Context, _ *slackevents.MessageEvent) string { return "ja" }
+			h := message.Build(innerHandler, message.TextRegexpAnyWith(resolver, res))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "hello"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("Keyword", func() {
+		It("matches whole words case-insensitively", func() {
+			h := message.Build(innerHandler, message.Keyword("deploy", "rollback"))
+			for _, text := range []string{"deploy", "please DEPLOY it", "Rollback!", "deploy,rollback"} {
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: text})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			for _, text := range []string{"deployment", "redeploy", "", "roll back"} {
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: text})
+				Expect(err).To(Equal(errors.NotInterested))
+			}
+			Expect(numHandlerCalled).To(Equal(4))
+		})
+
+		It("ignores Slack markup", func() {
+			h := message.Build(innerHandler, message.Keyword("deploy"))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "<@U123> deploy <#C123|general>"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "see <#C123|deploy> and <https://example.com/deploy|docs>"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("NormalizedTextRegexp", func() {
+		It("matches the regexp against the normalized text", func() {
+			h := message.Build(innerHandler, message.NormalizedTextRegexp(regexp.MustCompile(`^@bot deploy #prod & more$`)))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "<@U123|bot> deploy <#C123|prod> &amp; more"})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "<@U123> deploy <#C123> &amp; more"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("HasBlocksOfType", func() {
+		withBody := func(body string) context.Context {
+			return routerutils.WithRequest(ctx, []byte(body), nil)
+		}
+
+		It("calls the inner handler only when the message has blocks of the given types", func() {
+			h := message.Build(innerHandler, message.HasBlocksOfType("section", "header"))
+			err := h.HandleMessageEvent(withBody(`{"event": {"type": "message", "blocks": [{"type": "divider"}, {"type": "section"}]}}`), &slackevents.MessageEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(withBody(`{"event": {"type": "message", "blocks": [{"type": "divider"}]}}`), &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(withBody(`{"event": {"type": "message"}}`), &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("takes blocks of edited messages into account", func() {
+			h := message.Build(innerHandler, message.HasBlocksOfType("section"))
+			err := h.HandleMessageEvent(withBody(`{"event": {"type": "message", "subtype": "message_changed", "message": {"blocks": [{"type": "section"}]}}}`), &slackevents.MessageEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("is not interested in messages when the raw body is not available", func() {
+			h := message.Build(innerHandler, message.HasBlocksOfType("section"))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("AttachmentTextRegexp", func() {
+		It("calls the inner handler only when any of the attachments matches", func() {
+			h := message.Build(innerHandler, message.AttachmentTextRegexp(regexp.MustCompile(`FIRING`)))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Attachments: []slack.Attachment{
+				{Text: "resolved"},
+				{Title: "[FIRING] HighLatency"},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "FIRING", Attachments: []slack.Attachment{{Text: "resolved"}}})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "FIRING"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("EventAge", func() {
+		ts := func(t time.Time) string {
+			return fmt.Sprintf("%d.000100", t.Unix())
+		}
+
+		It("calls the inner handler only when the message is not older than the given age", func() {
+			now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+			h := message.Build(innerHandler, message.EventAge(time.Minute, message.WithNowFunc(func() time.Time { return now })))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: ts(now.Add(-10 * time.Second))})
+			Expect(err).NotTo(HaveOccurred())
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: ts(now.Add(-2 * time.Minute))})
+			Expect(err).To(Equal(errors.NotInterested))
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: "invalid"})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("When", func() {
+		It("calls the inner handler only when the condition matches", func() {
+			// 2021-03-01 is Monday.
+			now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+			window := schedule.Window(time.UTC, "Mon-Fri 09:00-18:00").WithNowFunc(func() time.Time { return now })
+			h := message.Build(innerHandler, message.When(window))
+			err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})
+			Expect(err).NotTo(HaveOccurred())
+			now = time.Date(2021, 3, 1, 20, 0, 0, 0, time.UTC)
+			err = h.HandleMessageEvent(ctx, &slackevents.MessageEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("Sample", func() {
+		It("processes a deterministic subset of messages", func() {
+			h := message.Build(innerHandler, message.Sample(0.3))
+			var sampled []string
+			for i := 0; i < 1000; i++ {
+				ts := fmt.Sprintf("1355517523.%06d", i)
+				if h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Channel: "C123", TimeStamp: ts}) == nil {
+					sampled = append(sampled, ts)
+				}
+			}
+			Expect(len(sampled)).To(BeNumerically("~", 300, 60))
+			for _, ts := range sampled {
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Channel: "C123", TimeStamp: ts})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("processes all or nothing when the rate is 1 or 0", func() {
+			all := message.Build(innerHandler, message.Sample(1))
+			none := message.Build(innerHandler, message.Sample(0))
+			for i := 0; i < 100; i++ {
+				e := &slackevents.MessageEvent{Channel: "C123", TimeStamp: fmt.Sprintf("1355517523.%06d", i)}
+				Expect(all.HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(none.HandleMessageEvent(ctx, e)).To(Equal(errors.NotInterested))
+			}
+		})
+	})
+
+	Describe("SampleByKey", func() {
+		It("samples messages by the given key", func() {
+			h := message.Build(innerHandler, message.SampleByKey(0.5, func(e *slackevents.MessageEvent) string { return e.User }))
+			for i := 0; i < 100; i++ {
+				user := fmt.Sprintf("U%03d", i)
+				first := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{User: user, TimeStamp: "1.000001"})
+				second := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{User: user, TimeStamp: "2.000002"})
+				Expect(second == nil).To(Equal(first == nil))
+			}
+			Expect(numHandlerCalled).To(BeNumerically("~", 100, 40))
+		})
+	})
+
+	Describe("ChannelIn and UserIn", func() {
+		It("calls the inner handler only when the value is in the set", func() {
+			set := dynamic.NewSet(dynamic.SourceFunc(func(_ context.Context) ([]string, error) {
+				return []string{"C001", "U001"}, nil
+			}), time.Hour)
+			h := message.Build(innerHandler, message.ChannelIn(set), message.UserIn(set))
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Channel: "C001", User: "U001"})).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Channel: "C002", User: "U001"})).To(Equal(errors.NotInterested))
+			Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Channel: "C001", User: "U002"})).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
 })
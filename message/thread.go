@@ -0,0 +1,76 @@
+package message
+
+import (
+	"context"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/errors"
+)
+
+// Subtypes of messages that are related to threads.
+const (
+	// SubTypeThreadBroadcast is the subtype of replies that are also sent to the channel.
+	SubTypeThreadBroadcast = "thread_broadcast"
+
+	// SubTypeReplyBroadcast is the deprecated subtype of replies that are also sent to the channel.
+	// Slack no longer sends this, but it may appear in old messages (e.g. in `previous_message` of `message_changed`).
+	SubTypeReplyBroadcast = "reply_broadcast"
+
+	// SubTypeMessageChanged is the subtype of events that notify edits of messages.
+	// The edited message is in `Message` and the original one is in `PreviousMessage`.
+	SubTypeMessageChanged = "message_changed"
+)
+
+// IsBroadcast returns true if `e` is a reply that is also sent to the channel, or an edit of such a reply.
+//
+// Such messages appear both in the channel and in the thread, so handlers that process both of them may want to skip them once.
+func IsBroadcast(e *slackevents.MessageEvent) bool {
+	e = latest(e)
+	return e.SubType == SubTypeThreadBroadcast || e.SubType == SubTypeReplyBroadcast
+}
+
+// IsThreadReply returns true if `e` is a reply in a thread (including broadcast ones), or an edit of such a reply.
+//
+// Parent messages of threads are not considered to be replies.
+func IsThreadReply(e *slackevents.MessageEvent) bool {
+	e = latest(e)
+	return e.ThreadTimeStamp != "" && e.ThreadTimeStamp != e.TimeStamp
+}
+
+// ThreadTimeStamp returns the timestamp of the parent message of the thread that `e` belongs to, or an empty string if it doesn't belong to any thread.
+//
+// Unlike `e.ThreadTimeStamp`, this takes edits of messages into account.
+func ThreadTimeStamp(e *slackevents.MessageEvent) string {
+	return latest(e).ThreadTimeStamp
+}
+
+// latest returns the message after the edit if `e` is `message_changed`, and otherwise `e` itself.
+func latest(e *slackevents.MessageEvent) *slackevents.MessageEvent {
+	if e.SubType == SubTypeMessageChanged && e.Message != nil {
+		return e.Message
+	}
+	return e
+}
+
+type threadBroadcastPredicate struct{}
+
+// IsThreadBroadcast is a predicate that is considered to be "true" if and only if a message is a reply that is also sent to the channel.
+//
+// Edits of such replies (i.e. `message_changed`) are not matched. Use IsBroadcast to detect them as well.
+func IsThreadBroadcast() Predicate {
+	return &threadBroadcastPredicate{}
+}
+
+func (p *threadBroadcastPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.SubType != SubTypeThreadBroadcast && e.SubType != SubTypeReplyBroadcast {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func (p *threadBroadcastPredicate) String() string {
+	return "IsThreadBroadcast()"
+}
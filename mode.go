@@ -0,0 +1,35 @@
+//go:build !slackrouter_minimal
+// +build !slackrouter_minimal
+
+package eventrouter
+
+import (
+	"encoding/json"
+
+	"github.com/genkami/go-slack-event-router/internal/jsonfields"
+	"github.com/genkami/go-slack-event-router/internal/mirror"
+)
+
+// MinimalMode reports whether the package is built with the `slackrouter_minimal` build tag.
+const MinimalMode = false
+
+func checkMode(r *Router) error {
+	return nil
+}
+
+func newMirror(t *mirrorTarget) requestMirror {
+	return mirror.New(t.url, t.percent)
+}
+
+func findUnknownFields(body []byte, data interface{}) (map[string]json.RawMessage, error) {
+	envelope := struct {
+		Event json.RawMessage `json:"event"`
+	}{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	if len(envelope.Event) == 0 {
+		return map[string]json.RawMessage{}, nil
+	}
+	return jsonfields.Unknown(envelope.Event, data)
+}
@@ -0,0 +1,34 @@
+//go:build slackrouter_minimal
+// +build slackrouter_minimal
+
+package eventrouter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MinimalMode reports whether the package is built with the `slackrouter_minimal` build tag.
+//
+// In the minimal mode, features that depend on reflection or outgoing HTTP requests
+// (PreserveUnknownFields and WithMirror) are not available, and New returns ErrUnsupportedInMinimalMode if they are given.
+const MinimalMode = true
+
+func checkMode(r *Router) error {
+	if r.preserveUnknownFields {
+		return fmt.Errorf("PreserveUnknownFields: %w", ErrUnsupportedInMinimalMode)
+	}
+	if r.mirrorTarget != nil {
+		return fmt.Errorf("WithMirror: %w", ErrUnsupportedInMinimalMode)
+	}
+	return nil
+}
+
+func newMirror(*mirrorTarget) requestMirror {
+	// Unreachable since checkMode rejects WithMirror.
+	return nil
+}
+
+func findUnknownFields(body []byte, data interface{}) (map[string]json.RawMessage, error) {
+	return nil, fmt.Errorf("PreserveUnknownFields: %w", ErrUnsupportedInMinimalMode)
+}
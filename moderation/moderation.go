@@ -0,0 +1,187 @@
+// Package moderation routes events depending on labels that a pluggable Classifier attaches to their texts (e.g. "pii" or "profanity").
+//
+// Texts are classified by Enricher, and the results are used by conditions like Labeled:
+//
+//	classifier := moderation.Regexps(map[string]*regexp.Regexp{"pii": creditCardPattern})
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.WithEnricher(moderation.NewEnricher(classifier)))
+//	r.OnMessage(handlePII, message.When(moderation.Labeled("pii")))
+//
+// Classification is lazy: the Classifier is called at most once per event, and only when a condition or a handler asks for labels.
+// So classifiers that call external APIs cost nothing for events that are not routed by labels.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+// Classifier attaches labels to texts.
+type Classifier interface {
+	Classify(ctx context.Context, text string) ([]string, error)
+}
+
+type ClassifierFunc func(ctx context.Context, text string) ([]string, error)
+
+func (f ClassifierFunc) Classify(ctx context.Context, text string) ([]string, error) {
+	return f(ctx, text)
+}
+
+// Regexps returns a Classifier that attaches each label whose pattern matches to the text. Labels are sorted in ascending order.
+func Regexps(patterns map[string]*regexp.Regexp) Classifier {
+	return ClassifierFunc(func(_ context.Context, text string) ([]string, error) {
+		var labels []string
+		for label, re := range patterns {
+			if re.MatchString(text) {
+				labels = append(labels, label)
+			}
+		}
+		sort.Strings(labels)
+		return labels, nil
+	})
+}
+
+// Option configures the Enricher.
+type Option interface {
+	apply(*Enricher)
+}
+
+type optionFunc func(*Enricher)
+
+func (f optionFunc) apply(e *Enricher) {
+	f(e)
+}
+
+// OnError sets a hook that is called when the Classifier fails.
+//
+// Conditions can't return errors, so they treat texts that failed to be classified as having no labels. If not set, errors are ignored.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(e *Enricher) {
+		e.errorHook = hook
+	})
+}
+
+// Enricher makes texts of events available for classification.
+//
+// It can be passed to `eventrouter.WithEnricher`.
+type Enricher struct {
+	classifier Classifier
+	errorHook  func(context.Context, error)
+}
+
+// NewEnricher creates a new Enricher that classifies texts by `classifier`.
+func NewEnricher(classifier Classifier, opts ...Option) *Enricher {
+	e := &Enricher{classifier: classifier}
+	for _, o := range opts {
+		o.apply(e)
+	}
+	return e
+}
+
+type resultKey struct{}
+
+type result struct {
+	enricher *Enricher
+	text     string
+
+	once   sync.Once
+	labels []string
+	err    error
+}
+
+func (r *result) get(ctx context.Context) ([]string, error) {
+	r.once.Do(func() {
+		if r.enricher == nil {
+			return
+		}
+		labels, err := r.enricher.classifier.Classify(ctx, r.text)
+		if err != nil {
+			r.err = fmt.Errorf("failed to classify text: %w", err)
+			if r.enricher.errorHook != nil {
+				r.enricher.errorHook(ctx, r.err)
+			}
+			return
+		}
+		r.labels = labels
+	})
+	return r.labels, r.err
+}
+
+// Enrich returns a new context that refers to the text of `ev`. It doesn't call the Classifier by itself.
+//
+// Texts of `message` (the edited text for `message_changed`) and `app_mention` events are classified. Other events have no labels.
+func (e *Enricher) Enrich(ctx context.Context, ev *slackevents.EventsAPIEvent) (context.Context, error) {
+	text, ok := Text(ev)
+	if !ok {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, resultKey{}, &result{enricher: e, text: text}), nil
+}
+
+// Labels returns labels of the text of the event being processed, classifying it if it has not been classified yet.
+//
+// It returns no labels if the context is not enriched by Enricher or the event has no text.
+func Labels(ctx context.Context) ([]string, error) {
+	r, ok := ctx.Value(resultKey{}).(*result)
+	if !ok {
+		return nil, nil
+	}
+	return r.get(ctx)
+}
+
+// WithLabels returns a new context in which Labels returns `labels`. This is mainly intended to test handlers that use Labels.
+func WithLabels(ctx context.Context, labels ...string) context.Context {
+	return context.WithValue(ctx, resultKey{}, &result{labels: labels})
+}
+
+// Text returns the text of `ev` to be classified, or false if it has no text.
+func Text(ev *slackevents.EventsAPIEvent) (string, bool) {
+	switch inner := ev.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		if inner.SubType == message.SubTypeMessageChanged && inner.Message != nil {
+			return inner.Message.Text, true
+		}
+		return inner.Text, true
+	case *slackevents.AppMentionEvent:
+		return inner.Text, true
+	}
+	return "", false
+}
+
+// LabelCondition is a condition returned by Labeled.
+type LabelCondition struct {
+	labels []string
+}
+
+// Labeled returns a condition that matches if and only if the text of the event has any of the given labels.
+//
+// It can be used with predicates named `When` (e.g. `message.When`).
+func Labeled(labels ...string) *LabelCondition {
+	return &LabelCondition{labels: labels}
+}
+
+func (c *LabelCondition) Match(ctx context.Context) bool {
+	labels, err := Labels(ctx)
+	if err != nil {
+		return false
+	}
+	for _, l := range labels {
+		for _, want := range c.labels {
+			if l == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *LabelCondition) String() string {
+	return fmt.Sprintf("Labeled(%s)", strings.Join(c.labels, ", "))
+}
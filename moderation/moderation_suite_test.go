@@ -0,0 +1,13 @@
+package moderation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestModeration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Moderation Suite")
+}
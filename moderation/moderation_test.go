@@ -0,0 +1,109 @@
+package moderation_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/moderation"
+)
+
+func messageEvent(text string) *slackevents.EventsAPIEvent {
+	return &slackevents.EventsAPIEvent{
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: slackevents.Message,
+			Data: &slackevents.MessageEvent{Text: text},
+		},
+	}
+}
+
+var _ = Describe("Moderation", func() {
+	var (
+		ctx        = context.Background()
+		numCalls   int
+		classifier moderation.Classifier
+	)
+	BeforeEach(func() {
+		numCalls = 0
+		regexps := moderation.Regexps(map[string]*regexp.Regexp{
+			"pii":       regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`),
+			"profanity": regexp.MustCompile(`(?i)\bdarn\b`),
+		})
+		classifier = moderation.ClassifierFunc(func(ctx context.Context, text string) ([]string, error) {
+			numCalls++
+			return regexps.Classify(ctx, text)
+		})
+	})
+
+	Describe("Regexps", func() {
+		It("attaches labels whose patterns match", func() {
+			labels, err := classifier.Classify(ctx, "Darn, my card is 1234-5678-9012-3456")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(Equal([]string{"pii", "profanity"}))
+		})
+	})
+
+	Describe("Labeled", func() {
+		It("classifies the text lazily and only once", func() {
+			enriched, err := moderation.NewEnricher(classifier).Enrich(ctx, messageEvent("my card is 1234-5678-9012-3456"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numCalls).To(Equal(0))
+			Expect(moderation.Labeled("pii").Match(enriched)).To(BeTrue())
+			Expect(moderation.Labeled("profanity").Match(enriched)).To(BeFalse())
+			Expect(moderation.Labeled("profanity", "pii").Match(enriched)).To(BeTrue())
+			Expect(numCalls).To(Equal(1))
+		})
+
+		It("classifies the edited text of message_changed", func() {
+			ev := &slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Type: slackevents.Message,
+					Data: &slackevents.MessageEvent{
+						SubType: message.SubTypeMessageChanged,
+						Message: &slackevents.MessageEvent{Text: "darn"},
+					},
+				},
+			}
+			enriched, err := moderation.NewEnricher(classifier).Enrich(ctx, ev)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moderation.Labeled("profanity").Match(enriched)).To(BeTrue())
+		})
+
+		It("does not match when the classifier fails", func() {
+			var hookErr error
+			failing := moderation.ClassifierFunc(func(context.Context, string) ([]string, error) {
+				return nil, errors.New("unavailable")
+			})
+			e := moderation.NewEnricher(failing, moderation.OnError(func(_ context.Context, err error) {
+				hookErr = err
+			}))
+			enriched, err := e.Enrich(ctx, messageEvent("hello"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(moderation.Labeled("pii").Match(enriched)).To(BeFalse())
+			Expect(hookErr).To(HaveOccurred())
+			_, err = moderation.Labels(enriched)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("does not match when the context is not enriched", func() {
+			Expect(moderation.Labeled("pii").Match(ctx)).To(BeFalse())
+		})
+
+		It("can be used with When predicates", func() {
+			numHandled := 0
+			h := message.Build(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+				numHandled++
+				return nil
+			}), message.When(moderation.Labeled("pii")))
+			Expect(h.HandleMessageEvent(moderation.WithLabels(ctx, "pii"), &slackevents.MessageEvent{})).To(Succeed())
+			Expect(h.HandleMessageEvent(moderation.WithLabels(ctx, "spam"), &slackevents.MessageEvent{})).To(MatchError(routererrors.NotInterested))
+			Expect(numHandled).To(Equal(1))
+		})
+	})
+})
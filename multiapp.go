@@ -0,0 +1,112 @@
+package eventrouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+// MultiApp is an http.Handler that serves more than one Slack app on a single endpoint.
+//
+// It dispatches each request to the Router of the app that the request is sent to, based on `api_app_id`.
+// Each Router verifies requests with its own signing secret.
+type MultiApp struct {
+	routers     map[string]*Router
+	appIDs      []string
+	maxBodySize int64
+}
+
+// NewMultiApp creates a new MultiApp from Routers keyed by `api_app_id`.
+//
+// MultiApp responds with Not Found to requests sent to unknown apps.
+//
+// Since `url_verification` events don't have `api_app_id`, MultiApp dispatches them to the first Router (in the order of app IDs)
+// whose signing secret matches the signature of the request.
+// Note that a Router configured with SkipVerification matches any signature, so it claims every `url_verification` request
+// unless another Router comes before it.
+//
+// MultiApp reads the whole body before it knows which Router the request is sent to. If any of the Routers is configured with WithMaxBodySize,
+// MultiApp limits the size of request bodies to the smallest of those limits and responds with Request Entity Too Large to requests exceeding it.
+func NewMultiApp(routers map[string]*Router) *MultiApp {
+	m := &MultiApp{routers: make(map[string]*Router, len(routers))}
+	for appID, r := range routers {
+		m.routers[appID] = r
+		m.appIDs = append(m.appIDs, appID)
+		if r.maxBodySize > 0 && (m.maxBodySize <= 0 || r.maxBodySize < m.maxBodySize) {
+			m.maxBodySize = r.maxBodySize
+		}
+	}
+	sort.Strings(m.appIDs)
+	return m
+}
+
+func (m *MultiApp) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := m.readBody(req)
+	if err != nil {
+		routerutils.RespondWithError(w, err, false)
+		return
+	}
+	req.Body = routerutils.NewMemoryBody(body)
+
+	router, err := m.findRouter(req, body)
+	if err != nil {
+		routerutils.RespondWithError(w, err, false)
+		return
+	}
+	router.ServeHTTP(w, req)
+}
+
+// readBody reads the request body, failing with ErrBodyTooLarge (and 413) if it exceeds the limit of the MultiApp.
+func (m *MultiApp) readBody(req *http.Request) ([]byte, error) {
+	if m.maxBodySize <= 0 {
+		return routerutils.ReadBody(req.Body, req.ContentLength)
+	}
+	tooLarge := func(err error) error {
+		return routererrors.WithStatus(err, http.StatusRequestEntityTooLarge)
+	}
+	if req.ContentLength > m.maxBodySize {
+		return nil, tooLarge(fmt.Errorf("Content-Length %d exceeds %d bytes: %w", req.ContentLength, m.maxBodySize, ErrBodyTooLarge))
+	}
+	body, err := routerutils.ReadBody(io.LimitReader(req.Body, m.maxBodySize+1), req.ContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > m.maxBodySize {
+		return nil, tooLarge(fmt.Errorf("exceeds the limit: %w", ErrBodyTooLarge))
+	}
+	return body, nil
+}
+
+func (m *MultiApp) findRouter(req *http.Request, body []byte) (*Router, error) {
+	envelope := struct {
+		APIAppID string `json:"api_app_id"`
+	}{}
+	// Bodies that are not JSON (e.g. form-encoded `url_verification`) are treated as ones without `api_app_id`.
+	_ = json.Unmarshal(body, &envelope)
+	if envelope.APIAppID != "" {
+		router, ok := m.routers[envelope.APIAppID]
+		if !ok {
+			return nil, fmt.Errorf("unknown app: %s: %w", envelope.APIAppID, routererrors.HttpError(http.StatusNotFound))
+		}
+		return router, nil
+	}
+
+	for _, appID := range m.appIDs {
+		router := m.routers[appID]
+		if router.shared.SkipVerification {
+			return router, nil
+		}
+		for _, secret := range append([]string{router.shared.SigningSecret}, router.shared.PreviousSigningSecrets...) {
+			if signature.Verify(req.Header, body, secret, router.shared.Now()) == nil {
+				return router, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no app matches the request: %w", routererrors.HttpError(http.StatusNotFound))
+}
@@ -0,0 +1,156 @@
+package eventrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+var _ = Describe("MultiApp", func() {
+	var (
+		m            *eventrouter.MultiApp
+		calledRouter string
+		newEvent     = func(appID string) string {
+			return `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "` + appID + `",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		}
+		urlVerification = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+	)
+	BeforeEach(func() {
+		calledRouter = ""
+		newRouter := func(name, secret string) *eventrouter.Router {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(secret))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				calledRouter = name
+				return nil
+			}))
+			return r
+		}
+		m = eventrouter.NewMultiApp(map[string]*eventrouter.Router{
+			"A111": newRouter("first", "FIRST_SECRET"),
+			"A222": newRouter("second", "SECOND_SECRET"),
+		})
+	})
+
+	Context("when the request is sent to a known app", func() {
+		It("dispatches the request to the corresponding Router", func() {
+			req, err := NewSignedRequest("SECOND_SECRET", newEvent("A222"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(calledRouter).To(Equal("second"))
+		})
+	})
+
+	Context("when the request is signed with the signing secret of another app", func() {
+		It("responds with 401", func() {
+			req, err := NewSignedRequest("FIRST_SECRET", newEvent("A222"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(calledRouter).To(Equal(""))
+		})
+	})
+
+	Context("when the request is sent to an unknown app", func() {
+		It("responds with 404", func() {
+			req, err := NewSignedRequest("FIRST_SECRET", newEvent("A999"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusNotFound))
+			Expect(calledRouter).To(Equal(""))
+		})
+	})
+
+	Context("when a Router limits the size of request bodies", func() {
+		BeforeEach(func() {
+			limited, err := eventrouter.New(eventrouter.WithSigningSecret("FIRST_SECRET"), eventrouter.WithMaxBodySize(64))
+			Expect(err).NotTo(HaveOccurred())
+			unlimited, err := eventrouter.New(eventrouter.WithSigningSecret("SECOND_SECRET"))
+			Expect(err).NotTo(HaveOccurred())
+			unlimited.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				calledRouter = "second"
+				return nil
+			}))
+			m = eventrouter.NewMultiApp(map[string]*eventrouter.Router{
+				"A111": limited,
+				"A222": unlimited,
+			})
+		})
+
+		It("responds with 413 before dispatching requests that exceed the smallest limit", func() {
+			req, err := NewSignedRequest("SECOND_SECRET", newEvent("A222"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(calledRouter).To(Equal(""))
+		})
+
+		It("responds with 413 to requests without Content-Length that exceed the limit", func() {
+			req, err := NewSignedRequest("SECOND_SECRET", newEvent("A222"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.ContentLength = -1
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(calledRouter).To(Equal(""))
+		})
+	})
+
+	Context("when the request is url_verification", func() {
+		It("dispatches the request to the Router whose signing secret matches", func() {
+			req, err := NewSignedRequest("SECOND_SECRET", urlVerification, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			var challenge slackevents.ChallengeResponse
+			Expect(json.Unmarshal(body, &challenge)).To(Succeed())
+			Expect(challenge.Challenge).To(Equal("3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"))
+		})
+
+		It("responds with 404 when no signing secret matches", func() {
+			req, err := NewSignedRequest("UNKNOWN_SECRET", urlVerification, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+// Package natsadapter distributes requests from Slack through NATS.
+//
+// At the edge, a Publisher publishes requests from Slack to a subject:
+//
+//	p, err := natsadapter.NewPublisher(nc, "slack.events", bridge.WithSigningSecret(secret))
+//	http.Handle("/slack/events", p)
+//
+// Internal services subscribe to the subject and dispatch requests to routers:
+//
+//	r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+//	s, err := bridge.NewSubscriber(r, bridge.WithSigningSecret(secret))
+//	err = natsadapter.Run(ctx, nc, "slack.events", "my-service", s)
+//
+// Messages are encoded as `bridge.Envelope`. This package is a separate module so that users of other packages don't depend on NATS.
+package natsadapter
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/genkami/go-slack-event-router/bridge"
+)
+
+// Conn is a subset of `*nats.Conn` that this package uses.
+type Conn interface {
+	Publish(subject string, data []byte) error
+	QueueSubscribe(subject, queue string, cb nats.MsgHandler) (*nats.Subscription, error)
+}
+
+var _ Conn = &nats.Conn{}
+
+// NewPublisher creates a new `bridge.Publisher` that publishes requests from Slack to `subject`.
+func NewPublisher(conn Conn, subject string, opts ...bridge.Option) (*bridge.Publisher, error) {
+	return bridge.NewPublisher(func(_ context.Context, data []byte) error {
+		return conn.Publish(subject, data)
+	}, opts...)
+}
+
+// Run subscribes to `subject` as a member of the queue group `queue` and dispatches messages to `s` until `ctx` is done.
+//
+// Each message is delivered to only one member of the queue group. Errors in dispatching are reported via `bridge.OnError`.
+func Run(ctx context.Context, conn Conn, subject, queue string, s *bridge.Subscriber) error {
+	sub, err := conn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+		_ = s.Dispatch(ctx, msg.Data)
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	_ = sub.Unsubscribe()
+	return nil
+}
@@ -0,0 +1,13 @@
+package natsadapter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestNatsadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Natsadapter Suite")
+}
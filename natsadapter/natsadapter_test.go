@@ -0,0 +1,111 @@
+package natsadapter_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/bridge"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/natsadapter"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+// fakeConn delivers published messages to subscribers synchronously.
+type fakeConn struct {
+	mu       sync.Mutex
+	handlers map[string]nats.MsgHandler
+}
+
+func (c *fakeConn) Publish(subject string, data []byte) error {
+	c.mu.Lock()
+	h, ok := c.handlers[subject]
+	c.mu.Unlock()
+	if ok {
+		h(&nats.Msg{Subject: subject, Data: data})
+	}
+	return nil
+}
+
+func (c *fakeConn) QueueSubscribe(subject, _ string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[subject] = cb
+	return &nats.Subscription{Subject: subject}, nil
+}
+
+func (c *fakeConn) subscribed(subject string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.handlers[subject]
+	return ok
+}
+
+const content = `
+{
+	"token": "XXYYZZ",
+	"team_id": "TXXXXXXXX",
+	"api_app_id": "AXXXXXXXXX",
+	"event": {
+		"type": "message",
+		"channel": "C2147483705",
+		"user": "U2147483697",
+		"text": "Hello world",
+		"ts": "1355517523.000005"
+	},
+	"type": "event_callback",
+	"event_id": "Ev08MFMKH6",
+	"event_time": 1234567890
+}`
+
+var _ = Describe("Natsadapter", func() {
+	It("delivers requests from the publisher to the subscriber", func() {
+		token := "THE_TOKEN"
+		conn := &fakeConn{handlers: map[string]nats.MsgHandler{}}
+
+		var mu sync.Mutex
+		numHandlerCalled := 0
+		r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			numHandlerCalled++
+			return nil
+		}))
+		s, err := bridge.NewSubscriber(r, bridge.WithSigningSecret(token))
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- natsadapter.Run(ctx, conn, "slack.events", "workers", s)
+		}()
+		Eventually(func() bool { return conn.subscribed("slack.events") }).Should(BeTrue())
+
+		p, err := natsadapter.NewPublisher(conn, "slack.events", bridge.WithSigningSecret(token))
+		Expect(err).NotTo(HaveOccurred())
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/slack/events", bytes.NewReader([]byte(content)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature.AddSignature(req.Header, []byte(token), []byte(content), time.Now())).To(Succeed())
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+
+		mu.Lock()
+		Expect(numHandlerCalled).To(Equal(1))
+		mu.Unlock()
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
@@ -0,0 +1,238 @@
+// Package origin provides a defense-in-depth check that requests come from Slack.
+//
+// The check is based on characteristics of requests from Slack (user agents and source IP addresses).
+// It is NOT a replacement for signature verification, which is the only reliable way to authenticate requests.
+// Use this as a request filter that runs before verification:
+//
+//	checker := &origin.Checker{Ranges: origin.StaticRanges(ranges)}
+//	r, err := eventrouter.New(
+//		eventrouter.WithSigningSecret(secret),
+//		eventrouter.WithRequestFilter(checker.Check),
+//	)
+package origin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// DefaultUserAgent matches user agents of requests from Slack (e.g. `Slackbot 1.0 (+https://api.slack.com/robots)`).
+var DefaultUserAgent = regexp.MustCompile(`^Slackbot \d+\.\d+ \(\+https://api\.slack\.com/robots\)$`)
+
+// Reason describes why a request is rejected.
+type Reason string
+
+const (
+	// ReasonUserAgent means that the user agent of the request does not match.
+	ReasonUserAgent Reason = "user_agent"
+
+	// ReasonIP means that the source IP address of the request is not in the allowed ranges.
+	ReasonIP Reason = "ip"
+
+	// ReasonRangesUnavailable means that the allowed IP ranges could not be obtained.
+	ReasonRangesUnavailable Reason = "ranges_unavailable"
+)
+
+// RejectedError is returned by Checker.Check when it rejects a request.
+// This is treated as Forbidden by routers.
+type RejectedError struct {
+	Reason Reason
+	Detail string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("request rejected (%s): %s", e.Reason, e.Detail)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return routererrors.HttpError(http.StatusForbidden)
+}
+
+// RangeSource provides IP ranges that requests from Slack come from.
+type RangeSource interface {
+	Ranges(ctx context.Context) ([]*net.IPNet, error)
+}
+
+// StaticRanges is a RangeSource that always returns the same ranges.
+type StaticRanges []*net.IPNet
+
+func (s StaticRanges) Ranges(_ context.Context) ([]*net.IPNet, error) {
+	return s, nil
+}
+
+// ParseCIDRs parses IP ranges in CIDR notation.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	ranges := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(c))
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, n)
+	}
+	return ranges, nil
+}
+
+// RefreshingRanges is a RangeSource that caches ranges obtained by Fetch and refreshes them periodically.
+//
+// If Fetch fails, the last known ranges are used. The cache is refreshed synchronously in Ranges,
+// so Fetch should be reasonably fast.
+type RefreshingRanges struct {
+	// Fetch obtains the latest ranges (e.g. from a published list).
+	Fetch func(ctx context.Context) ([]*net.IPNet, error)
+
+	// Interval is the interval of refreshes. If zero, ranges are refreshed every hour.
+	Interval time.Duration
+
+	// Now returns the current time. If nil, `time.Now` is used.
+	Now func() time.Time
+
+	mu          sync.Mutex
+	ranges      []*net.IPNet
+	refreshedAt time.Time
+}
+
+func (s *RefreshingRanges) Ranges(ctx context.Context) ([]*net.IPNet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	interval := s.Interval
+	if interval == 0 {
+		interval = time.Hour
+	}
+	if s.ranges != nil && now().Sub(s.refreshedAt) < interval {
+		return s.ranges, nil
+	}
+	ranges, err := s.Fetch(ctx)
+	if err != nil {
+		if s.ranges != nil {
+			return s.ranges, nil
+		}
+		return nil, err
+	}
+	s.ranges = ranges
+	s.refreshedAt = now()
+	return ranges, nil
+}
+
+// Checker checks whether requests look like ones from Slack.
+type Checker struct {
+	// UserAgent is a pattern that user agents must match. If nil, DefaultUserAgent is used.
+	UserAgent *regexp.Regexp
+
+	// SkipUserAgent disables the user agent check.
+	SkipUserAgent bool
+
+	// Ranges provides IP ranges that requests must come from. If nil, source IP addresses are not checked.
+	Ranges RangeSource
+
+	// TrustedProxies are IP ranges of proxies in front of the server (e.g. load balancers).
+	//
+	// If the request comes from a trusted proxy, Checker walks `X-Forwarded-For` from the right, skipping trusted proxies,
+	// and uses the first address that is not a trusted proxy as the source IP address. Addresses to the left of it are
+	// ignored since anyone can set them. If nil, `X-Forwarded-For` is never used and `RemoteAddr` is the source IP address.
+	TrustedProxies []*net.IPNet
+
+	// OnReject is called every time Checker rejects a request. This is useful to record logs or metrics.
+	OnReject func(req *http.Request, reason Reason)
+
+	rejectedUserAgent         uint64
+	rejectedIP                uint64
+	rejectedRangesUnavailable uint64
+}
+
+// Check returns a RejectedError if `req` does not look like a request from Slack.
+//
+// The signature of this method matches to the one of request filters (e.g. `eventrouter.WithRequestFilter`).
+func (c *Checker) Check(req *http.Request) error {
+	if !c.SkipUserAgent {
+		pattern := c.UserAgent
+		if pattern == nil {
+			pattern = DefaultUserAgent
+		}
+		if ua := req.UserAgent(); !pattern.MatchString(ua) {
+			return c.reject(req, ReasonUserAgent, fmt.Sprintf("unexpected user agent %q", ua))
+		}
+	}
+	if c.Ranges != nil {
+		ranges, err := c.Ranges.Ranges(req.Context())
+		if err != nil {
+			return c.reject(req, ReasonRangesUnavailable, err.Error())
+		}
+		ip := c.sourceIP(req)
+		if ip == nil || !contains(ranges, ip) {
+			return c.reject(req, ReasonIP, fmt.Sprintf("unexpected source address %s", ip))
+		}
+	}
+	return nil
+}
+
+// Stats is the numbers of rejected requests by reasons.
+type Stats map[Reason]uint64
+
+// Stats returns the numbers of requests that Checker has rejected so far.
+func (c *Checker) Stats() Stats {
+	return Stats{
+		ReasonUserAgent:         atomic.LoadUint64(&c.rejectedUserAgent),
+		ReasonIP:                atomic.LoadUint64(&c.rejectedIP),
+		ReasonRangesUnavailable: atomic.LoadUint64(&c.rejectedRangesUnavailable),
+	}
+}
+
+func (c *Checker) reject(req *http.Request, reason Reason, detail string) error {
+	switch reason {
+	case ReasonUserAgent:
+		atomic.AddUint64(&c.rejectedUserAgent, 1)
+	case ReasonIP:
+		atomic.AddUint64(&c.rejectedIP, 1)
+	case ReasonRangesUnavailable:
+		atomic.AddUint64(&c.rejectedRangesUnavailable, 1)
+	}
+	if c.OnReject != nil {
+		c.OnReject(req, reason)
+	}
+	return &RejectedError{Reason: reason, Detail: detail}
+}
+
+func (c *Checker) sourceIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !contains(c.TrustedProxies, ip) {
+		return ip
+	}
+	var hops []string
+	for _, h := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil || !contains(c.TrustedProxies, ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func contains(ranges []*net.IPNet, ip net.IP) bool {
+	for _, r := range ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
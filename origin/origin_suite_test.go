@@ -0,0 +1,13 @@
+package origin_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrigin(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Origin Suite")
+}
@@ -0,0 +1,172 @@
+package origin_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/origin"
+)
+
+var _ = Describe("Origin", func() {
+	const slackUserAgent = "Slackbot 1.0 (+https://api.slack.com/robots)"
+	var (
+		ranges   []*net.IPNet
+		req      *http.Request
+		rejects  []origin.Reason
+		onReject = func(_ *http.Request, reason origin.Reason) {
+			rejects = append(rejects, reason)
+		}
+	)
+	BeforeEach(func() {
+		var err error
+		ranges, err = origin.ParseCIDRs("192.0.2.0/24", "2001:db8::/32")
+		Expect(err).NotTo(HaveOccurred())
+		req, err = http.NewRequest(http.MethodPost, "http://example.com/path", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("User-Agent", slackUserAgent)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rejects = nil
+	})
+
+	Describe("Checker", func() {
+		Context("when the request looks like one from Slack", func() {
+			It("returns nil", func() {
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), OnReject: onReject}
+				Expect(c.Check(req)).To(Succeed())
+				Expect(rejects).To(BeEmpty())
+			})
+		})
+
+		Context("when the user agent does not match", func() {
+			It("rejects the request with Forbidden", func() {
+				req.Header.Set("User-Agent", "curl/7.64.1")
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), OnReject: onReject}
+				err := c.Check(req)
+				var httpErr routererrors.HttpError
+				Expect(errors.As(err, &httpErr)).To(BeTrue())
+				Expect(httpErr).To(Equal(routererrors.HttpError(http.StatusForbidden)))
+				Expect(rejects).To(Equal([]origin.Reason{origin.ReasonUserAgent}))
+				Expect(c.Stats()[origin.ReasonUserAgent]).To(Equal(uint64(1)))
+			})
+
+			It("accepts the request if SkipUserAgent is set", func() {
+				req.Header.Set("User-Agent", "curl/7.64.1")
+				c := &origin.Checker{SkipUserAgent: true, Ranges: origin.StaticRanges(ranges)}
+				Expect(c.Check(req)).To(Succeed())
+			})
+		})
+
+		Context("when the source address is out of the ranges", func() {
+			It("rejects the request", func() {
+				req.RemoteAddr = "198.51.100.1:12345"
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), OnReject: onReject}
+				err := c.Check(req)
+				var rejected *origin.RejectedError
+				Expect(errors.As(err, &rejected)).To(BeTrue())
+				Expect(rejected.Reason).To(Equal(origin.ReasonIP))
+				Expect(c.Stats()[origin.ReasonIP]).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when TrustedProxies is set", func() {
+			var proxies []*net.IPNet
+			BeforeEach(func() {
+				var err error
+				proxies, err = origin.ParseCIDRs("10.0.0.0/8")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("uses the rightmost untrusted address in X-Forwarded-For as the source address", func() {
+				req.RemoteAddr = "10.0.0.1:12345"
+				req.Header.Set("X-Forwarded-For", "2001:db8::1, 10.0.0.2")
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), TrustedProxies: proxies}
+				Expect(c.Check(req)).To(Succeed())
+			})
+
+			It("ignores spoofed addresses to the left of the client", func() {
+				req.RemoteAddr = "10.0.0.1:12345"
+				req.Header.Set("X-Forwarded-For", "192.0.2.1, 198.51.100.1, 10.0.0.2")
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), TrustedProxies: proxies, OnReject: onReject}
+				Expect(c.Check(req)).To(MatchError(routererrors.HttpError(http.StatusForbidden)))
+				Expect(rejects).To(Equal([]origin.Reason{origin.ReasonIP}))
+			})
+
+			It("ignores X-Forwarded-For when the request doesn't come from a trusted proxy", func() {
+				req.RemoteAddr = "198.51.100.1:12345"
+				req.Header.Set("X-Forwarded-For", "192.0.2.1")
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges), TrustedProxies: proxies}
+				Expect(c.Check(req)).To(MatchError(routererrors.HttpError(http.StatusForbidden)))
+			})
+		})
+
+		Context("when TrustedProxies is not set", func() {
+			It("ignores X-Forwarded-For", func() {
+				req.RemoteAddr = "198.51.100.1:12345"
+				req.Header.Set("X-Forwarded-For", "192.0.2.1")
+				c := &origin.Checker{Ranges: origin.StaticRanges(ranges)}
+				Expect(c.Check(req)).To(MatchError(routererrors.HttpError(http.StatusForbidden)))
+			})
+		})
+	})
+
+	Describe("RefreshingRanges", func() {
+		var (
+			now        time.Time
+			numFetched int
+			fetchErr   error
+			source     *origin.RefreshingRanges
+		)
+		BeforeEach(func() {
+			now = time.Now()
+			numFetched = 0
+			fetchErr = nil
+			source = &origin.RefreshingRanges{
+				Fetch: func(_ context.Context) ([]*net.IPNet, error) {
+					numFetched++
+					if fetchErr != nil {
+						return nil, fetchErr
+					}
+					return ranges, nil
+				},
+				Interval: time.Minute,
+				Now:      func() time.Time { return now },
+			}
+		})
+
+		It("caches ranges until the interval passes", func() {
+			_, err := source.Ranges(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			_, err = source.Ranges(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numFetched).To(Equal(1))
+
+			now = now.Add(2 * time.Minute)
+			_, err = source.Ranges(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numFetched).To(Equal(2))
+		})
+
+		It("keeps the last known ranges when Fetch fails", func() {
+			_, err := source.Ranges(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			now = now.Add(2 * time.Minute)
+			fetchErr = errors.New("unavailable")
+			got, err := source.Ranges(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(ranges))
+		})
+
+		It("returns an error when no ranges are known", func() {
+			fetchErr = errors.New("unavailable")
+			_, err := source.Ranges(context.Background())
+			Expect(err).To(MatchError("unavailable"))
+		})
+	})
+})
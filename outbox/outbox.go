@@ -0,0 +1,145 @@
+// Package outbox helps handlers perform side effects exactly once, even when Slack retries events.
+//
+// Handlers record "the event is processed" together with side effects they intend (e.g. rows in an outbox table that another process delivers)
+// in the same transaction. If the event has already been processed, the transaction is not performed at all.
+//
+// This package doesn't depend on any specific database. Users provide a TxScope that runs functions in transactions,
+// and a DedupStore that records processed events within those transactions.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// ErrNoEventID indicates that the ID of the event being processed is not available.
+var ErrNoEventID = errors.New("no event ID is available")
+
+// Tx is a transaction provided by TxScope, such as `*sql.Tx`.
+type Tx interface{}
+
+// TxScope runs `fn` in a transaction.
+//
+// It must commit the transaction if `fn` returns nil, and roll it back otherwise.
+type TxScope func(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+
+// DedupStore records processed events.
+type DedupStore interface {
+	// MarkProcessed records that the event identified by `eventID` is processed, as a part of `tx`.
+	// It returns false if the event has already been recorded.
+	MarkProcessed(ctx context.Context, tx Tx, eventID string) (bool, error)
+}
+
+type DedupStoreFunc func(ctx context.Context, tx Tx, eventID string) (bool, error)
+
+func (f DedupStoreFunc) MarkProcessed(ctx context.Context, tx Tx, eventID string) (bool, error) {
+	return f(ctx, tx, eventID)
+}
+
+// Option configures the Outbox.
+type Option interface {
+	apply(*Outbox)
+}
+
+type optionFunc func(*Outbox)
+
+func (f optionFunc) apply(o *Outbox) {
+	f(o)
+}
+
+// OnDuplicate sets a hook that is called when an event that has already been processed is given.
+func OnDuplicate(hook func(ctx context.Context, eventID string)) Option {
+	return optionFunc(func(o *Outbox) {
+		o.duplicateHook = hook
+	})
+}
+
+// Outbox runs functions at most once per event.
+type Outbox struct {
+	scope         TxScope
+	dedup         DedupStore
+	duplicateHook func(context.Context, string)
+}
+
+// New creates a new Outbox.
+func New(scope TxScope, dedup DedupStore, opts ...Option) *Outbox {
+	o := &Outbox{scope: scope, dedup: dedup}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+// Do runs `fn` in a transaction unless the event being processed has already been processed.
+//
+// The event is identified by EventID, so this can be used in any handler registered to `eventrouter.Router`.
+// `fn` should record side effects it intends by using `tx`, instead of performing them directly.
+// If `fn` returns an error, nothing is recorded, so the event can be processed again when Slack retries it.
+func (o *Outbox) Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	eventID := EventID(ctx)
+	if eventID == "" {
+		return ErrNoEventID
+	}
+	return o.DoEvent(ctx, eventID, fn)
+}
+
+// DoEvent is the same as Do except that the event is identified by `eventID`.
+func (o *Outbox) DoEvent(ctx context.Context, eventID string, fn func(ctx context.Context, tx Tx) error) error {
+	duplicate := false
+	err := o.scope(ctx, func(ctx context.Context, tx Tx) error {
+		first, err := o.dedup.MarkProcessed(ctx, tx, eventID)
+		if err != nil {
+			return fmt.Errorf("failed to mark event %s as processed: %w", eventID, err)
+		}
+		if !first {
+			duplicate = true
+			return nil
+		}
+		return fn(ctx, tx)
+	})
+	if err != nil {
+		return err
+	}
+	if duplicate && o.duplicateHook != nil {
+		o.duplicateHook(ctx, eventID)
+	}
+	return nil
+}
+
+// Handler returns an `eventrouter.Handler` that calls `h` by Do.
+func (o *Outbox) Handler(h func(ctx context.Context, tx Tx, e *slackevents.EventsAPIEvent) error) eventrouter.Handler {
+	return eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		eventID := EventID(ctx)
+		if cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent); ok && cb.EventID != "" {
+			eventID = cb.EventID
+		}
+		if eventID == "" {
+			return ErrNoEventID
+		}
+		return o.DoEvent(ctx, eventID, func(ctx context.Context, tx Tx) error {
+			return h(ctx, tx, e)
+		})
+	})
+}
+
+// EventID returns the ID of the event (i.e. `event_id` in the outer event) being processed, or an empty string if it is not available.
+func EventID(ctx context.Context) string {
+	body := routerutils.RawBody(ctx)
+	if len(body) == 0 {
+		return ""
+	}
+	var envelope struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return ""
+	}
+	return envelope.EventID
+}
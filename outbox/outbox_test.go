@@ -0,0 +1,172 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/outbox"
+)
+
+// fakeDB is an in-memory database whose transactions are applied only when they succeed.
+type fakeDB struct {
+	processed map[string]bool
+	intents   []string
+}
+
+type fakeTx struct {
+	processed []string
+	intents   []string
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{processed: make(map[string]bool)}
+}
+
+func (db *fakeDB) scope(ctx context.Context, fn func(context.Context, outbox.Tx) error) error {
+	tx := &fakeTx{}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	for _, id := range tx.processed {
+		db.processed[id] = true
+	}
+	db.intents = append(db.intents, tx.intents...)
+	return nil
+}
+
+func (db *fakeDB) markProcessed(_ context.Context, tx outbox.Tx, eventID string) (bool, error) {
+	if db.processed[eventID] {
+		return false, nil
+	}
+	t := tx.(*fakeTx)
+	t.processed = append(t.processed, eventID)
+	return true, nil
+}
+
+var _ = Describe("Outbox", func() {
+	var (
+		db *fakeDB
+		o  *outbox.Outbox
+	)
+	BeforeEach(func() {
+		db = newFakeDB()
+		o = outbox.New(db.scope, outbox.DedupStoreFunc(db.markProcessed))
+	})
+
+	Describe("DoEvent", func() {
+		It("runs the function only once per event", func() {
+			numCalled := 0
+			for i := 0; i < 3; i++ {
+				err := o.DoEvent(context.Background(), "Ev1", func(_ context.Context, tx outbox.Tx) error {
+					numCalled++
+					tx.(*fakeTx).intents = append(tx.(*fakeTx).intents, "create ticket")
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(numCalled).To(Equal(1))
+			Expect(db.intents).To(Equal([]string{"create ticket"}))
+		})
+
+		It("records nothing if the function fails", func() {
+			err := o.DoEvent(context.Background(), "Ev1", func(_ context.Context, tx outbox.Tx) error {
+				tx.(*fakeTx).intents = append(tx.(*fakeTx).intents, "create ticket")
+				return errors.New("oops")
+			})
+			Expect(err).To(MatchError("oops"))
+			Expect(db.processed).To(BeEmpty())
+			Expect(db.intents).To(BeEmpty())
+
+			err = o.DoEvent(context.Background(), "Ev1", func(_ context.Context, tx outbox.Tx) error {
+				tx.(*fakeTx).intents = append(tx.(*fakeTx).intents, "create ticket")
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(db.intents).To(Equal([]string{"create ticket"}))
+		})
+
+		It("calls the hook for duplicate events", func() {
+			var duplicates []string
+			o = outbox.New(db.scope, outbox.DedupStoreFunc(db.markProcessed), outbox.OnDuplicate(func(_ context.Context, eventID string) {
+				duplicates = append(duplicates, eventID)
+			}))
+			noop := func(context.Context, outbox.Tx) error { return nil }
+			Expect(o.DoEvent(context.Background(), "Ev1", noop)).To(Succeed())
+			Expect(o.DoEvent(context.Background(), "Ev1", noop)).To(Succeed())
+			Expect(duplicates).To(Equal([]string{"Ev1"}))
+		})
+	})
+
+	Describe("Do", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			serve = func(r *eventrouter.Router) int {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+
+		It("identifies events processed by the Router", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+				return o.Do(ctx, func(_ context.Context, tx outbox.Tx) error {
+					tx.(*fakeTx).intents = append(tx.(*fakeTx).intents, e.Text)
+					return nil
+				})
+			}))
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(serve(r)).To(Equal(http.StatusOK))
+			Expect(db.processed).To(HaveKey("Ev08MFMKH6"))
+			Expect(db.intents).To(Equal([]string{"Hello world"}))
+		})
+
+		It("returns ErrNoEventID outside of the Router", func() {
+			err := o.Do(context.Background(), func(context.Context, outbox.Tx) error { return nil })
+			Expect(err).To(MatchError(outbox.ErrNoEventID))
+		})
+	})
+
+	Describe("Handler", func() {
+		It("passes the transaction to the handler", func() {
+			h := o.Handler(func(_ context.Context, tx outbox.Tx, e *slackevents.EventsAPIEvent) error {
+				tx.(*fakeTx).intents = append(tx.(*fakeTx).intents, e.InnerEvent.Type)
+				return nil
+			})
+			e := &slackevents.EventsAPIEvent{
+				Data:       &slackevents.EventsAPICallbackEvent{EventID: "Ev1"},
+				InnerEvent: slackevents.EventsAPIInnerEvent{Type: "message"},
+			}
+			Expect(h.HandleEventsAPIEvent(context.Background(), e)).To(Succeed())
+			Expect(h.HandleEventsAPIEvent(context.Background(), e)).To(Succeed())
+			Expect(db.intents).To(Equal([]string{"message"}))
+		})
+	})
+})
@@ -0,0 +1,41 @@
+package eventrouter
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Precompile does the work that the Router otherwise does lazily when it receives the first request of each event type,
+// so that the first requests after cold starts (e.g. on AWS Lambda) are not slower than others.
+//
+// It parses a synthetic event of each type that has handlers, which makes `encoding/json` build and cache its decoders for them,
+// and applies the redaction policy once, which compiles its patterns. It doesn't call any handlers.
+//
+// It should be called after all handlers are registered, typically during the initialization of the process.
+// Calling it more than once is harmless, and it is safe to call concurrently with ServeHTTP.
+func (r *Router) Precompile() {
+	for _, body := range r.syntheticEvents() {
+		_, _ = slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	}
+	_ = r.shared.Redaction.String("")
+}
+
+// syntheticEvents returns minimal bodies of events that the Router may receive.
+func (r *Router) syntheticEvents() [][]byte {
+	types := make([]string, 0, len(r.callbackHandlers))
+	for eventType := range r.callbackHandlers {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	bodies := [][]byte{[]byte(`{"type": "url_verification", "challenge": ""}`)}
+	for _, eventType := range types {
+		inner, err := json.Marshal(map[string]string{"type": eventType})
+		if err != nil {
+			continue
+		}
+		bodies = append(bodies, []byte(`{"type": "event_callback", "event": `+string(inner)+`}`))
+	}
+	return bodies
+}
@@ -0,0 +1,137 @@
+package eventrouter
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrImplausibleSigningSecret indicates that a signing secret doesn't look like the ones issued by Slack
+// (e.g. it has surrounding whitespaces or it is a bot token pasted by mistake).
+var ErrImplausibleSigningSecret = errors.New("implausible signing secret")
+
+// signingSecretLength is the length of signing secrets issued by Slack, which are hex-encoded 16 bytes.
+const signingSecretLength = 32
+
+// PreflightChecker is implemented by enrichers, result sinks and other components that depend on external resources
+// (e.g. `authctx.Resolver` and `checkpoint.Recorder`), so that Preflight can find unreachable ones.
+type PreflightChecker interface {
+	PreflightCheck(ctx context.Context) error
+}
+
+type PreflightCheckerFunc func(ctx context.Context) error
+
+func (f PreflightCheckerFunc) PreflightCheck(ctx context.Context) error {
+	return f(ctx)
+}
+
+type namedChecker struct {
+	name    string
+	checker PreflightChecker
+}
+
+// WithPreflightCheck adds a check that Preflight performs in addition to the built-in ones.
+//
+// `name` is used in error messages. This is useful for resources that handlers depend on, such as databases.
+func WithPreflightCheck(name string, c PreflightChecker) Option {
+	return optionFunc(func(r *Router) {
+		r.preflightCheckers = append(r.preflightCheckers, namedChecker{name: name, checker: c})
+	})
+}
+
+// PreflightError is returned by Preflight and holds all the problems that were found.
+type PreflightError struct {
+	Errors []error
+}
+
+func (e *PreflightError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("preflight failed: %s", strings.Join(msgs, "; "))
+}
+
+// Is returns true if any of the problems is `target` in the sense of `errors.Is`.
+func (e *PreflightError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *PreflightError) Unwrap() []error {
+	return e.Errors
+}
+
+// Preflight verifies the configuration of the Router, so that misconfigurations fail deployments instead of the first event.
+//
+// It checks that signing secrets look like the ones issued by Slack, that options are consistent with each other,
+// and that enrichers, the result sink and checks added by WithPreflightCheck that implement PreflightChecker are ready.
+// All the checks are performed even if some of them fail, and it returns a PreflightError that holds all the problems.
+// It returns nil if there are no problems.
+//
+// This is intended to be called on startup, typically before the server starts to listen.
+func (r *Router) Preflight(ctx context.Context) error {
+	var errs []error
+	if !r.shared.SkipVerification {
+		if err := checkSigningSecret(r.shared.SigningSecret); err != nil {
+			errs = append(errs, fmt.Errorf("WithSigningSecret: %w", err))
+		}
+		for i, secret := range r.shared.PreviousSigningSecrets {
+			if err := checkSigningSecret(secret); err != nil {
+				errs = append(errs, fmt.Errorf("WithPreviousSigningSecrets[%d]: %w", i, err))
+			}
+		}
+	}
+	errs = append(errs, r.checkOptions()...)
+
+	checkers := append([]namedChecker(nil), r.preflightCheckers...)
+	for i, e := range r.enrichers {
+		if c, ok := e.(PreflightChecker); ok {
+			checkers = append(checkers, namedChecker{name: fmt.Sprintf("enricher %d (%T)", i, e), checker: c})
+		}
+	}
+	if c, ok := r.resultSink.(PreflightChecker); ok {
+		checkers = append(checkers, namedChecker{name: fmt.Sprintf("result sink (%T)", r.resultSink), checker: c})
+	}
+	for _, c := range checkers {
+		if err := c.checker.PreflightCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PreflightError{Errors: errs}
+}
+
+func checkSigningSecret(secret string) error {
+	if strings.TrimSpace(secret) != secret {
+		return fmt.Errorf("it has surrounding whitespaces: %w", ErrImplausibleSigningSecret)
+	}
+	if strings.HasPrefix(secret, "xox") {
+		return fmt.Errorf("it looks like a token: %w", ErrImplausibleSigningSecret)
+	}
+	if _, err := hex.DecodeString(secret); err != nil || len(secret) != signingSecretLength {
+		return fmt.Errorf("it is not a %d-digit hex string: %w", signingSecretLength, ErrImplausibleSigningSecret)
+	}
+	return nil
+}
+
+// checkOptions finds options that are accepted by New but have no effect in combination with others.
+func (r *Router) checkOptions() []error {
+	var errs []error
+	if r.staleEventHandler != nil && r.maxEventAge <= 0 {
+		errs = append(errs, errors.New("OnStaleEvent has no effect without WithMaxEventAge"))
+	}
+	if r.backgroundErrorHook != nil && r.ackTimeout <= 0 {
+		errs = append(errs, errors.New("OnBackgroundError has no effect without AckBefore"))
+	}
+	return errs
+}
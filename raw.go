@@ -0,0 +1,163 @@
+package eventrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/envelope"
+)
+
+// RawInnerEvent is an inner event that slack-go failed to parse, such as an event of a type that slack-go doesn't know yet,
+// or an event whose fields have been changed by Slack.
+//
+// Events given to the handler set by SetRawHandler have this as `InnerEvent.Data`.
+type RawInnerEvent struct {
+	// Type is the type of the inner event. This may be empty if it is not available.
+	Type string
+
+	// JSON is the inner event as is.
+	JSON json.RawMessage
+
+	// Err is the error that slack-go returned.
+	Err error
+}
+
+// FallbackToRawOnTypeMismatch makes the Router pass events whose `InnerEvent.Data` is not of the expected type
+// (i.e. ErrInnerEventTypeMismatch) to the handler set by SetRawHandler, instead of responding with 400 Bad Request.
+//
+// Such events can never be processed by typed handlers like OnMessage no matter how many times Slack retries them,
+// so responding with 400 only causes useless retries.
+// `InnerEvent.Data` of the events given to the raw handler is `*RawInnerEvent` whose `Err` wraps ErrInnerEventTypeMismatch.
+// If no raw handler is set, the events are passed to the fallback handlers, and treated as unmatched if they are not processed either.
+func FallbackToRawOnTypeMismatch() Option {
+	return optionFunc(func(r *Router) {
+		r.rawOnTypeMismatch = true
+	})
+}
+
+// OnTypeMismatch sets a hook that is called when `InnerEvent.Data` of an event is not of the type that the handler expects.
+//
+// `err` wraps ErrInnerEventTypeMismatch. This is useful to record metrics of events that slack-go parses differently than expected,
+// regardless of whether FallbackToRawOnTypeMismatch is specified.
+func OnTypeMismatch(hook func(ctx context.Context, e *slackevents.EventsAPIEvent, err error)) Option {
+	return optionFunc(func(r *Router) {
+		r.typeMismatchHook = hook
+	})
+}
+
+// SetRawHandler sets a handler that processes `event_callback` events that slack-go fails to parse.
+//
+// The Router parses envelopes of such events by itself, so fields of the envelope (e.g. `TeamID` and `EventID`) are available as usual,
+// and enrichers are called before `h`. `InnerEvent.Data` of the events is `*RawInnerEvent`.
+//
+// The handler is also used for events whose `InnerEvent.Data` is of an unexpected type if FallbackToRawOnTypeMismatch is specified.
+//
+// Events of types that typed handlers decode by themselves because slack-go doesn't know them (e.g. OnAppRequested)
+// are passed to those handlers instead.
+//
+// If more than one handlers are registered, the last one will be used.
+// If no handler is set, the Router responds to such events with 400 Bad Request.
+func (r *Router) SetRawHandler(h Handler) {
+	r.rawHandler = h
+	r.rawHandlerName = r.handlerName(h)
+}
+
+// parseRawEvent parses the envelope of `body` that slack-go failed to parse with `parseErr`.
+// It returns false if the event can't be processed by the raw handler.
+func (r *Router) parseRawEvent(body []byte, parseErr error) (*slackevents.EventsAPIEvent, bool) {
+	if r.rawHandler == nil && len(r.rawTypes) == 0 {
+		return nil, false
+	}
+	env, err := envelope.Parse(body)
+	if err != nil || env.Type != slackevents.CallbackEvent {
+		return nil, false
+	}
+	if r.rawHandler == nil && !r.rawTypes[env.InnerType] {
+		return nil, false
+	}
+	inner := env.Event
+	return &slackevents.EventsAPIEvent{
+		Token:        env.Token,
+		TeamID:       env.TeamID,
+		Type:         env.Type,
+		APIAppID:     env.APIAppID,
+		EnterpriseID: env.EnterpriseID,
+		Data: &slackevents.EventsAPICallbackEvent{
+			Type:         env.Type,
+			Token:        env.Token,
+			TeamID:       env.TeamID,
+			APIAppID:     env.APIAppID,
+			InnerEvent:   &inner,
+			EventID:      env.EventID,
+			EventTime:    env.EventTime,
+			EventContext: env.EventContext,
+		},
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: env.InnerType,
+			Data: &RawInnerEvent{Type: env.InnerType, JSON: env.Event, Err: parseErr},
+		},
+	}, true
+}
+
+// onRaw registers `h` as a handler of events of a type that slack-go doesn't know, which the Router passes as RawInnerEvent.
+// `h` decodes them by decodeInnerEvent.
+func (r *Router) onRaw(eventType, name string, h Handler) {
+	r.rawTypes[eventType] = true
+	r.on(eventType, name, h)
+}
+
+// decodeInnerEvent decodes the inner event of `e` into `v` from its JSON, regardless of whether slack-go has parsed it,
+// so that typed handlers with their own payload types keep working when slack-go starts to know the type.
+func decodeInnerEvent(e *slackevents.EventsAPIEvent, v interface{}) error {
+	var (
+		data      json.RawMessage
+		eventType = e.InnerEvent.Type
+	)
+	if raw, ok := e.InnerEvent.Data.(*RawInnerEvent); ok {
+		data = raw.JSON
+	} else {
+		data = innerEventJSON(e)
+	}
+	if data == nil {
+		return typeMismatchError(e, v)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s event: %s: %w", eventType, err.Error(), routererrors.HttpError(http.StatusBadRequest))
+	}
+	return nil
+}
+
+// typeMismatchError returns an error that tells the Router that `InnerEvent.Data` of `e` is not of the same type as `expected`.
+func typeMismatchError(e *slackevents.EventsAPIEvent, expected interface{}) error {
+	return fmt.Errorf("expected %T but got %T: %w", expected, e.InnerEvent.Data, ErrInnerEventTypeMismatch)
+}
+
+// handleTypeMismatch processes an event that the handler named `name` failed to process with `err`, which wraps ErrInnerEventTypeMismatch.
+func (r *Router) handleTypeMismatch(ctx context.Context, e *slackevents.EventsAPIEvent, name string, err error) (string, error) {
+	if r.typeMismatchHook != nil {
+		r.typeMismatchHook(ctx, e, err)
+	}
+	if !r.rawOnTypeMismatch {
+		return name, fmt.Errorf("%s: %w", err.Error(), routererrors.HttpError(http.StatusBadRequest))
+	}
+	if r.rawHandler == nil {
+		return r.handleFallback(ctx, e)
+	}
+	raw := *e
+	raw.InnerEvent.Data = &RawInnerEvent{Type: e.InnerEvent.Type, JSON: innerEventJSON(e), Err: err}
+	return r.rawHandlerName, r.rawHandler.HandleEventsAPIEvent(ctx, &raw)
+}
+
+// innerEventJSON returns the inner event of `e` as is, or nil if it is not available.
+func innerEventJSON(e *slackevents.EventsAPIEvent) json.RawMessage {
+	cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent)
+	if !ok || cb.InnerEvent == nil {
+		return nil
+	}
+	return *cb.InnerEvent
+}
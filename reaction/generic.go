@@ -0,0 +1,72 @@
+package reaction
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// AddedToGeneric converts `h` to a generic `router.Handler`.
+func AddedToGeneric(h AddedHandler) router.Handler[*slackevents.ReactionAddedEvent] {
+	return router.HandlerFunc[*slackevents.ReactionAddedEvent](h.HandleReactionAddedEvent)
+}
+
+// AddedFromGeneric converts a generic `router.Handler` to an AddedHandler.
+func AddedFromGeneric(h router.Handler[*slackevents.ReactionAddedEvent]) AddedHandler {
+	return AddedHandlerFunc(h.Handle)
+}
+
+// RemovedToGeneric converts `h` to a generic `router.Handler`.
+func RemovedToGeneric(h RemovedHandler) router.Handler[*slackevents.ReactionRemovedEvent] {
+	return router.HandlerFunc[*slackevents.ReactionRemovedEvent](h.HandleReactionRemovedEvent)
+}
+
+// RemovedFromGeneric converts a generic `router.Handler` to a RemovedHandler.
+func RemovedFromGeneric(h router.Handler[*slackevents.ReactionRemovedEvent]) RemovedHandler {
+	return RemovedHandlerFunc(h.Handle)
+}
+
+type genericPredicate struct {
+	added   router.Predicate[*slackevents.ReactionAddedEvent]
+	removed router.Predicate[*slackevents.ReactionRemovedEvent]
+}
+
+// FromGenericPredicate converts generic `router.Predicate`s to a Predicate, so that generic middleware can be used with this package.
+//
+// `added` and `removed` are used for AddedHandlers and RemovedHandlers respectively. Typically they are instantiations of the same generic predicate.
+func FromGenericPredicate(added router.Predicate[*slackevents.ReactionAddedEvent], removed router.Predicate[*slackevents.ReactionRemovedEvent]) Predicate {
+	return &genericPredicate{added: added, removed: removed}
+}
+
+func (p *genericPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedFromGeneric(p.added.Wrap(AddedToGeneric(h)))
+}
+
+func (p *genericPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedFromGeneric(p.removed.Wrap(RemovedToGeneric(h)))
+}
+
+func (p *genericPredicate) String() string {
+	if s, ok := p.added.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p.added)
+}
+
+// Unwrap returns the generic predicate, so that route introspection can see through the adapter.
+func (p *genericPredicate) Unwrap() interface{} {
+	return p.added
+}
+
+func isOutermost(p Predicate) bool {
+	switch p := p.(type) {
+	case router.Outermost:
+		return true
+	case *genericPredicate:
+		_, ok := p.added.(router.Outermost)
+		return ok
+	}
+	return false
+}
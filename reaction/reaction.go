@@ -7,9 +7,16 @@ package reaction
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"strings"
 
+	"github.com/genkami/go-slack-event-router/channeltype"
+	"github.com/genkami/go-slack-event-router/enrich"
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
@@ -35,6 +42,30 @@ func (f RemovedHandlerFunc) HandleReactionRemovedEvent(ctx context.Context, e *s
 	return f(ctx, e)
 }
 
+// AddedHandlerProvider constructs AddedHandlers lazily. See `router.HandlerProvider`.
+type AddedHandlerProvider = router.HandlerProvider[AddedHandler]
+
+type AddedHandlerProviderFunc = router.HandlerProviderFunc[AddedHandler]
+
+// AddedFromProvider returns a AddedHandler that obtains a AddedHandler from `p` every time it processes an event and delegates the event to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func AddedFromProvider(p AddedHandlerProvider) AddedHandler {
+	return AddedFromGeneric(router.FromProvider(p, AddedHandler.HandleReactionAddedEvent))
+}
+
+// RemovedHandlerProvider constructs RemovedHandlers lazily. See `router.HandlerProvider`.
+type RemovedHandlerProvider = router.HandlerProvider[RemovedHandler]
+
+type RemovedHandlerProviderFunc = router.HandlerProviderFunc[RemovedHandler]
+
+// RemovedFromProvider returns a RemovedHandler that obtains a RemovedHandler from `p` every time it processes an event and delegates the event to it.
+//
+// The provider is called only when all predicates of the handler are true. Errors returned from `p` are returned as is.
+func RemovedFromProvider(p RemovedHandlerProvider) RemovedHandler {
+	return RemovedFromGeneric(router.FromProvider(p, RemovedHandler.HandleReactionRemovedEvent))
+}
+
 // Predicate disthinguishes whether or not a certain handler should process coming events.
 // This can be used with both `AddedHandler` and `RemovedHandler`.
 type Predicate interface {
@@ -69,6 +100,10 @@ func (p *namePredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
 	})
 }
 
+func (p *namePredicate) String() string {
+	return fmt.Sprintf("Name(%s)", p.reaction)
+}
+
 type inChannelPredicate struct {
 	channel string
 }
@@ -96,6 +131,10 @@ func (p *inChannelPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
 	})
 }
 
+func (p *inChannelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.channel)
+}
+
 type messageTextRegexpPredicate struct {
 	re *regexp.Regexp
 }
@@ -134,6 +173,74 @@ func (p *messageTextRegexpPredicate) WrapRemoved(h RemovedHandler) RemovedHandle
 	})
 }
 
+func (p *messageTextRegexpPredicate) String() string {
+	return fmt.Sprintf("MessageTextRegexp(%s)", p.re)
+}
+
+type resolvedMessagePredicate struct {
+	name  string
+	arg   string
+	match func(*slack.Message) bool
+}
+
+// ResolvedMessageTextRegexp is a predicate that is considered to be "true" if and only if a text of a reacted message matches to the given regexp.
+//
+// Unlike MessageTextRegexp, this uses the message resolved by `enrich.WithReactedMessages`, since `reaction_*` events rarely contain the message itself.
+// It is considered to be "false" if the message is not resolved.
+func ResolvedMessageTextRegexp(re *regexp.Regexp) Predicate {
+	return &resolvedMessagePredicate{
+		name: "ResolvedMessageTextRegexp",
+		arg:  re.String(),
+		match: func(msg *slack.Message) bool {
+			return re.MatchString(msg.Text)
+		},
+	}
+}
+
+// ResolvedMessageUser is a predicate that is considered to be "true" if and only if the author of a reacted message is the given one.
+//
+// This uses the message resolved by `enrich.WithReactedMessages`, and is considered to be "false" if the message is not resolved.
+// Unlike ItemUser, this can distinguish messages posted by bots, whose `item_user` is often empty.
+func ResolvedMessageUser(id string) Predicate {
+	return &resolvedMessagePredicate{
+		name: "ResolvedMessageUser",
+		arg:  id,
+		match: func(msg *slack.Message) bool {
+			return msg.User == id || (msg.User == "" && msg.BotID == id)
+		},
+	}
+}
+
+func (p *resolvedMessagePredicate) check(ctx context.Context) error {
+	msg := enrich.ReactedMessageFromContext(ctx)
+	if msg == nil || !p.match(msg) {
+		return errors.NotInterested
+	}
+	return nil
+}
+
+func (p *resolvedMessagePredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		if err := p.check(ctx); err != nil {
+			return err
+		}
+		return h.HandleReactionAddedEvent(ctx, e)
+	})
+}
+
+func (p *resolvedMessagePredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		if err := p.check(ctx); err != nil {
+			return err
+		}
+		return h.HandleReactionRemovedEvent(ctx, e)
+	})
+}
+
+func (p *resolvedMessagePredicate) String() string {
+	return fmt.Sprintf("%s(%s)", p.name, p.arg)
+}
+
 type itemUserPredicate struct {
 	id string
 }
@@ -161,18 +268,156 @@ func (p *itemUserPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
 	})
 }
 
+func (p *itemUserPredicate) String() string {
+	return fmt.Sprintf("ItemUser(%s)", p.id)
+}
+
+type channelTypePredicate struct {
+	resolver channeltype.Resolver
+	types    []string
+}
+
+// ChannelType is a predicate that is considered to be "true" if and only if the type of the channel of the reacted item is one of the given ones.
+//
+// Since `reaction_*` events don't have `channel_type`, the type is resolved by `resolver` (e.g. `channeltype.ByPrefix`).
+// Errors returned from `resolver` are returned as is.
+func ChannelType(resolver channeltype.Resolver, types ...string) Predicate {
+	return &channelTypePredicate{resolver: resolver, types: types}
+}
+
+func (p *channelTypePredicate) match(ctx context.Context, item *slackevents.Item) error {
+	channelType, err := p.resolver.Resolve(ctx, item.Channel)
+	if err != nil {
+		return err
+	}
+	for _, t := range p.types {
+		if channelType == t {
+			return nil
+		}
+	}
+	return errors.NotInterested
+}
+
+func (p *channelTypePredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		if err := p.match(ctx, &e.Item); err != nil {
+			return err
+		}
+		return h.HandleReactionAddedEvent(ctx, e)
+	})
+}
+
+func (p *channelTypePredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		if err := p.match(ctx, &e.Item); err != nil {
+			return err
+		}
+		return h.HandleReactionRemovedEvent(ctx, e)
+	})
+}
+
+func (p *channelTypePredicate) String() string {
+	return fmt.Sprintf("ChannelType(%s)", strings.Join(p.types, ", "))
+}
+
+// Condition is a condition that does not depend on events. See `router.Condition`.
+type Condition = router.Condition
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When(cond Condition) Predicate {
+	return FromGenericPredicate(router.When[*slackevents.ReactionAddedEvent](cond), router.When[*slackevents.ReactionRemovedEvent](cond))
+}
+
+// Set is a set of values that may change over time. See `router.Set`.
+type Set = router.Set
+
+// NameIn is a predicate that is considered to be "true" if and only if a reaction name is in `set`.
+func NameIn(set Set) Predicate {
+	return FromGenericPredicate(
+		router.In("NameIn", set, func(e *slackevents.ReactionAddedEvent) string { return e.Reaction }),
+		router.In("NameIn", set, func(e *slackevents.ReactionRemovedEvent) string { return e.Reaction }),
+	)
+}
+
+// ChannelIn is a predicate that is considered to be "true" if and only if the channel where an event happened is in `set`.
+func ChannelIn(set Set) Predicate {
+	return FromGenericPredicate(
+		router.In("ChannelIn", set, func(e *slackevents.ReactionAddedEvent) string { return e.Item.Channel }),
+		router.In("ChannelIn", set, func(e *slackevents.ReactionRemovedEvent) string { return e.Item.Channel }),
+	)
+}
+
+// UserIn is a predicate that is considered to be "true" if and only if the user who added or removed a reaction is in `set`.
+func UserIn(set Set) Predicate {
+	return FromGenericPredicate(
+		router.In("UserIn", set, func(e *slackevents.ReactionAddedEvent) string { return e.User }),
+		router.In("UserIn", set, func(e *slackevents.ReactionRemovedEvent) string { return e.User }),
+	)
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description(text string) Predicate {
+	return FromGenericPredicate(router.Description[*slackevents.ReactionAddedEvent](text), router.Description[*slackevents.ReactionRemovedEvent](text))
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue(key, value interface{}) Predicate {
+	return FromGenericPredicate(router.WithValue[*slackevents.ReactionAddedEvent](key, value), router.WithValue[*slackevents.ReactionRemovedEvent](key, value))
+}
+
+type failOnMismatchPredicate struct{}
+
+// FailOnMismatch is a predicate that turns mismatches of the other predicates into `errors.Mismatch`.
+//
+// By default, the Router silently falls back to other handlers when predicates don't match.
+// With FailOnMismatch, the Router stops there and reports the mismatch (e.g. to the hook set by `eventrouter.OnError`) as a misrouted event,
+// while still acknowledging the event. Unlike other predicates, it takes effect regardless of its position in the predicates.
+func FailOnMismatch() Predicate {
+	return &failOnMismatchPredicate{}
+}
+
+func (p *failOnMismatchPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		return routerutils.ToMismatch(h.HandleReactionAddedEvent(ctx, e))
+	})
+}
+
+func (p *failOnMismatchPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		return routerutils.ToMismatch(h.HandleReactionRemovedEvent(ctx, e))
+	})
+}
+
+func (p *failOnMismatchPredicate) Outermost() {}
+
+func (p *failOnMismatchPredicate) String() string {
+	return "FailOnMismatch()"
+}
+
 // BuildAdded decorates `AddedHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func BuildAdded(h AddedHandler, preds ...Predicate) AddedHandler {
-	for _, p := range preds {
-		h = p.WrapAdded(h)
-	}
-	return h
+	return routerutils.BuildMarked(h, preds, Predicate.WrapAdded, isOutermost, markAddedHandler)
 }
 
 // BuildRemoved decorates `RemovedHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func BuildRemoved(h RemovedHandler, preds ...Predicate) RemovedHandler {
-	for _, p := range preds {
-		h = p.WrapRemoved(h)
-	}
-	return h
+	return routerutils.BuildMarked(h, preds, Predicate.WrapRemoved, isOutermost, markRemovedHandler)
+}
+
+// markAddedHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markAddedHandler(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		return routerutils.MarkHandlerError(h.HandleReactionAddedEvent(ctx, e))
+	})
+}
+
+// markRemovedHandler marks errors of `h` so that FailOnMismatch doesn't mistake NotInterested returned by `h` itself for a mismatch.
+func markRemovedHandler(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		return routerutils.MarkHandlerError(h.HandleReactionRemovedEvent(ctx, e))
+	})
 }
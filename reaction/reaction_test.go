@@ -3,13 +3,20 @@ package reaction_test
 import (
 	"context"
 	"regexp"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/channeltype"
+	"github.com/genkami/go-slack-event-router/dynamic"
+	"github.com/genkami/go-slack-event-router/enrich"
 	"github.com/genkami/go-slack-event-router/errors"
 	"github.com/genkami/go-slack-event-router/reaction"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/schedule"
 )
 
 var _ = Describe("Reaction", func() {
@@ -398,6 +405,66 @@ var _ = Describe("Reaction", func() {
 		})
 	})
 
+	Describe("ResolvedMessageTextRegexp", func() {
+		var (
+			pred     = reaction.ResolvedMessageTextRegexp(regexp.MustCompile(`apple`))
+			resolved = func(text string) context.Context {
+				msg := &slack.Message{}
+				msg.Text = text
+				return enrich.WithReactedMessage(ctx, msg)
+			}
+		)
+
+		Context("When the text of the resolved message matches to the pattern", func() {
+			It("calls the inner handler", func() {
+				Expect(pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(resolved("I ate an apple"), &slackevents.ReactionAddedEvent{})).To(Succeed())
+				Expect(pred.WrapRemoved(innerRemovedHandler).HandleReactionRemovedEvent(resolved("I ate an apple"), &slackevents.ReactionRemovedEvent{})).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("When the text of the resolved message does not match to the pattern", func() {
+			It("does not call the inner handler", func() {
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(resolved("I ate a banana"), &slackevents.ReactionAddedEvent{})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("When the message is not resolved", func() {
+			It("does not call the inner handler", func() {
+				e := &slackevents.ReactionAddedEvent{
+					Item: slackevents.Item{
+						Message: &slackevents.ItemMessage{Text: "I ate an apple"},
+					},
+				}
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("ResolvedMessageUser", func() {
+		It("matches on the author of the resolved message", func() {
+			msg := &slack.Message{}
+			msg.User = "U001"
+			resolved := enrich.WithReactedMessage(ctx, msg)
+			Expect(reaction.ResolvedMessageUser("U001").WrapAdded(innerAddedHandler).HandleReactionAddedEvent(resolved, &slackevents.ReactionAddedEvent{})).To(Succeed())
+			err := reaction.ResolvedMessageUser("U002").WrapAdded(innerAddedHandler).HandleReactionAddedEvent(resolved, &slackevents.ReactionAddedEvent{})
+			Expect(err).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("matches on the bot that posted the resolved message", func() {
+			msg := &slack.Message{}
+			msg.BotID = "B001"
+			resolved := enrich.WithReactedMessage(ctx, msg)
+			Expect(reaction.ResolvedMessageUser("B001").WrapAdded(innerAddedHandler).HandleReactionAddedEvent(resolved, &slackevents.ReactionAddedEvent{})).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
 	Describe("ItemUser", func() {
 		Describe("WrapAdded", func() {
 			Context("When the author of the reacted item is the given one", func() {
@@ -455,4 +522,179 @@ var _ = Describe("Reaction", func() {
 			})
 		})
 	})
+
+	Describe("WithValue", func() {
+		type key struct{}
+		var got interface{}
+		BeforeEach(func() {
+			got = nil
+		})
+
+		Describe("WrapAdded", func() {
+			It("passes the value to the inner handler", func() {
+				h := reaction.BuildAdded(reaction.AddedHandlerFunc(func(ctx context.Context, _ *slackevents.ReactionAddedEvent) error {
+					got = ctx.Value(key{})
+					return nil
+				}), reaction.WithValue(key{}, "the value"))
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "tada"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(got).To(Equal("the value"))
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			It("passes the value to the inner handler", func() {
+				h := reaction.BuildRemoved(reaction.RemovedHandlerFunc(func(ctx context.Context, _ *slackevents.ReactionRemovedEvent) error {
+					got = ctx.Value(key{})
+					return nil
+				}), reaction.WithValue(key{}, "the value"))
+				err := h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "tada"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(got).To(Equal("the value"))
+			})
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		var (
+			numSeen int
+			counted = func() router.PredicateFunc[*slackevents.ReactionAddedEvent] {
+				return func(h router.Handler[*slackevents.ReactionAddedEvent]) router.Handler[*slackevents.ReactionAddedEvent] {
+					return router.HandlerFunc[*slackevents.ReactionAddedEvent](func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+						numSeen++
+						return h.Handle(ctx, e)
+					})
+				}
+			}
+		)
+		BeforeEach(func() {
+			numSeen = 0
+		})
+
+		It("uses the predicate for added events", func() {
+			p := reaction.FromGenericPredicate(counted(), router.FailOnMismatch[*slackevents.ReactionRemovedEvent]())
+			h := reaction.BuildAdded(innerAddedHandler, p)
+			Expect(h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile"})).To(Succeed())
+			Expect(numSeen).To(Equal(1))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("uses the predicate for removed events", func() {
+			p := reaction.FromGenericPredicate(counted(), router.FailOnMismatch[*slackevents.ReactionRemovedEvent]())
+			h := reaction.BuildRemoved(innerRemovedHandler, reaction.Name("tada"), p)
+			err := h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "smile"})
+			Expect(err).To(MatchError(errors.Mismatch))
+			Expect(numSeen).To(Equal(0))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		Describe("WrapAdded", func() {
+			It("returns Mismatch when the other predicates don't match", func() {
+				h := reaction.BuildAdded(innerAddedHandler, reaction.Name("tada"), reaction.FailOnMismatch())
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile"})
+				Expect(err).To(MatchError(errors.Mismatch))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+
+			It("does not turn NotInterested returned by the inner handler itself into Mismatch", func() {
+				notInterested := reaction.AddedHandlerFunc(func(context.Context, *slackevents.ReactionAddedEvent) error {
+					return errors.NotInterested
+				})
+				h := reaction.BuildAdded(notInterested, reaction.Name("tada"), reaction.FailOnMismatch())
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "tada"})
+				Expect(err).To(MatchError(errors.NotInterested))
+				Expect(err).NotTo(MatchError(errors.Mismatch))
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			It("returns Mismatch when the other predicates don't match", func() {
+				h := reaction.BuildRemoved(innerRemovedHandler, reaction.Name("tada"), reaction.FailOnMismatch())
+				err := h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "smile"})
+				Expect(err).To(MatchError(errors.Mismatch))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("ChannelType", func() {
+		Describe("WrapAdded", func() {
+			It("calls the inner handler only when the type matches", func() {
+				h := reaction.BuildAdded(innerAddedHandler, reaction.ChannelType(channeltype.ByPrefix, channeltype.Channel))
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Item: slackevents.Item{Channel: "C0123"}})
+				Expect(err).NotTo(HaveOccurred())
+				err = h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Item: slackevents.Item{Channel: "D0123"}})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			It("calls the inner handler only when the type matches", func() {
+				h := reaction.BuildRemoved(innerRemovedHandler, reaction.ChannelType(channeltype.ByPrefix, channeltype.IM))
+				err := h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Item: slackevents.Item{Channel: "D0123"}})
+				Expect(err).NotTo(HaveOccurred())
+				err = h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Item: slackevents.Item{Channel: "C0123"}})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("When", func() {
+		// 2021-03-01 is Monday.
+		var (
+			window  = schedule.Window(time.UTC, "Mon-Fri 09:00-18:00")
+			inside  = window.WithNowFunc(func() time.Time { return time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC) })
+			outside = window.WithNowFunc(func() time.Time { return time.Date(2021, 3, 6, 10, 0, 0, 0, time.UTC) })
+		)
+
+		Describe("WrapAdded", func() {
+			It("calls the inner handler only when the condition matches", func() {
+				err := reaction.BuildAdded(innerAddedHandler, reaction.When(inside)).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{})
+				Expect(err).NotTo(HaveOccurred())
+				err = reaction.BuildAdded(innerAddedHandler, reaction.When(outside)).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			It("calls the inner handler only when the condition matches", func() {
+				err := reaction.BuildRemoved(innerRemovedHandler, reaction.When(inside)).HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{})
+				Expect(err).NotTo(HaveOccurred())
+				err = reaction.BuildRemoved(innerRemovedHandler, reaction.When(schedule.Not(inside))).HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("NameIn", func() {
+		var names *dynamic.Set
+		BeforeEach(func() {
+			names = dynamic.NewSet(dynamic.SourceFunc(func(_ context.Context) ([]string, error) {
+				return []string{"tada", "eyes"}, nil
+			}), time.Hour)
+		})
+
+		Describe("WrapAdded", func() {
+			It("calls the inner handler only when the reaction is in the set", func() {
+				h := reaction.BuildAdded(innerAddedHandler, reaction.NameIn(names))
+				Expect(h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "eyes"})).To(Succeed())
+				Expect(h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile"})).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			It("calls the inner handler only when the reaction is in the set", func() {
+				h := reaction.BuildRemoved(innerRemovedHandler, reaction.NameIn(names))
+				Expect(h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "tada"})).To(Succeed())
+				Expect(h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "smile"})).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
 })
@@ -0,0 +1,138 @@
+// Package redact removes sensitive content (e.g. tokens, email addresses and long texts of messages) from strings and errors
+// before they are written to logs or shown in responses.
+//
+// The same Policy can be given to routers (by their `WithRedaction` options) and to `audit.WithPolicy`,
+// so that all the outputs of an app are redacted consistently:
+//
+//	policy := redact.New(redact.StripTokens(), redact.MaskEmails(), redact.Truncate(200))
+//	r, err := eventrouter.New(eventrouter.WithSigningSecret(secret), eventrouter.VerboseResponse(), eventrouter.WithRedaction(policy))
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rule rewrites a string to remove sensitive content.
+type Rule interface {
+	Apply(s string) string
+}
+
+type RuleFunc func(s string) string
+
+func (f RuleFunc) Apply(s string) string {
+	return f(s)
+}
+
+// Token is the text that StripTokens replaces tokens with.
+const Token = "[TOKEN]"
+
+// lazyRegexp compiles its pattern on first use, so that importing this package doesn't slow down cold starts.
+type lazyRegexp struct {
+	once    sync.Once
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (l *lazyRegexp) get() *regexp.Regexp {
+	l.once.Do(func() {
+		l.re = regexp.MustCompile(l.pattern)
+	})
+	return l.re
+}
+
+var tokenPattern = &lazyRegexp{pattern: `\bx(?:ox[abposre]|app)-[0-9A-Za-z-]+`}
+
+// StripTokens returns a Rule that replaces Slack tokens (e.g. `xoxb-...`, `xoxp-...` and `xapp-...`) with Token.
+func StripTokens() Rule {
+	return RuleFunc(func(s string) string {
+		return tokenPattern.get().ReplaceAllString(s, Token)
+	})
+}
+
+var emailPattern = &lazyRegexp{pattern: `[0-9A-Za-z._%+-]+@([0-9A-Za-z.-]+\.[A-Za-z]{2,})`}
+
+// MaskEmails returns a Rule that masks local parts of email addresses (e.g. `alice@example.com` becomes `***@example.com`).
+//
+// Domains are kept since they are often useful to debug problems of a specific organization.
+func MaskEmails() Rule {
+	return RuleFunc(func(s string) string {
+		return emailPattern.get().ReplaceAllString(s, "***@$1")
+	})
+}
+
+// Truncate returns a Rule that truncates strings longer than `n` characters (not bytes) and marks them as truncated.
+func Truncate(n int) Rule {
+	return RuleFunc(func(s string) string {
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		var b strings.Builder
+		i := 0
+		for _, r := range s {
+			if i == n {
+				break
+			}
+			b.WriteRune(r)
+			i++
+		}
+		b.WriteString("...(truncated)")
+		return b.String()
+	})
+}
+
+// Policy is a list of Rules that are applied in order.
+//
+// A nil Policy is valid and doesn't change anything.
+type Policy struct {
+	rules []Rule
+}
+
+// New creates a new Policy that applies `rules` in the order in which they are given.
+func New(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// Default is a Policy that strips tokens, masks email addresses and truncates strings longer than 1000 characters.
+var Default = New(StripTokens(), MaskEmails(), Truncate(1000))
+
+// String applies the Policy to `s`.
+func (p *Policy) String(s string) string {
+	if p == nil {
+		return s
+	}
+	for _, rule := range p.rules {
+		s = rule.Apply(s)
+	}
+	return s
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Error returns an error whose message is redacted by the Policy.
+//
+// The original error is still available by `errors.Is`, `errors.As` and `errors.Unwrap`, so callers can distinguish errors as usual.
+// It returns nil if `err` is nil.
+func (p *Policy) Error(err error) error {
+	if p == nil || err == nil {
+		return err
+	}
+	msg := p.String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
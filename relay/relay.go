@@ -0,0 +1,205 @@
+// Package relay provides an http.Handler that relays requests from Slack to another endpoint.
+//
+// This is useful when you receive requests from Slack at an edge server and forward them to internal services.
+package relay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+// forwardedHeaders are request headers that the Relay forwards to the upstream as they are.
+var forwardedHeaders = []string{
+	"Content-Type",
+	slackheaders.RetryNum,
+	slackheaders.RetryReason,
+}
+
+// Option configures the Relay.
+type Option interface {
+	apply(*Relay)
+}
+
+type optionFunc func(*Relay)
+
+func (f optionFunc) apply(r *Relay) {
+	f(r)
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful to test your relays, but do not use this in production environments.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(r *Relay) {
+		r.skipVerification = true
+	})
+}
+
+// WithSigningSecret sets a signing token to verify requests from Slack.
+//
+// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func WithSigningSecret(token string) Option {
+	return optionFunc(func(r *Relay) {
+		r.signingSecret = token
+	})
+}
+
+// WithResigningSecret makes the Relay re-sign forwarded requests with the given secret instead of forwarding the original signature.
+//
+// This allows upstream services to verify requests with an internal secret.
+func WithResigningSecret(token string) Option {
+	return optionFunc(func(r *Relay) {
+		r.resigningSecret = token
+	})
+}
+
+// WithHTTPClient sets an HTTP client that is used to forward requests.
+//
+// If not set, the Relay uses `http.DefaultClient`.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(r *Relay) {
+		r.httpClient = client
+	})
+}
+
+// If VerboseResponse is set, the Relay shows error details when it fails to process requests.
+func VerboseResponse() Option {
+	return optionFunc(func(r *Relay) {
+		r.verboseResponse = true
+	})
+}
+
+// WithRedaction makes the Relay redact error details by `policy` before showing them (see VerboseResponse).
+func WithRedaction(policy *redact.Policy) Option {
+	return optionFunc(func(r *Relay) {
+		r.redaction = policy
+	})
+}
+
+// WithNowFunc sets a function that returns the current time.
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(r *Relay) {
+		r.now = now
+	})
+}
+
+// Relay is an http.Handler that verifies requests from Slack and forwards them to the upstream.
+//
+// Responses from the upstream are written back to Slack as they are.
+type Relay struct {
+	upstream         *url.URL
+	signingSecret    string
+	skipVerification bool
+	resigningSecret  string
+	verboseResponse  bool
+	redaction        *redact.Policy
+	httpClient       *http.Client
+	now              func() time.Time
+	httpHandler      http.Handler
+}
+
+// New creates a new Relay that forwards requests to `upstream`.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func New(upstream string, opts ...Option) (*Relay, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream: %w", err)
+	}
+	r := &Relay{
+		upstream:   u,
+		httpClient: http.DefaultClient,
+		now:        time.Now,
+	}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	if r.signingSecret == "" && !r.skipVerification {
+		return nil, fmt.Errorf("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification: %w", routererrors.ErrMissingSigningSecret)
+	}
+	if r.signingSecret != "" && r.skipVerification {
+		return nil, fmt.Errorf("both WithSigningSecret and InsecureSkipVerification are given: %w", routererrors.ErrConflictingOptions)
+	}
+
+	r.httpHandler = http.HandlerFunc(r.serveHTTP)
+	if !r.skipVerification {
+		r.httpHandler = &signature.Middleware{
+			SigningSecret:   r.signingSecret,
+			VerboseResponse: r.verboseResponse,
+			Handler:         r.httpHandler,
+			Now:             r.now,
+		}
+	}
+	return r, nil
+}
+
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.httpHandler.ServeHTTP(w, req)
+}
+
+func (r *Relay) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		r.respondWithError(w, err)
+		return
+	}
+	upstreamReq, err := r.newUpstreamRequest(req, body)
+	if err != nil {
+		r.respondWithError(w, err)
+		return
+	}
+	resp, err := r.httpClient.Do(upstreamReq)
+	if err != nil {
+		r.respondWithError(w, fmt.Errorf("failed to forward the request: %s: %w", err.Error(), routererrors.HttpError(http.StatusBadGateway)))
+		return
+	}
+	defer resp.Body.Close()
+
+	for name, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (r *Relay) newUpstreamRequest(req *http.Request, body []byte) (*http.Request, error) {
+	u := *r.upstream
+	if u.RawQuery == "" {
+		u.RawQuery = req.URL.RawQuery
+	}
+	upstreamReq, err := http.NewRequestWithContext(req.Context(), req.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range forwardedHeaders {
+		if v := req.Header.Get(name); v != "" {
+			upstreamReq.Header.Set(name, v)
+		}
+	}
+	if r.resigningSecret == "" {
+		upstreamReq.Header.Set(slackheaders.RequestTimestamp, req.Header.Get(slackheaders.RequestTimestamp))
+		upstreamReq.Header.Set(slackheaders.Signature, req.Header.Get(slackheaders.Signature))
+		return upstreamReq, nil
+	}
+	if err := signature.AddSignature(upstreamReq.Header, []byte(r.resigningSecret), body, r.now()); err != nil {
+		return nil, err
+	}
+	return upstreamReq, nil
+}
+
+func (r *Relay) respondWithError(w http.ResponseWriter, err error) {
+	routerutils.RespondWithError(w, r.redaction.Error(err), r.verboseResponse)
+}
@@ -0,0 +1,145 @@
+package relay_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/relay"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+var _ = Describe("Relay", func() {
+	var (
+		token          = "THE_TOKEN"
+		internalToken  = "THE_INTERNAL_TOKEN"
+		content        = `{"type": "event_callback"}`
+		upstream       *httptest.Server
+		upstreamStatus int
+		received       *http.Request
+		receivedBody   []byte
+	)
+	BeforeEach(func() {
+		upstreamStatus = http.StatusOK
+		received = nil
+		receivedBody = nil
+		upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			received = req
+			receivedBody, _ = ioutil.ReadAll(req.Body)
+			w.Header().Set(slackheaders.NoRetry, "1")
+			w.WriteHeader(upstreamStatus)
+			_, _ = w.Write([]byte("upstream response"))
+		}))
+	})
+	AfterEach(func() {
+		upstream.Close()
+	})
+
+	Describe("New", func() {
+		Context("when neither WithSigningSecret nor InsecureSkipVerification is given", func() {
+			It("returns an error", func() {
+				_, err := relay.New(upstream.URL)
+				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+			})
+		})
+
+		Context("when both WithSigningSecret and InsecureSkipVerification are given", func() {
+			It("returns an error", func() {
+				_, err := relay.New(upstream.URL, relay.WithSigningSecret(token), relay.InsecureSkipVerification())
+				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+			})
+		})
+	})
+
+	Context("when the signature is valid", func() {
+		It("forwards the request and the original signature", func() {
+			r, err := relay.New(upstream.URL, relay.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			req := newSignedRequest(token, content)
+			req.Header.Set(slackheaders.RetryNum, "1")
+			req.Header.Set(slackheaders.RetryReason, "http_timeout")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get(slackheaders.NoRetry)).To(Equal("1"))
+			Expect(w.Body.String()).To(Equal("upstream response"))
+			Expect(string(receivedBody)).To(Equal(content))
+			Expect(received.Header.Get(slackheaders.Signature)).To(Equal(req.Header.Get(slackheaders.Signature)))
+			Expect(received.Header.Get(slackheaders.RetryNum)).To(Equal("1"))
+			Expect(received.Header.Get(slackheaders.RetryReason)).To(Equal("http_timeout"))
+		})
+
+		It("passes through the status code of the upstream", func() {
+			upstreamStatus = http.StatusInternalServerError
+			r, err := relay.New(upstream.URL, relay.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newSignedRequest(token, content))
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("when the signature is invalid", func() {
+		It("does not forward the request", func() {
+			r, err := relay.New(upstream.URL, relay.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newSignedRequest("WRONG_TOKEN", content))
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(received).To(BeNil())
+		})
+	})
+
+	Context("when WithResigningSecret is given", func() {
+		It("re-signs the forwarded request", func() {
+			r, err := relay.New(upstream.URL, relay.WithSigningSecret(token), relay.WithResigningSecret(internalToken))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newSignedRequest(token, content))
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			verified := false
+			m := &signature.Middleware{
+				SigningSecret: internalToken,
+				Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+					verified = true
+				}),
+			}
+			forwarded, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(receivedBody))
+			Expect(err).NotTo(HaveOccurred())
+			forwarded.Header = received.Header
+			m.ServeHTTP(httptest.NewRecorder(), forwarded)
+			Expect(verified).To(BeTrue())
+		})
+	})
+
+	Context("when the upstream is unreachable", func() {
+		It("responds with BadGateway", func() {
+			r, err := relay.New("http://127.0.0.1:0/", relay.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, newSignedRequest(token, content))
+			resp := w.Result()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		})
+	})
+})
+
+func newSignedRequest(signingSecret, body string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader([]byte(body)))
+	Expect(err).NotTo(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+	err = signature.AddSignature(req.Header, []byte(signingSecret), []byte(body), time.Now())
+	Expect(err).NotTo(HaveOccurred())
+	return req
+}
@@ -0,0 +1,165 @@
+// Package replyguard prevents handlers from replying to the same channel too many times in a short period.
+//
+// When two bots reply to each other's messages, they can flood a channel within seconds. A Guard wraps handlers that reply,
+// and stops calling them for a channel once they are called `limit` times within `window`:
+//
+//	guard := replyguard.New(5, time.Minute, replyguard.OnLimited(func(ctx context.Context, channel string) {
+//		log.Printf("too many replies to %s; possibly a loop", channel)
+//	}))
+//	r.OnMessage(guard.Message(handleGreeting), message.TextRegexp(greetingPattern))
+//	r.OnAppMention(guard.AppMention(handleMention))
+//
+// Every call of a wrapped handler counts as a reply, regardless of whether it actually posts anything.
+// The limit is shared by all handlers wrapped by the same Guard.
+package replyguard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+// Store records replies per channel.
+type Store interface {
+	// Allow records a reply to `channel` at `now` and returns true if fewer than `limit` replies are recorded within `window` before `now`.
+	// Otherwise it returns false without recording the reply.
+	//
+	// Implementations must check and record atomically, so that concurrent calls don't exceed the limit.
+	Allow(ctx context.Context, channel string, limit int, window time.Duration, now time.Time) (bool, error)
+}
+
+// MemoryStore is a Store that keeps timestamps of recent replies in memory.
+//
+// It is enough for a single process. Bots that run in more than one process need a shared Store to enforce limits across processes.
+type MemoryStore struct {
+	mu      sync.Mutex
+	replies map[string][]time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{replies: make(map[string][]time.Time)}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, channel string, limit int, window time.Duration, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	since := now.Add(-window)
+	recent := s.replies[channel][:0]
+	for _, t := range s.replies[channel] {
+		if t.After(since) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		s.replies[channel] = recent
+		return false, nil
+	}
+	s.replies[channel] = append(recent, now)
+	return true, nil
+}
+
+// Option configures the Guard.
+type Option interface {
+	apply(*Guard)
+}
+
+type optionFunc func(*Guard)
+
+func (f optionFunc) apply(g *Guard) {
+	f(g)
+}
+
+// WithStore sets the Store of the Guard. If not set, a new MemoryStore is used.
+func WithStore(store Store) Option {
+	return optionFunc(func(g *Guard) {
+		g.store = store
+	})
+}
+
+// OnLimited sets a hook that is called every time the Guard suppresses a handler.
+func OnLimited(hook func(ctx context.Context, channel string)) Option {
+	return optionFunc(func(g *Guard) {
+		g.limitedHook = hook
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. This is mainly intended to make tests deterministic.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(g *Guard) {
+		g.now = now
+	})
+}
+
+// Guard limits the number of replies per channel.
+type Guard struct {
+	limit       int
+	window      time.Duration
+	store       Store
+	now         func() time.Time
+	limitedHook func(ctx context.Context, channel string)
+}
+
+// New creates a new Guard that allows at most `limit` replies per channel within `window`.
+func New(limit int, window time.Duration, opts ...Option) *Guard {
+	g := &Guard{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+	for _, o := range opts {
+		o.apply(g)
+	}
+	if g.store == nil {
+		g.store = NewMemoryStore()
+	}
+	return g
+}
+
+// Allow records a reply to `channel` and returns true if the reply is within the limit.
+//
+// This is useful to guard replies that are not made by handlers of `message` or `app_mention` events.
+func (g *Guard) Allow(ctx context.Context, channel string) (bool, error) {
+	ok, err := g.store.Allow(ctx, channel, g.limit, g.window, g.now())
+	if err != nil {
+		return false, fmt.Errorf("failed to check replies to %s: %w", channel, err)
+	}
+	if !ok && g.limitedHook != nil {
+		g.limitedHook(ctx, channel)
+	}
+	return ok, nil
+}
+
+// Message returns a handler that calls `h` unless the channel of the message exceeds the limit.
+//
+// Suppressed messages are considered to be processed successfully, so that Slack doesn't retry them.
+func (g *Guard) Message(h message.Handler) message.Handler {
+	return message.HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		ok, err := g.Allow(ctx, e.Channel)
+		if err != nil || !ok {
+			return err
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+// AppMention returns a handler that calls `h` unless the channel of the mention exceeds the limit.
+//
+// Suppressed mentions are considered to be processed successfully, so that Slack doesn't retry them.
+func (g *Guard) AppMention(h appmention.Handler) appmention.Handler {
+	return appmention.HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		ok, err := g.Allow(ctx, e.Channel)
+		if err != nil || !ok {
+			return err
+		}
+		return h.HandleAppMentionEvent(ctx, e)
+	})
+}
@@ -0,0 +1,13 @@
+package replyguard_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestReplyguard(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replyguard Suite")
+}
@@ -0,0 +1,104 @@
+package replyguard_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/replyguard"
+)
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, int, time.Duration, time.Time) (bool, error) {
+	return false, errors.New("store is down")
+}
+
+var _ = Describe("Replyguard", func() {
+	var (
+		ctx     = context.Background()
+		now     time.Time
+		calls   int
+		limited []string
+		guard   *replyguard.Guard
+		h       message.Handler
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+		calls = 0
+		limited = nil
+		guard = replyguard.New(2, time.Minute,
+			replyguard.WithNowFunc(func() time.Time { return now }),
+			replyguard.OnLimited(func(_ context.Context, channel string) {
+				limited = append(limited, channel)
+			}))
+		h = guard.Message(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+			calls++
+			return nil
+		}))
+	})
+
+	messageIn := func(channel string) *slackevents.MessageEvent {
+		return &slackevents.MessageEvent{Channel: channel}
+	}
+
+	Describe("Message", func() {
+		It("calls the handler up to the limit", func() {
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(calls).To(Equal(2))
+			Expect(limited).To(Equal([]string{"C001"}))
+		})
+
+		It("limits each channel separately", func() {
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, messageIn("C002"))).To(Succeed())
+			Expect(calls).To(Equal(3))
+			Expect(limited).To(BeEmpty())
+		})
+
+		It("calls the handler again after the window", func() {
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			now = now.Add(30 * time.Second)
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			now = now.Add(31 * time.Second)
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(calls).To(Equal(3))
+			Expect(limited).To(Equal([]string{"C001"}))
+		})
+
+		It("returns an error when the store fails", func() {
+			g := replyguard.New(2, time.Minute, replyguard.WithStore(failingStore{}))
+			h := g.Message(message.HandlerFunc(func(context.Context, *slackevents.MessageEvent) error {
+				calls++
+				return nil
+			}))
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(MatchError(ContainSubstring("store is down")))
+			Expect(calls).To(Equal(0))
+		})
+	})
+
+	Describe("AppMention", func() {
+		It("shares the limit with other handlers of the same Guard", func() {
+			mh := guard.AppMention(appmention.HandlerFunc(func(context.Context, *slackevents.AppMentionEvent) error {
+				calls++
+				return nil
+			}))
+			Expect(h.HandleMessageEvent(ctx, messageIn("C001"))).To(Succeed())
+			Expect(mh.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Channel: "C001"})).To(Succeed())
+			Expect(mh.HandleAppMentionEvent(ctx, &slackevents.AppMentionEvent{Channel: "C001"})).To(Succeed())
+			Expect(calls).To(Equal(2))
+			Expect(limited).To(Equal([]string{"C001"}))
+		})
+	})
+})
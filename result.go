@@ -0,0 +1,53 @@
+package eventrouter
+
+import (
+	"context"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/attempt"
+)
+
+// Result is an outcome of processing an event.
+type Result struct {
+	// EventID is the ID of the event (i.e. `event_id` in the outer event).
+	EventID string
+
+	// Attempt identifies the attempt to deliver the event, so that results of retries of the same event can be correlated.
+	// This is the zero value if the event has no ID.
+	Attempt attempt.Key
+
+	// EventType is the type of the inner event.
+	EventType string
+
+	// Handler is the name of the handler that processed the event.
+	// This is empty if no handler processed the event.
+	Handler string
+
+	// Duration is the time taken to process the event, including enrichers.
+	Duration time.Duration
+
+	// Err is the error returned from the handler, if any.
+	// This is (or wraps) `routererrors.NotInterested` if no handler processed the event.
+	Err error
+}
+
+// ResultSink receives Results of events.
+type ResultSink interface {
+	Record(ctx context.Context, result *Result)
+}
+
+type ResultSinkFunc func(ctx context.Context, result *Result)
+
+func (f ResultSinkFunc) Record(ctx context.Context, result *Result) {
+	f(ctx, result)
+}
+
+// WithResultSink makes the Router report the Result of every event to `sink` after handlers finish.
+//
+// This is especially useful with AckBefore, since outcomes of events processed in the background can't be observed by responses.
+// `sink` is called synchronously from the goroutine that processed the event, so it should return quickly.
+func WithResultSink(sink ResultSink) Option {
+	return optionFunc(func(r *Router) {
+		r.resultSink = sink
+	})
+}
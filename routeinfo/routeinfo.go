@@ -0,0 +1,31 @@
+// Package routeinfo provides types that describe routes registered to routers.
+package routeinfo
+
+// Route describes a handler registered to a router.
+type Route struct {
+	// Type is the type of events or interactions that the handler processes (e.g. `message`, `block_actions`).
+	Type string `json:"type"`
+
+	// Predicates are human-readable descriptions of the predicates of the handler.
+	Predicates []string `json:"predicates,omitempty"`
+
+	// Handler is the name of the handler.
+	Handler string `json:"handler"`
+
+	// Description is the description supplied at registration (e.g. by `message.Description`).
+	Description string `json:"description,omitempty"`
+
+	// Labels are key-value pairs that predicates expose to describe which events the handler processes
+	// (e.g. `enterprise` and `api_app_id`).
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Describer is implemented by predicates that describe routes instead of filtering events.
+type Describer interface {
+	Description() string
+}
+
+// Labeler is implemented by predicates that expose key-value pairs in Route.Labels.
+type Labeler interface {
+	Labels() map[string]string
+}
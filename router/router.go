@@ -0,0 +1,241 @@
+// Package router provides the generic core of handlers and predicates shared by all kinds of events and interactions.
+//
+// Packages such as `message`, `appmention`, `reaction` and `interactionrouter` have their own Handler and Predicate types,
+// and they provide adapters from and to the types in this package. This makes it possible to write middleware once
+// and use it for any of them:
+//
+//	func Logging[T any](logger *log.Logger) router.Predicate[T] {
+//		return router.PredicateFunc[T](func(h router.Handler[T]) router.Handler[T] {
+//			return router.HandlerFunc[T](func(ctx context.Context, e T) error {
+//				err := h.Handle(ctx, e)
+//				logger.Printf("handled %T: %v", e, err)
+//				return err
+//			})
+//		})
+//	}
+//
+//	r.OnMessage(handler, message.FromGenericPredicate(Logging[*slackevents.MessageEvent](logger)))
+//
+// The per-package Handler and Predicate types cannot be aliases of Handler[T] and Predicate[T], because their methods
+// have different names (e.g. `HandleMessageEvent`, `HandleAppMentionEvent`, `WrapAdded`). This is what allows a single
+// type to implement handlers of several kinds at once, and renaming them would break existing handlers.
+// Types that do not have such methods (HandlerProvider, Condition and Set) are defined as aliases of the types in this package.
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// Handler processes events (or interactions) of type T.
+type Handler[T any] interface {
+	Handle(ctx context.Context, e T) error
+}
+
+type HandlerFunc[T any] func(ctx context.Context, e T) error
+
+func (f HandlerFunc[T]) Handle(ctx context.Context, e T) error {
+	return f(ctx, e)
+}
+
+// HandlerProvider constructs handlers of type H lazily.
+//
+// This is an integration point for dependency injection frameworks. Providers can construct handlers per event
+// (e.g. to begin a database transaction for each request).
+// Packages such as `message` define their HandlerProviders as aliases of this type.
+type HandlerProvider[H any] interface {
+	Provide(context.Context) (H, error)
+}
+
+type HandlerProviderFunc[H any] func(context.Context) (H, error)
+
+func (f HandlerProviderFunc[H]) Provide(ctx context.Context) (H, error) {
+	return f(ctx)
+}
+
+// FromProvider returns a Handler that obtains a handler from `p` every time it processes an event,
+// and delegates the event to it by `handle` (e.g. `message.Handler.HandleMessageEvent`).
+//
+// Errors returned from `p` are returned as is.
+func FromProvider[H, T any](p HandlerProvider[H], handle func(H, context.Context, T) error) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		h, err := p.Provide(ctx)
+		if err != nil {
+			return err
+		}
+		return handle(h, ctx, e)
+	})
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate[T any] interface {
+	Wrap(Handler[T]) Handler[T]
+}
+
+type PredicateFunc[T any] func(Handler[T]) Handler[T]
+
+func (f PredicateFunc[T]) Wrap(h Handler[T]) Handler[T] {
+	return f(h)
+}
+
+// Outermost is implemented by Predicates that Build applies after all the other predicates regardless of their positions.
+type Outermost interface {
+	Outermost()
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build[T any](h Handler[T], preds ...Predicate[T]) Handler[T] {
+	outermost := func(p Predicate[T]) bool {
+		_, ok := p.(Outermost)
+		return ok
+	}
+	return routerutils.BuildMarked(h, preds, Predicate[T].Wrap, outermost, func(h Handler[T]) Handler[T] {
+		return HandlerFunc[T](func(ctx context.Context, e T) error {
+			return routerutils.MarkHandlerError(h.Handle(ctx, e))
+		})
+	})
+}
+
+type filterPredicate[T any] struct {
+	match func(context.Context, T) bool
+}
+
+// Filter is a predicate that is considered to be "true" if and only if `match` returns true.
+func Filter[T any](match func(ctx context.Context, e T) bool) Predicate[T] {
+	return &filterPredicate[T]{match: match}
+}
+
+func (p *filterPredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		if !p.match(ctx, e) {
+			return errors.NotInterested
+		}
+		return h.Handle(ctx, e)
+	})
+}
+
+func (p *filterPredicate[T]) String() string {
+	return "Filter()"
+}
+
+// Condition is a condition that does not depend on events (e.g. `schedule.Window`).
+type Condition interface {
+	Match(ctx context.Context) bool
+}
+
+type whenPredicate[T any] struct {
+	cond Condition
+}
+
+// When is a predicate that is considered to be "true" if and only if `cond` matches.
+func When[T any](cond Condition) Predicate[T] {
+	return &whenPredicate[T]{cond: cond}
+}
+
+func (p *whenPredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		if !p.cond.Match(ctx) {
+			return errors.NotInterested
+		}
+		return h.Handle(ctx, e)
+	})
+}
+
+func (p *whenPredicate[T]) String() string {
+	return fmt.Sprintf("When(%v)", p.cond)
+}
+
+// Set is a set of values that may change over time (e.g. `dynamic.Set`).
+type Set interface {
+	Contains(ctx context.Context, value string) bool
+}
+
+type inPredicate[T any] struct {
+	name  string
+	set   Set
+	value func(T) string
+}
+
+// In is a predicate that is considered to be "true" if and only if `value` of an event is in `set`.
+// `name` is used to describe the predicate (e.g. "ChannelIn").
+func In[T any](name string, set Set, value func(T) string) Predicate[T] {
+	return &inPredicate[T]{name: name, set: set, value: value}
+}
+
+func (p *inPredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		if !p.set.Contains(ctx, p.value(e)) {
+			return errors.NotInterested
+		}
+		return h.Handle(ctx, e)
+	})
+}
+
+func (p *inPredicate[T]) String() string {
+	return fmt.Sprintf("%s(%v)", p.name, p.set)
+}
+
+type descriptionPredicate[T any] struct {
+	text string
+}
+
+// Description is a predicate that is always considered to be "true".
+// It just describes what the handler does, which can be seen in route introspection (e.g. `routerdoc`).
+func Description[T any](text string) Predicate[T] {
+	return &descriptionPredicate[T]{text: text}
+}
+
+func (p *descriptionPredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return h
+}
+
+func (p *descriptionPredicate[T]) Description() string {
+	return p.text
+}
+
+type valuePredicate[T any] struct {
+	key   interface{}
+	value interface{}
+}
+
+// WithValue is a predicate that is always considered to be "true".
+// It seeds the context given to the handler with `value` associated with `key`, in the same way as `context.WithValue`.
+//
+// This is useful to bind dependencies of handlers (e.g. database handles, configurations) at registration time.
+func WithValue[T any](key, value interface{}) Predicate[T] {
+	return &valuePredicate[T]{key: key, value: value}
+}
+
+func (p *valuePredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		return h.Handle(context.WithValue(ctx, p.key, p.value), e)
+	})
+}
+
+func (p *valuePredicate[T]) String() string {
+	return fmt.Sprintf("WithValue(%v)", p.key)
+}
+
+type failOnMismatchPredicate[T any] struct{}
+
+// FailOnMismatch makes the handler return `errors.Mismatch` instead of `errors.NotInterested` when the other predicates are not satisfied.
+//
+// This is applied after all the other predicates regardless of its position.
+func FailOnMismatch[T any]() Predicate[T] {
+	return failOnMismatchPredicate[T]{}
+}
+
+func (failOnMismatchPredicate[T]) Outermost() {}
+
+func (failOnMismatchPredicate[T]) String() string {
+	return "FailOnMismatch()"
+}
+
+func (failOnMismatchPredicate[T]) Wrap(h Handler[T]) Handler[T] {
+	return HandlerFunc[T](func(ctx context.Context, e T) error {
+		return routerutils.ToMismatch(h.Handle(ctx, e))
+	})
+}
@@ -0,0 +1,177 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// staticCondition is a Condition that always returns the same result.
+type staticCondition bool
+
+func (c staticCondition) Match(context.Context) bool {
+	return bool(c)
+}
+
+// staticSet is a Set that never changes.
+type staticSet []string
+
+func (s staticSet) Contains(_ context.Context, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var _ = Describe("Router", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		innerHandler     = router.HandlerFunc[string](func(context.Context, string) error {
+			numHandlerCalled++
+			return nil
+		})
+		hasPrefix = func(prefix string) router.Predicate[string] {
+			return router.Filter(func(_ context.Context, s string) bool {
+				return strings.HasPrefix(s, prefix)
+			})
+		}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Build", func() {
+		It("returns the original handler when no predicate is given", func() {
+			h := router.Build[string](innerHandler)
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("calls the inner handler if all the predicates match", func() {
+			h := router.Build[string](innerHandler, hasPrefix("he"), hasPrefix("hel"))
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not call the inner handler if any of the predicates does not match", func() {
+			h := router.Build[string](innerHandler, hasPrefix("he"), hasPrefix("bye"))
+			Expect(h.Handle(ctx, "hello")).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FailOnMismatch", func() {
+		It("returns Mismatch when the other predicates don't match, regardless of its position", func() {
+			h := router.Build[string](innerHandler, router.FailOnMismatch[string](), hasPrefix("bye"))
+			Expect(h.Handle(ctx, "hello")).To(MatchError(errors.Mismatch))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+
+		It("calls the inner handler when the other predicates match", func() {
+			h := router.Build[string](innerHandler, router.FailOnMismatch[string](), hasPrefix("he"))
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("does not turn NotInterested returned by the inner handler itself into Mismatch", func() {
+			notInterested := router.HandlerFunc[string](func(context.Context, string) error {
+				return errors.NotInterested
+			})
+			h := router.Build[string](notInterested, router.FailOnMismatch[string](), hasPrefix("he"))
+			err := h.Handle(ctx, "hello")
+			Expect(err).To(MatchError(errors.NotInterested))
+			Expect(err).NotTo(MatchError(errors.Mismatch))
+		})
+	})
+
+	Describe("PredicateFunc", func() {
+		It("can be used as middleware", func() {
+			var seen []string
+			logging := router.PredicateFunc[string](func(h router.Handler[string]) router.Handler[string] {
+				return router.HandlerFunc[string](func(ctx context.Context, s string) error {
+					seen = append(seen, s)
+					return h.Handle(ctx, s)
+				})
+			})
+			h := router.Build[string](innerHandler, logging)
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(seen).To(Equal([]string{"hello"}))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("FromProvider", func() {
+		It("obtains a handler from the provider every time it handles an event", func() {
+			numProvided := 0
+			var p router.HandlerProvider[router.Handler[string]] = router.HandlerProviderFunc[router.Handler[string]](func(context.Context) (router.Handler[string], error) {
+				numProvided++
+				return innerHandler, nil
+			})
+			h := router.FromProvider(p, router.Handler[string].Handle)
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(numProvided).To(Equal(2))
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("returns errors from the provider as is", func() {
+			var p router.HandlerProvider[router.Handler[string]] = router.HandlerProviderFunc[router.Handler[string]](func(context.Context) (router.Handler[string], error) {
+				return nil, errors.NotInterested
+			})
+			h := router.FromProvider(p, router.Handler[string].Handle)
+			Expect(h.Handle(ctx, "hello")).To(Equal(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("When", func() {
+		It("calls the inner handler only when the condition matches", func() {
+			Expect(router.Build[string](innerHandler, router.When[string](staticCondition(true))).Handle(ctx, "hello")).To(Succeed())
+			Expect(router.Build[string](innerHandler, router.When[string](staticCondition(false))).Handle(ctx, "hello")).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+	})
+
+	Describe("In", func() {
+		It("calls the inner handler only when the value is in the set", func() {
+			p := router.In("PrefixIn", staticSet{"he"}, func(s string) string { return s[:2] })
+			h := router.Build[string](innerHandler, p)
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(h.Handle(ctx, "world")).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(1))
+			Expect(p.(fmt.Stringer).String()).To(Equal("PrefixIn([he])"))
+		})
+	})
+
+	Describe("Description", func() {
+		It("describes the handler without affecting it", func() {
+			p := router.Description[string]("greets")
+			Expect(router.Build[string](innerHandler, p).Handle(ctx, "hello")).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(1))
+			Expect(p.(interface{ Description() string }).Description()).To(Equal("greets"))
+		})
+	})
+
+	Describe("WithValue", func() {
+		It("seeds the context given to the handler", func() {
+			type key struct{}
+			var got interface{}
+			h := router.Build[string](router.HandlerFunc[string](func(ctx context.Context, _ string) error {
+				got = ctx.Value(key{})
+				return nil
+			}), router.WithValue[string](key{}, "value"))
+			Expect(h.Handle(ctx, "hello")).To(Succeed())
+			Expect(got).To(Equal("value"))
+		})
+	})
+})
@@ -0,0 +1,52 @@
+// Package routerdoc renders routing tables of routers as documents.
+//
+// This is useful to publish the list of commands that your bot supports.
+//
+//	r.OnMessage(message.HandlerFunc(handleDeploy),
+//		message.TextRegexp(regexp.MustCompile(`^deploy`)),
+//		message.Description("Deploys the given service"))
+//	_ = routerdoc.Markdown(os.Stdout, r.Routes())
+package routerdoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/genkami/go-slack-event-router/routeinfo"
+)
+
+// Markdown renders routes as a Markdown table.
+func Markdown(w io.Writer, routes []routeinfo.Route) error {
+	if _, err := io.WriteString(w, "| Type | Predicates | Handler | Description |\n| --- | --- | --- | --- |\n"); err != nil {
+		return err
+	}
+	for _, route := range routes {
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %s |\n",
+			escapeMarkdown(route.Type),
+			escapeMarkdown(strings.Join(route.Predicates, ", ")),
+			escapeMarkdown(route.Handler),
+			escapeMarkdown(route.Description))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON renders routes as a JSON array.
+func JSON(w io.Writer, routes []routeinfo.Route) error {
+	if routes == nil {
+		routes = []routeinfo.Route{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(routes)
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
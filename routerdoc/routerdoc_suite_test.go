@@ -0,0 +1,13 @@
+package routerdoc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRouterdoc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Routerdoc Suite")
+}
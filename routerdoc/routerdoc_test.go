@@ -0,0 +1,68 @@
+package routerdoc_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/routerdoc"
+)
+
+func handleDeploy(_ context.Context, _ *slackevents.MessageEvent) error {
+	return nil
+}
+
+func handleApprove(_ context.Context, _ *slack.InteractionCallback) error {
+	return nil
+}
+
+var _ = Describe("Routerdoc", func() {
+	Describe("Markdown", func() {
+		It("renders routes of the event router as a table", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(handleDeploy),
+				message.TextRegexp(regexp.MustCompile(`^deploy|release`)),
+				message.Channel("C123"),
+				message.Description("Deploys the given service"))
+			buf := &bytes.Buffer{}
+			err = routerdoc.Markdown(buf, r.Routes())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(Equal(
+				"| Type | Predicates | Handler | Description |\n" +
+					"| --- | --- | --- | --- |\n" +
+					"| message | TextRegexp(^deploy\\|release), Channel(C123) | github.com/genkami/go-slack-event-router/routerdoc_test.handleDeploy | Deploys the given service |\n"))
+		})
+	})
+
+	Describe("JSON", func() {
+		It("renders routes of the interaction router as JSON", func() {
+			r, err := ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(handleApprove), ir.BlockAction("BLOCK_ID", "ACTION_ID"))
+			buf := &bytes.Buffer{}
+			err = routerdoc.JSON(buf, r.Routes())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(MatchJSON(`[{
+				"type": "block_actions",
+				"predicates": ["BlockAction(BLOCK_ID, ACTION_ID)"],
+				"handler": "github.com/genkami/go-slack-event-router/routerdoc_test.handleApprove"
+			}]`))
+		})
+
+		It("renders an empty array when there are no routes", func() {
+			buf := &bytes.Buffer{}
+			err := routerdoc.JSON(buf, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf.String()).To(MatchJSON(`[]`))
+		})
+	})
+})
@@ -0,0 +1,291 @@
+// Package routeroptions provides options that are shared by all routers (`eventrouter`, `interactionrouter` and `commandrouter`).
+//
+// Every router accepts them through its `WithShared` option, so cross-cutting settings can be given to all routers at once:
+//
+//	shared := []routeroptions.Option{
+//		routeroptions.WithSigningSecret(secret),
+//		routeroptions.VerboseResponse(),
+//		routeroptions.WithRedaction(redact.Default),
+//		routeroptions.OnError(logError),
+//	}
+//	er, err := eventrouter.New(eventrouter.WithShared(shared...))
+//	ir, err := interactionrouter.New(interactionrouter.WithShared(shared...))
+//
+// Router-specific options with the same names (e.g. `eventrouter.WithSigningSecret`) are equivalent to ones in this package.
+package routeroptions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/spool"
+)
+
+// Config holds settings shared by all routers.
+//
+// Routers hold a Config and use its methods to verify requests and to respond with errors, so that they behave consistently.
+// Users usually don't need to use this directly.
+type Config struct {
+	SigningSecret          string
+	PreviousSigningSecrets []string
+	SkipVerification       bool
+	VerboseResponse        bool
+	Redaction              *redact.Policy
+	ErrorHook              func(ctx context.Context, err error)
+	HTTPMiddlewares        []func(http.Handler) http.Handler
+	HealthProbes           bool
+	VerificationRecorder   signature.Recorder
+	BodySpooler            spool.BodySpooler
+
+	// Now returns the current time. NewConfig sets `time.Now`.
+	Now func() time.Time
+}
+
+// NewConfig returns a Config with default settings.
+func NewConfig() Config {
+	return Config{Now: time.Now}
+}
+
+// Option configures a Config.
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(c *Config) {
+	f(c)
+}
+
+// Apply applies `opts` to the Config in order.
+func (c *Config) Apply(opts ...Option) {
+	for _, o := range opts {
+		o.apply(c)
+	}
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful to test your handlers, but do not use this in production environments.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(c *Config) {
+		c.SkipVerification = true
+	})
+}
+
+// WithSigningSecret sets a signing token to verify requests from Slack.
+//
+// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func WithSigningSecret(token string) Option {
+	return optionFunc(func(c *Config) {
+		c.SigningSecret = token
+	})
+}
+
+// WithPreviousSigningSecrets sets signing secrets that are also accepted in addition to the one set by WithSigningSecret.
+//
+// This is useful to rotate signing secrets without downtime. Handlers can find which secret matched by `signature.VerificationFromContext`.
+func WithPreviousSigningSecrets(secrets ...string) Option {
+	return optionFunc(func(c *Config) {
+		c.PreviousSigningSecrets = secrets
+	})
+}
+
+// If VerboseResponse is set, routers show error details when they fail to process requests.
+func VerboseResponse() Option {
+	return optionFunc(func(c *Config) {
+		c.VerboseResponse = true
+	})
+}
+
+// WithRedaction makes routers redact error details by `policy` before showing them (see VerboseResponse) and passing them to the hook set by OnError.
+func WithRedaction(policy *redact.Policy) Option {
+	return optionFunc(func(c *Config) {
+		c.Redaction = policy
+	})
+}
+
+// OnError sets a hook that is called every time a router responds with an error, including verification failures.
+//
+// Errors given to the hook wrap sentinel errors such as `signature.ErrBadSignature` when applicable,
+// so the hook can distinguish reasons by `errors.Is`. This is typically used to log errors.
+func OnError(hook func(ctx context.Context, err error)) Option {
+	return optionFunc(func(c *Config) {
+		c.ErrorHook = hook
+	})
+}
+
+// WithNowFunc sets a function that returns the current time.
+//
+// Routers use it wherever they depend on the current time (e.g. checking whether request timestamps are too old).
+// This is mainly intended to make tests deterministic. If not set, `time.Now` is used.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(c *Config) {
+		c.Now = now
+	})
+}
+
+// WithHTTPMiddleware adds middlewares that wrap signature verification of routers.
+//
+// The first middleware is the outermost one. Middlewares see requests as they are sent from Slack, so they can add
+// authentication, CORS headers or response compression without re-wrapping routers. See each router for where exactly they are applied.
+//
+// Middlewares may read request bodies: routers still verify the original bodies, as if they were not read at all.
+// If a middleware replaces a body (e.g. to decompress it), the replaced one is used instead.
+// If this option is specified more than once, middlewares are appended in the order they are given.
+func WithHTTPMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return optionFunc(func(c *Config) {
+		c.HTTPMiddlewares = append(c.HTTPMiddlewares, mw...)
+	})
+}
+
+// WithVerificationRecorder makes routers report the outcome of signature verification of every request to `rec`
+// (e.g. a `signature.Counters`). This has no effect with InsecureSkipVerification.
+func WithVerificationRecorder(rec signature.Recorder) Option {
+	return optionFunc(func(c *Config) {
+		c.VerificationRecorder = rec
+	})
+}
+
+// WithBodySpooler makes routers store request bodies by `s` (e.g. `spool.TempFile`) while verifying them, instead of keeping them in memory.
+// This has no effect with InsecureSkipVerification.
+func WithBodySpooler(s spool.BodySpooler) Option {
+	return optionFunc(func(c *Config) {
+		c.BodySpooler = s
+	})
+}
+
+// AcceptHealthProbes makes routers respond to GET and HEAD requests with 200 (OK) without processing them,
+// so that load balancers can probe the same paths as the ones Slack sends requests to.
+//
+// Regardless of this option, routers respond to requests with other methods than POST (and GET and HEAD if this is set)
+// with 405 (Method Not Allowed).
+func AcceptHealthProbes() Option {
+	return optionFunc(func(c *Config) {
+		c.HealthProbes = true
+	})
+}
+
+// Validate returns an error that wraps `routererrors.ErrMissingSigningSecret` or `routererrors.ErrConflictingOptions` if the Config is inconsistent.
+func (c *Config) Validate() error {
+	if c.SigningSecret == "" && !c.SkipVerification {
+		return fmt.Errorf("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification: %w", routererrors.ErrMissingSigningSecret)
+	}
+	if c.SigningSecret != "" && c.SkipVerification {
+		return fmt.Errorf("both WithSigningSecret and InsecureSkipVerification are given: %w", routererrors.ErrConflictingOptions)
+	}
+	if len(c.PreviousSigningSecrets) > 0 && c.SkipVerification {
+		return fmt.Errorf("WithPreviousSigningSecrets and InsecureSkipVerification are given: %w", routererrors.ErrConflictingOptions)
+	}
+	return nil
+}
+
+// Verify wraps `h` so that requests are verified by their signatures, unless InsecureSkipVerification is set.
+//
+// Verification failures are reported to the hook set by OnError.
+func (c *Config) Verify(h http.Handler) http.Handler {
+	if c.SkipVerification {
+		return h
+	}
+	return &signature.Middleware{
+		SigningSecret:          c.SigningSecret,
+		PreviousSigningSecrets: c.PreviousSigningSecrets,
+		VerboseResponse:        c.VerboseResponse,
+		Handler:                h,
+		Now:                    c.Now,
+		Recorder:               c.VerificationRecorder,
+		Spooler:                c.BodySpooler,
+		OnFailure: func(req *http.Request, err error) {
+			c.ReportError(req.Context(), err)
+		},
+	}
+}
+
+// CheckMethod wraps `h` so that only POST requests are passed to `h`, and health probes are responded if AcceptHealthProbes is set.
+func (c *Config) CheckMethod(h http.Handler) http.Handler {
+	allowed := http.MethodPost
+	if c.HealthProbes {
+		allowed = strings.Join([]string{http.MethodGet, http.MethodHead, http.MethodPost}, ", ")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodPost:
+			h.ServeHTTP(w, req)
+		case c.HealthProbes && (req.Method == http.MethodGet || req.Method == http.MethodHead):
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Allow", allowed)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// WrapHTTP wraps `h` with the middlewares set by WithHTTPMiddleware.
+func (c *Config) WrapHTTP(h http.Handler) http.Handler {
+	if len(c.HTTPMiddlewares) == 0 {
+		return h
+	}
+	wrapped := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if body, ok := req.Body.(*replayableBody); ok {
+			req.Body = body.replay()
+		}
+		h.ServeHTTP(w, req)
+	})
+	var outer http.Handler = wrapped
+	for i := len(c.HTTPMiddlewares) - 1; i >= 0; i-- {
+		outer = c.HTTPMiddlewares[i](outer)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &replayableBody{body: req.Body}
+		}
+		outer.ServeHTTP(w, req)
+	})
+}
+
+// replayableBody remembers what middlewares read, so that the whole body can be read again after them.
+type replayableBody struct {
+	body io.ReadCloser
+	read bytes.Buffer
+}
+
+func (b *replayableBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.read.Write(p[:n])
+	return n, err
+}
+
+// Close does nothing, since the body is still to be read by the router. The server closes the original body.
+func (b *replayableBody) Close() error {
+	return nil
+}
+
+func (b *replayableBody) replay() io.ReadCloser {
+	return io.NopCloser(io.MultiReader(bytes.NewReader(b.read.Bytes()), b.body))
+}
+
+// ReportError passes `err` to the hook set by OnError after redacting it, without responding.
+func (c *Config) ReportError(ctx context.Context, err error) {
+	if c.ErrorHook != nil {
+		c.ErrorHook(ctx, c.Redaction.Error(err))
+	}
+}
+
+// RespondWithError reports `err` and responds with the status code corresponding to it.
+//
+// Error details are shown only if VerboseResponse is set, and redacted by the policy set by WithRedaction.
+func (c *Config) RespondWithError(ctx context.Context, w http.ResponseWriter, err error) {
+	err = c.Redaction.Error(err)
+	if c.ErrorHook != nil {
+		c.ErrorHook(ctx, err)
+	}
+	routerutils.RespondWithError(w, err, c.VerboseResponse)
+}
@@ -0,0 +1,13 @@
+package routeroptions_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRouteroptions(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Routeroptions Suite")
+}
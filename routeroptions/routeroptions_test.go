@@ -0,0 +1,163 @@
+package routeroptions_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/redact"
+	"github.com/genkami/go-slack-event-router/routeroptions"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/spool"
+)
+
+var _ = Describe("Routeroptions", func() {
+	Describe("Validate", func() {
+		It("returns typed errors", func() {
+			c := routeroptions.NewConfig()
+			Expect(errors.Is(c.Validate(), routererrors.ErrMissingSigningSecret)).To(BeTrue())
+
+			c.Apply(routeroptions.WithSigningSecret("THE_TOKEN"), routeroptions.InsecureSkipVerification())
+			Expect(errors.Is(c.Validate(), routererrors.ErrConflictingOptions)).To(BeTrue())
+
+			c = routeroptions.NewConfig()
+			c.Apply(routeroptions.InsecureSkipVerification(), routeroptions.WithPreviousSigningSecrets("THE_OLD_TOKEN"))
+			Expect(errors.Is(c.Validate(), routererrors.ErrConflictingOptions)).To(BeTrue())
+		})
+	})
+
+	Describe("WithShared", func() {
+		var (
+			errs     []error
+			counters *signature.Counters
+			shared   []routeroptions.Option
+		)
+		BeforeEach(func() {
+			errs = nil
+			counters = signature.NewCounters()
+			shared = []routeroptions.Option{
+				routeroptions.WithSigningSecret("THE_TOKEN"),
+				routeroptions.VerboseResponse(),
+				routeroptions.WithRedaction(redact.New(redact.RuleFunc(func(string) string { return "redacted" }))),
+				routeroptions.OnError(func(_ context.Context, err error) {
+					errs = append(errs, err)
+				}),
+				routeroptions.WithVerificationRecorder(counters),
+			}
+		})
+
+		It("applies the same options to every router", func() {
+			er, err := eventrouter.New(eventrouter.WithShared(shared...))
+			Expect(err).NotTo(HaveOccurred())
+			interactions, err := ir.New(ir.WithShared(shared...))
+			Expect(err).NotTo(HaveOccurred())
+			commands, err := commandrouter.New(commandrouter.WithShared(shared...))
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, h := range []http.Handler{er, interactions, commands} {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte("{}")))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+			}
+			Expect(errs).To(HaveLen(3))
+			for _, err := range errs {
+				Expect(err).To(MatchError("redacted"))
+				Expect(errors.Is(err, signature.ErrMissingHeaders)).To(BeTrue())
+			}
+			Expect(counters.Snapshot().ByReason).To(Equal(map[signature.Reason]int64{signature.ReasonMissingHeaders: 3}))
+		})
+
+		It("can be overridden by router-specific options", func() {
+			_, err := ir.New(ir.WithShared(shared...), ir.InsecureSkipVerification())
+			Expect(errors.Is(err, routererrors.ErrConflictingOptions)).To(BeTrue())
+		})
+	})
+
+	Describe("WithBodySpooler", func() {
+		It("makes routers verify and parse spooled bodies", func() {
+			dir, err := os.MkdirTemp("", "routeroptions-test-")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			spooled := 0
+			tempFile := spool.TempFile(1, dir)
+			spooler := spool.BodySpoolerFunc(func(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+				spooled++
+				return tempFile.Spool(ctx, r)
+			})
+			er, err := eventrouter.New(eventrouter.WithShared(
+				routeroptions.WithSigningSecret("THE_TOKEN"),
+				routeroptions.WithBodySpooler(spooler),
+			))
+			Expect(err).NotTo(HaveOccurred())
+
+			body := []byte(`{"type": "url_verification", "challenge": "THE_CHALLENGE"}`)
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature.AddSignature(req.Header, []byte("THE_TOKEN"), body, time.Now())).To(Succeed())
+			w := httptest.NewRecorder()
+			er.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("THE_CHALLENGE"))
+			Expect(spooled).To(Equal(1))
+
+			files, err := os.ReadDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(BeEmpty())
+		})
+	})
+
+	Describe("WrapHTTP", func() {
+		serve := func(c *routeroptions.Config) string {
+			var got []byte
+			h := c.WrapHTTP(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				var err error
+				got, err = io.ReadAll(req.Body)
+				Expect(err).NotTo(HaveOccurred())
+			}))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path", bytes.NewReader([]byte("hello world")))
+			Expect(err).NotTo(HaveOccurred())
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			return string(got)
+		}
+
+		It("passes the whole body even if middlewares read a part of it", func() {
+			c := routeroptions.NewConfig()
+			c.Apply(routeroptions.WithHTTPMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					buf := make([]byte, 5)
+					_, err := io.ReadFull(req.Body, buf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(req.Body.Close()).To(Succeed())
+					next.ServeHTTP(w, req)
+				})
+			}))
+			Expect(serve(&c)).To(Equal("hello world"))
+		})
+
+		It("passes bodies replaced by middlewares", func() {
+			c := routeroptions.NewConfig()
+			c.Apply(routeroptions.WithHTTPMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					req.Body = io.NopCloser(strings.NewReader("replaced"))
+					next.ServeHTTP(w, req)
+				})
+			}))
+			Expect(serve(&c)).To(Equal("replaced"))
+		})
+	})
+})
@@ -0,0 +1,227 @@
+// Package schedule provides conditions that depend on the time of day and the day of week.
+//
+// A Window can be used with predicates named `When` in packages for typed registration, for example:
+//
+//	businessHours := schedule.Window(tokyo, "Mon-Fri 09:00-18:00")
+//	r.OnMessage(handleDuringBusinessHours, message.When(businessHours))
+//	r.OnMessage(handleOutsideBusinessHours, message.When(schedule.Not(businessHours)))
+//
+// In multi-workspace apps, InTeamTimeZone interprets a Window in the time zone of each workspace.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/enrich"
+)
+
+// Condition is a condition that depends on the current time.
+type Condition interface {
+	Match(ctx context.Context) bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Windows is a set of periods in a week.
+type Windows struct {
+	loc   *time.Location
+	specs []string
+	spans []span
+	now   func() time.Time
+}
+
+type span struct {
+	days  [7]bool
+	start int // minutes since midnight
+	end   int // minutes since midnight; the span continues to the next day if end <= start
+}
+
+// Parse parses specs of periods in a week in the given location.
+//
+// Each spec is either "<days> <from>-<to>" or "<from>-<to>", where <days> is a comma-separated list of
+// days of week (e.g. "Mon") or ranges of them (e.g. "Mon-Fri"), and <from> and <to> are times in "HH:MM" format.
+// If <to> is earlier than or equal to <from>, the period continues to the next day (e.g. "Fri 22:00-02:00").
+// If <days> is omitted, the period applies to every day.
+//
+// The resulting Windows matches if the current time is in any of the periods.
+func Parse(loc *time.Location, specs ...string) (*Windows, error) {
+	w := &Windows{loc: loc, specs: specs, now: time.Now}
+	for _, spec := range specs {
+		s, err := parseSpan(spec)
+		if err != nil {
+			return nil, err
+		}
+		w.spans = append(w.spans, s)
+	}
+	return w, nil
+}
+
+// Window is the same as Parse except that it panics if specs are invalid.
+func Window(loc *time.Location, specs ...string) *Windows {
+	w, err := Parse(loc, specs...)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func parseSpan(spec string) (span, error) {
+	var s span
+	fields := strings.Fields(spec)
+	var hours string
+	switch len(fields) {
+	case 1:
+		for i := range s.days {
+			s.days[i] = true
+		}
+		hours = fields[0]
+	case 2:
+		if err := parseDays(fields[0], &s.days); err != nil {
+			return s, fmt.Errorf("schedule: invalid spec %q: %w", spec, err)
+		}
+		hours = fields[1]
+	default:
+		return s, fmt.Errorf("schedule: invalid spec %q", spec)
+	}
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return s, fmt.Errorf("schedule: invalid spec %q: hours must be in the form of HH:MM-HH:MM", spec)
+	}
+	var err error
+	if s.start, err = parseClock(parts[0]); err != nil {
+		return s, fmt.Errorf("schedule: invalid spec %q: %w", spec, err)
+	}
+	if s.end, err = parseClock(parts[1]); err != nil {
+		return s, fmt.Errorf("schedule: invalid spec %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+func parseDays(text string, days *[7]bool) error {
+	for _, item := range strings.Split(text, ",") {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %q", item)
+		}
+		from, ok := weekdays[strings.ToLower(bounds[0])]
+		if !ok {
+			return fmt.Errorf("unknown day of week %q", bounds[0])
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, ok = weekdays[strings.ToLower(bounds[1])]; !ok {
+				return fmt.Errorf("unknown day of week %q", bounds[1])
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseClock(text string) (int, error) {
+	t, err := time.Parse("15:04", text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains returns true if `t` is in any of the periods.
+func (w *Windows) Contains(t time.Time) bool {
+	return w.containsIn(t, w.loc)
+}
+
+func (w *Windows) containsIn(t time.Time, loc *time.Location) bool {
+	t = t.In(loc)
+	day := t.Weekday()
+	prev := (day + 6) % 7
+	m := t.Hour()*60 + t.Minute()
+	for _, s := range w.spans {
+		if s.start < s.end {
+			if s.days[day] && s.start <= m && m < s.end {
+				return true
+			}
+			continue
+		}
+		if s.days[day] && s.start <= m {
+			return true
+		}
+		if s.days[prev] && m < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the current time is in any of the periods.
+func (w *Windows) Match(_ context.Context) bool {
+	return w.Contains(w.now())
+}
+
+// WithNowFunc returns a copy of `w` that uses `now` to get the current time. This is mainly intended to make tests deterministic.
+func (w *Windows) WithNowFunc(now func() time.Time) *Windows {
+	copied := *w
+	copied.now = now
+	return &copied
+}
+
+func (w *Windows) String() string {
+	return fmt.Sprintf("Window(%s, %s)", w.loc, strings.Join(w.specs, ", "))
+}
+
+type inTeamTimeZone struct {
+	w *Windows
+}
+
+// InTeamTimeZone returns a Condition that interprets the periods in the time zone of the workspace where the event happened,
+// which is resolved by `enrich.WithTeams`.
+//
+// The location given to Parse (or Window) is used if the context is not enriched with the workspace.
+func (w *Windows) InTeamTimeZone() Condition {
+	return &inTeamTimeZone{w: w}
+}
+
+func (c *inTeamTimeZone) Match(ctx context.Context) bool {
+	loc := c.w.loc
+	if t := enrich.TeamFromContext(ctx); t != nil {
+		loc = t.Location
+	}
+	return c.w.containsIn(c.w.now(), loc)
+}
+
+func (c *inTeamTimeZone) String() string {
+	return fmt.Sprintf("InTeamTimeZone(%v)", c.w)
+}
+
+type not struct {
+	c Condition
+}
+
+// Not returns a Condition that matches if and only if `c` does not match.
+func Not(c Condition) Condition {
+	return &not{c: c}
+}
+
+func (n *not) Match(ctx context.Context) bool {
+	return !n.c.Match(ctx)
+}
+
+func (n *not) String() string {
+	return fmt.Sprintf("Not(%v)", n.c)
+}
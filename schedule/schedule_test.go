@@ -0,0 +1,92 @@
+package schedule_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/enrich"
+	"github.com/genkami/go-slack-event-router/schedule"
+)
+
+var _ = Describe("Schedule", func() {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	// 2021-03-01 is Monday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2021, 3, day, hour, min, 0, 0, tokyo)
+	}
+
+	Describe("Parse", func() {
+		It("rejects invalid specs", func() {
+			for _, spec := range []string{"", "Mon", "Foo 09:00-18:00", "Mon-Fri 9-18", "Mon-Fri 09:00-25:00", "Mon-Fri 09:00-18:00 extra"} {
+				_, err := schedule.Parse(tokyo, spec)
+				Expect(err).To(HaveOccurred(), spec)
+			}
+		})
+	})
+
+	Describe("Contains", func() {
+		It("matches days and hours", func() {
+			w := schedule.Window(tokyo, "Mon-Fri 09:00-18:00")
+			Expect(w.Contains(at(1, 9, 0))).To(BeTrue())
+			Expect(w.Contains(at(5, 17, 59))).To(BeTrue())
+			Expect(w.Contains(at(5, 18, 0))).To(BeFalse())
+			Expect(w.Contains(at(1, 8, 59))).To(BeFalse())
+			Expect(w.Contains(at(6, 12, 0))).To(BeFalse())
+		})
+
+		It("converts times into the location", func() {
+			w := schedule.Window(tokyo, "Mon-Fri 09:00-18:00")
+			Expect(w.Contains(time.Date(2021, 3, 1, 0, 30, 0, 0, time.UTC))).To(BeTrue())
+			Expect(w.Contains(time.Date(2021, 3, 1, 9, 30, 0, 0, time.UTC))).To(BeFalse())
+		})
+
+		It("supports periods that continue to the next day", func() {
+			w := schedule.Window(tokyo, "Fri 22:00-02:00")
+			Expect(w.Contains(at(5, 23, 0))).To(BeTrue())
+			Expect(w.Contains(at(6, 1, 59))).To(BeTrue())
+			Expect(w.Contains(at(6, 2, 0))).To(BeFalse())
+			Expect(w.Contains(at(5, 1, 0))).To(BeFalse())
+		})
+
+		It("supports lists of days, wrapping ranges and multiple specs", func() {
+			w := schedule.Window(tokyo, "Sat-Sun 10:00-12:00", "Mon,Wed 13:00-14:00")
+			Expect(w.Contains(at(7, 11, 0))).To(BeTrue())
+			Expect(w.Contains(at(3, 13, 30))).To(BeTrue())
+			Expect(w.Contains(at(2, 13, 30))).To(BeFalse())
+		})
+
+		It("applies to every day if days are omitted", func() {
+			w := schedule.Window(tokyo, "00:00-06:00")
+			for day := 1; day <= 7; day++ {
+				Expect(w.Contains(at(day, 3, 0))).To(BeTrue())
+			}
+		})
+	})
+
+	Describe("Match", func() {
+		It("uses the current time", func() {
+			w := schedule.Window(tokyo, "Mon-Fri 09:00-18:00").WithNowFunc(func() time.Time { return at(1, 10, 0) })
+			Expect(w.Match(context.Background())).To(BeTrue())
+			Expect(schedule.Not(w).Match(context.Background())).To(BeFalse())
+		})
+	})
+
+	Describe("InTeamTimeZone", func() {
+		w := schedule.Window(tokyo, "Mon-Fri 09:00-18:00").WithNowFunc(func() time.Time {
+			return time.Date(2021, 3, 1, 1, 0, 0, 0, time.UTC)
+		})
+
+		It("uses the time zone of the workspace", func() {
+			ctx := enrich.WithTeam(context.Background(), &enrich.Team{Location: time.UTC})
+			Expect(w.InTeamTimeZone().Match(ctx)).To(BeFalse())
+			Expect(w.Match(ctx)).To(BeTrue())
+		})
+
+		It("falls back to the location of the Window", func() {
+			Expect(w.InTeamTimeZone().Match(context.Background())).To(BeTrue())
+		})
+	})
+})
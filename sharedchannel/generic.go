@@ -0,0 +1,70 @@
+package sharedchannel
+
+import (
+	"fmt"
+
+	"github.com/genkami/go-slack-event-router/router"
+)
+
+// SharedToGeneric converts `h` to a generic `router.Handler`.
+func SharedToGeneric(h SharedHandler) router.Handler[*SharedEvent] {
+	return router.HandlerFunc[*SharedEvent](h.HandleChannelSharedEvent)
+}
+
+// SharedFromGeneric converts a generic `router.Handler` to a SharedHandler.
+func SharedFromGeneric(h router.Handler[*SharedEvent]) SharedHandler {
+	return SharedHandlerFunc(h.Handle)
+}
+
+// UnsharedToGeneric converts `h` to a generic `router.Handler`.
+func UnsharedToGeneric(h UnsharedHandler) router.Handler[*UnsharedEvent] {
+	return router.HandlerFunc[*UnsharedEvent](h.HandleChannelUnsharedEvent)
+}
+
+// UnsharedFromGeneric converts a generic `router.Handler` to a UnsharedHandler.
+func UnsharedFromGeneric(h router.Handler[*UnsharedEvent]) UnsharedHandler {
+	return UnsharedHandlerFunc(h.Handle)
+}
+
+type genericPredicate struct {
+	shared   router.Predicate[*SharedEvent]
+	unshared router.Predicate[*UnsharedEvent]
+}
+
+// FromGenericPredicate converts generic `router.Predicate`s to a Predicate, so that generic middleware can be used with this package.
+//
+// `shared` and `unshared` are used for SharedHandlers and UnsharedHandlers respectively. Typically they are instantiations of the same generic predicate.
+func FromGenericPredicate(shared router.Predicate[*SharedEvent], unshared router.Predicate[*UnsharedEvent]) Predicate {
+	return &genericPredicate{shared: shared, unshared: unshared}
+}
+
+func (p *genericPredicate) WrapShared(h SharedHandler) SharedHandler {
+	return SharedFromGeneric(p.shared.Wrap(SharedToGeneric(h)))
+}
+
+func (p *genericPredicate) WrapUnshared(h UnsharedHandler) UnsharedHandler {
+	return UnsharedFromGeneric(p.unshared.Wrap(UnsharedToGeneric(h)))
+}
+
+func (p *genericPredicate) String() string {
+	if s, ok := p.shared.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p.shared)
+}
+
+// Unwrap returns the generic predicate, so that route introspection can see through the adapter.
+func (p *genericPredicate) Unwrap() interface{} {
+	return p.shared
+}
+
+func isOutermost(p Predicate) bool {
+	switch p := p.(type) {
+	case router.Outermost:
+		return true
+	case *genericPredicate:
+		_, ok := p.shared.(router.Outermost)
+		return ok
+	}
+	return false
+}
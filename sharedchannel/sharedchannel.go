@@ -0,0 +1,161 @@
+// Package sharedchannel provides handlers to process events that are sent when channels are shared with other organizations via Slack Connect, or stop being shared.
+//
+// slack-go doesn't know these events, so the Router decodes them into the types in this package.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/channel_shared
+//   - https://api.slack.com/events/channel_unshared
+package sharedchannel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+// Types of events that this package processes.
+const (
+	// ChannelShared is the type of events that are sent when a channel is shared with another organization.
+	ChannelShared = "channel_shared"
+
+	// ChannelUnshared is the type of events that are sent when a channel stops being shared with an organization.
+	ChannelUnshared = "channel_unshared"
+)
+
+// SharedEvent is an inner event of ChannelShared.
+type SharedEvent struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+
+	// ConnectedTeamID is the ID of the workspace (or the organization) that the channel is shared with.
+	ConnectedTeamID string `json:"connected_team_id"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// UnsharedEvent is an inner event of ChannelUnshared.
+type UnsharedEvent struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+
+	// PreviouslyConnectedTeamID is the ID of the workspace (or the organization) that the channel was shared with.
+	PreviouslyConnectedTeamID string `json:"previously_connected_team_id"`
+
+	// IsExtShared is true if the channel is still shared with other organizations.
+	IsExtShared bool `json:"is_ext_shared"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// SharedHandler processes `channel_shared` events.
+type SharedHandler interface {
+	HandleChannelSharedEvent(context.Context, *SharedEvent) error
+}
+
+type SharedHandlerFunc func(context.Context, *SharedEvent) error
+
+func (f SharedHandlerFunc) HandleChannelSharedEvent(ctx context.Context, e *SharedEvent) error {
+	return f(ctx, e)
+}
+
+// UnsharedHandler processes `channel_unshared` events.
+type UnsharedHandler interface {
+	HandleChannelUnsharedEvent(context.Context, *UnsharedEvent) error
+}
+
+type UnsharedHandlerFunc func(context.Context, *UnsharedEvent) error
+
+func (f UnsharedHandlerFunc) HandleChannelUnsharedEvent(ctx context.Context, e *UnsharedEvent) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+// This can be used with both `SharedHandler` and `UnsharedHandler`.
+type Predicate interface {
+	WrapShared(SharedHandler) SharedHandler
+	WrapUnshared(UnsharedHandler) UnsharedHandler
+}
+
+type channelPredicate struct {
+	id string
+}
+
+// Channel is a predicate that is considered to be "true" if and only if the event is about the given channel.
+func Channel(id string) Predicate {
+	return &channelPredicate{id: id}
+}
+
+func (p *channelPredicate) WrapShared(h SharedHandler) SharedHandler {
+	return SharedHandlerFunc(func(ctx context.Context, e *SharedEvent) error {
+		if e.Channel != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleChannelSharedEvent(ctx, e)
+	})
+}
+
+func (p *channelPredicate) WrapUnshared(h UnsharedHandler) UnsharedHandler {
+	return UnsharedHandlerFunc(func(ctx context.Context, e *UnsharedEvent) error {
+		if e.Channel != p.id {
+			return errors.NotInterested
+		}
+		return h.HandleChannelUnsharedEvent(ctx, e)
+	})
+}
+
+func (p *channelPredicate) String() string {
+	return fmt.Sprintf("Channel(%s)", p.id)
+}
+
+type connectedTeamPredicate struct {
+	ids []string
+}
+
+// ConnectedTeam is a predicate that is considered to be "true" if and only if the channel is shared (or was shared) with any of the given workspaces or organizations.
+func ConnectedTeam(ids ...string) Predicate {
+	return &connectedTeamPredicate{ids: ids}
+}
+
+func (p *connectedTeamPredicate) match(id string) bool {
+	for _, expected := range p.ids {
+		if id == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *connectedTeamPredicate) WrapShared(h SharedHandler) SharedHandler {
+	return SharedHandlerFunc(func(ctx context.Context, e *SharedEvent) error {
+		if !p.match(e.ConnectedTeamID) {
+			return errors.NotInterested
+		}
+		return h.HandleChannelSharedEvent(ctx, e)
+	})
+}
+
+func (p *connectedTeamPredicate) WrapUnshared(h UnsharedHandler) UnsharedHandler {
+	return UnsharedHandlerFunc(func(ctx context.Context, e *UnsharedEvent) error {
+		if !p.match(e.PreviouslyConnectedTeamID) {
+			return errors.NotInterested
+		}
+		return h.HandleChannelUnsharedEvent(ctx, e)
+	})
+}
+
+func (p *connectedTeamPredicate) String() string {
+	return fmt.Sprintf("ConnectedTeam(%s)", strings.Join(p.ids, ", "))
+}
+
+// BuildShared decorates `SharedHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func BuildShared(h SharedHandler, preds ...Predicate) SharedHandler {
+	return routerutils.Build(h, preds, Predicate.WrapShared, isOutermost)
+}
+
+// BuildUnshared decorates `UnsharedHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func BuildUnshared(h UnsharedHandler, preds ...Predicate) UnsharedHandler {
+	return routerutils.Build(h, preds, Predicate.WrapUnshared, isOutermost)
+}
@@ -0,0 +1,13 @@
+package sharedchannel_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSharedchannel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sharedchannel Suite")
+}
@@ -0,0 +1,75 @@
+package sharedchannel_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/router"
+	"github.com/genkami/go-slack-event-router/sharedchannel"
+)
+
+var _ = Describe("Sharedchannel", func() {
+	var (
+		ctx              context.Context
+		numHandlerCalled int
+		sharedHandler    = sharedchannel.SharedHandlerFunc(func(_ context.Context, _ *sharedchannel.SharedEvent) error {
+			numHandlerCalled++
+			return nil
+		})
+		unsharedHandler = sharedchannel.UnsharedHandlerFunc(func(_ context.Context, _ *sharedchannel.UnsharedEvent) error {
+			numHandlerCalled++
+			return nil
+		})
+		sharedEvent   = &sharedchannel.SharedEvent{Channel: "C001", ConnectedTeamID: "E001"}
+		unsharedEvent = &sharedchannel.UnsharedEvent{Channel: "C001", PreviouslyConnectedTeamID: "E001"}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numHandlerCalled = 0
+	})
+
+	Describe("Channel", func() {
+		It("calls the inner handler if the event matches", func() {
+			Expect(sharedchannel.BuildShared(sharedHandler, sharedchannel.Channel("C001")).HandleChannelSharedEvent(ctx, sharedEvent)).To(Succeed())
+			Expect(sharedchannel.BuildUnshared(unsharedHandler, sharedchannel.Channel("C001")).HandleChannelUnsharedEvent(ctx, unsharedEvent)).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			Expect(sharedchannel.BuildShared(sharedHandler, sharedchannel.Channel("C002")).HandleChannelSharedEvent(ctx, sharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(sharedchannel.BuildUnshared(unsharedHandler, sharedchannel.Channel("C002")).HandleChannelUnsharedEvent(ctx, unsharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("ConnectedTeam", func() {
+		It("calls the inner handler if the channel is (or was) shared with any of the teams", func() {
+			pred := sharedchannel.ConnectedTeam("E002", "E001")
+			Expect(sharedchannel.BuildShared(sharedHandler, pred).HandleChannelSharedEvent(ctx, sharedEvent)).To(Succeed())
+			Expect(sharedchannel.BuildUnshared(unsharedHandler, pred).HandleChannelUnsharedEvent(ctx, unsharedEvent)).To(Succeed())
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+
+		It("does not call the inner handler if the event does not match", func() {
+			pred := sharedchannel.ConnectedTeam("E002")
+			Expect(sharedchannel.BuildShared(sharedHandler, pred).HandleChannelSharedEvent(ctx, sharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(sharedchannel.BuildUnshared(unsharedHandler, pred).HandleChannelUnsharedEvent(ctx, unsharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("FromGenericPredicate", func() {
+		It("applies generic predicates", func() {
+			pred := sharedchannel.FromGenericPredicate(
+				router.Filter(func(context.Context, *sharedchannel.SharedEvent) bool { return false }),
+				router.Filter(func(context.Context, *sharedchannel.UnsharedEvent) bool { return false }),
+			)
+			Expect(sharedchannel.BuildShared(sharedHandler, pred).HandleChannelSharedEvent(ctx, sharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(sharedchannel.BuildUnshared(unsharedHandler, pred).HandleChannelUnsharedEvent(ctx, unsharedEvent)).To(MatchError(errors.NotInterested))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Reason is the reason why verification succeeded or failed.
+type Reason string
+
+const (
+	// ReasonOK means that verification succeeded.
+	ReasonOK Reason = "ok"
+
+	// ReasonMissingHeaders means that verification failed with ErrMissingHeaders.
+	ReasonMissingHeaders Reason = "missing_headers"
+
+	// ReasonInvalidHeaders means that verification failed with ErrInvalidHeaders.
+	ReasonInvalidHeaders Reason = "invalid_headers"
+
+	// ReasonExpiredTimestamp means that verification failed with ErrExpiredTimestamp.
+	ReasonExpiredTimestamp Reason = "expired_timestamp"
+
+	// ReasonBadSignature means that verification failed with ErrBadSignature.
+	ReasonBadSignature Reason = "bad_signature"
+
+	// ReasonOther means that verification failed for other reasons (e.g. failing to read the request body).
+	ReasonOther Reason = "other"
+)
+
+// ReasonOf returns the Reason corresponding to an error returned from verification. It returns ReasonOK if `err` is nil.
+func ReasonOf(err error) Reason {
+	switch {
+	case err == nil:
+		return ReasonOK
+	case errors.Is(err, ErrMissingHeaders):
+		return ReasonMissingHeaders
+	case errors.Is(err, ErrInvalidHeaders):
+		return ReasonInvalidHeaders
+	case errors.Is(err, ErrExpiredTimestamp):
+		return ReasonExpiredTimestamp
+	case errors.Is(err, ErrBadSignature):
+		return ReasonBadSignature
+	default:
+		return ReasonOther
+	}
+}
+
+// Outcome is an outcome of verification of a request.
+type Outcome struct {
+	Reason Reason
+
+	// HMACDuration is the time taken to read the body and compute its HMACs (one for each signing secret).
+	// This is zero if verification failed before computing HMACs (e.g. because of missing headers).
+	HMACDuration time.Duration
+}
+
+// Recorder receives Outcomes of verification, so that anomalies (e.g. spikes of bad signatures) can be detected without scraping logs.
+//
+// It is called synchronously once per request, so it should return quickly.
+type Recorder interface {
+	RecordVerification(ctx context.Context, outcome *Outcome)
+}
+
+type RecorderFunc func(ctx context.Context, outcome *Outcome)
+
+func (f RecorderFunc) RecordVerification(ctx context.Context, outcome *Outcome) {
+	f(ctx, outcome)
+}
+
+// Counters is a Recorder that counts Outcomes by Reason and sums up HMACDurations.
+//
+// It is safe for concurrent use. Its Snapshot can be exported by metrics libraries.
+type Counters struct {
+	mu           sync.Mutex
+	byReason     map[Reason]int64
+	hmacCount    int64
+	hmacDuration time.Duration
+}
+
+var _ Recorder = &Counters{}
+
+// NewCounters creates a new Counters whose counts are all zero.
+func NewCounters() *Counters {
+	return &Counters{byReason: make(map[Reason]int64)}
+}
+
+func (c *Counters) RecordVerification(_ context.Context, outcome *Outcome) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byReason[outcome.Reason]++
+	if outcome.HMACDuration > 0 {
+		c.hmacCount++
+		c.hmacDuration += outcome.HMACDuration
+	}
+}
+
+// CountersSnapshot is a copy of Counters at some point.
+type CountersSnapshot struct {
+	// ByReason is the number of verifications for each Reason.
+	ByReason map[Reason]int64
+
+	// HMACCount is the number of verifications that computed HMACs.
+	HMACCount int64
+
+	// HMACDuration is the total time taken to compute HMACs.
+	HMACDuration time.Duration
+}
+
+// Snapshot returns the current counts.
+func (c *Counters) Snapshot() CountersSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	byReason := make(map[Reason]int64, len(c.byReason))
+	for reason, n := range c.byReason {
+		byReason[reason] = n
+	}
+	return CountersSnapshot{ByReason: byReason, HMACCount: c.hmacCount, HMACDuration: c.hmacDuration}
+}
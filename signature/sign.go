@@ -0,0 +1,76 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+// SignOption configures AddSignature.
+type SignOption interface {
+	apply(*signConfig)
+}
+
+type signOptionFunc func(*signConfig)
+
+func (f signOptionFunc) apply(c *signConfig) {
+	f(c)
+}
+
+type signConfig struct {
+	timestampHeader string
+	signatureHeader string
+}
+
+// WithTimestampHeader changes the name of the header that AddSignature puts the timestamp to.
+// The default is `X-Slack-Request-Timestamp`.
+func WithTimestampHeader(name string) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.timestampHeader = name
+	})
+}
+
+// WithSignatureHeader changes the name of the header that AddSignature puts the signature to.
+// The default is `X-Slack-Signature`.
+func WithSignatureHeader(name string) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.signatureHeader = name
+	})
+}
+
+// AddSignature signs `body` with `signingSecret` in the same way as Slack does, and sets the timestamp and the signature to `h`.
+//
+// This is useful to write tests for your handlers, or to build proxies that forward requests to applications that verify signatures.
+func AddSignature(h http.Header, signingSecret, body []byte, timestamp time.Time, opts ...SignOption) error {
+	c := &signConfig{
+		timestampHeader: slackheaders.RequestTimestamp,
+		signatureHeader: slackheaders.Signature,
+	}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	strTimestamp := strconv.FormatInt(timestamp.Unix(), 10)
+	sig, err := computeSignature(signingSecret, strTimestamp, body)
+	if err != nil {
+		return err
+	}
+	h.Set(c.timestampHeader, strTimestamp)
+	h.Set(c.signatureHeader, "v0="+hex.EncodeToString(sig))
+	return nil
+}
+
+func computeSignature(signingSecret []byte, strTimestamp string, body []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, signingSecret)
+	if _, err := mac.Write([]byte("v0:" + strTimestamp + ":")); err != nil {
+		return nil, err
+	}
+	if _, err := mac.Write(body); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
@@ -4,13 +4,16 @@
 package signature
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"time"
 
-	"github.com/slack-go/slack"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/spool"
 )
 
 // Middleware is an `http.Handler` middleware that automatically verifies request signatures.
@@ -25,33 +28,157 @@ type Middleware struct {
 
 	// Handler is an internal handler to perform actual request processing.
 	Handler http.Handler
+
+	// PreviousSigningSecrets are signing secrets that are also accepted in addition to SigningSecret.
+	//
+	// This is intended to be used during rotation of signing secrets.
+	PreviousSigningSecrets []string
+
+	// Now returns the current time, which is used to check whether the request timestamp is too old.
+	// If nil, `time.Now` is used.
+	Now func() time.Time
+
+	// OnFailure is called when the middleware rejects a request. If nil, nothing is called.
+	//
+	// `err` is (or wraps) one of ErrMissingHeaders, ErrInvalidHeaders, ErrExpiredTimestamp and ErrBadSignature,
+	// unless the middleware fails to read the request body.
+	OnFailure func(r *http.Request, err error)
+
+	// Spooler stores request bodies while they are verified. If nil, bodies are kept in memory.
+	//
+	// The spooled body is passed to Handler, and released after Handler returns.
+	Spooler spool.BodySpooler
+
+	// Recorder receives the Outcome of verification of every request. If nil, nothing is recorded.
+	Recorder Recorder
 }
 
 func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	verifier, err := slack.NewSecretsVerifier(r.Header, m.SigningSecret)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if m.VerboseResponse {
-			fmt.Fprintf(w, "failed to initialize verifier: %s", err.Error())
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	verifiedAt := now()
+	// Most apps have only one secret, so this usually doesn't allocate a slice.
+	var buf [2]*verifier
+	verifiers := buf[:0]
+	for i := 0; i <= len(m.PreviousSigningSecrets); i++ {
+		secret := m.SigningSecret
+		if i > 0 {
+			secret = m.PreviousSigningSecrets[i-1]
 		}
-		return
+		verifier, err := newVerifier(r.Header, secret, verifiedAt)
+		if err != nil {
+			m.onFailure(r, err, 0)
+			w.WriteHeader(http.StatusBadRequest)
+			if m.VerboseResponse {
+				fmt.Fprintf(w, "failed to initialize verifier: %s", err.Error())
+			}
+			return
+		}
+		verifiers = append(verifiers, verifier)
 	}
-	tee := io.TeeReader(r.Body, &verifier)
-	body, err := ioutil.ReadAll(tee)
+	hmacStart := time.Now()
+	body, err := m.readBody(r, verifiers)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		m.onFailure(r, err, time.Since(hmacStart))
+		// Bodies may be limited by routers (e.g. `eventrouter.WithMaxBodySize`), which tell the status by errors.
+		status := http.StatusInternalServerError
+		var httpErr routererrors.HttpError
+		if errors.As(err, &httpErr) {
+			status = int(httpErr)
+		}
+		w.WriteHeader(status)
 		if m.VerboseResponse {
 			fmt.Fprintf(w, "failed to read response: %s", err.Error())
 		}
 		return
 	}
-	if err := verifier.Ensure(); err != nil {
+	defer body.Close()
+	matched := -1
+	for i, verifier := range verifiers {
+		if err = verifier.Ensure(); err == nil {
+			matched = i
+			break
+		}
+	}
+	hmacDuration := time.Since(hmacStart)
+	if matched < 0 {
+		m.onFailure(r, err, hmacDuration)
 		w.WriteHeader(http.StatusUnauthorized)
 		if m.VerboseResponse {
 			fmt.Fprintf(w, "verification failed: %s", err.Error())
 		}
 		return
 	}
-	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r = r.WithContext(WithVerification(r.Context(), &Verification{
+		Timestamp:   verifiers[matched].timestamp,
+		Skew:        verifiedAt.Sub(verifiers[matched].timestamp),
+		SecretIndex: matched,
+	}))
+	m.record(r, nil, hmacDuration)
+	r.Body = body
 	m.Handler.ServeHTTP(w, r)
 }
+
+// readBody reads the whole body and passes it to `verifiers` so that its HMACs are computed. The returned reader must be closed.
+func (m *Middleware) readBody(r *http.Request, verifiers []*verifier) (io.ReadCloser, error) {
+	if m.Spooler != nil {
+		writers := make([]io.Writer, 0, len(verifiers))
+		for _, v := range verifiers {
+			writers = append(writers, v)
+		}
+		return m.Spooler.Spool(r.Context(), io.TeeReader(r.Body, io.MultiWriter(writers...)))
+	}
+	body, err := routerutils.ReadBody(r.Body, r.ContentLength)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range verifiers {
+		if _, err := v.Write(body); err != nil {
+			return nil, err
+		}
+	}
+	return routerutils.NewMemoryBody(body), nil
+}
+
+func (m *Middleware) onFailure(r *http.Request, err error, hmacDuration time.Duration) {
+	m.record(r, err, hmacDuration)
+	if m.OnFailure != nil {
+		m.OnFailure(r, err)
+	}
+}
+
+func (m *Middleware) record(r *http.Request, err error, hmacDuration time.Duration) {
+	if m.Recorder != nil {
+		m.Recorder.RecordVerification(r.Context(), &Outcome{Reason: ReasonOf(err), HMACDuration: hmacDuration})
+	}
+}
+
+// Verification describes the outcome of a successful verification.
+type Verification struct {
+	// Timestamp is the request timestamp.
+	Timestamp time.Time
+
+	// Skew is the difference between the time of verification and Timestamp.
+	Skew time.Duration
+
+	// SecretIndex is the index of the signing secret that matched.
+	// 0 means SigningSecret, and i (i > 0) means PreviousSigningSecrets[i-1].
+	SecretIndex int
+}
+
+type verificationKey struct{}
+
+// WithVerification returns a new context that holds `v`.
+func WithVerification(ctx context.Context, v *Verification) context.Context {
+	return context.WithValue(ctx, verificationKey{}, v)
+}
+
+// VerificationFromContext returns the Verification of the request that is being processed.
+//
+// This is available in contexts of requests that passed Middleware. Otherwise it returns nil.
+func VerificationFromContext(ctx context.Context) *Verification {
+	v, _ := ctx.Value(verificationKey{}).(*Verification)
+	return v
+}
@@ -2,6 +2,8 @@ package signature_test
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"time"
@@ -9,10 +11,21 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
-	"github.com/genkami/go-slack-event-router/internal/testutils"
 	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackheaders"
+	"github.com/genkami/go-slack-event-router/spool"
 )
 
+type closeRecorder struct {
+	io.Reader
+	closed *bool
+}
+
+func (c *closeRecorder) Close() error {
+	*c.closed = true
+	return nil
+}
+
 var _ = Describe("Signature", func() {
 	Describe("Middleware", func() {
 		var (
@@ -22,13 +35,18 @@ var _ = Describe("Signature", func() {
 				w.WriteHeader(http.StatusOK)
 			})
 			middleware *signature.Middleware
+			failure    error
 		)
 
 		BeforeEach(func() {
+			failure = nil
 			middleware = &signature.Middleware{
 				SigningSecret:   token,
 				VerboseResponse: true,
 				Handler:         innerHandler,
+				OnFailure: func(_ *http.Request, err error) {
+					failure = err
+				},
 			}
 		})
 
@@ -36,12 +54,13 @@ var _ = Describe("Signature", func() {
 			It("calls the inner handler", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
 				Expect(err).NotTo(HaveOccurred())
-				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now())
+				err = signature.AddSignature(req.Header, []byte(token), content, time.Now())
 				Expect(err).NotTo(HaveOccurred())
 				w := httptest.NewRecorder()
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(failure).NotTo(HaveOccurred())
 			})
 		})
 
@@ -53,6 +72,7 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(failure).To(MatchError(signature.ErrMissingHeaders))
 			})
 		})
 
@@ -60,13 +80,14 @@ var _ = Describe("Signature", func() {
 			It("responds with BadRequest", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
 				Expect(err).NotTo(HaveOccurred())
-				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now())
+				err = signature.AddSignature(req.Header, []byte(token), content, time.Now())
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "WRONG_HEADER")
+				req.Header.Set(slackheaders.Signature, "WRONG_HEADER")
 				w := httptest.NewRecorder()
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(failure).To(MatchError(signature.ErrInvalidHeaders))
 			})
 		})
 
@@ -74,12 +95,22 @@ var _ = Describe("Signature", func() {
 			It("responds with Unauthorized", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
 				Expect(err).NotTo(HaveOccurred())
-				err = testutils.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
+				err = signature.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
 				Expect(err).NotTo(HaveOccurred())
 				w := httptest.NewRecorder()
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(failure).To(MatchError(signature.ErrBadSignature))
+			})
+
+			It("does not expose the computed signature", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				middleware.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(failure).To(BeIdenticalTo(signature.ErrBadSignature))
 			})
 		})
 
@@ -87,13 +118,94 @@ var _ = Describe("Signature", func() {
 			It("responds with BadRequest", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
 				Expect(err).NotTo(HaveOccurred())
-				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now())
+				err = signature.AddSignature(req.Header, []byte(token), content, time.Now())
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Del(testutils.HeaderTimestamp)
+				req.Header.Del(slackheaders.RequestTimestamp)
 				w := httptest.NewRecorder()
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(failure).To(MatchError(signature.ErrMissingHeaders))
+			})
+		})
+
+		Context("when Now is set", func() {
+			It("uses it to check the timestamp", func() {
+				ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+				middleware.Now = func() time.Time { return ts.Add(1 * time.Minute) }
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte(token), content, ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("rejects timestamps that are too old compared to it", func() {
+				ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+				middleware.Now = func() time.Time { return ts.Add(1 * time.Hour) }
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte(token), content, ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the request passes verification", func() {
+			It("puts the Verification into the context", func() {
+				ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+				middleware.Now = func() time.Time { return ts.Add(3 * time.Second) }
+				var verification *signature.Verification
+				middleware.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					verification = signature.VerificationFromContext(r.Context())
+				})
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte(token), content, ts)
+				Expect(err).NotTo(HaveOccurred())
+				middleware.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(verification).NotTo(BeNil())
+				Expect(verification.Timestamp).To(BeTemporally("==", ts))
+				Expect(verification.Skew).To(Equal(3 * time.Second))
+				Expect(verification.SecretIndex).To(Equal(0))
+			})
+		})
+
+		Context("when PreviousSigningSecrets is set", func() {
+			BeforeEach(func() {
+				middleware.PreviousSigningSecrets = []string{"OLD_TOKEN", "OLDER_TOKEN"}
+			})
+
+			It("accepts requests signed with previous secrets", func() {
+				var verification *signature.Verification
+				middleware.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					verification = signature.VerificationFromContext(r.Context())
+				})
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte("OLDER_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(verification.SecretIndex).To(Equal(2))
+			})
+
+			It("rejects requests signed with unknown secrets", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte("UNKNOWN_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(failure).To(MatchError(signature.ErrBadSignature))
 			})
 		})
 
@@ -101,13 +213,141 @@ var _ = Describe("Signature", func() {
 			It("responds with BadRequest", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
 				Expect(err).NotTo(HaveOccurred())
-				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-1*time.Hour))
+				err = signature.AddSignature(req.Header, []byte(token), content, time.Now().Add(-1*time.Hour))
 				Expect(err).NotTo(HaveOccurred())
 				w := httptest.NewRecorder()
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(failure).To(MatchError(signature.ErrExpiredTimestamp))
+			})
+		})
+
+		Context("when Spooler is set", func() {
+			var closed bool
+
+			BeforeEach(func() {
+				closed = false
+				middleware.Spooler = spool.BodySpoolerFunc(func(_ context.Context, r io.Reader) (io.ReadCloser, error) {
+					data, err := io.ReadAll(r)
+					if err != nil {
+						return nil, err
+					}
+					return &closeRecorder{Reader: bytes.NewReader(data), closed: &closed}, nil
+				})
 			})
+
+			It("passes the spooled body to the inner handler and closes it afterwards", func() {
+				var received []byte
+				middleware.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					var err error
+					received, err = io.ReadAll(r.Body)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(closed).To(BeFalse())
+				})
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte(token), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(received).To(Equal(content))
+				Expect(closed).To(BeTrue())
+			})
+
+			It("closes the spooled body when verification fails", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = signature.AddSignature(req.Header, []byte("WRONG_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(closed).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Recorder", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = []byte(`{"body": "this is a request body"}`)
+			serve   = func(m *signature.Middleware, sign func(*http.Request)) {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				sign(req)
+				m.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		)
+
+		It("records outcomes of verification", func() {
+			counters := signature.NewCounters()
+			var outcomes []*signature.Outcome
+			m := &signature.Middleware{
+				SigningSecret: token,
+				Handler:       http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				Recorder: signature.RecorderFunc(func(ctx context.Context, outcome *signature.Outcome) {
+					outcomes = append(outcomes, outcome)
+					counters.RecordVerification(ctx, outcome)
+				}),
+			}
+			serve(m, func(req *http.Request) {
+				Expect(signature.AddSignature(req.Header, []byte(token), content, time.Now())).To(Succeed())
+			})
+			serve(m, func(req *http.Request) {
+				Expect(signature.AddSignature(req.Header, []byte("WRONG_TOKEN"), content, time.Now())).To(Succeed())
+			})
+			serve(m, func(*http.Request) {})
+			serve(m, func(req *http.Request) {
+				Expect(signature.AddSignature(req.Header, []byte(token), content, time.Now().Add(-time.Hour))).To(Succeed())
+			})
+
+			Expect(outcomes).To(HaveLen(4))
+			Expect(outcomes[0].Reason).To(Equal(signature.ReasonOK))
+			Expect(outcomes[0].HMACDuration).To(BeNumerically(">", 0))
+			Expect(outcomes[1].Reason).To(Equal(signature.ReasonBadSignature))
+			Expect(outcomes[2].Reason).To(Equal(signature.ReasonMissingHeaders))
+			Expect(outcomes[2].HMACDuration).To(BeZero())
+			Expect(outcomes[3].Reason).To(Equal(signature.ReasonExpiredTimestamp))
+
+			snapshot := counters.Snapshot()
+			Expect(snapshot.ByReason).To(Equal(map[signature.Reason]int64{
+				signature.ReasonOK:               1,
+				signature.ReasonBadSignature:     1,
+				signature.ReasonMissingHeaders:   1,
+				signature.ReasonExpiredTimestamp: 1,
+			}))
+			Expect(snapshot.HMACCount).To(Equal(int64(2)))
+			Expect(snapshot.HMACDuration).To(Equal(outcomes[0].HMACDuration + outcomes[1].HMACDuration))
+		})
+	})
+
+	Describe("AddSignature", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = []byte(`{"body": "this is a request body"}`)
+			ts      = time.Unix(1609459200, 0)
+		)
+
+		It("sets the timestamp and the signature", func() {
+			h := http.Header{}
+			err := signature.AddSignature(h, []byte(token), content, ts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.Get(slackheaders.RequestTimestamp)).To(Equal("1609459200"))
+			Expect(h.Get(slackheaders.Signature)).To(MatchRegexp("^v0=[0-9a-f]{64}$"))
+		})
+
+		It("uses the given header names", func() {
+			h := http.Header{}
+			err := signature.AddSignature(h, []byte(token), content, ts,
+				signature.WithTimestampHeader("X-Internal-Timestamp"),
+				signature.WithSignatureHeader("X-Internal-Signature"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.Get("X-Internal-Timestamp")).To(Equal("1609459200"))
+			Expect(h.Get("X-Internal-Signature")).To(MatchRegexp("^v0=[0-9a-f]{64}$"))
+			Expect(h.Get(slackheaders.RequestTimestamp)).To(BeEmpty())
+			Expect(h.Get(slackheaders.Signature)).To(BeEmpty())
 		})
 	})
 })
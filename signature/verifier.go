@@ -0,0 +1,99 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+var (
+	// ErrMissingHeaders indicates that the request doesn't have the signature or the timestamp header.
+	// This is the same value as `slack.ErrMissingHeaders`.
+	ErrMissingHeaders = slack.ErrMissingHeaders
+
+	// ErrInvalidHeaders indicates that the signature or the timestamp header is malformed.
+	ErrInvalidHeaders = errors.New("invalid signature headers")
+
+	// ErrExpiredTimestamp indicates that the request timestamp is too far from the current time.
+	// This is the same value as `slack.ErrExpiredTimestamp`.
+	ErrExpiredTimestamp = slack.ErrExpiredTimestamp
+
+	// ErrBadSignature indicates that the signature doesn't match to the request.
+	ErrBadSignature = errors.New("signature mismatch")
+)
+
+const (
+	// maxTimestampSkew is the maximum difference between the request timestamp and the current time.
+	maxTimestampSkew = 5 * time.Minute
+)
+
+// verifier is almost the same as `slack.SecretsVerifier` except that it can use an arbitrary current time.
+type verifier struct {
+	signature []byte
+	timestamp time.Time
+	hmac      hash.Hash
+}
+
+func newVerifier(header http.Header, secret string, now time.Time) (*verifier, error) {
+	signature := header.Get(slackheaders.Signature)
+	strTimestamp := header.Get(slackheaders.RequestTimestamp)
+	if signature == "" || strTimestamp == "" {
+		return nil, ErrMissingHeaders
+	}
+	rawSignature, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), ErrInvalidHeaders)
+	}
+	timestamp, err := strconv.ParseInt(strTimestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), ErrInvalidHeaders)
+	}
+	ts := time.Unix(timestamp, 0)
+	skew := now.Sub(ts)
+	if skew > maxTimestampSkew || skew < -maxTimestampSkew {
+		return nil, ErrExpiredTimestamp
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	if _, err := mac.Write([]byte("v0:" + strTimestamp + ":")); err != nil {
+		return nil, err
+	}
+	return &verifier{signature: rawSignature, timestamp: ts, hmac: mac}, nil
+}
+
+func (v *verifier) Write(p []byte) (int, error) {
+	return v.hmac.Write(p)
+}
+
+func (v *verifier) Ensure() error {
+	computed := v.hmac.Sum(nil)
+	if hmac.Equal(computed, v.signature) {
+		return nil
+	}
+	// Don't include the computed signature, since errors may be exposed to clients (e.g. by VerboseResponse).
+	return ErrBadSignature
+}
+
+// Verify verifies the signature of a request whose headers are `header` and body is `body`.
+//
+// `now` is used to check whether the request timestamp is too old.
+func Verify(header http.Header, body []byte, secret string, now time.Time) error {
+	v, err := newVerifier(header, secret, now)
+	if err != nil {
+		return err
+	}
+	if _, err := v.Write(body); err != nil {
+		return err
+	}
+	return v.Ensure()
+}
@@ -0,0 +1,61 @@
+// Package slackheaders provides names of HTTP headers that Slack uses, and helpers to parse them.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/authentication/verifying-requests-from-slack
+//   - https://api.slack.com/apis/connections/events-api#retries
+package slackheaders
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// RequestTimestamp is a header that contains the time (in UNIX seconds) when the request was sent.
+	RequestTimestamp = "X-Slack-Request-Timestamp"
+
+	// Signature is a header that contains the signature of the request.
+	Signature = "X-Slack-Signature"
+
+	// RetryNum is a header that contains the number of times the request has been retried.
+	RetryNum = "X-Slack-Retry-Num"
+
+	// RetryReason is a header that contains the reason why the request is retried (e.g. `http_timeout`).
+	RetryReason = "X-Slack-Retry-Reason"
+
+	// NoRetry is a header that apps can set on their responses to tell Slack not to retry the request.
+	NoRetry = "X-Slack-No-Retry"
+)
+
+// IsRetry returns true if and only if the request is a retry of a previous request.
+func IsRetry(h http.Header) bool {
+	return h.Get(RetryNum) != ""
+}
+
+// ParseRetryNum returns the number of retries. If the header is absent, it returns 0.
+func ParseRetryNum(h http.Header) (int, error) {
+	v := h.Get(RetryNum)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", RetryNum, err)
+	}
+	return n, nil
+}
+
+// ParseRequestTimestamp returns the time when the request was sent.
+func ParseRequestTimestamp(h http.Header) (time.Time, error) {
+	v := h.Get(RequestTimestamp)
+	if v == "" {
+		return time.Time{}, fmt.Errorf("missing %s", RequestTimestamp)
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", RequestTimestamp, err)
+	}
+	return time.Unix(sec, 0), nil
+}
@@ -0,0 +1,13 @@
+package slackheaders_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlackheaders(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Slackheaders Suite")
+}
@@ -0,0 +1,63 @@
+package slackheaders_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/slackheaders"
+)
+
+var _ = Describe("Slackheaders", func() {
+	Describe("IsRetry", func() {
+		It("returns true if the request is a retry", func() {
+			h := http.Header{}
+			h.Set(slackheaders.RetryNum, "1")
+			Expect(slackheaders.IsRetry(h)).To(BeTrue())
+		})
+
+		It("returns false if the request is not a retry", func() {
+			Expect(slackheaders.IsRetry(http.Header{})).To(BeFalse())
+		})
+	})
+
+	Describe("ParseRetryNum", func() {
+		It("returns the number of retries", func() {
+			h := http.Header{}
+			h.Set(slackheaders.RetryNum, "2")
+			n, err := slackheaders.ParseRetryNum(h)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(2))
+		})
+
+		It("returns 0 if the header is absent", func() {
+			n, err := slackheaders.ParseRetryNum(http.Header{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(0))
+		})
+
+		It("returns an error if the header is malformed", func() {
+			h := http.Header{}
+			h.Set(slackheaders.RetryNum, "many")
+			_, err := slackheaders.ParseRetryNum(h)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseRequestTimestamp", func() {
+		It("returns the timestamp", func() {
+			h := http.Header{}
+			h.Set(slackheaders.RequestTimestamp, "1609459200")
+			ts, err := slackheaders.ParseRequestTimestamp(h)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ts.Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+		})
+
+		It("returns an error if the header is absent", func() {
+			_, err := slackheaders.ParseRequestTimestamp(http.Header{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,106 @@
+// Package slacktext provides helpers to process texts formatted in Slack's markup.
+//
+// For more details, see https://api.slack.com/reference/surfaces/formatting.
+package slacktext
+
+import (
+	"strings"
+	"unicode"
+)
+
+var unescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+// Normalize converts a text formatted in Slack's markup into a plain text.
+//
+// Markup is resolved as follows:
+//
+//	<@U123>              -> @U123
+//	<@U123|alice>        -> @alice
+//	<#C123|general>      -> #general
+//	<!here>              -> @here
+//	<!subteam^S123|@sre> -> @sre
+//	<!date^...|fallback> -> fallback
+//	<https://example.com|label> -> label
+//	<https://example.com>       -> https://example.com
+//
+// Then escaped entities (`&amp;`, `&lt;` and `&gt;`) are unescaped.
+func Normalize(text string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '>')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(unescaper.Replace(text[:start]))
+		b.WriteString(unescaper.Replace(resolve(text[start+1 : end])))
+		text = text[end+1:]
+	}
+	b.WriteString(unescaper.Replace(text))
+	return b.String()
+}
+
+func resolve(markup string) string {
+	target, label := markup, ""
+	if i := strings.IndexByte(markup, '|'); i >= 0 {
+		target, label = markup[:i], markup[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(target, "@"):
+		if label != "" {
+			return "@" + label
+		}
+		return target
+	case strings.HasPrefix(target, "#"):
+		if label != "" {
+			return "#" + label
+		}
+		return target
+	case strings.HasPrefix(target, "!"):
+		if label != "" {
+			return label
+		}
+		name := target[1:]
+		if i := strings.IndexByte(name, '^'); i >= 0 {
+			name = name[:i]
+		}
+		return "@" + name
+	}
+	if label != "" {
+		return label
+	}
+	return target
+}
+
+// Words splits a text into words, skipping Slack markup enclosed by angle brackets.
+//
+// A word is a sequence of letters, digits and underscores.
+func Words(text string) []string {
+	text = strings.NewReplacer("&amp;", " ", "&lt;", " ", "&gt;", " ").Replace(text)
+	var words []string
+	start := -1
+	inMarkup := false
+	for i, r := range text {
+		isWordChar := !inMarkup && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+		if isWordChar && start < 0 {
+			start = i
+		} else if !isWordChar && start >= 0 {
+			words = append(words, text[start:i])
+			start = -1
+		}
+		switch r {
+		case '<':
+			inMarkup = true
+		case '>':
+			inMarkup = false
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+	return words
+}
@@ -0,0 +1,13 @@
+package slacktext_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlacktext(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Slacktext Suite")
+}
@@ -0,0 +1,40 @@
+package slacktext_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/slacktext"
+)
+
+var _ = Describe("Slacktext", func() {
+	Describe("Normalize", func() {
+		It("resolves markup and unescapes entities", func() {
+			for text, expected := range map[string]string{
+				"hello":                                  "hello",
+				"<@U123> hi":                             "@U123 hi",
+				"<@U123|alice> hi":                       "@alice hi",
+				"see <#C123|general>":                    "see #general",
+				"see <#C123>":                            "see #C123",
+				"<!here> <!channel>":                     "@here @channel",
+				"<!subteam^S123|@sre> ping":              "@sre ping",
+				"<!date^1392734382^{date}|Feb 18, 2014>": "Feb 18, 2014",
+				"<https://example.com|docs>":             "docs",
+				"<https://example.com>":                  "https://example.com",
+				"a &lt;b&gt; &amp;amp; c":                "a <b> &amp; c",
+				"<@U123|a&amp;b>":                        "@a&b",
+				"unclosed <@U123":                        "unclosed <@U123",
+			} {
+				Expect(slacktext.Normalize(text)).To(Equal(expected), text)
+			}
+		})
+	})
+
+	Describe("Words", func() {
+		It("splits a text into words, skipping markup", func() {
+			Expect(slacktext.Words("<@U123> Deploy app_1, now! <#C123|general>")).To(Equal([]string{"Deploy", "app_1", "now"}))
+			Expect(slacktext.Words("a&amp;b")).To(Equal([]string{"a", "b"}))
+			Expect(slacktext.Words("")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,261 @@
+// Package socketmodeadapter converts handlers of this module from/to handlers used with `slack-go/slack/socketmode`.
+//
+// This makes it possible to reuse handlers unchanged when migrating between HTTP endpoints and Socket Mode.
+//
+// For more details about Socket Mode, see https://api.slack.com/apis/connections/socket.
+package socketmodeadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/validation"
+)
+
+// HandlerFunc is a handler that processes events received by `socketmode.Client`.
+//
+// This has the same signature as `socketmode.SocketmodeHandlerFunc` in newer versions of slack-go.
+type HandlerFunc func(*socketmode.Event, *socketmode.Client)
+
+// Option configures HandlerFuncs created by EventsAPI, Interaction and SlashCommand.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+// OnError sets a hook that is called when a handler returns an error.
+//
+// Requests are not acknowledged in such case, so Slack retries them. If not set, errors are silently ignored.
+func OnError(hook func(context.Context, *socketmode.Event, error)) Option {
+	return optionFunc(func(c *config) {
+		c.errorHook = hook
+	})
+}
+
+// WithContext sets a function that creates a context given to handlers. If not set, `context.Background` is used.
+func WithContext(newContext func(*socketmode.Event) context.Context) Option {
+	return optionFunc(func(c *config) {
+		c.newContext = newContext
+	})
+}
+
+// WithHTTPClient sets an HTTP client that is used to post messages to response_url. If not set, `http.DefaultClient` is used.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(c *config) {
+		c.httpClient = client
+	})
+}
+
+type config struct {
+	errorHook  func(context.Context, *socketmode.Event, error)
+	newContext func(*socketmode.Event) context.Context
+	httpClient *http.Client
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		newContext: func(*socketmode.Event) context.Context { return context.Background() },
+		httpClient: http.DefaultClient,
+	}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	return c
+}
+
+func (c *config) onError(ctx context.Context, evt *socketmode.Event, err error) {
+	if c.errorHook != nil {
+		c.errorHook(ctx, evt, err)
+	}
+}
+
+// EventsAPI converts `h` into a HandlerFunc that processes `events_api` events. Other types of events are ignored.
+//
+// Requests are acknowledged after `h` returns nil or `routererrors.NotInterested`.
+func EventsAPI(h eventrouter.Handler, opts ...Option) HandlerFunc {
+	c := newConfig(opts)
+	return func(evt *socketmode.Event, client *socketmode.Client) {
+		if evt.Type != socketmode.EventTypeEventsAPI {
+			return
+		}
+		ctx := c.newContext(evt)
+		e, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			c.onError(ctx, evt, fmt.Errorf("unexpected data type: %T", evt.Data))
+			return
+		}
+		ctx = withRequest(ctx, evt)
+		err := h.HandleEventsAPIEvent(ctx, &e)
+		if err != nil && !errors.Is(err, routererrors.NotInterested) {
+			c.onError(ctx, evt, err)
+			return
+		}
+		ack(client, evt)
+	}
+}
+
+// Interaction converts `h` into a HandlerFunc that processes `interactive` events. Other types of events are ignored.
+//
+// Requests are acknowledged after `h` returns nil or `routererrors.NotInterested`.
+// As is the case with `interactionrouter.Router`, `h` may also return `validation.Errors` or `*interactionrouter.MessageResponse`.
+func Interaction(h interactionrouter.Handler, opts ...Option) HandlerFunc {
+	c := newConfig(opts)
+	return func(evt *socketmode.Event, client *socketmode.Client) {
+		if evt.Type != socketmode.EventTypeInteractive {
+			return
+		}
+		ctx := c.newContext(evt)
+		callback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			c.onError(ctx, evt, fmt.Errorf("unexpected data type: %T", evt.Data))
+			return
+		}
+		ctx = withRequest(ctx, evt)
+		err := h.HandleInteraction(ctx, &callback)
+
+		var validationErrs validation.Errors
+		if errors.As(err, &validationErrs) {
+			ack(client, evt, slack.NewErrorsViewSubmissionResponse(validationErrs))
+			return
+		}
+		var msgResp *interactionrouter.MessageResponse
+		if errors.As(err, &msgResp) {
+			err = c.postToResponseURL(ctx, &callback, msgResp)
+		}
+		if err != nil && !errors.Is(err, routererrors.NotInterested) {
+			c.onError(ctx, evt, err)
+			return
+		}
+		ack(client, evt)
+	}
+}
+
+// SlashCommand converts `h` into a HandlerFunc that processes `slash_commands` events. Other types of events are ignored.
+//
+// Requests are acknowledged after `h` returns nil or `routererrors.NotInterested`.
+// If `h` returns `*commandrouter.Response`, its message is sent as a payload of the acknowledgement.
+func SlashCommand(h commandrouter.Handler, opts ...Option) HandlerFunc {
+	c := newConfig(opts)
+	return func(evt *socketmode.Event, client *socketmode.Client) {
+		if evt.Type != socketmode.EventTypeSlashCommand {
+			return
+		}
+		ctx := c.newContext(evt)
+		cmd, ok := evt.Data.(slack.SlashCommand)
+		if !ok {
+			c.onError(ctx, evt, fmt.Errorf("unexpected data type: %T", evt.Data))
+			return
+		}
+		ctx = withRequest(ctx, evt)
+		err := h.HandleSlashCommand(ctx, &cmd)
+
+		var resp *commandrouter.Response
+		if errors.As(err, &resp) {
+			ack(client, evt, resp.Message)
+			return
+		}
+		if err != nil && !errors.Is(err, routererrors.NotInterested) {
+			c.onError(ctx, evt, err)
+			return
+		}
+		ack(client, evt)
+	}
+}
+
+func (c *config) postToResponseURL(ctx context.Context, callback *slack.InteractionCallback, resp *interactionrouter.MessageResponse) error {
+	if callback.ResponseURL == "" {
+		return errors.New("the callback does not have response_url")
+	}
+	if err := slack.PostWebhookCustomHTTPContext(ctx, callback.ResponseURL, c.httpClient, resp.Message); err != nil {
+		return fmt.Errorf("failed to post a message to response_url: %w", err)
+	}
+	return nil
+}
+
+func withRequest(ctx context.Context, evt *socketmode.Event) context.Context {
+	if evt.Request == nil {
+		return ctx
+	}
+	return routerutils.WithRequest(ctx, evt.Request.Payload, http.Header{})
+}
+
+func ack(client *socketmode.Client, evt *socketmode.Event, payload ...interface{}) {
+	if client == nil || evt.Request == nil {
+		return
+	}
+	client.Ack(*evt.Request, payload...)
+}
+
+// ToEventsAPIHandler converts `f` into `eventrouter.Handler`.
+//
+// `f` receives a `socketmode.Client` that wraps `api`, so it can call Web API methods as usual.
+// Acknowledgements sent by `f` are discarded since the Router acknowledges requests by HTTP responses.
+func ToEventsAPIHandler(f HandlerFunc, api *slack.Client) eventrouter.Handler {
+	return eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		f(&socketmode.Event{
+			Type:    socketmode.EventTypeEventsAPI,
+			Data:    *e,
+			Request: newRequest(ctx, socketmode.RequestTypeEventsAPI, e),
+		}, discardingClient(api))
+		return nil
+	})
+}
+
+// ToInteractionHandler converts `f` into `interactionrouter.Handler`. See ToEventsAPIHandler for details.
+func ToInteractionHandler(f HandlerFunc, api *slack.Client) interactionrouter.Handler {
+	return interactionrouter.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		f(&socketmode.Event{
+			Type:    socketmode.EventTypeInteractive,
+			Data:    *callback,
+			Request: newRequest(ctx, socketmode.RequestTypeInteractive, callback),
+		}, discardingClient(api))
+		return nil
+	})
+}
+
+// ToSlashCommandHandler converts `f` into `commandrouter.Handler`. See ToEventsAPIHandler for details.
+func ToSlashCommandHandler(f HandlerFunc, api *slack.Client) commandrouter.Handler {
+	return commandrouter.HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) error {
+		f(&socketmode.Event{
+			Type:    socketmode.EventTypeSlashCommand,
+			Data:    *cmd,
+			Request: newRequest(ctx, socketmode.RequestTypeSlashCommands, cmd),
+		}, discardingClient(api))
+		return nil
+	})
+}
+
+func newRequest(ctx context.Context, typ string, data interface{}) *socketmode.Request {
+	payload := routerutils.RawBody(ctx)
+	if typ != socketmode.RequestTypeEventsAPI || payload == nil {
+		// Only the Events API sends JSON bodies, so the others are re-encoded.
+		payload, _ = json.Marshal(data)
+	}
+	return &socketmode.Request{Type: typ, Payload: payload}
+}
+
+// discardingClient returns a client that is not connected to Slack.
+// Its responses are buffered in a channel that nobody reads, and dropped together with the client.
+func discardingClient(api *slack.Client) *socketmode.Client {
+	if api == nil {
+		api = slack.New("")
+	}
+	return socketmode.New(api)
+}
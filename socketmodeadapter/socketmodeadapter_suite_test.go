@@ -0,0 +1,13 @@
+package socketmodeadapter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSocketmodeadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Socketmodeadapter Suite")
+}
@@ -0,0 +1,202 @@
+package socketmodeadapter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/commandrouter"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/socketmodeadapter"
+)
+
+var _ = Describe("Socketmodeadapter", func() {
+	var (
+		client *socketmode.Client
+	)
+	BeforeEach(func() {
+		client = socketmode.New(slack.New("xoxb-token"))
+	})
+
+	Describe("EventsAPI", func() {
+		var (
+			evt = &socketmode.Event{
+				Type: socketmode.EventTypeEventsAPI,
+				Data: slackevents.EventsAPIEvent{
+					Type: slackevents.CallbackEvent,
+					InnerEvent: slackevents.EventsAPIInnerEvent{
+						Type: slackevents.Message,
+						Data: &slackevents.MessageEvent{Type: slackevents.Message, Text: "hello"},
+					},
+				},
+				Request: &socketmode.Request{Type: socketmode.RequestTypeEventsAPI, EnvelopeID: "ENVELOPE"},
+			}
+		)
+
+		It("calls the handler with the event", func() {
+			var got *slackevents.EventsAPIEvent
+			f := socketmodeadapter.EventsAPI(eventrouter.HandlerFunc(func(_ context.Context, e *slackevents.EventsAPIEvent) error {
+				got = e
+				return nil
+			}))
+			f(evt, client)
+			Expect(got).NotTo(BeNil())
+			Expect(got.InnerEvent.Data.(*slackevents.MessageEvent).Text).To(Equal("hello"))
+		})
+
+		It("works with handlers built from predicates", func() {
+			numCalled := 0
+			h := message.Build(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				numCalled++
+				return nil
+			}), message.TextRegexp(regexp.MustCompile("hel+o")))
+			f := socketmodeadapter.EventsAPI(eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+				return h.HandleMessageEvent(ctx, e.InnerEvent.Data.(*slackevents.MessageEvent))
+			}))
+			f(evt, client)
+			Expect(numCalled).To(Equal(1))
+		})
+
+		It("ignores other types of events", func() {
+			numCalled := 0
+			f := socketmodeadapter.EventsAPI(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				numCalled++
+				return nil
+			}))
+			f(&socketmode.Event{Type: socketmode.EventTypeHello}, client)
+			Expect(numCalled).To(Equal(0))
+		})
+
+		It("calls the error hook when the handler fails", func() {
+			var gotErr error
+			f := socketmodeadapter.EventsAPI(
+				eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return errors.New("oops")
+				}),
+				socketmodeadapter.OnError(func(_ context.Context, _ *socketmode.Event, err error) {
+					gotErr = err
+				}))
+			f(evt, client)
+			Expect(gotErr).To(MatchError("oops"))
+		})
+
+		It("does not call the error hook when the handler is not interested", func() {
+			var gotErr error
+			f := socketmodeadapter.EventsAPI(
+				eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return routererrors.NotInterested
+				}),
+				socketmodeadapter.OnError(func(_ context.Context, _ *socketmode.Event, err error) {
+					gotErr = err
+				}))
+			f(evt, client)
+			Expect(gotErr).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Interaction", func() {
+		It("posts MessageResponses to response_url", func() {
+			var posted string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				body, _ := io.ReadAll(req.Body)
+				posted = string(body)
+			}))
+			defer server.Close()
+
+			f := socketmodeadapter.Interaction(
+				interactionrouter.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return interactionrouter.ReplaceEphemeral("updated")
+				}),
+				socketmodeadapter.WithHTTPClient(server.Client()))
+			f(&socketmode.Event{
+				Type:    socketmode.EventTypeInteractive,
+				Data:    slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, ResponseURL: server.URL},
+				Request: &socketmode.Request{Type: socketmode.RequestTypeInteractive, EnvelopeID: "ENVELOPE"},
+			}, client)
+			Expect(posted).To(ContainSubstring("updated"))
+		})
+	})
+
+	Describe("SlashCommand", func() {
+		It("calls the handler with the command", func() {
+			var got *slack.SlashCommand
+			f := socketmodeadapter.SlashCommand(commandrouter.HandlerFunc(func(_ context.Context, cmd *slack.SlashCommand) error {
+				got = cmd
+				return commandrouter.Ephemeral("ok")
+			}))
+			f(&socketmode.Event{
+				Type:    socketmode.EventTypeSlashCommand,
+				Data:    slack.SlashCommand{Command: "/deploy"},
+				Request: &socketmode.Request{Type: socketmode.RequestTypeSlashCommands, EnvelopeID: "ENVELOPE"},
+			}, client)
+			Expect(got).NotTo(BeNil())
+			Expect(got.Command).To(Equal("/deploy"))
+		})
+	})
+
+	Describe("ToEventsAPIHandler", func() {
+		It("passes the event to the HandlerFunc", func() {
+			var got *socketmode.Event
+			h := socketmodeadapter.ToEventsAPIHandler(func(evt *socketmode.Event, c *socketmode.Client) {
+				c.Ack(*evt.Request)
+				got = evt
+			}, slack.New("xoxb-token"))
+			err := h.HandleEventsAPIEvent(context.Background(), &slackevents.EventsAPIEvent{Type: slackevents.CallbackEvent, TeamID: "T123"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got.Type).To(Equal(socketmode.EventTypeEventsAPI))
+			Expect(got.Data.(slackevents.EventsAPIEvent).TeamID).To(Equal("T123"))
+			Expect(got.Request.Type).To(Equal(socketmode.RequestTypeEventsAPI))
+		})
+	})
+
+	Describe("ToInteractionHandler", func() {
+		It("passes the callback to the HandlerFunc", func() {
+			var got *socketmode.Event
+			h := socketmodeadapter.ToInteractionHandler(func(evt *socketmode.Event, _ *socketmode.Client) {
+				got = evt
+			}, nil)
+			err := h.HandleInteraction(context.Background(), &slack.InteractionCallback{CallbackID: "the-callback"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got.Type).To(Equal(socketmode.EventTypeInteractive))
+			Expect(got.Data.(slack.InteractionCallback).CallbackID).To(Equal("the-callback"))
+			Expect(string(got.Request.Payload)).To(ContainSubstring("the-callback"))
+		})
+	})
+
+	Describe("ToSlashCommandHandler", func() {
+		It("can be used with commandrouter", func() {
+			var got *socketmode.Event
+			h := socketmodeadapter.ToSlashCommandHandler(func(evt *socketmode.Event, _ *socketmode.Client) {
+				got = evt
+			}, nil)
+			r, err := commandrouter.New(commandrouter.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			r.On("/deploy", h)
+
+			form := url.Values{}
+			form.Set("command", "/deploy")
+			form.Set("text", "production")
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/commands", strings.NewReader(form.Encode()))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(got.Data.(slack.SlashCommand).Text).To(Equal("production"))
+		})
+	})
+})
@@ -0,0 +1,145 @@
+// Package spool keeps request bodies out of memory while routers verify them.
+//
+// By default, routers read whole request bodies into memory to verify their signatures, and then copy them again to parse them.
+// A BodySpooler given by `routeroptions.WithBodySpooler` makes routers write bodies to another storage during verification instead,
+// so that memory stays bounded for requests that fail verification (e.g. forged, very large ones):
+//
+//	r, err := eventrouter.New(eventrouter.WithShared(
+//		routeroptions.WithSigningSecret(secret),
+//		routeroptions.WithBodySpooler(spool.TempFile(64*1024, "")),
+//	))
+//
+// Note that verified bodies are still read into memory once, since slack-go parses events from byte slices.
+package spool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// DefaultMaxSize is the default maximum size of bodies that TempFile stores. See WithMaxSize.
+const DefaultMaxSize = 16 * 1024 * 1024
+
+// ErrTooLarge indicates that a body exceeds the limit set by WithMaxSize.
+//
+// Spoolers return errors that wrap this together with `errors.HttpError(http.StatusRequestEntityTooLarge)`,
+// so that routers respond with Request Entity Too Large.
+var ErrTooLarge = errors.New("body too large to spool")
+
+// BodySpooler stores request bodies.
+type BodySpooler interface {
+	// Spool reads `r` until EOF and returns a reader that reads the same content from the beginning.
+	// Routers close the returned reader after they finish processing the request, so that the storage can be released.
+	Spool(ctx context.Context, r io.Reader) (io.ReadCloser, error)
+}
+
+type BodySpoolerFunc func(ctx context.Context, r io.Reader) (io.ReadCloser, error)
+
+func (f BodySpoolerFunc) Spool(ctx context.Context, r io.Reader) (io.ReadCloser, error) {
+	return f(ctx, r)
+}
+
+// Option configures BodySpoolers.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+type config struct {
+	maxSize int64
+}
+
+// WithMaxSize sets the maximum size of bodies (DefaultMaxSize by default).
+// Spoolers stop reading bodies that exceed the limit and return an error that wraps ErrTooLarge.
+//
+// Since bodies are spooled before their signatures are verified, setting 0 or less (i.e. no limit) lets anyone fill the disk.
+func WithMaxSize(n int64) Option {
+	return optionFunc(func(c *config) {
+		c.maxSize = n
+	})
+}
+
+// TempFile returns a BodySpooler that keeps bodies up to `threshold` bytes in memory, and spills larger ones to temporary files in `dir`.
+//
+// If `dir` is empty, the default directory for temporary files (see `os.TempDir`) is used.
+// Temporary files are removed when the returned readers are closed.
+func TempFile(threshold int64, dir string, opts ...Option) BodySpooler {
+	c := &config{maxSize: DefaultMaxSize}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	if c.maxSize > 0 && threshold > c.maxSize {
+		threshold = c.maxSize
+	}
+	return BodySpoolerFunc(func(_ context.Context, r io.Reader) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		n, err := io.Copy(&buf, io.LimitReader(r, threshold+1))
+		if err != nil {
+			return nil, err
+		}
+		if n <= threshold {
+			return io.NopCloser(&buf), nil
+		}
+		if n > c.maxSize && c.maxSize > 0 {
+			return nil, c.tooLarge()
+		}
+		return c.spill(dir, io.MultiReader(&buf, r))
+	})
+}
+
+func (c *config) tooLarge() error {
+	return routererrors.WithStatus(fmt.Errorf("exceeds %d bytes: %w", c.maxSize, ErrTooLarge), http.StatusRequestEntityTooLarge)
+}
+
+func (c *config) spill(dir string, r io.Reader) (io.ReadCloser, error) {
+	f, err := os.CreateTemp(dir, "slack-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	body := &fileBody{f: f}
+	if c.maxSize > 0 {
+		r = io.LimitReader(r, c.maxSize+1)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if c.maxSize > 0 && n > c.maxSize {
+		body.Close()
+		return nil, c.tooLarge()
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to rewind spool file: %w", err)
+	}
+	return body, nil
+}
+
+type fileBody struct {
+	f *os.File
+}
+
+func (b *fileBody) Read(p []byte) (int, error) {
+	return b.f.Read(p)
+}
+
+func (b *fileBody) Close() error {
+	err := b.f.Close()
+	if rmErr := os.Remove(b.f.Name()); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		return rmErr
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package spool_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/spool"
+)
+
+var _ = Describe("Spool", func() {
+	Describe("TempFile", func() {
+		var (
+			dir     string
+			spooler spool.BodySpooler
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "spool-test-")
+			Expect(err).NotTo(HaveOccurred())
+			spooler = spool.TempFile(8, dir)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		tempFiles := func() []os.DirEntry {
+			files, err := os.ReadDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+			return files
+		}
+
+		Context("when the body is not larger than the threshold", func() {
+			It("keeps it in memory", func() {
+				body, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("12345678")))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tempFiles()).To(BeEmpty())
+				data, err := io.ReadAll(body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("12345678"))
+				Expect(body.Close()).To(Succeed())
+			})
+		})
+
+		Context("when the body is larger than the threshold", func() {
+			It("spills it to a temporary file, and removes the file when closed", func() {
+				body, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("123456789")))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tempFiles()).To(HaveLen(1))
+				data, err := io.ReadAll(body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("123456789"))
+				Expect(body.Close()).To(Succeed())
+				Expect(tempFiles()).To(BeEmpty())
+			})
+		})
+
+		Context("when the body is larger than WithMaxSize", func() {
+			It("returns ErrTooLarge without leaving temporary files", func() {
+				spooler = spool.TempFile(8, dir, spool.WithMaxSize(16))
+				_, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("12345678901234567")))
+				Expect(errors.Is(err, spool.ErrTooLarge)).To(BeTrue())
+				var status routererrors.HttpError
+				Expect(errors.As(err, &status)).To(BeTrue())
+				Expect(status).To(Equal(routererrors.HttpError(http.StatusRequestEntityTooLarge)))
+				Expect(tempFiles()).To(BeEmpty())
+			})
+
+			It("returns ErrTooLarge even if the threshold is larger than the limit", func() {
+				spooler = spool.TempFile(1024, dir, spool.WithMaxSize(16))
+				_, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("12345678901234567")))
+				Expect(errors.Is(err, spool.ErrTooLarge)).To(BeTrue())
+			})
+
+			It("accepts bodies up to the limit", func() {
+				spooler = spool.TempFile(8, dir, spool.WithMaxSize(16))
+				body, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("1234567890123456")))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body.Close()).To(Succeed())
+			})
+		})
+
+		Context("when the directory doesn't exist", func() {
+			It("returns an error", func() {
+				spooler = spool.TempFile(8, dir+"/no-such-dir")
+				_, err := spooler.Spool(context.Background(), bytes.NewReader([]byte("123456789")))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
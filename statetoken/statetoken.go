@@ -0,0 +1,158 @@
+// Package statetoken provides signed tokens to embed small states into interactive components.
+//
+// Values of buttons, action IDs and `private_metadata` are sent back from Slack as they are,
+// but nothing prevents users from forging interaction payloads. Signing states prevents handlers from trusting forged ones:
+//
+//	signer := statetoken.New(secret)
+//	button := slack.NewButtonBlockElement("approve", signer.Sign(requestID), text)
+//
+//	// in the handler of `block_actions`
+//	requestID, err := signer.Verify(action.Value)
+//	if err != nil {
+//		return err // the Router responds with Unauthorized
+//	}
+//
+// Tokens are bound to the purpose given by WithPurpose, so a token issued for one component can't be replayed to another
+// that shares the secret, and they can be made to expire by WithTTL.
+package statetoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// ErrInvalidToken indicates that the token is malformed or its signature does not match.
+//
+// Errors returned by Verify wrap this, and are also treated as `routererrors.HttpError(http.StatusUnauthorized)`,
+// so handlers can return them as is to make routers respond with Unauthorized.
+var ErrInvalidToken = errors.New("invalid state token")
+
+// ErrExpiredToken indicates that the token is older than the TTL set by WithTTL. Errors that wrap this also wrap ErrInvalidToken.
+var ErrExpiredToken = fmt.Errorf("expired: %w", ErrInvalidToken)
+
+var encoding = base64.RawURLEncoding
+
+// Option configures a Signer.
+type Option interface {
+	apply(*Signer)
+}
+
+type optionFunc func(*Signer)
+
+func (f optionFunc) apply(s *Signer) {
+	f(s)
+}
+
+// WithPurpose binds tokens to `purpose` (e.g. the action ID of a button).
+//
+// Tokens signed for a purpose are rejected by Signers with other purposes, even if they share the same secret.
+func WithPurpose(purpose string) Option {
+	return optionFunc(func(s *Signer) {
+		s.purpose = purpose
+	})
+}
+
+// WithTTL makes the Signer reject tokens that were issued more than `ttl` ago.
+// If not set (or `ttl` is not positive), tokens don't expire.
+func WithTTL(ttl time.Duration) Option {
+	return optionFunc(func(s *Signer) {
+		s.ttl = ttl
+	})
+}
+
+// WithNowFunc sets a function that returns the current time. The default is `time.Now`.
+func WithNowFunc(now func() time.Time) Option {
+	return optionFunc(func(s *Signer) {
+		s.now = now
+	})
+}
+
+// Signer signs and verifies state tokens.
+type Signer struct {
+	secret  []byte
+	purpose string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// New creates a new Signer that signs tokens with `secret`.
+func New(secret []byte, opts ...Option) *Signer {
+	s := &Signer{secret: secret, now: time.Now}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	return s
+}
+
+// Sign returns a token that contains `state`, the time when it is issued and their signature.
+//
+// Note that the token is not encrypted; anyone who obtains the token can read `state`.
+func (s *Signer) Sign(state string) string {
+	payload := encoding.EncodeToString([]byte(state)) + "." + strconv.FormatInt(s.now().Unix(), 36)
+	return payload + "." + encoding.EncodeToString(s.mac(payload))
+}
+
+// Verify verifies `token` and returns the state in it.
+//
+// It fails with ErrExpiredToken if the token has expired (see WithTTL), or with ErrInvalidToken for other reasons.
+func (s *Signer) Verify(token string) (string, error) {
+	idx := strings.LastIndex(token, ".")
+	if idx < 0 {
+		return "", invalid("missing signature")
+	}
+	payload, sig := token[:idx], token[idx+1:]
+	rawSig, err := encoding.DecodeString(sig)
+	if err != nil {
+		return "", invalid("malformed signature")
+	}
+	if !hmac.Equal(rawSig, s.mac(payload)) {
+		return "", invalid("signature mismatch")
+	}
+	encodedState, encodedIssuedAt, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", invalid("missing issued-at")
+	}
+	issuedAt, err := strconv.ParseInt(encodedIssuedAt, 36, 64)
+	if err != nil {
+		return "", invalid("malformed issued-at")
+	}
+	if s.ttl > 0 && s.now().Sub(time.Unix(issuedAt, 0)) > s.ttl {
+		return "", routererrors.WithStatus(ErrExpiredToken, http.StatusUnauthorized)
+	}
+	state, err := encoding.DecodeString(encodedState)
+	if err != nil {
+		return "", invalid("malformed payload")
+	}
+	return string(state), nil
+}
+
+// Encode is the same as Sign except that it has the signature of encoders of `interactionrouter.WithPrivateMetadataCodec`.
+func (s *Signer) Encode(state string) (string, error) {
+	return s.Sign(state), nil
+}
+
+// Decode is the same as Verify. This can be used as decoders of `interactionrouter.WithPrivateMetadataCodec`.
+func (s *Signer) Decode(token string) (string, error) {
+	return s.Verify(token)
+}
+
+func (s *Signer) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	// The length prefix keeps purposes from being confused with payloads.
+	_, _ = mac.Write([]byte(strconv.Itoa(len(s.purpose)) + ":" + s.purpose))
+	_, _ = mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func invalid(reason string) error {
+	return routererrors.WithStatus(fmt.Errorf("%s: %w", reason, ErrInvalidToken), http.StatusUnauthorized)
+}
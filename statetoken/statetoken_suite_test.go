@@ -0,0 +1,13 @@
+package statetoken_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestStatetoken(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Statetoken Suite")
+}
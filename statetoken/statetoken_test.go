@@ -0,0 +1,98 @@
+package statetoken_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/statetoken"
+)
+
+var _ = Describe("Statetoken", func() {
+	var signer *statetoken.Signer
+	BeforeEach(func() {
+		signer = statetoken.New([]byte("THE_SECRET"))
+	})
+
+	Context("when the token is signed by the same secret", func() {
+		It("returns the original state", func() {
+			token := signer.Sign(`{"request_id": 123}`)
+			state, err := signer.Verify(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(`{"request_id": 123}`))
+		})
+	})
+
+	Context("when the token is signed by another secret", func() {
+		It("returns ErrInvalidToken", func() {
+			token := statetoken.New([]byte("ANOTHER_SECRET")).Sign("123")
+			_, err := signer.Verify(token)
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+		})
+	})
+
+	Context("when the state is forged", func() {
+		It("returns an error that is treated as Unauthorized", func() {
+			token := signer.Sign("123")
+			forged := base64.RawURLEncoding.EncodeToString([]byte("456")) + token[strings.Index(token, "."):]
+			_, err := signer.Verify(forged)
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+			var httpErr routererrors.HttpError
+			Expect(errors.As(err, &httpErr)).To(BeTrue())
+			Expect(httpErr).To(Equal(routererrors.HttpError(http.StatusUnauthorized)))
+		})
+	})
+
+	Context("when the token is signed for another purpose", func() {
+		It("returns ErrInvalidToken", func() {
+			secret := []byte("THE_SECRET")
+			token := statetoken.New(secret, statetoken.WithPurpose("approve")).Sign("123")
+			_, err := statetoken.New(secret, statetoken.WithPurpose("reject")).Verify(token)
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+			state, err := statetoken.New(secret, statetoken.WithPurpose("approve")).Verify(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal("123"))
+		})
+	})
+
+	Context("when WithTTL is given", func() {
+		var now time.Time
+		BeforeEach(func() {
+			now = time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+			signer = statetoken.New([]byte("THE_SECRET"), statetoken.WithTTL(time.Hour),
+				statetoken.WithNowFunc(func() time.Time { return now }))
+		})
+
+		It("accepts tokens within the TTL", func() {
+			token := signer.Sign("123")
+			now = now.Add(time.Hour)
+			state, err := signer.Verify(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal("123"))
+		})
+
+		It("rejects expired tokens with ErrExpiredToken", func() {
+			token := signer.Sign("123")
+			now = now.Add(time.Hour + time.Second)
+			_, err := signer.Verify(token)
+			Expect(err).To(MatchError(statetoken.ErrExpiredToken))
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+			Expect(errors.Is(err, routererrors.HttpError(http.StatusUnauthorized))).To(BeTrue())
+		})
+	})
+
+	Context("when the token is malformed", func() {
+		It("returns ErrInvalidToken", func() {
+			_, err := signer.Verify("no signature")
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+			_, err = signer.Verify("payload.!!!")
+			Expect(err).To(MatchError(statetoken.ErrInvalidToken))
+		})
+	})
+})
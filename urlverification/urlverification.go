@@ -5,8 +5,15 @@ package urlverification
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 
 	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
 )
 
 // Handler processes `url_verification` events.
@@ -26,3 +33,50 @@ var DefaultHandler Handler = HandlerFunc(func(_ context.Context, e *slackevents.
 		Challenge: e.Challenge,
 	}, nil
 })
+
+// StrictHandler returns a handler that echoes back the given challenge value only if the token of the event equals to `verificationToken`.
+// Otherwise it returns `routererrors.HttpError(http.StatusUnauthorized)`.
+//
+// The verification token can be found in the "Basic Information" page of your app's settings.
+func StrictHandler(verificationToken string) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIURLVerificationEvent) (*slackevents.ChallengeResponse, error) {
+		if verificationToken == "" || subtle.ConstantTimeCompare([]byte(e.Token), []byte(verificationToken)) != 1 {
+			return nil, fmt.Errorf("invalid verification token: %w", routererrors.HttpError(http.StatusUnauthorized))
+		}
+		return DefaultHandler.HandleURLVerification(ctx, e)
+	})
+}
+
+// DisabledHandler rejects every `url_verification` event with `routererrors.HttpError(http.StatusNotFound)`.
+//
+// This is useful when you want to accept URL verification only during certain periods (e.g. install windows).
+var DisabledHandler Handler = HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIURLVerificationEvent) (*slackevents.ChallengeResponse, error) {
+	return nil, fmt.Errorf("url_verification is disabled: %w", routererrors.HttpError(http.StatusNotFound))
+})
+
+// Responder writes a response to a `url_verification` event.
+type Responder interface {
+	RespondURLVerification(http.ResponseWriter, *slackevents.ChallengeResponse) error
+}
+
+type ResponderFunc func(http.ResponseWriter, *slackevents.ChallengeResponse) error
+
+func (f ResponderFunc) RespondURLVerification(w http.ResponseWriter, resp *slackevents.ChallengeResponse) error {
+	return f(w, resp)
+}
+
+// JSONResponder responds with the challenge value in JSON (e.g. `{"challenge":"..."}`).
+// This is the default responder that the Router uses.
+var JSONResponder Responder = ResponderFunc(func(w http.ResponseWriter, resp *slackevents.ChallengeResponse) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(resp)
+})
+
+// PlainTextResponder responds with the raw challenge value in `text/plain`.
+//
+// Slack accepts both JSON and plain text responses. This is useful when middleboxes mangle JSON responses.
+var PlainTextResponder Responder = ResponderFunc(func(w http.ResponseWriter, resp *slackevents.ChallengeResponse) error {
+	w.Header().Set("Content-Type", "text/plain")
+	_, err := io.WriteString(w, resp.Challenge)
+	return err
+})
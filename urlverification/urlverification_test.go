@@ -2,11 +2,15 @@ package urlverification_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/slack-go/slack/slackevents"
 
+	routererrors "github.com/genkami/go-slack-event-router/errors"
 	"github.com/genkami/go-slack-event-router/urlverification"
 )
 
@@ -20,4 +24,71 @@ var _ = Describe("URLVerification", func() {
 			Expect(resp).To(Equal(&slackevents.ChallengeResponse{Challenge: "hello"}))
 		})
 	})
+
+	Describe("StrictHandler", func() {
+		Context("when the token matches to the verification token", func() {
+			It("returns the given challenge", func() {
+				ctx := context.Background()
+				e := &slackevents.EventsAPIURLVerificationEvent{Token: "THE_TOKEN", Challenge: "hello"}
+				resp, err := urlverification.StrictHandler("THE_TOKEN").HandleURLVerification(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).To(Equal(&slackevents.ChallengeResponse{Challenge: "hello"}))
+			})
+		})
+
+		Context("when the token differs from the verification token", func() {
+			It("returns Unauthorized", func() {
+				ctx := context.Background()
+				e := &slackevents.EventsAPIURLVerificationEvent{Token: "WRONG_TOKEN", Challenge: "hello"}
+				_, err := urlverification.StrictHandler("THE_TOKEN").HandleURLVerification(ctx, e)
+				var httpErr routererrors.HttpError
+				Expect(errors.As(err, &httpErr)).To(BeTrue())
+				Expect(httpErr).To(Equal(routererrors.HttpError(http.StatusUnauthorized)))
+			})
+		})
+
+		Context("when the verification token is empty", func() {
+			It("returns Unauthorized", func() {
+				ctx := context.Background()
+				e := &slackevents.EventsAPIURLVerificationEvent{Token: "", Challenge: "hello"}
+				_, err := urlverification.StrictHandler("").HandleURLVerification(ctx, e)
+				var httpErr routererrors.HttpError
+				Expect(errors.As(err, &httpErr)).To(BeTrue())
+				Expect(httpErr).To(Equal(routererrors.HttpError(http.StatusUnauthorized)))
+			})
+		})
+	})
+
+	Describe("DisabledHandler", func() {
+		It("returns NotFound", func() {
+			ctx := context.Background()
+			e := &slackevents.EventsAPIURLVerificationEvent{Challenge: "hello"}
+			_, err := urlverification.DisabledHandler.HandleURLVerification(ctx, e)
+			var httpErr routererrors.HttpError
+			Expect(errors.As(err, &httpErr)).To(BeTrue())
+			Expect(httpErr).To(Equal(routererrors.HttpError(http.StatusNotFound)))
+		})
+	})
+
+	Describe("JSONResponder", func() {
+		It("writes the challenge in JSON", func() {
+			w := httptest.NewRecorder()
+			err := urlverification.JSONResponder.RespondURLVerification(w, &slackevents.ChallengeResponse{Challenge: "hello"})
+			Expect(err).NotTo(HaveOccurred())
+			resp := w.Result()
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(w.Body.String()).To(MatchJSON(`{"Challenge": "hello"}`))
+		})
+	})
+
+	Describe("PlainTextResponder", func() {
+		It("writes the raw challenge", func() {
+			w := httptest.NewRecorder()
+			err := urlverification.PlainTextResponder.RespondURLVerification(w, &slackevents.ChallengeResponse{Challenge: "hello"})
+			Expect(err).NotTo(HaveOccurred())
+			resp := w.Result()
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain"))
+			Expect(w.Body.String()).To(Equal("hello"))
+		})
+	})
 })
@@ -0,0 +1,131 @@
+package eventrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SubscriptionSource provides event types that the Slack app is subscribed to.
+type SubscriptionSource interface {
+	SubscribedEvents(ctx context.Context) ([]string, error)
+}
+
+// ValidationReport describes inconsistencies between the Router and the configuration of the Slack app.
+type ValidationReport struct {
+	// Unsubscribed are event types that have handlers but the app is not subscribed to.
+	// Handlers for these events will never be called.
+	Unsubscribed []string
+
+	// Unhandled are event types that the app is subscribed to but have no handlers.
+	// This is always empty if the Router has fallback handlers.
+	Unhandled []string
+}
+
+// OK returns true if and only if there are no inconsistencies.
+func (r *ValidationReport) OK() bool {
+	return len(r.Unsubscribed) == 0 && len(r.Unhandled) == 0
+}
+
+// Validate compares event types that have handlers with ones that the Slack app is subscribed to.
+//
+// This is intended to be called on startup to find misconfigurations early. Typically `source` is a ManifestSource.
+func (r *Router) Validate(ctx context.Context, source SubscriptionSource) (*ValidationReport, error) {
+	subscribed, err := source.SubscribedEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed events: %w", err)
+	}
+	subscribedSet := make(map[string]struct{}, len(subscribed))
+	for _, eventType := range subscribed {
+		subscribedSet[eventType] = struct{}{}
+	}
+
+	report := &ValidationReport{}
+	for eventType := range r.callbackHandlers {
+		if _, ok := subscribedSet[eventType]; !ok {
+			report.Unsubscribed = append(report.Unsubscribed, eventType)
+		}
+	}
+	if len(r.fallbackHandlers) == 0 {
+		for eventType := range subscribedSet {
+			if _, ok := r.callbackHandlers[eventType]; !ok {
+				report.Unhandled = append(report.Unhandled, eventType)
+			}
+		}
+	}
+	sort.Strings(report.Unsubscribed)
+	sort.Strings(report.Unhandled)
+	return report, nil
+}
+
+// DefaultSlackAPIURL is the default base URL of Slack Web API.
+const DefaultSlackAPIURL = "https://slack.com/api/"
+
+// ManifestSource is a SubscriptionSource that reads event subscriptions from the app manifest via `apps.manifest.export`.
+//
+// For more details, see https://api.slack.com/methods/apps.manifest.export.
+type ManifestSource struct {
+	// Token is an app configuration token.
+	Token string
+
+	// AppID is the ID of the app.
+	AppID string
+
+	// HTTPClient is used to call Slack API. If nil, `http.DefaultClient` is used.
+	HTTPClient *http.Client
+
+	// APIURL is the base URL of Slack Web API. If empty, DefaultSlackAPIURL is used.
+	APIURL string
+}
+
+func (s *ManifestSource) SubscribedEvents(ctx context.Context) ([]string, error) {
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	apiURL := s.APIURL
+	if apiURL == "" {
+		apiURL = DefaultSlackAPIURL
+	}
+	form := url.Values{}
+	form.Set("app_id", s.AppID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"apps.manifest.export", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body := struct {
+		OK       bool   `json:"ok"`
+		Error    string `json:"error"`
+		Manifest struct {
+			Settings struct {
+				EventSubscriptions struct {
+					BotEvents  []string `json:"bot_events"`
+					UserEvents []string `json:"user_events"`
+				} `json:"event_subscriptions"`
+			} `json:"settings"`
+		} `json:"manifest"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse apps.manifest.export response: %w", err)
+	}
+	if !body.OK {
+		return nil, fmt.Errorf("apps.manifest.export failed: %s", body.Error)
+	}
+	subscriptions := body.Manifest.Settings.EventSubscriptions
+	events := make([]string, 0, len(subscriptions.BotEvents)+len(subscriptions.UserEvents))
+	events = append(events, subscriptions.BotEvents...)
+	events = append(events, subscriptions.UserEvents...)
+	return events, nil
+}
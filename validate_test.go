@@ -0,0 +1,100 @@
+package eventrouter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/appmention"
+	"github.com/genkami/go-slack-event-router/message"
+)
+
+type staticSubscriptions []string
+
+func (s staticSubscriptions) SubscribedEvents(_ context.Context) ([]string, error) {
+	return s, nil
+}
+
+var _ = Describe("Validate", func() {
+	var (
+		r   *eventrouter.Router
+		ctx context.Context
+	)
+	BeforeEach(func() {
+		var err error
+		r, err = eventrouter.New(eventrouter.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		ctx = context.Background()
+		r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error { return nil }))
+		r.OnAppMention(appmention.HandlerFunc(func(_ context.Context, _ *slackevents.AppMentionEvent) error { return nil }))
+	})
+
+	Context("when the subscriptions match to the handlers", func() {
+		It("reports nothing", func() {
+			report, err := r.Validate(ctx, staticSubscriptions{"message", "app_mention"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.OK()).To(BeTrue())
+		})
+	})
+
+	Context("when the subscriptions differ from the handlers", func() {
+		It("reports the differences", func() {
+			report, err := r.Validate(ctx, staticSubscriptions{"message", "reaction_added"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.OK()).To(BeFalse())
+			Expect(report.Unsubscribed).To(Equal([]string{"app_mention"}))
+			Expect(report.Unhandled).To(Equal([]string{"reaction_added"}))
+		})
+	})
+
+	Context("when the Router has a fallback handler", func() {
+		It("does not report unhandled events", func() {
+			r.AddFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error { return nil }))
+			report, err := r.Validate(ctx, staticSubscriptions{"message", "app_mention", "reaction_added"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.OK()).To(BeTrue())
+		})
+	})
+
+	Describe("ManifestSource", func() {
+		var (
+			server       *httptest.Server
+			responseBody string
+			authHeader   string
+			appID        string
+		)
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				authHeader = req.Header.Get("Authorization")
+				appID = req.FormValue("app_id")
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(responseBody))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("returns events in the manifest", func() {
+			responseBody = `{"ok": true, "manifest": {"settings": {"event_subscriptions": {"bot_events": ["message"], "user_events": ["reaction_added"]}}}}`
+			source := &eventrouter.ManifestSource{Token: "xoxe-TOKEN", AppID: "A123", APIURL: server.URL + "/"}
+			events, err := source.SubscribedEvents(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(Equal([]string{"message", "reaction_added"}))
+			Expect(authHeader).To(Equal("Bearer xoxe-TOKEN"))
+			Expect(appID).To(Equal("A123"))
+		})
+
+		It("returns an error when the API fails", func() {
+			responseBody = `{"ok": false, "error": "invalid_auth"}`
+			source := &eventrouter.ManifestSource{Token: "xoxe-TOKEN", AppID: "A123", APIURL: server.URL + "/"}
+			_, err := source.SubscribedEvents(ctx)
+			Expect(err).To(MatchError(MatchRegexp("invalid_auth")))
+		})
+	})
+})
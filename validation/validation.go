@@ -0,0 +1,31 @@
+// Package validation provides errors to report invalid inputs of `view_submission` callbacks.
+//
+// For more details, see https://api.slack.com/surfaces/modals/using#displaying_errors.
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Errors maps block IDs to error messages that are displayed to users.
+//
+// When a handler of `interactionrouter.Router` returns Errors (or its equivalents in the sense of `errors.As`),
+// the Router responds with `response_action: errors` so that Slack shows the messages next to the corresponding input blocks.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	blockIDs := make([]string, 0, len(e))
+	for blockID := range e {
+		blockIDs = append(blockIDs, blockID)
+	}
+	sort.Strings(blockIDs)
+	msgs := make([]string, 0, len(blockIDs))
+	for _, blockID := range blockIDs {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", blockID, e[blockID]))
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+var _ error = Errors{}
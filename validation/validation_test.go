@@ -0,0 +1,20 @@
+package validation_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/validation"
+)
+
+var _ = Describe("Validation", func() {
+	Describe("Errors", func() {
+		It("describes all errors in the order of block IDs", func() {
+			err := validation.Errors{
+				"title_block": "too long",
+				"date_block":  "must be in the future",
+			}
+			Expect(err.Error()).To(Equal("validation failed: date_block: must be in the future, title_block: too long"))
+		})
+	})
+})